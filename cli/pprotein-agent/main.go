@@ -1,15 +1,65 @@
 package main
 
 import (
+	"flag"
+	"log"
 	"os"
+	"strings"
 
+	"github.com/kaz/pprotein/integration"
 	"github.com/kaz/pprotein/integration/standalone"
 )
 
 func main() {
+	token := flag.String("token", os.Getenv("PPROTEIN_AGENT_TOKEN"), "shared secret required on every agent request")
+	tlsOpts := &standalone.TLSOptions{}
+	flag.StringVar(&tlsOpts.CertFile, "tls-cert", os.Getenv("PPROTEIN_AGENT_TLS_CERT"), "server certificate file; enables TLS")
+	flag.StringVar(&tlsOpts.KeyFile, "tls-key", os.Getenv("PPROTEIN_AGENT_TLS_KEY"), "server private key file")
+	flag.StringVar(&tlsOpts.ClientCAFile, "tls-client-ca", os.Getenv("PPROTEIN_AGENT_TLS_CLIENT_CA"), "CA file used to verify client certificates; enables mTLS")
+	pushOpts := &integration.PushOptions{}
+	flag.StringVar(&pushOpts.Server, "push", os.Getenv("PPROTEIN_PUSH_SERVER"), "pprotein server URL to push collected data to")
+	flag.StringVar(&pushOpts.Name, "name", os.Getenv("PPROTEIN_AGENT_NAME"), "agent name registered to the server (defaults to hostname)")
+	flag.StringVar(&pushOpts.URL, "advertise", os.Getenv("PPROTEIN_AGENT_ADVERTISE"), "URL the server can reach this agent at; registers as collect targets instead of pushing")
+	flag.StringVar(&pushOpts.Token, "push-token", os.Getenv("PPROTEIN_PUSH_TOKEN"), "token presented to the server in push mode")
+	pushTypes := flag.String("push-types", "pprof,httplog,slowlog", "comma separated types collected in push mode")
+	flag.IntVar(&pushOpts.Duration, "push-duration", 30, "collection duration in seconds in push mode")
+	flag.DurationVar(&pushOpts.Interval, "push-interval", 0, "collect and push periodically in addition to server commands")
+	logFilters := map[string][2]*string{}
+	for _, name := range []string{"httplog", "slowlog", "pgslowlog"} {
+		prefix := "PPROTEIN_" + strings.ToUpper(name)
+		logFilters[name] = [2]*string{
+			flag.String(name+"-include", os.Getenv(prefix+"_INCLUDE"), "only serve "+name+" entries matching this regexp"),
+			flag.String(name+"-exclude", os.Getenv(prefix+"_EXCLUDE"), "drop "+name+" entries matching this regexp"),
+		}
+	}
+	flag.Parse()
+
+	for name, filter := range logFilters {
+		if err := integration.SetLogFilter(name, *filter[0], *filter[1]); err != nil {
+			log.Fatalf("invalid %v filter: %v", name, err)
+		}
+	}
+
+	if *token == "" {
+		log.Println("[!] no agent token configured, endpoints are open to anyone who can reach the port")
+	}
+	integration.SetAgentToken(*token)
+
+	if pushOpts.Server != "" {
+		if pushOpts.Name == "" {
+			pushOpts.Name, _ = os.Hostname()
+		}
+		pushOpts.Types = strings.Split(*pushTypes, ",")
+		go func() {
+			if err := integration.Push(pushOpts); err != nil {
+				log.Fatalf("failed to start push mode: %v", err)
+			}
+		}()
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "19000"
 	}
-	standalone.Integrate(":" + port)
+	standalone.IntegrateTLS(":"+port, tlsOpts)
 }
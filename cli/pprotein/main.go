@@ -1,88 +1,356 @@
 package main
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kaz/pprotein/integration/echov4"
+	"github.com/kaz/pprotein/internal/alert"
+	"github.com/kaz/pprotein/internal/auth"
 	"github.com/kaz/pprotein/internal/collect"
 	"github.com/kaz/pprotein/internal/collect/group"
+	"github.com/kaz/pprotein/internal/collect/health"
+	"github.com/kaz/pprotein/internal/collect/push"
+	"github.com/kaz/pprotein/internal/collect/run"
 	"github.com/kaz/pprotein/internal/event"
+	"github.com/kaz/pprotein/internal/export"
+	"github.com/kaz/pprotein/internal/extproc"
 	"github.com/kaz/pprotein/internal/extproc/alp"
+	_ "github.com/kaz/pprotein/internal/extproc/command"
+	"github.com/kaz/pprotein/internal/extproc/pgslow"
 	"github.com/kaz/pprotein/internal/extproc/slp"
+	"github.com/kaz/pprotein/internal/goroutine"
 	"github.com/kaz/pprotein/internal/memo"
+	"github.com/kaz/pprotein/internal/metrics"
+	"github.com/kaz/pprotein/internal/openapi"
 	"github.com/kaz/pprotein/internal/pprof"
+	"github.com/kaz/pprotein/internal/project"
+	"github.com/kaz/pprotein/internal/runtimestats"
 	"github.com/kaz/pprotein/internal/storage"
+	"github.com/kaz/pprotein/internal/sysmetrics"
+	"github.com/kaz/pprotein/internal/trace"
+	"github.com/kaz/pprotein/internal/tracing"
+	"github.com/kaz/pprotein/internal/webhook"
 	"github.com/kaz/pprotein/view"
 	"github.com/labstack/echo/v4"
 )
 
+type (
+	server struct {
+		port      string
+		publicURL string
+		authn     *auth.Authenticator
+		pool      *collect.WorkerPool
+		retry     *collect.RetryPolicy
+		quota     *collect.Quota
+		pprofTool *pprof.ToolOptions
+
+		echo *echo.Echo
+		main *instance
+	}
+
+	instance struct {
+		registry     *collect.Registry
+		hub          *event.Hub
+		runs         *run.Handler
+		orchestrator *run.Orchestrator
+	}
+)
+
 func start() error {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "9000"
 	}
 
-	store, err := storage.New("data")
+	if err := configureTracing(); err != nil {
+		return err
+	}
+
+	s, err := newServer(port, "data")
 	if err != nil {
 		return err
 	}
+	return s.echo.Start(":" + port)
+}
 
+func newServer(port string, workdir string) (*server, error) {
 	e := echo.New()
 	echov4.Integrate(e)
 
 	fs, err := view.FS()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	e.GET("/*", echo.WrapHandler(http.FileServer(http.FS(fs))))
 
-	api := e.Group("/api", func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			c.Response().Header().Set("Cache-Control", "no-store")
-			return next(c)
+	publicURL := os.Getenv("PPROTEIN_PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost:" + port
+	}
+	authn, err := newAuthenticator(publicURL)
+	if err != nil {
+		return nil, err
+	}
+
+	workers, err := strconv.Atoi(os.Getenv("PPROTEIN_WORKERS"))
+	if err != nil {
+		workers = 0
+	}
+	pool := collect.NewWorkerPool(workers)
+
+	retry, err := retryPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := diskQuota()
+	if err != nil {
+		return nil, err
+	}
+
+	pprofTool, err := toolOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &server{
+		port:      port,
+		publicURL: publicURL,
+		authn:     authn,
+		pool:      pool,
+		retry:     retry,
+		quota:     quota,
+		pprofTool: pprofTool,
+		echo:      e,
+	}
+
+	api := e.Group("/api", noStore, authn.Middleware)
+	authn.RegisterHandlers(api.Group("/auth"))
+
+	store, err := newStore(workdir, "")
+	if err != nil {
+		return nil, err
+	}
+	if s.main, err = s.mount(api, store, workdir, project.Default); err != nil {
+		return nil, err
+	}
+	metrics.NewHandler(s.main.registry, s.main.hub).RegisterHandlers(e, authn.Middleware)
+
+	projects, err := project.NewManager(store, e, func(name string) (http.Handler, error) {
+		workdir := path.Join(workdir, "projects", name)
+		store, err := newStore(workdir, path.Join("projects", name))
+		if err != nil {
+			return nil, err
+		}
+
+		pe := echo.New()
+		pe.Debug = e.Debug
+		if _, err := s.mount(pe.Group("/api", noStore, authn.Middleware), store, workdir, name); err != nil {
+			return nil, err
 		}
+		return pe, nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	projects.RegisterHandlers(e, api.Group("/projects"))
+	e.Pre(projects.Select)
+
+	return s, nil
+}
+
+func (s *server) mount(api *echo.Group, store storage.Storage, workdir string, name string) (*instance, error) {
+	endpoint := fmt.Sprintf("http://localhost:%s/api", s.port)
+	if name != project.Default {
+		endpoint += "/projects/" + name
+	}
 
 	hub := event.NewHub()
 	hub.RegisterHandlers(api.Group("/event"))
 
-	pprofOpts := &collect.Options{
-		Type:     "pprof",
-		Ext:      "-pprof.pb.gz",
-		Store:    store,
-		EventHub: hub,
+	registry := collect.NewRegistry()
+
+	webhooks, err := webhook.New(store)
+	if err != nil {
+		return nil, err
 	}
-	if err := pprof.NewHandler(pprofOpts).Register(api.Group("/pprof")); err != nil {
-		return err
+	webhooks.RegisterHandlers(api.Group("/webhooks"))
+
+	exporter, err := export.New(store, registry)
+	if err != nil {
+		return nil, err
 	}
+	exporter.RegisterHandlers(api.Group("/exporters"))
 
-	alpOpts := &collect.Options{
-		Type:     "httplog",
-		Ext:      "-httplog.log",
-		Store:    store,
-		EventHub: hub,
+	alerts, err := alert.New(store, registry, hub)
+	if err != nil {
+		return nil, err
+	}
+	alerts.RegisterHandlers(api.Group("/alerts"))
+
+	notifier := collect.Notifiers{webhooks, exporter, alerts}
+
+	index, err := collect.NewIndex(workdir)
+	if err != nil {
+		return nil, err
 	}
+	index.RegisterHandlers(api.Group("/history"))
+	index.RegisterSearchHandlers(api.Group("/search"))
+
+	options := func(typ string, ext string) (*collect.Options, error) {
+		retention, err := retentionPolicy(typ)
+		if err != nil {
+			return nil, err
+		}
+		client, err := clientOptions(typ)
+		if err != nil {
+			return nil, err
+		}
+		return &collect.Options{
+			Type:        typ,
+			Ext:         ext,
+			Store:       store,
+			EventHub:    hub,
+			Registry:    registry,
+			Index:       index,
+			Retention:   retention,
+			Pool:        s.pool,
+			Retry:       s.retry,
+			Quota:       s.quota,
+			Notifier:    notifier,
+			Client:      client,
+			Deduplicate: os.Getenv("PPROTEIN_DEDUPLICATE") == "true",
+		}, nil
+	}
+
+	pprofOpts, err := options("pprof", "-pprof.pb.gz")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.NewHandler(pprofOpts, s.pprofTool).Register(api.Group("/pprof")); err != nil {
+		return nil, err
+	}
+
+	fgprofOpts, err := options("fgprof", "-fgprof.pb.gz")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.NewHandler(fgprofOpts, s.pprofTool).Register(api.Group("/fgprof")); err != nil {
+		return nil, err
+	}
+
+	blockOpts, err := options("block", "-block.pb.gz")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.NewHandler(blockOpts, s.pprofTool).Register(api.Group("/block")); err != nil {
+		return nil, err
+	}
+
+	mutexOpts, err := options("mutex", "-mutex.pb.gz")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.NewHandler(mutexOpts, s.pprofTool).Register(api.Group("/mutex")); err != nil {
+		return nil, err
+	}
+
+	javaOpts, err := options("java", "-java.collapsed")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.NewHandler(javaOpts, s.pprofTool).Register(api.Group("/java")); err != nil {
+		return nil, err
+	}
+
+	nodeOpts, err := options("node", "-node.cpuprofile")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.NewHandler(nodeOpts, s.pprofTool).Register(api.Group("/node")); err != nil {
+		return nil, err
+	}
+
+	traceOpts, err := options("trace", "-trace.out")
+	if err != nil {
+		return nil, err
+	}
+	if err := trace.NewHandler(traceOpts).Register(api.Group("/trace")); err != nil {
+		return nil, err
+	}
+
+	alpOpts, err := options("httplog", "-httplog.log")
+	if err != nil {
+		return nil, err
+	}
+	alpOpts.Compress = os.Getenv("PPROTEIN_HTTPLOG_COMPRESS") == "true"
 	alpHandler, err := alp.NewHandler(alpOpts, store)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err := alpHandler.Register(api.Group("/httplog")); err != nil {
-		return err
+		return nil, err
 	}
 
-	slpOpts := &collect.Options{
-		Type:     "slowlog",
-		Ext:      "-slowlog.log",
-		Store:    store,
-		EventHub: hub,
+	slpOpts, err := options("slowlog", "-slowlog.log")
+	if err != nil {
+		return nil, err
 	}
-	slpHandler, err := slp.NewHandler(slpOpts, store)
+	slpOpts.Compress = os.Getenv("PPROTEIN_SLOWLOG_COMPRESS") == "true"
+	slpHandler, err := slp.NewHandler(slpOpts, store, os.Getenv("PPROTEIN_SLOWLOG_ANALYZER"))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err := slpHandler.Register(api.Group("/slowlog")); err != nil {
-		return err
+		return nil, err
+	}
+
+	pgslowlogOpts, err := options("pgslowlog", "-pgslowlog.log")
+	if err != nil {
+		return nil, err
+	}
+	pgslowlogOpts.Compress = os.Getenv("PPROTEIN_PGSLOWLOG_COMPRESS") == "true"
+	if err := extproc.NewHandler(extproc.QueryStage(pgslow.New()), pgslowlogOpts).Register(api.Group("/pgslowlog")); err != nil {
+		return nil, err
+	}
+
+	goroutineOpts, err := options("goroutine", "-goroutine.txt")
+	if err != nil {
+		return nil, err
+	}
+	goroutineOpts.Instant = true
+	if err := goroutine.NewHandler(goroutineOpts).Register(api.Group("/goroutine")); err != nil {
+		return nil, err
+	}
+
+	runtimeInterval := time.Second
+	if v := os.Getenv("PPROTEIN_RUNTIME_INTERVAL"); v != "" {
+		if runtimeInterval, err = time.ParseDuration(v); err != nil || runtimeInterval <= 0 {
+			return nil, fmt.Errorf("invalid PPROTEIN_RUNTIME_INTERVAL: %v", v)
+		}
+	}
+	runtimeOpts, err := options("runtime", "-runtime.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	runtimeOpts.Poll = runtimeInterval
+	if err := runtimestats.NewHandler(runtimeOpts).Register(api.Group("/runtime")); err != nil {
+		return nil, err
+	}
+
+	systemOpts, err := options("system", "-system.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	if err := sysmetrics.NewHandler(systemOpts).Register(api.Group("/system")); err != nil {
+		return nil, err
 	}
 
 	memoOpts := &collect.Options{
@@ -90,21 +358,343 @@ func start() error {
 		Ext:      "-memo.log",
 		Store:    store,
 		EventHub: hub,
+		Registry: registry,
+		Index:    index,
 	}
 	if err := memo.NewHandler(memoOpts).Register(api.Group("/memo")); err != nil {
-		return err
+		return nil, err
 	}
 
-	grp, err := group.NewCollector(store, port)
+	types, err := extproc.NewTypes(store, []string{"pprof", "fgprof", "block", "mutex", "java", "node", "trace", "httplog", "slowlog", "pgslowlog", "goroutine", "runtime", "system", "memo", "event", "history", "search", "group", "runs", "agents", "targets", "collect", "webhooks", "exporters", "alerts", "scores", "commits", "types", "auth", "projects"})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	types.RegisterHandlers(api.Group("/types"))
+
+	err = types.Register(api, func(cfg *extproc.TypeConfig) (*collect.Options, error) {
+		opts, err := options(cfg.Type, cfg.Extension())
+		if err != nil {
+			return nil, err
+		}
+		opts.Compress = cfg.Compress
+		return opts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	grp, err := group.NewCollector(store, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	for _, typ := range []string{"pprof", "fgprof", "block", "mutex", "java", "node", "pgslowlog", "goroutine", "runtime", "system", "memo"} {
+		grp.RegisterType(typ)
+	}
+	grp.RegisterType("trace", &group.Tool{Name: "go"})
+	grp.RegisterType("httplog", &group.Tool{Name: "alp", Optional: true})
+	if os.Getenv("PPROTEIN_SLOWLOG_ANALYZER") == "pt-query-digest" {
+		grp.RegisterType("slowlog", &group.Tool{Name: "pt-query-digest"})
+	} else {
+		grp.RegisterType("slowlog", &group.Tool{Name: "slp"})
+	}
+
+	customTypes, err := types.Configs()
+	if err != nil {
+		return nil, err
+	}
+	for _, cfg := range customTypes {
+		grp.RegisterType(cfg.Type)
 	}
+	grp.OnRequest(s.authn.Authorize)
 	grp.RegisterHandlers(api.Group("/group"))
 
-	return e.Start(":" + port)
+	agents := push.NewHub(registry, os.Getenv("PPROTEIN_PUSH_TOKEN"))
+	agents.RegisterHandlers(api.Group("/agents"))
+	agents.SyncTargets(grp)
+	grp.OnCollect(agents.Dispatch)
+
+	healthInterval := 30 * time.Second
+	if v := os.Getenv("PPROTEIN_HEALTH_INTERVAL"); v != "" {
+		if healthInterval, err = time.ParseDuration(v); err != nil || healthInterval <= 0 {
+			return nil, fmt.Errorf("invalid PPROTEIN_HEALTH_INTERVAL: %v", v)
+		}
+	}
+	prober := health.NewProber(registry, grp, agents, hub, healthInterval)
+	prober.RegisterHandlers(api.Group("/targets/health"))
+	prober.Start()
+
+	runs := run.NewHandler(registry)
+	runs.RegisterHandlers(api.Group("/runs"))
+	webhooks.WatchRuns(runs, s.publicURL)
+	orchestrator := run.NewOrchestrator(runs, grp, hub)
+	orchestrator.RegisterHandlers(api.Group("/collect"))
+	scores := run.NewScores(runs, store, hub)
+	scores.RegisterHandlers(api.Group("/scores"))
+	runs.UseScores(scores)
+	commits := run.NewCommits(runs, scores, store, os.Getenv("PPROTEIN_COMMIT_URL"))
+	commits.RegisterHandlers(api.Group("/commits"))
+	grp.OnRun(commits.Observe)
+	registry.RegisterHandlers(api)
+	openapi.RegisterHandlers(api)
+
+	return &instance{registry: registry, hub: hub, runs: runs, orchestrator: orchestrator}, nil
+}
+
+func noStore(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "no-store")
+		return next(c)
+	}
+}
+
+func newAuthenticator(publicURL string) (*auth.Authenticator, error) {
+	tokens, err := auth.ParseTokens(os.Getenv("PPROTEIN_AUTH_TOKENS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PPROTEIN_AUTH_TOKENS: %w", err)
+	}
+	anonymous, err := auth.ParseRole(os.Getenv("PPROTEIN_AUTH_ANONYMOUS_ROLE"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PPROTEIN_AUTH_ANONYMOUS_ROLE: %w", err)
+	}
+
+	cfg := &auth.Config{
+		Tokens:        tokens,
+		Anonymous:     anonymous,
+		AgentToken:    os.Getenv("PPROTEIN_PUSH_TOKEN"),
+		SessionKey:    []byte(os.Getenv("PPROTEIN_AUTH_SESSION_KEY")),
+		SecureCookies: strings.HasPrefix(publicURL, "https://"),
+	}
+	if v := os.Getenv("PPROTEIN_AUTH_SESSION_TTL"); v != "" {
+		if cfg.SessionTTL, err = time.ParseDuration(v); err != nil || cfg.SessionTTL <= 0 {
+			return nil, fmt.Errorf("invalid PPROTEIN_AUTH_SESSION_TTL: %v", v)
+		}
+	}
+
+	if issuer := os.Getenv("PPROTEIN_OIDC_ISSUER"); issuer != "" {
+		oidc := &auth.OIDCConfig{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("PPROTEIN_OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("PPROTEIN_OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("PPROTEIN_OIDC_REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv("PPROTEIN_OIDC_SCOPES")),
+			RoleClaim:    os.Getenv("PPROTEIN_OIDC_ROLE_CLAIM"),
+			Roles:        map[string]auth.Role{},
+		}
+		if oidc.RedirectURL == "" {
+			oidc.RedirectURL = strings.TrimSuffix(publicURL, "/") + "/api/auth/oidc/callback"
+		}
+		if oidc.DefaultRole, err = auth.ParseRole(os.Getenv("PPROTEIN_OIDC_DEFAULT_ROLE")); err != nil {
+			return nil, fmt.Errorf("invalid PPROTEIN_OIDC_DEFAULT_ROLE: %w", err)
+		}
+		for _, grant := range []struct {
+			env  string
+			role auth.Role
+		}{
+			{"PPROTEIN_OIDC_VIEWERS", auth.RoleViewer},
+			{"PPROTEIN_OIDC_OPERATORS", auth.RoleOperator},
+			{"PPROTEIN_OIDC_ADMINS", auth.RoleAdmin},
+		} {
+			for _, subject := range strings.Split(os.Getenv(grant.env), ",") {
+				if subject = strings.TrimSpace(subject); subject != "" {
+					oidc.Roles[subject] = grant.role
+				}
+			}
+		}
+		cfg.OIDC = oidc
+	}
+
+	return auth.New(cfg)
+}
+
+func configureTracing() error {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return nil
+	}
+
+	rawHeaders := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS")
+	if rawHeaders == "" {
+		rawHeaders = os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")
+	}
+	headers, err := tracing.ParseHeaders(rawHeaders)
+	if err != nil {
+		return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS: %w", err)
+	}
+
+	return tracing.Configure(&tracing.Config{
+		Endpoint:    endpoint,
+		Headers:     headers,
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+	})
+}
+
+func newStore(workdir string, namespace string) (storage.Storage, error) {
+	provider := os.Getenv("PPROTEIN_STORAGE")
+	if provider == "" || provider == "local" {
+		return storage.New(workdir)
+	}
+
+	cacheSize := int64(0)
+	if v := os.Getenv("PPROTEIN_STORAGE_CACHE_SIZE"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PPROTEIN_STORAGE_CACHE_SIZE: %w", err)
+		}
+		cacheSize = n
+	}
+
+	return storage.NewObject(workdir, &storage.ObjectConfig{
+		Provider:  provider,
+		Endpoint:  os.Getenv("PPROTEIN_STORAGE_ENDPOINT"),
+		Region:    os.Getenv("PPROTEIN_STORAGE_REGION"),
+		Bucket:    os.Getenv("PPROTEIN_STORAGE_BUCKET"),
+		Prefix:    path.Join(os.Getenv("PPROTEIN_STORAGE_PREFIX"), namespace),
+		AccessKey: os.Getenv("PPROTEIN_STORAGE_ACCESS_KEY"),
+		SecretKey: os.Getenv("PPROTEIN_STORAGE_SECRET_KEY"),
+		Insecure:  os.Getenv("PPROTEIN_STORAGE_INSECURE") == "true",
+		CacheSize: cacheSize,
+	})
+}
+
+func retentionPolicy(typ string) (*collect.RetentionPolicy, error) {
+	prefix := fmt.Sprintf("PPROTEIN_%s_RETENTION_", strings.ToUpper(typ))
+	policy := &collect.RetentionPolicy{}
+
+	if v := os.Getenv(prefix + "MAX_COUNT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %sMAX_COUNT: %w", prefix, err)
+		}
+		policy.MaxCount = n
+	}
+	if v := os.Getenv(prefix + "MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %sMAX_AGE: %w", prefix, err)
+		}
+		policy.MaxAge = d
+	}
+	return policy, nil
+}
+
+func clientOptions(typ string) (*collect.ClientOptions, error) {
+	lookup := func(name string) string {
+		if v := os.Getenv(fmt.Sprintf("PPROTEIN_%s_HTTP_%s", strings.ToUpper(typ), name)); v != "" {
+			return v
+		}
+		return os.Getenv("PPROTEIN_HTTP_" + name)
+	}
+	seconds := func(name string) (int, error) {
+		v := lookup(name)
+		if v == "" {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid HTTP_%s: %w", name, err)
+		}
+		return int(d.Seconds()), nil
+	}
+
+	opts := &collect.ClientOptions{
+		InsecureSkipVerify: lookup("INSECURE") == "true",
+		CAFile:             lookup("CA_FILE"),
+		CertFile:           lookup("CERT_FILE"),
+		KeyFile:            lookup("KEY_FILE"),
+		Proxy:              lookup("PROXY"),
+		DisableKeepAlives:  lookup("DISABLE_KEEPALIVES") == "true",
+	}
+
+	var err error
+	if opts.Timeout, err = seconds("TIMEOUT"); err != nil {
+		return nil, err
+	}
+	if opts.IdleConnTimeout, err = seconds("IDLE_CONN_TIMEOUT"); err != nil {
+		return nil, err
+	}
+	if v := lookup("MAX_IDLE_CONNS"); v != "" {
+		if opts.MaxIdleConns, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid HTTP_MAX_IDLE_CONNS: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+func retryPolicy() (*collect.RetryPolicy, error) {
+	policy := &collect.RetryPolicy{}
+
+	if v := os.Getenv("PPROTEIN_RETRY_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PPROTEIN_RETRY_ATTEMPTS: %w", err)
+		}
+		policy.Attempts = n
+	}
+	if v := os.Getenv("PPROTEIN_RETRY_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PPROTEIN_RETRY_BACKOFF: %w", err)
+		}
+		policy.Backoff = d
+	}
+	if v := os.Getenv("PPROTEIN_RETRY_MAX_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PPROTEIN_RETRY_MAX_BACKOFF: %w", err)
+		}
+		policy.MaxBackoff = d
+	}
+	return policy, nil
+}
+
+func diskQuota() (*collect.Quota, error) {
+	v := os.Getenv("PPROTEIN_DISK_QUOTA")
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PPROTEIN_DISK_QUOTA: %w", err)
+	}
+	return collect.NewQuota(n, os.Getenv("PPROTEIN_DISK_QUOTA_EVICT") == "true"), nil
+}
+
+func toolOptions() (*pprof.ToolOptions, error) {
+	opts := &pprof.ToolOptions{
+		Command: strings.Fields(os.Getenv("PPROTEIN_PPROF_COMMAND")),
+		Args:    strings.Fields(os.Getenv("PPROTEIN_PPROF_ARGS")),
+	}
+	if v := os.Getenv("PPROTEIN_PPROF_VIEWER_IDLE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid PPROTEIN_PPROF_VIEWER_IDLE: %v", v)
+		}
+		opts.IdleTimeout = d
+	}
+	if v := os.Getenv("PPROTEIN_PPROF_VIEWER_MAX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid PPROTEIN_PPROF_VIEWER_MAX: %v", v)
+		}
+		opts.MaxSessions = n
+	}
+	return opts, nil
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "oneshot" {
+		if err := oneshot(os.Args[2:]); err != nil {
+			log.Fatalf("[!] %v", err)
+		}
+		return
+	}
+
 	if err := start(); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/collect/group"
+	"github.com/kaz/pprotein/internal/collect/run"
+)
+
+const oneshotPollInterval = time.Second
+
+var (
+	oneshotProfileTypes = map[string]bool{"pprof": true, "fgprof": true, "block": true, "mutex": true, "java": true, "node": true}
+	artifactExtensions  = map[string]string{"text/tab-separated-values": ".tsv", "application/json": ".json", "text/plain": ".txt"}
+)
+
+func oneshot(args []string) error {
+	fs := flag.NewFlagSet("oneshot", flag.ExitOnError)
+	config := fs.String("config", "", "collect targets file, in the same format as the group targets")
+	out := fs.String("out", "pprotein-report", "directory to write artifacts and reports to")
+	label := fs.String("label", "", "label recorded as the run tag")
+	duration := fs.Int("duration", 0, "collection duration in seconds (defaults to each target's duration)")
+	types := fs.String("types", "", "comma separated types to collect (defaults to every target)")
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum time to wait for collection and processing")
+	top := fs.Int("top", 10, "number of rows in each report section")
+	fs.Parse(args)
+
+	if *config == "" {
+		return fmt.Errorf("-config is required")
+	}
+	targets, err := os.ReadFile(*config)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	workdir, err := os.MkdirTemp("", "pprotein-oneshot-")
+	if err != nil {
+		return fmt.Errorf("failed to create workdir: %w", err)
+	}
+	defer os.RemoveAll(workdir)
+	if err := os.WriteFile(path.Join(workdir, "targets.json"), targets, 0644); err != nil {
+		return fmt.Errorf("failed to write targets: %w", err)
+	}
+
+	// the seeded targets only exist locally, so a oneshot run never uses remote storage
+	os.Unsetenv("PPROTEIN_STORAGE")
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	if err := configureTracing(); err != nil {
+		return err
+	}
+	s, err := newServer(port, workdir)
+	if err != nil {
+		return err
+	}
+	s.echo.HideBanner = true
+	s.echo.HidePort = true
+	s.echo.Listener = listener
+	go func() {
+		if err := s.echo.Start(""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[!] oneshot server stopped: %v", err)
+		}
+	}()
+	defer s.echo.Close()
+
+	if err := s.validateTargets(targets); err != nil {
+		return err
+	}
+
+	req := &run.CollectAllRequest{Label: *label, Duration: *duration}
+	if *types != "" {
+		req.Types = strings.Split(*types, ",")
+	}
+	resp, err := s.main.orchestrator.CollectAll(req)
+	if err != nil {
+		return fmt.Errorf("failed to start collection: %w", err)
+	}
+	log.Printf("collecting run %v from %d targets", resp.RunId, resp.Targets)
+
+	r, err := s.awaitRun(resp.RunId, resp.Targets, *timeout)
+	if err != nil {
+		return err
+	}
+
+	doc, err := s.main.runs.Document(r, *top)
+	if err != nil {
+		return fmt.Errorf("failed to build report: %w", err)
+	}
+	if err := s.writeArtifacts(*out, doc); err != nil {
+		return err
+	}
+	log.Printf("report written to %v", path.Join(*out, "report.md"))
+
+	if r.Status != collect.StatusOk {
+		return fmt.Errorf("run %v finished with status %v", r.RunId, r.Status)
+	}
+	return nil
+}
+
+func (s *server) validateTargets(targets []byte) error {
+	req := httptest.NewRequest(http.MethodPost, "/api/group/validate", bytes.NewReader(targets))
+	req.Header.Set("Content-Type", "application/json")
+	s.authn.Authorize(req)
+	rec := httptest.NewRecorder()
+	s.echo.ServeHTTP(rec, req)
+
+	report := &group.ValidationReport{}
+	if err := json.Unmarshal(rec.Body.Bytes(), report); err != nil {
+		return fmt.Errorf("failed to validate targets: status %d", rec.Code)
+	}
+	if report.Valid {
+		return nil
+	}
+
+	problems := []string{}
+	if report.Error != "" {
+		problems = append(problems, report.Error)
+	}
+	for _, t := range report.Targets {
+		for _, e := range t.Errors {
+			problems = append(problems, fmt.Sprintf("target #%d (%v): %v", t.Index, t.Label, e))
+		}
+	}
+	return fmt.Errorf("invalid config: %v", strings.Join(problems, "; "))
+}
+
+func (s *server) awaitRun(id string, expected int, timeout time.Duration) (*run.Run, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if r, ok := s.main.runs.Get(id); ok && len(r.Entries) >= expected && r.Status != collect.StatusPending {
+			return r, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for run %v", id)
+		}
+		time.Sleep(oneshotPollInterval)
+	}
+}
+
+func (s *server) writeArtifacts(out string, doc *run.Document) error {
+	if err := os.MkdirAll(path.Join(out, "raw"), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, ent := range doc.Run.Entries {
+		if ent.Status != collect.StatusOk {
+			continue
+		}
+		id := ent.Snapshot.ID
+
+		if c, ok := s.main.registry.Get(ent.Snapshot.Type); ok {
+			if body, err := c.Raw(id); err != nil {
+				log.Printf("[!] failed to read raw snapshot %v: %v", id, err)
+			} else {
+				err := writeFile(path.Join(out, "raw", id), body)
+				body.Close()
+				if err != nil {
+					return err
+				}
+				doc.Artifacts[id] = append(doc.Artifacts[id], path.Join("raw", id))
+			}
+		}
+
+		endpoint := fmt.Sprintf("/api/%s/%s", ent.Snapshot.Type, id)
+		if oneshotProfileTypes[ent.Snapshot.Type] {
+			endpoint += "/top"
+		}
+		name, err := s.writeProcessed(out, id, endpoint)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			doc.Artifacts[id] = append(doc.Artifacts[id], name)
+		}
+	}
+
+	render := map[string]func(io.Writer) error{"report.md": doc.Markdown, "report.html": doc.HTML}
+	for name, fn := range render {
+		buf := &bytes.Buffer{}
+		if err := fn(buf); err != nil {
+			return err
+		}
+		if err := writeFile(path.Join(out, name), buf); err != nil {
+			return err
+		}
+	}
+
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	return writeFile(path.Join(out, "report.json"), bytes.NewReader(raw))
+}
+
+func (s *server) writeProcessed(out string, id string, endpoint string) (string, error) {
+	req := httptest.NewRequest(http.MethodGet, endpoint, nil)
+	s.authn.Authorize(req)
+	rec := httptest.NewRecorder()
+	s.echo.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		log.Printf("[!] no processed output for %v: status %d", id, rec.Code)
+		return "", nil
+	}
+
+	ext := ".out"
+	if mediaType, _, err := mime.ParseMediaType(rec.Header().Get("Content-Type")); err == nil {
+		if v, ok := artifactExtensions[mediaType]; ok {
+			ext = v
+		}
+	}
+	name := id + ext
+	if err := writeFile(path.Join(out, name), rec.Body); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func writeFile(name string, r io.Reader) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %v: %w", name, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to write %v: %w", name, err)
+	}
+	return nil
+}
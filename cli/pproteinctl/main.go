@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kaz/pprotein/client"
+)
+
+type (
+	command struct {
+		usage string
+		run   func(ctx context.Context, c *client.Client, args []string) error
+	}
+
+	tags map[string]string
+)
+
+const waitInterval = 2 * time.Second
+
+var (
+	commands = map[string]*command{
+		"collect": {"collect [-env name] [-types a,b] [-label l] [-duration s] [-tag k=v] [-wait] [-timeout d]", runCollect},
+		"wait":    {"wait [-expect n] [-timeout d] <run>", runWait},
+		"list":    {"list [-type t] [-label l] [-group g] [-run r] [-status s] [-limit n]", runList},
+		"get":     {"get [-raw] [-format f] [-o file] <type> <id>", runGet},
+		"top":     {"top [-n rows] [-sort column] [-asc] <type> <id>", runTop},
+	}
+
+	errUsage  = errors.New("invalid arguments")
+	errFailed = errors.New("run failed")
+)
+
+func (t tags) String() string {
+	pairs := []string{}
+	for k, v := range t {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (t tags) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("tag must be formatted as key=value")
+	}
+	t[key] = value
+	return nil
+}
+
+func main() {
+	server := flag.String("server", envOr("PPROTEIN_SERVER", "http://localhost:9000"), "pprotein server URL")
+	token := flag.String("token", os.Getenv("PPROTEIN_TOKEN"), "API token sent as a bearer credential")
+	project := flag.String("project", os.Getenv("PPROTEIN_PROJECT"), "project to operate on")
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	c := client.New(*server)
+	c.Project = *project
+	if *token != "" {
+		c.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd.run(ctx, c, flag.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "usage: pproteinctl %s\n", cmd.usage)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "pproteinctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: pproteinctl [-server url] [-token t] [-project p] <command> [args]\n\ncommands:\n")
+	for _, name := range []string{"collect", "wait", "list", "get", "top"} {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func runCollect(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("collect", flag.ExitOnError)
+	env := fs.String("env", "", "environment to collect (defaults to the active one)")
+	types := fs.String("types", "", "comma separated types to collect (defaults to every target)")
+	label := fs.String("label", "", "label recorded as the run tag")
+	duration := fs.Int("duration", 0, "collection duration in seconds (defaults to each target's duration)")
+	tagged := tags{}
+	fs.Var(tagged, "tag", "tag attached to every snapshot, formatted as key=value (repeatable)")
+	wait := fs.Bool("wait", false, "wait until every snapshot is processed")
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum time to wait")
+	fs.Parse(args)
+
+	req := &client.CollectAllRequest{Environment: *env, Label: *label, Duration: *duration, Tags: tagged}
+	if *types != "" {
+		req.Types = strings.Split(*types, ",")
+	}
+
+	resp, err := c.CollectAll(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to start collection: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "started run %s on %s with %d targets\n", resp.RunId, resp.Environment, resp.Targets)
+
+	if !*wait {
+		fmt.Println(resp.RunId)
+		return nil
+	}
+	return waitRun(ctx, c, resp.RunId, resp.Targets, *timeout)
+}
+
+func runWait(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("wait", flag.ExitOnError)
+	expect := fs.Int("expect", 0, "number of snapshots the run is expected to have")
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum time to wait")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	return waitRun(ctx, c, fs.Arg(0), *expect, *timeout)
+}
+
+func waitRun(ctx context.Context, c *client.Client, id string, expect int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(waitInterval)
+	defer ticker.Stop()
+
+	for {
+		r, err := c.GetRun(ctx, id)
+		var apiErr *client.Error
+		if err != nil && !(errors.As(err, &apiErr) && apiErr.StatusCode == 404) {
+			return fmt.Errorf("failed to get run: %w", err)
+		}
+		if err == nil && len(r.Entries) >= expect && r.Status != client.StatusPending {
+			printEntries(r.Entries)
+			if r.Status == client.StatusFail {
+				return fmt.Errorf("%w: %s", errFailed, id)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up waiting for run %s: %w", id, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func runList(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	params := &client.QueryHistoryParams{Order: "desc"}
+	fs.StringVar(&params.Type, "type", "", "snapshot type")
+	fs.StringVar(&params.Label, "label", "", "snapshot label")
+	fs.StringVar(&params.Group, "group", "", "group ID")
+	fs.StringVar(&params.Run, "run", "", "run ID")
+	status := fs.String("status", "", "snapshot status")
+	fs.IntVar(&params.Limit, "limit", 20, "maximum number of snapshots")
+	fs.Parse(args)
+	params.Status = client.Status(*status)
+
+	entries, err := c.QueryHistory(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	printEntries(entries)
+	return nil
+}
+
+func runGet(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	raw := fs.Bool("raw", false, "download the collected data instead of the processed output")
+	format := fs.String("format", "", "output of profile types: folded, flamegraph, speedscope or top (defaults to the profile itself)")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	typ, id := fs.Arg(0), fs.Arg(1)
+
+	var body io.ReadCloser
+	var err error
+	switch {
+	case *format == "folded":
+		body, err = c.GetFoldedStacks(ctx, typ, id)
+	case *format == "flamegraph":
+		body, err = c.GetFlameGraph(ctx, typ, id)
+	case *format == "speedscope":
+		body, err = c.GetSpeedscope(ctx, typ, id)
+	case *format == "top":
+		body, err = c.GetTopSummary(ctx, typ, id)
+	case *format != "":
+		return fmt.Errorf("unknown format: %v", *format)
+	case *raw || profileTypes[typ]:
+		body, err = c.GetRawSnapshot(ctx, typ, id, nil)
+	default:
+		body, err = c.GetSnapshot(ctx, typ, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	defer body.Close()
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return fmt.Errorf("failed to download snapshot: %w", err)
+	}
+	return nil
+}
+
+func printEntries(entries []*client.Entry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "TYPE\tID\tDATETIME\tSTATUS\tLABEL\tGROUP\tMESSAGE")
+	for _, ent := range entries {
+		s := ent.Snapshot
+		if s == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Type, s.ID, s.Datetime.Local().Format(time.DateTime), ent.Status, s.Label, s.GroupId, ent.Message)
+	}
+}
+
+func envOr(name string, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
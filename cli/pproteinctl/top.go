@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kaz/pprotein/client"
+)
+
+var profileTypes = map[string]bool{"pprof": true, "fgprof": true, "block": true, "mutex": true, "java": true, "node": true}
+
+func runTop(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	rows := fs.Int("n", 10, "number of rows to print")
+	column := fs.String("sort", "", "column to sort tabular output by (defaults to the server order)")
+	asc := fs.Bool("asc", false, "sort in ascending order")
+	width := fs.Int("width", 60, "truncate cells longer than this many characters (0 to disable)")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	typ, id := fs.Arg(0), fs.Arg(1)
+
+	if profileTypes[typ] {
+		body, err := c.GetTopSummary(ctx, typ, id)
+		if err != nil {
+			return fmt.Errorf("failed to get summary: %w", err)
+		}
+		defer body.Close()
+		return printProfileTop(body, *rows)
+	}
+
+	body, err := c.GetSnapshot(ctx, typ, id)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	defer body.Close()
+
+	table, err := readTSV(body)
+	if err != nil {
+		return err
+	}
+	if *column != "" {
+		if err := sortTable(table, *column, *asc); err != nil {
+			return err
+		}
+	}
+	printTable(table, *rows, *width)
+	return nil
+}
+
+func printProfileTop(r io.Reader, rows int) error {
+	scanner := bufio.NewScanner(r)
+	printed := -1
+	for scanner.Scan() && printed < rows {
+		line := scanner.Text()
+		fmt.Println(line)
+		if printed >= 0 {
+			printed++
+		} else if strings.Contains(line, "flat%") {
+			printed = 0
+		}
+	}
+	return scanner.Err()
+}
+
+func readTSV(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	table, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse output as TSV: %w", err)
+	}
+	if len(table) == 0 {
+		return nil, fmt.Errorf("snapshot has no rows")
+	}
+	return table, nil
+}
+
+func sortTable(table [][]string, column string, asc bool) error {
+	idx := -1
+	for i, h := range table[0] {
+		if strings.EqualFold(h, column) {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("no such column: %v (available: %v)", column, strings.Join(table[0], ", "))
+	}
+
+	cell := func(row []string) string {
+		if idx < len(row) {
+			return row[idx]
+		}
+		return ""
+	}
+	body := table[1:]
+	sort.SliceStable(body, func(i, j int) bool {
+		a, b := cell(body[i]), cell(body[j])
+		af, aerr := strconv.ParseFloat(a, 64)
+		bf, berr := strconv.ParseFloat(b, 64)
+		if aerr == nil && berr == nil {
+			if asc {
+				return af < bf
+			}
+			return af > bf
+		}
+		if asc {
+			return a < b
+		}
+		return a > b
+	})
+	return nil
+}
+
+func printTable(table [][]string, rows int, width int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	for i, row := range table {
+		if i > rows {
+			break
+		}
+		cells := make([]string, len(row))
+		for j, v := range row {
+			if r := []rune(v); width > 3 && len(r) > width {
+				v = string(r[:width-3]) + "..."
+			}
+			cells[j] = v
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+}
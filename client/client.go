@@ -0,0 +1,151 @@
+package client
+
+//go:generate go run ../internal/openapi/gen -o client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	Client struct {
+		BaseURL    string
+		HTTPClient *http.Client
+		Header     http.Header
+		Project    string
+	}
+
+	Error struct {
+		StatusCode int
+		Message    string
+	}
+)
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Header:     http.Header{},
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("pprotein: status=%d, message=%s", e.StatusCode, e.Message)
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	if c.Project != "" {
+		req.Header.Set("X-Pprotein-Project", c.Project)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+func readError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(raw, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(raw))
+	}
+	return apiErr
+}
+
+func (c *Client) call(ctx context.Context, method string, path string, query url.Values, in any, out any) error {
+	var (
+		body        io.Reader
+		contentType string
+	)
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal: %w", err)
+		}
+		body, contentType = bytes.NewReader(raw), "application/json"
+	}
+	return c.upload(ctx, method, path, query, body, contentType, out)
+}
+
+func (c *Client) upload(ctx context.Context, method string, path string, query url.Values, body io.Reader, contentType string, out any) error {
+	resp, err := c.send(ctx, method, path, query, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) stream(ctx context.Context, method string, path string, query url.Values) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, method, path, query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func setString(q url.Values, key string, v string) {
+	if v != "" {
+		q.Set(key, v)
+	}
+}
+
+func setInt(q url.Values, key string, v int) {
+	if v != 0 {
+		q.Set(key, strconv.Itoa(v))
+	}
+}
+
+func setBool(q url.Values, key string, v bool) {
+	if v {
+		q.Set(key, "true")
+	}
+}
+
+func setTime(q url.Values, key string, v time.Time) {
+	if !v.IsZero() {
+		q.Set(key, v.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,1187 @@
+// Code generated by internal/openapi/gen. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"time"
+)
+
+type Alert struct {
+	Metric    string  `json:"Metric,omitempty"`
+	Rule      string  `json:"Rule,omitempty"`
+	Subject   string  `json:"Subject,omitempty"`
+	Threshold float64 `json:"Threshold,omitempty"`
+	Value     float64 `json:"Value,omitempty"`
+}
+
+type BasicAuth struct {
+	Password string `json:"Password,omitempty"`
+	Username string `json:"Username,omitempty"`
+}
+
+type Binary struct {
+	BuildID   string    `json:"BuildID,omitempty"`
+	Datetime  time.Time `json:"Datetime,omitempty"`
+	GoBuildID string    `json:"GoBuildID,omitempty"`
+	Name      string    `json:"Name,omitempty"`
+	Size      int64     `json:"Size,omitempty"`
+	Target    string    `json:"Target,omitempty"`
+}
+
+type BulkRequest struct {
+	Filter    *IndexQuery       `json:"Filter,omitempty"`
+	IDs       []string          `json:"IDs,omitempty"`
+	Operation string            `json:"Operation,omitempty"`
+	Tags      map[string]string `json:"Tags,omitempty"`
+}
+
+type BulkResult struct {
+	Error string `json:"Error,omitempty"`
+	ID    string `json:"ID,omitempty"`
+}
+
+type ClientOptions struct {
+	CAFile             string `json:"CAFile,omitempty"`
+	CertFile           string `json:"CertFile,omitempty"`
+	DisableKeepAlives  bool   `json:"DisableKeepAlives,omitempty"`
+	IdleConnTimeout    int    `json:"IdleConnTimeout,omitempty"`
+	InsecureSkipVerify bool   `json:"InsecureSkipVerify,omitempty"`
+	KeyFile            string `json:"KeyFile,omitempty"`
+	MaxIdleConns       int    `json:"MaxIdleConns,omitempty"`
+	Proxy              string `json:"Proxy,omitempty"`
+	Timeout            int    `json:"Timeout,omitempty"`
+}
+
+type CollectAllRequest struct {
+	Duration    int               `json:"Duration,omitempty"`
+	Environment string            `json:"Environment,omitempty"`
+	Label       string            `json:"Label,omitempty"`
+	Tags        map[string]string `json:"Tags,omitempty"`
+	Types       []string          `json:"Types,omitempty"`
+}
+
+type CollectAllResponse struct {
+	Environment string `json:"Environment,omitempty"`
+	GroupId     string `json:"GroupId,omitempty"`
+	RunId       string `json:"RunId,omitempty"`
+	Targets     int    `json:"Targets,omitempty"`
+}
+
+type CollectTarget struct {
+	AgentToken       string            `json:"AgentToken,omitempty"`
+	BasicAuth        *BasicAuth        `json:"BasicAuth,omitempty"`
+	BearerToken      string            `json:"BearerToken,omitempty"`
+	Client           *ClientOptions    `json:"Client,omitempty"`
+	Duration         int               `json:"Duration,omitempty"`
+	Headers          map[string]string `json:"Headers,omitempty"`
+	Label            string            `json:"Label,omitempty"`
+	ProcessorOptions json.RawMessage   `json:"ProcessorOptions,omitempty"`
+	Tags             map[string]string `json:"Tags,omitempty"`
+	Type             string            `json:"Type,omitempty"`
+	URL              string            `json:"URL,omitempty"`
+	URLs             []string          `json:"URLs,omitempty"`
+}
+
+type Comment struct {
+	Author     string    `json:"Author,omitempty"`
+	Body       string    `json:"Body,omitempty"`
+	Datetime   time.Time `json:"Datetime,omitempty"`
+	ID         string    `json:"ID,omitempty"`
+	SnapshotID string    `json:"SnapshotID,omitempty"`
+}
+
+type CommentRequest struct {
+	Author string `json:"Author,omitempty"`
+	Body   string `json:"Body,omitempty"`
+}
+
+type Commit struct {
+	Author   string    `json:"Author,omitempty"`
+	Datetime time.Time `json:"Datetime,omitempty"`
+	Hash     string    `json:"Hash,omitempty"`
+	Message  string    `json:"Message,omitempty"`
+	Ref      string    `json:"Ref,omitempty"`
+	Remote   string    `json:"Remote,omitempty"`
+	RunId    string    `json:"RunId,omitempty"`
+	Source   string    `json:"Source,omitempty"`
+}
+
+type CommitSummary struct {
+	Author      string    `json:"Author,omitempty"`
+	BestScore   *float64  `json:"BestScore,omitempty"`
+	Hash        string    `json:"Hash,omitempty"`
+	LastRun     time.Time `json:"LastRun,omitempty"`
+	LatestScore *float64  `json:"LatestScore,omitempty"`
+	Message     string    `json:"Message,omitempty"`
+	Ref         string    `json:"Ref,omitempty"`
+	Runs        []string  `json:"Runs,omitempty"`
+	Scores      []float64 `json:"Scores,omitempty"`
+}
+
+type Comparison struct {
+	Base       *CommitSummary   `json:"Base,omitempty"`
+	BaseRun    string           `json:"BaseRun,omitempty"`
+	Endpoints  []*EndpointDelta `json:"Endpoints,omitempty"`
+	Functions  []*FunctionDelta `json:"Functions,omitempty"`
+	Queries    []*QueryDelta    `json:"Queries,omitempty"`
+	ScoreDelta *float64         `json:"ScoreDelta,omitempty"`
+	Target     *CommitSummary   `json:"Target,omitempty"`
+	TargetRun  string           `json:"TargetRun,omitempty"`
+}
+
+type EndpointDelta struct {
+	Base   float64 `json:"Base,omitempty"`
+	Delta  float64 `json:"Delta,omitempty"`
+	Method string  `json:"Method,omitempty"`
+	Target float64 `json:"Target,omitempty"`
+	Uri    string  `json:"Uri,omitempty"`
+}
+
+type Entry struct {
+	Alerts   []*Alert  `json:"Alerts,omitempty"`
+	Message  string    `json:"Message,omitempty"`
+	Progress *Progress `json:"Progress,omitempty"`
+	Snapshot *Snapshot `json:"Snapshot,omitempty"`
+	Status   Status    `json:"Status,omitempty"`
+}
+
+type Environments struct {
+	Active string   `json:"Active,omitempty"`
+	Names  []string `json:"Names,omitempty"`
+}
+
+type FunctionDelta struct {
+	Base   int64  `json:"Base,omitempty"`
+	Delta  int64  `json:"Delta,omitempty"`
+	Name   string `json:"Name,omitempty"`
+	Target int64  `json:"Target,omitempty"`
+	Unit   string `json:"Unit,omitempty"`
+}
+
+type HTTPError struct {
+	Message string `json:"message,omitempty"`
+}
+
+type Hint struct {
+	Kind       string  `json:"Kind,omitempty"`
+	Message    string  `json:"Message,omitempty"`
+	Share      float64 `json:"Share,omitempty"`
+	Source     string  `json:"Source,omitempty"`
+	Subject    string  `json:"Subject,omitempty"`
+	Suggestion string  `json:"Suggestion,omitempty"`
+}
+
+type IndexQuery struct {
+	GroupId string    `json:"GroupId,omitempty"`
+	Label   string    `json:"Label,omitempty"`
+	Limit   int       `json:"Limit,omitempty"`
+	Offset  int       `json:"Offset,omitempty"`
+	Order   string    `json:"Order,omitempty"`
+	RunId   string    `json:"RunId,omitempty"`
+	Since   time.Time `json:"Since,omitempty"`
+	Status  Status    `json:"Status,omitempty"`
+	Type    string    `json:"Type,omitempty"`
+	URL     string    `json:"URL,omitempty"`
+	Until   time.Time `json:"Until,omitempty"`
+}
+
+type LabelRequest struct {
+	Label string            `json:"Label,omitempty"`
+	Tags  map[string]string `json:"Tags,omitempty"`
+}
+
+type Memo struct {
+	Datetime   time.Time `json:"Datetime,omitempty"`
+	GroupId    string    `json:"GroupId,omitempty"`
+	HTML       string    `json:"HTML,omitempty"`
+	ID         string    `json:"ID,omitempty"`
+	Label      string    `json:"Label,omitempty"`
+	RunId      string    `json:"RunId,omitempty"`
+	SnapshotId string    `json:"SnapshotId,omitempty"`
+	Text       string    `json:"Text,omitempty"`
+}
+
+type MemoRequest struct {
+	GroupId    string `json:"GroupId,omitempty"`
+	Label      string `json:"Label,omitempty"`
+	RunId      string `json:"RunId,omitempty"`
+	SnapshotId string `json:"SnapshotId,omitempty"`
+	Text       string `json:"Text,omitempty"`
+}
+
+type Progress struct {
+	Bytes     int64   `json:"Bytes,omitempty"`
+	Elapsed   float64 `json:"Elapsed,omitempty"`
+	Remaining float64 `json:"Remaining,omitempty"`
+}
+
+type Project struct {
+	Created     time.Time `json:"Created,omitempty"`
+	Description string    `json:"Description,omitempty"`
+	Name        string    `json:"Name,omitempty"`
+}
+
+type ProjectRequest struct {
+	Description string `json:"Description,omitempty"`
+	Name        string `json:"Name"`
+}
+
+type QueryDelta struct {
+	Base    float64 `json:"Base,omitempty"`
+	Delta   float64 `json:"Delta,omitempty"`
+	Query   string  `json:"Query,omitempty"`
+	QueryID string  `json:"QueryID,omitempty"`
+	Target  float64 `json:"Target,omitempty"`
+}
+
+type RepositoryInfo struct {
+	Author  string `json:"Author,omitempty"`
+	Hash    string `json:"Hash,omitempty"`
+	Message string `json:"Message,omitempty"`
+	Ref     string `json:"Ref,omitempty"`
+	Remote  string `json:"Remote,omitempty"`
+}
+
+type Run struct {
+	Datetime time.Time `json:"Datetime,omitempty"`
+	Entries  []*Entry  `json:"Entries,omitempty"`
+	RunId    string    `json:"RunId,omitempty"`
+	Status   Status    `json:"Status,omitempty"`
+}
+
+type Schedule struct {
+	Enabled bool             `json:"Enabled,omitempty"`
+	ID      string           `json:"ID,omitempty"`
+	Spec    string           `json:"Spec,omitempty"`
+	Targets []*CollectTarget `json:"Targets,omitempty"`
+}
+
+type ScheduleStatus struct {
+	Enabled bool      `json:"Enabled,omitempty"`
+	ID      string    `json:"ID,omitempty"`
+	Next    time.Time `json:"Next,omitempty"`
+	Prev    time.Time `json:"Prev,omitempty"`
+}
+
+type Score struct {
+	Datetime time.Time       `json:"Datetime,omitempty"`
+	Detail   json.RawMessage `json:"Detail,omitempty"`
+	ID       string          `json:"ID,omitempty"`
+	RunId    string          `json:"RunId,omitempty"`
+	Score    float64         `json:"Score,omitempty"`
+}
+
+type ScorePoint struct {
+	Datetime  time.Time       `json:"Datetime,omitempty"`
+	Detail    json.RawMessage `json:"Detail,omitempty"`
+	ID        string          `json:"ID,omitempty"`
+	RunId     string          `json:"RunId,omitempty"`
+	Score     float64         `json:"Score,omitempty"`
+	Snapshots []*SnapshotLink `json:"Snapshots,omitempty"`
+	Status    Status          `json:"Status,omitempty"`
+}
+
+type ScoreRequest struct {
+	Datetime *time.Time      `json:"Datetime,omitempty"`
+	Detail   json.RawMessage `json:"Detail,omitempty"`
+	RunId    string          `json:"RunId,omitempty"`
+	Score    float64         `json:"Score"`
+}
+
+type SearchHit struct {
+	Entry   *Entry  `json:"Entry,omitempty"`
+	Field   string  `json:"Field,omitempty"`
+	Rank    float64 `json:"Rank,omitempty"`
+	Snippet string  `json:"Snippet,omitempty"`
+}
+
+type SessionStatus struct {
+	Expires  time.Time `json:"Expires,omitempty"`
+	Key      string    `json:"Key,omitempty"`
+	LastUsed time.Time `json:"LastUsed,omitempty"`
+	Requests int       `json:"Requests,omitempty"`
+	Restarts int       `json:"Restarts,omitempty"`
+	Started  time.Time `json:"Started,omitempty"`
+	State    string    `json:"State,omitempty"`
+}
+
+type Snapshot struct {
+	AgentToken       string            `json:"AgentToken,omitempty"`
+	BasicAuth        *BasicAuth        `json:"BasicAuth,omitempty"`
+	BearerToken      string            `json:"BearerToken,omitempty"`
+	Client           *ClientOptions    `json:"Client,omitempty"`
+	Datetime         time.Time         `json:"Datetime,omitempty"`
+	DuplicateOf      string            `json:"DuplicateOf,omitempty"`
+	Duration         int               `json:"Duration,omitempty"`
+	Encoding         string            `json:"Encoding,omitempty"`
+	GroupId          string            `json:"GroupId,omitempty"`
+	Hash             string            `json:"Hash,omitempty"`
+	Headers          map[string]string `json:"Headers,omitempty"`
+	ID               string            `json:"ID,omitempty"`
+	Label            string            `json:"Label,omitempty"`
+	Pinned           bool              `json:"Pinned,omitempty"`
+	ProcessorOptions json.RawMessage   `json:"ProcessorOptions,omitempty"`
+	Repository       *RepositoryInfo   `json:"Repository,omitempty"`
+	RunId            string            `json:"RunId,omitempty"`
+	ScheduleId       string            `json:"ScheduleId,omitempty"`
+	Tags             map[string]string `json:"Tags,omitempty"`
+	Type             string            `json:"Type,omitempty"`
+	URL              string            `json:"URL,omitempty"`
+	URLs             []string          `json:"URLs,omitempty"`
+}
+
+type SnapshotLink struct {
+	GroupId string `json:"GroupId,omitempty"`
+	ID      string `json:"ID,omitempty"`
+	Label   string `json:"Label,omitempty"`
+	Status  Status `json:"Status,omitempty"`
+	Type    string `json:"Type,omitempty"`
+}
+
+type SnapshotTarget struct {
+	AgentToken       string            `json:"AgentToken,omitempty"`
+	BasicAuth        *BasicAuth        `json:"BasicAuth,omitempty"`
+	BearerToken      string            `json:"BearerToken,omitempty"`
+	Client           *ClientOptions    `json:"Client,omitempty"`
+	Duration         int               `json:"Duration,omitempty"`
+	GroupId          string            `json:"GroupId,omitempty"`
+	Headers          map[string]string `json:"Headers,omitempty"`
+	Label            string            `json:"Label,omitempty"`
+	ProcessorOptions json.RawMessage   `json:"ProcessorOptions,omitempty"`
+	RunId            string            `json:"RunId,omitempty"`
+	ScheduleId       string            `json:"ScheduleId,omitempty"`
+	Tags             map[string]string `json:"Tags,omitempty"`
+	URL              string            `json:"URL,omitempty"`
+	URLs             []string          `json:"URLs,omitempty"`
+}
+
+type Status string
+
+const (
+	StatusOk      Status = "ok"
+	StatusFail    Status = "fail"
+	StatusPending Status = "pending"
+	StatusDeleted Status = "deleted"
+	StatusCorrupt Status = "corrupt"
+)
+
+type TargetReport struct {
+	Errors   []string     `json:"Errors,omitempty"`
+	Index    int          `json:"Index,omitempty"`
+	Label    string       `json:"Label,omitempty"`
+	Type     string       `json:"Type,omitempty"`
+	URLs     []*URLReport `json:"URLs,omitempty"`
+	Warnings []string     `json:"Warnings,omitempty"`
+}
+
+type ToolReport struct {
+	Found    bool     `json:"Found,omitempty"`
+	Name     string   `json:"Name,omitempty"`
+	Optional bool     `json:"Optional,omitempty"`
+	Path     string   `json:"Path,omitempty"`
+	Types    []string `json:"Types,omitempty"`
+}
+
+type URLReport struct {
+	Error     string  `json:"Error,omitempty"`
+	Latency   float64 `json:"Latency,omitempty"`
+	Reachable bool    `json:"Reachable,omitempty"`
+	Status    int     `json:"Status,omitempty"`
+	URL       string  `json:"URL,omitempty"`
+}
+
+type ValidationReport struct {
+	Error   string          `json:"Error,omitempty"`
+	Targets []*TargetReport `json:"Targets,omitempty"`
+	Tools   []*ToolReport   `json:"Tools,omitempty"`
+	Valid   bool            `json:"Valid,omitempty"`
+}
+
+type Version struct {
+	Added       int       `json:"Added,omitempty"`
+	Author      string    `json:"Author,omitempty"`
+	Comment     string    `json:"Comment,omitempty"`
+	Content     string    `json:"Content,omitempty"`
+	Datetime    time.Time `json:"Datetime,omitempty"`
+	Diff        string    `json:"Diff,omitempty"`
+	Environment string    `json:"Environment,omitempty"`
+	File        string    `json:"File,omitempty"`
+	ID          string    `json:"ID,omitempty"`
+	Previous    string    `json:"Previous,omitempty"`
+	Removed     int       `json:"Removed,omitempty"`
+}
+
+// POST /api/{type}/{id}/comments: Comment on a snapshot
+func (c *Client) AddComment(ctx context.Context, typ string, id string, body *CommentRequest) (*Comment, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/comments"
+	query := url.Values{}
+	var out *Comment
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// POST /api/memo: Add a memo
+func (c *Client) AddMemo(ctx context.Context, body *MemoRequest) error {
+	path := "/api/memo"
+	query := url.Values{}
+	return c.call(ctx, "POST", path, query, body, nil)
+}
+
+// POST /api/{type}/bulk: Apply an operation to several snapshots
+func (c *Client) Bulk(ctx context.Context, typ string, body *BulkRequest) ([]*BulkResult, error) {
+	path := "/api/" + url.PathEscape(typ) + "/bulk"
+	query := url.Values{}
+	var out []*BulkResult
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// POST /api/{type}/{id}/cancel: Cancel an in-progress snapshot
+func (c *Client) CancelEntry(ctx context.Context, typ string, id string) error {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/cancel"
+	query := url.Values{}
+	return c.call(ctx, "POST", path, query, nil, nil)
+}
+
+// POST /api/{type}: Start collecting a snapshot
+func (c *Client) Collect(ctx context.Context, typ string, body *SnapshotTarget) error {
+	path := "/api/" + url.PathEscape(typ)
+	query := url.Values{}
+	return c.call(ctx, "POST", path, query, body, nil)
+}
+
+// POST /api/collect/all: Collect every target of an environment as one run
+func (c *Client) CollectAll(ctx context.Context, body *CollectAllRequest) (*CollectAllResponse, error) {
+	path := "/api/collect/all"
+	query := url.Values{}
+	var out *CollectAllResponse
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// GET /api/group/collect: Collect every target as one group
+func (c *Client) CollectGroup(ctx context.Context) error {
+	path := "/api/group/collect"
+	query := url.Values{}
+	return c.call(ctx, "GET", path, query, nil, nil)
+}
+
+// GET /api/group/env/{env}/collect: Collect every target as one group
+func (c *Client) CollectGroupInEnvironment(ctx context.Context, env string) error {
+	path := "/api/group/env/" + url.PathEscape(env) + "/collect"
+	query := url.Values{}
+	return c.call(ctx, "GET", path, query, nil, nil)
+}
+
+type CompareCommitsParams struct {
+	Base   string
+	Target string
+	Top    int
+}
+
+// GET /api/commits/compare: Compare two commits
+func (c *Client) CompareCommits(ctx context.Context, params *CompareCommitsParams) (*Comparison, error) {
+	path := "/api/commits/compare"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "base", params.Base)
+		setString(query, "target", params.Target)
+		setInt(query, "top", params.Top)
+	}
+	var out *Comparison
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// POST /api/projects: Create a project
+func (c *Client) CreateProject(ctx context.Context, body *ProjectRequest) (*Project, error) {
+	path := "/api/projects"
+	query := url.Values{}
+	var out *Project
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// DELETE /api/{type}/binaries/{buildId}: Delete a stored binary
+func (c *Client) DeleteBinary(ctx context.Context, typ string, buildId string) error {
+	path := "/api/" + url.PathEscape(typ) + "/binaries/" + url.PathEscape(buildId)
+	query := url.Values{}
+	return c.call(ctx, "DELETE", path, query, nil, nil)
+}
+
+// DELETE /api/{type}/{id}/comments/{commentId}: Delete a comment
+func (c *Client) DeleteComment(ctx context.Context, typ string, id string, commentId string) error {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/comments/" + url.PathEscape(commentId)
+	query := url.Values{}
+	return c.call(ctx, "DELETE", path, query, nil, nil)
+}
+
+// DELETE /api/{type}/{id}: Delete a snapshot
+func (c *Client) DeleteEntry(ctx context.Context, typ string, id string) error {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id)
+	query := url.Values{}
+	return c.call(ctx, "DELETE", path, query, nil, nil)
+}
+
+// DELETE /api/scores/{id}: Delete a score
+func (c *Client) DeleteScore(ctx context.Context, id string) error {
+	path := "/api/scores/" + url.PathEscape(id)
+	query := url.Values{}
+	return c.call(ctx, "DELETE", path, query, nil, nil)
+}
+
+// POST /api/{type}/binaries/fetch: Fetch the running binary from an agent
+func (c *Client) FetchBinary(ctx context.Context, typ string, body *SnapshotTarget) (*Binary, error) {
+	path := "/api/" + url.PathEscape(typ) + "/binaries/fetch"
+	query := url.Values{}
+	var out *Binary
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// GET /api/group/history/{id}: Get a config version with its diff
+func (c *Client) GetConfigVersion(ctx context.Context, id string) (*Version, error) {
+	path := "/api/group/history/" + url.PathEscape(id)
+	query := url.Values{}
+	var out *Version
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/group/environments: Get environments
+func (c *Client) GetEnvironments(ctx context.Context) (*Environments, error) {
+	path := "/api/group/environments"
+	query := url.Values{}
+	var out *Environments
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/{type}/{id}/flamegraph.json: Get a profile snapshot as a flame graph tree
+func (c *Client) GetFlameGraph(ctx context.Context, typ string, id string) (io.ReadCloser, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/flamegraph.json"
+	query := url.Values{}
+	return c.stream(ctx, "GET", path, query)
+}
+
+// GET /api/{type}/{id}/folded: Get a profile snapshot as folded stacks
+func (c *Client) GetFoldedStacks(ctx context.Context, typ string, id string) (io.ReadCloser, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/folded"
+	query := url.Values{}
+	return c.stream(ctx, "GET", path, query)
+}
+
+// GET /api/memo/{id}: Get a memo
+func (c *Client) GetMemo(ctx context.Context, id string) (*Memo, error) {
+	path := "/api/memo/" + url.PathEscape(id)
+	query := url.Values{}
+	var out *Memo
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+type GetRawSnapshotParams struct {
+	Lines int
+	From  string
+}
+
+// GET /api/{type}/{id}/raw: Get the raw collected data of a snapshot
+func (c *Client) GetRawSnapshot(ctx context.Context, typ string, id string, params *GetRawSnapshotParams) (io.ReadCloser, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/raw"
+	query := url.Values{}
+	if params != nil {
+		setInt(query, "lines", params.Lines)
+		setString(query, "from", params.From)
+	}
+	return c.stream(ctx, "GET", path, query)
+}
+
+// GET /api/runs/{id}: Get a run
+func (c *Client) GetRun(ctx context.Context, id string) (*Run, error) {
+	path := "/api/runs/" + url.PathEscape(id)
+	query := url.Values{}
+	var out *Run
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/runs/{id}/hints: Get bottleneck hints of a run
+func (c *Client) GetRunHints(ctx context.Context, id string) ([]*Hint, error) {
+	path := "/api/runs/" + url.PathEscape(id) + "/hints"
+	query := url.Values{}
+	var out []*Hint
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/group/schedules/status: Get schedule status
+func (c *Client) GetScheduleStatus(ctx context.Context) ([]*ScheduleStatus, error) {
+	path := "/api/group/schedules/status"
+	query := url.Values{}
+	var out []*ScheduleStatus
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/group/env/{env}/schedules/status: Get schedule status
+func (c *Client) GetScheduleStatusInEnvironment(ctx context.Context, env string) ([]*ScheduleStatus, error) {
+	path := "/api/group/env/" + url.PathEscape(env) + "/schedules/status"
+	query := url.Values{}
+	var out []*ScheduleStatus
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/group/schedules: Get schedules
+func (c *Client) GetSchedules(ctx context.Context) ([]*Schedule, error) {
+	path := "/api/group/schedules"
+	query := url.Values{}
+	var out []*Schedule
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/group/env/{env}/schedules: Get schedules
+func (c *Client) GetSchedulesInEnvironment(ctx context.Context, env string) ([]*Schedule, error) {
+	path := "/api/group/env/" + url.PathEscape(env) + "/schedules"
+	query := url.Values{}
+	var out []*Schedule
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/{type}/{id}: Get the processed content of a snapshot
+func (c *Client) GetSnapshot(ctx context.Context, typ string, id string) (io.ReadCloser, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id)
+	query := url.Values{}
+	return c.stream(ctx, "GET", path, query)
+}
+
+// GET /api/{type}/{id}/views/{name}: Get a named view of a snapshot
+func (c *Client) GetSnapshotView(ctx context.Context, typ string, id string, name string) (io.ReadCloser, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/views/" + url.PathEscape(name)
+	query := url.Values{}
+	return c.stream(ctx, "GET", path, query)
+}
+
+// GET /api/{type}/{id}/speedscope.json: Get a profile snapshot in speedscope format
+func (c *Client) GetSpeedscope(ctx context.Context, typ string, id string) (io.ReadCloser, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/speedscope.json"
+	query := url.Values{}
+	return c.stream(ctx, "GET", path, query)
+}
+
+// GET /api/group/targets: Get collect targets
+func (c *Client) GetTargets(ctx context.Context) ([]*CollectTarget, error) {
+	path := "/api/group/targets"
+	query := url.Values{}
+	var out []*CollectTarget
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/group/env/{env}/targets: Get collect targets
+func (c *Client) GetTargetsInEnvironment(ctx context.Context, env string) ([]*CollectTarget, error) {
+	path := "/api/group/env/" + url.PathEscape(env) + "/targets"
+	query := url.Values{}
+	var out []*CollectTarget
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/{type}/{id}/top: Get the top functions of a profile snapshot
+func (c *Client) GetTopSummary(ctx context.Context, typ string, id string) (io.ReadCloser, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/top"
+	query := url.Values{}
+	return c.stream(ctx, "GET", path, query)
+}
+
+// GET /api/{type}/binaries: List stored binaries used for symbolization
+func (c *Client) ListBinaries(ctx context.Context, typ string) ([]*Binary, error) {
+	path := "/api/" + url.PathEscape(typ) + "/binaries"
+	query := url.Values{}
+	var out []*Binary
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/{type}/{id}/comments: List comments on a snapshot
+func (c *Client) ListComments(ctx context.Context, typ string, id string) ([]*Comment, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/comments"
+	query := url.Values{}
+	var out []*Comment
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/commits: List commits with their runs and scores
+func (c *Client) ListCommits(ctx context.Context) ([]*CommitSummary, error) {
+	path := "/api/commits"
+	query := url.Values{}
+	var out []*CommitSummary
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+type ListConfigVersionsParams struct {
+	Env  string
+	File string
+}
+
+// GET /api/group/history: List config history
+func (c *Client) ListConfigVersions(ctx context.Context, params *ListConfigVersionsParams) ([]*Version, error) {
+	path := "/api/group/history"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "env", params.Env)
+		setString(query, "file", params.File)
+	}
+	var out []*Version
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+type ListEntriesParams struct {
+	Status Status
+	Label  string
+	Group  string
+	Run    string
+	Url    string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+	Order  string
+}
+
+// GET /api/{type}: List snapshots of a type
+func (c *Client) ListEntries(ctx context.Context, typ string, params *ListEntriesParams) ([]*Entry, error) {
+	path := "/api/" + url.PathEscape(typ)
+	query := url.Values{}
+	if params != nil {
+		setString(query, "status", string(params.Status))
+		setString(query, "label", params.Label)
+		setString(query, "group", params.Group)
+		setString(query, "run", params.Run)
+		setString(query, "url", params.Url)
+		setTime(query, "since", params.Since)
+		setTime(query, "until", params.Until)
+		setInt(query, "limit", params.Limit)
+		setInt(query, "offset", params.Offset)
+		setString(query, "order", params.Order)
+	}
+	var out []*Entry
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+type ListMemoRefsParams struct {
+	Snapshot string
+	Run      string
+}
+
+// GET /api/memo/refs: List memos referencing a snapshot or run
+func (c *Client) ListMemoRefs(ctx context.Context, params *ListMemoRefsParams) ([]*Memo, error) {
+	path := "/api/memo/refs"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "snapshot", params.Snapshot)
+		setString(query, "run", params.Run)
+	}
+	var out []*Memo
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+type ListMemosParams struct {
+	Status Status
+	Label  string
+	Group  string
+	Run    string
+	Url    string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+	Order  string
+}
+
+// GET /api/memo: List memos
+func (c *Client) ListMemos(ctx context.Context, params *ListMemosParams) ([]*Entry, error) {
+	path := "/api/memo"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "status", string(params.Status))
+		setString(query, "label", params.Label)
+		setString(query, "group", params.Group)
+		setString(query, "run", params.Run)
+		setString(query, "url", params.Url)
+		setTime(query, "since", params.Since)
+		setTime(query, "until", params.Until)
+		setInt(query, "limit", params.Limit)
+		setInt(query, "offset", params.Offset)
+		setString(query, "order", params.Order)
+	}
+	var out []*Entry
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/projects: List projects
+func (c *Client) ListProjects(ctx context.Context) ([]*Project, error) {
+	path := "/api/projects"
+	query := url.Values{}
+	var out []*Project
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/runs: List runs
+func (c *Client) ListRuns(ctx context.Context) ([]*Run, error) {
+	path := "/api/runs"
+	query := url.Values{}
+	var out []*Run
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+type ListScoresParams struct {
+	Run string
+}
+
+// GET /api/scores: List benchmark scores
+func (c *Client) ListScores(ctx context.Context, params *ListScoresParams) ([]*ScorePoint, error) {
+	path := "/api/scores"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "run", params.Run)
+	}
+	var out []*ScorePoint
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/{type}/{id}/views: List the named views of a snapshot
+func (c *Client) ListSnapshotViews(ctx context.Context, typ string, id string) ([]string, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/views"
+	query := url.Values{}
+	var out []string
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// GET /api/{type}/sessions: List running pprof web UI sessions
+func (c *Client) ListViewerSessions(ctx context.Context, typ string) ([]*SessionStatus, error) {
+	path := "/api/" + url.PathEscape(typ) + "/sessions"
+	query := url.Values{}
+	var out []*SessionStatus
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// PUT /api/{type}/{id}/pin: Pin a snapshot so retention keeps it
+func (c *Client) PinEntry(ctx context.Context, typ string, id string) error {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/pin"
+	query := url.Values{}
+	return c.call(ctx, "PUT", path, query, nil, nil)
+}
+
+type QueryHistoryParams struct {
+	Type   string
+	Status Status
+	Label  string
+	Group  string
+	Run    string
+	Url    string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+	Order  string
+}
+
+// GET /api/history: Search snapshots of every type
+func (c *Client) QueryHistory(ctx context.Context, params *QueryHistoryParams) ([]*Entry, error) {
+	path := "/api/history"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "type", params.Type)
+		setString(query, "status", string(params.Status))
+		setString(query, "label", params.Label)
+		setString(query, "group", params.Group)
+		setString(query, "run", params.Run)
+		setString(query, "url", params.Url)
+		setTime(query, "since", params.Since)
+		setTime(query, "until", params.Until)
+		setInt(query, "limit", params.Limit)
+		setInt(query, "offset", params.Offset)
+		setString(query, "order", params.Order)
+	}
+	var out []*Entry
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+// POST /api/commits: Associate a commit with a run
+func (c *Client) RecordCommit(ctx context.Context, body *Commit) (*Commit, error) {
+	path := "/api/commits"
+	query := url.Values{}
+	var out *Commit
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// POST /api/scores: Record a benchmark score
+func (c *Client) RecordScore(ctx context.Context, body *ScoreRequest) (*Score, error) {
+	path := "/api/scores"
+	query := url.Values{}
+	var out *Score
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// PUT /api/{type}/{id}/label: Change the label and tags of a snapshot
+func (c *Client) RelabelEntry(ctx context.Context, typ string, id string, body *LabelRequest) error {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/label"
+	query := url.Values{}
+	return c.call(ctx, "PUT", path, query, body, nil)
+}
+
+// POST /api/{type}/{id}/reprocess: Process a snapshot again
+func (c *Client) ReprocessEntry(ctx context.Context, typ string, id string) error {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/reprocess"
+	query := url.Values{}
+	return c.call(ctx, "POST", path, query, nil, nil)
+}
+
+// POST /api/{type}/{id}/retry: Retry a failed snapshot
+func (c *Client) RetryEntry(ctx context.Context, typ string, id string) error {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/retry"
+	query := url.Values{}
+	return c.call(ctx, "POST", path, query, nil, nil)
+}
+
+type RollbackConfigVersionParams struct {
+	Before bool
+	Author string
+}
+
+// POST /api/group/history/{id}/rollback: Restore a config version
+func (c *Client) RollbackConfigVersion(ctx context.Context, id string, params *RollbackConfigVersionParams) error {
+	path := "/api/group/history/" + url.PathEscape(id) + "/rollback"
+	query := url.Values{}
+	if params != nil {
+		setBool(query, "before", params.Before)
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, nil, nil)
+}
+
+type SearchParams struct {
+	Q     string
+	Type  string
+	Limit int
+}
+
+// GET /api/search: Full-text search over labels, memos and processed outputs
+func (c *Client) Search(ctx context.Context, params *SearchParams) ([]*SearchHit, error) {
+	path := "/api/search"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "q", params.Q)
+		setString(query, "type", params.Type)
+		setInt(query, "limit", params.Limit)
+	}
+	var out []*SearchHit
+	err := c.call(ctx, "GET", path, query, nil, &out)
+	return out, err
+}
+
+type SetEnvironmentsParams struct {
+	Author string
+}
+
+// POST /api/group/environments: Replace environments
+func (c *Client) SetEnvironments(ctx context.Context, params *SetEnvironmentsParams, body *Environments) error {
+	path := "/api/group/environments"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, body, nil)
+}
+
+type SetSchedulesParams struct {
+	Author string
+}
+
+// POST /api/group/schedules: Replace schedules
+func (c *Client) SetSchedules(ctx context.Context, params *SetSchedulesParams, body []*Schedule) error {
+	path := "/api/group/schedules"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, body, nil)
+}
+
+type SetSchedulesInEnvironmentParams struct {
+	Author string
+}
+
+// POST /api/group/env/{env}/schedules: Replace schedules
+func (c *Client) SetSchedulesInEnvironment(ctx context.Context, env string, params *SetSchedulesInEnvironmentParams, body []*Schedule) error {
+	path := "/api/group/env/" + url.PathEscape(env) + "/schedules"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, body, nil)
+}
+
+type SetTargetsParams struct {
+	Author string
+}
+
+// POST /api/group/targets: Replace collect targets
+func (c *Client) SetTargets(ctx context.Context, params *SetTargetsParams, body []*CollectTarget) error {
+	path := "/api/group/targets"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, body, nil)
+}
+
+type SetTargetsInEnvironmentParams struct {
+	Author string
+}
+
+// POST /api/group/env/{env}/targets: Replace collect targets
+func (c *Client) SetTargetsInEnvironment(ctx context.Context, env string, params *SetTargetsInEnvironmentParams, body []*CollectTarget) error {
+	path := "/api/group/env/" + url.PathEscape(env) + "/targets"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, body, nil)
+}
+
+type StartScheduleParams struct {
+	Author string
+}
+
+// POST /api/group/schedules/{id}/start: Enable a schedule
+func (c *Client) StartSchedule(ctx context.Context, id string, params *StartScheduleParams) error {
+	path := "/api/group/schedules/" + url.PathEscape(id) + "/start"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, nil, nil)
+}
+
+type StartScheduleInEnvironmentParams struct {
+	Author string
+}
+
+// POST /api/group/env/{env}/schedules/{id}/start: Enable a schedule
+func (c *Client) StartScheduleInEnvironment(ctx context.Context, env string, id string, params *StartScheduleInEnvironmentParams) error {
+	path := "/api/group/env/" + url.PathEscape(env) + "/schedules/" + url.PathEscape(id) + "/start"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, nil, nil)
+}
+
+type StopScheduleParams struct {
+	Author string
+}
+
+// POST /api/group/schedules/{id}/stop: Disable a schedule
+func (c *Client) StopSchedule(ctx context.Context, id string, params *StopScheduleParams) error {
+	path := "/api/group/schedules/" + url.PathEscape(id) + "/stop"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, nil, nil)
+}
+
+type StopScheduleInEnvironmentParams struct {
+	Author string
+}
+
+// POST /api/group/env/{env}/schedules/{id}/stop: Disable a schedule
+func (c *Client) StopScheduleInEnvironment(ctx context.Context, env string, id string, params *StopScheduleInEnvironmentParams) error {
+	path := "/api/group/env/" + url.PathEscape(env) + "/schedules/" + url.PathEscape(id) + "/stop"
+	query := url.Values{}
+	if params != nil {
+		setString(query, "author", params.Author)
+	}
+	return c.call(ctx, "POST", path, query, nil, nil)
+}
+
+// DELETE /api/{type}/sessions/{key}: Shut down a pprof web UI session; it restarts on next access
+func (c *Client) StopViewerSession(ctx context.Context, typ string, key string) error {
+	path := "/api/" + url.PathEscape(typ) + "/sessions/" + url.PathEscape(key)
+	query := url.Values{}
+	return c.call(ctx, "DELETE", path, query, nil, nil)
+}
+
+// DELETE /api/{type}/{id}/pin: Unpin a snapshot
+func (c *Client) UnpinEntry(ctx context.Context, typ string, id string) error {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/pin"
+	query := url.Values{}
+	return c.call(ctx, "DELETE", path, query, nil, nil)
+}
+
+// POST /api/{type}/binaries: Upload a target binary, stored by build ID
+func (c *Client) UploadBinary(ctx context.Context, typ string, body io.Reader, contentType string) (*Binary, error) {
+	path := "/api/" + url.PathEscape(typ) + "/binaries"
+	query := url.Values{}
+	var out *Binary
+	err := c.upload(ctx, "POST", path, query, body, contentType, &out)
+	return out, err
+}
+
+// POST /api/{type}/upload: Upload a snapshot file
+func (c *Client) UploadSnapshot(ctx context.Context, typ string, body io.Reader, contentType string) (*Snapshot, error) {
+	path := "/api/" + url.PathEscape(typ) + "/upload"
+	query := url.Values{}
+	var out *Snapshot
+	err := c.upload(ctx, "POST", path, query, body, contentType, &out)
+	return out, err
+}
+
+// POST /api/group/validate: Validate collect targets without saving them
+func (c *Client) ValidateTargets(ctx context.Context, body []*CollectTarget) (*ValidationReport, error) {
+	path := "/api/group/validate"
+	query := url.Values{}
+	var out *ValidationReport
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// POST /api/group/env/{env}/validate: Validate collect targets without saving them
+func (c *Client) ValidateTargetsInEnvironment(ctx context.Context, env string, body []*CollectTarget) (*ValidationReport, error) {
+	path := "/api/group/env/" + url.PathEscape(env) + "/validate"
+	query := url.Values{}
+	var out *ValidationReport
+	err := c.call(ctx, "POST", path, query, body, &out)
+	return out, err
+}
+
+// POST /api/{type}/{id}/verify: Verify the checksum of a snapshot
+func (c *Client) VerifyEntry(ctx context.Context, typ string, id string) (*Entry, error) {
+	path := "/api/" + url.PathEscape(typ) + "/" + url.PathEscape(id) + "/verify"
+	query := url.Values{}
+	var out *Entry
+	err := c.call(ctx, "POST", path, query, nil, &out)
+	return out, err
+}
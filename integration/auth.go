@@ -0,0 +1,42 @@
+package integration
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+const agentTokenHeader = "X-Pprotein-Token"
+
+var agentToken atomic.Value
+
+func init() {
+	agentToken.Store(getEnvOrDefault("PPROTEIN_AGENT_TOKEN", ""))
+}
+
+func SetAgentToken(token string) {
+	agentToken.Store(token)
+}
+
+func tokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		token := agentToken.Load().(string)
+		if token == "" || validToken(r, token) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		rw.Header().Set("WWW-Authenticate", `Bearer realm="pprotein"`)
+		http.Error(rw, "invalid or missing agent token", http.StatusUnauthorized)
+	})
+}
+
+func validToken(r *http.Request, token string) bool {
+	given := r.Header.Get(agentTokenHeader)
+	if given == "" {
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			given = bearer
+		}
+	}
+	return given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
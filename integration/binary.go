@@ -0,0 +1,33 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+func handleBinary(w http.ResponseWriter, r *http.Request) {
+	exe, err := os.Executable()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to locate executable: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(exe)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to open executable: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	finfo, err := file.Stat()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to stat executable: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(exe)))
+	http.ServeContent(w, r, "", finfo.ModTime(), file)
+}
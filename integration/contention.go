@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+type (
+	contentionProfile struct {
+		mu      *sync.Mutex
+		active  int
+		name    string
+		enable  func()
+		apply   func()
+		disable func()
+	}
+)
+
+var (
+	blockProfileRate      = getEnvIntOrDefault("PPROTEIN_BLOCK_PROFILE_RATE", 1)
+	mutexProfileFraction  = getEnvIntOrDefault("PPROTEIN_MUTEX_PROFILE_FRACTION", 1)
+	previousMutexFraction int
+
+	blockProfile = newBlockProfile()
+	mutexProfile = newMutexProfile()
+)
+
+func newBlockProfile() *contentionProfile {
+	return &contentionProfile{
+		mu:      &sync.Mutex{},
+		name:    "block",
+		enable:  func() { runtime.SetBlockProfileRate(blockProfileRate) },
+		apply:   func() { runtime.SetBlockProfileRate(blockProfileRate) },
+		disable: func() { runtime.SetBlockProfileRate(0) },
+	}
+}
+
+func newMutexProfile() *contentionProfile {
+	return &contentionProfile{
+		mu:      &sync.Mutex{},
+		name:    "mutex",
+		enable:  func() { previousMutexFraction = runtime.SetMutexProfileFraction(mutexProfileFraction) },
+		apply:   func() { runtime.SetMutexProfileFraction(mutexProfileFraction) },
+		disable: func() { runtime.SetMutexProfileFraction(previousMutexFraction) },
+	}
+}
+
+func (p *contentionProfile) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("seconds") == "" {
+		q := r.URL.Query()
+		q.Set("seconds", "10")
+		r.URL.RawQuery = q.Encode()
+	}
+
+	p.acquire()
+	defer p.release()
+
+	pprof.Handler(p.name).ServeHTTP(w, r)
+}
+
+func (p *contentionProfile) configure(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fn()
+	if p.active > 0 {
+		p.apply()
+	}
+}
+
+func (p *contentionProfile) inspect(fn func(active bool)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fn(p.active > 0)
+}
+
+func (p *contentionProfile) acquire() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.active == 0 {
+		p.enable()
+	}
+	p.active++
+}
+
+func (p *contentionProfile) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.active--
+	if p.active == 0 {
+		p.disable()
+	}
+}
+
+func getEnvIntOrDefault(key string, def int) int {
+	v, err := strconv.Atoi(getEnvOrDefault(key, strconv.Itoa(def)))
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	Control struct {
+		Duration int
+
+		HTTPLog    *bool
+		SampleRate *float64
+
+		BlockProfile         *bool
+		BlockProfileRate     *int
+		MutexProfile         *bool
+		MutexProfileFraction *int
+	}
+
+	ControlState struct {
+		HTTPLog              *AccessLogSettings
+		BlockProfile         bool
+		BlockProfileRate     int
+		MutexProfile         bool
+		MutexProfileFraction int
+	}
+)
+
+func ApplyControl(ctl *Control) error {
+	if ctl.Duration < 0 {
+		return fmt.Errorf("duration must not be negative: %v", ctl.Duration)
+	}
+	if (isTrue(ctl.BlockProfile) || isTrue(ctl.MutexProfile)) && ctl.Duration == 0 {
+		return fmt.Errorf("duration is required to enable contention profiling")
+	}
+	revertAfter := time.Duration(ctl.Duration) * time.Second
+
+	if ctl.HTTPLog != nil || ctl.SampleRate != nil {
+		prev := accessLogSettings.Load()
+		settings := GetAccessLogSettings()
+		if ctl.HTTPLog != nil {
+			settings.Disabled = !*ctl.HTTPLog
+		}
+		if ctl.SampleRate != nil {
+			settings.SampleRate = *ctl.SampleRate
+		}
+		if err := SetAccessLogSettings(settings); err != nil {
+			return err
+		}
+		if revertAfter > 0 {
+			applied := accessLogSettings.Load()
+			time.AfterFunc(revertAfter, func() {
+				accessLogSettings.CompareAndSwap(applied, prev)
+			})
+		}
+	}
+
+	if ctl.BlockProfileRate != nil {
+		blockProfile.configure(func() { blockProfileRate = *ctl.BlockProfileRate })
+	}
+	if ctl.MutexProfileFraction != nil {
+		mutexProfile.configure(func() { mutexProfileFraction = *ctl.MutexProfileFraction })
+	}
+	for _, toggle := range []struct {
+		on      *bool
+		profile *contentionProfile
+	}{{ctl.BlockProfile, blockProfile}, {ctl.MutexProfile, mutexProfile}} {
+		if isTrue(toggle.on) {
+			toggle.profile.acquire()
+			time.AfterFunc(revertAfter, toggle.profile.release)
+		}
+	}
+	return nil
+}
+
+func CurrentControlState() *ControlState {
+	state := &ControlState{HTTPLog: GetAccessLogSettings()}
+	blockProfile.inspect(func(active bool) {
+		state.BlockProfile = active
+		state.BlockProfileRate = blockProfileRate
+	})
+	mutexProfile.inspect(func(active bool) {
+		state.MutexProfile = active
+		state.MutexProfileFraction = mutexProfileFraction
+	})
+	return state
+}
+
+func handleControl(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		ctl := &Control{}
+		if err := json.NewDecoder(r.Body).Decode(ctl); err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := ApplyControl(ctl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CurrentControlState())
+}
+
+func isTrue(v *bool) bool {
+	return v != nil && *v
+}
@@ -1,10 +1,13 @@
 package integration
 
 import (
+	"expvar"
 	"log"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/felixge/fgprof"
 	"github.com/goccy/go-json"
@@ -16,7 +19,23 @@ import (
 var (
 	httplogPath       = getEnvOrDefault("PPROTEIN_HTTPLOG", "/var/log/nginx/access.log")
 	slowlogPath       = getEnvOrDefault("PPROTEIN_SLOWLOG", "/var/log/mysql/mysql-slow.log")
+	pgslowlogPath     = getEnvOrDefault("PPROTEIN_PGSLOWLOG", "/var/log/postgresql/postgresql.log")
 	gitRepositoryPath = getEnvOrDefault("PPROTEIN_GIT_REPOSITORY", ".")
+
+	logFiltersMu = &sync.Mutex{}
+	logFilters   = map[string][2]string{}
+
+	httplogFormat   = getEnvOrDefault("PPROTEIN_HTTPLOG_FORMAT", "ltsv")
+	slowlogFormat   = getEnvOrDefault("PPROTEIN_SLOWLOG_FORMAT", "slowlog")
+	pgslowlogFormat = getEnvOrDefault("PPROTEIN_PGSLOWLOG_FORMAT", "pgslowlog")
+
+	httplogUnit   = os.Getenv("PPROTEIN_HTTPLOG_UNIT")
+	slowlogUnit   = os.Getenv("PPROTEIN_SLOWLOG_UNIT")
+	pgslowlogUnit = os.Getenv("PPROTEIN_PGSLOWLOG_UNIT")
+
+	httplogContainer   = os.Getenv("PPROTEIN_HTTPLOG_CONTAINER")
+	slowlogContainer   = os.Getenv("PPROTEIN_SLOWLOG_CONTAINER")
+	pgslowlogContainer = os.Getenv("PPROTEIN_PGSLOWLOG_CONTAINER")
 )
 
 func NewDebugHandler() http.Handler {
@@ -26,12 +45,22 @@ func NewDebugHandler() http.Handler {
 }
 
 func RegisterDebugHandlers(r *mux.Router) {
+	r.Use(tokenMiddleware)
 	r.Use(gitRepositoryMiddleware)
 
-	r.Handle("/debug/log/httplog", tail.NewTailHandler(httplogPath))
-	r.Handle("/debug/log/slowlog", tail.NewTailHandler(slowlogPath))
+	r.Handle("/debug/log/httplog", newLogHandler("httplog", httplogPath, httplogUnit, httplogContainer, httplogFormat))
+	r.Handle("/debug/log/slowlog", slowlogFlushMiddleware(newLogHandler("slowlog", slowlogPath, slowlogUnit, slowlogContainer, slowlogFormat)))
+	r.HandleFunc("/debug/mysql/slowlog", handleSlowlogControl)
+	r.Handle("/debug/log/pgslowlog", newLogHandler("pgslowlog", pgslowlogPath, pgslowlogUnit, pgslowlogContainer, pgslowlogFormat))
 
 	r.Handle("/debug/fgprof", fgprof.Handler())
+	r.Handle("/debug/contention/block", blockProfile)
+	r.Handle("/debug/contention/mutex", mutexProfile)
+	r.Handle("/debug/vars", expvar.Handler())
+	r.Handle("/debug/system", &systemHandler{})
+	r.HandleFunc("/debug/settings/httplog", handleAccessLogSettings)
+	r.HandleFunc("/debug/control", handleControl)
+	r.HandleFunc("/debug/binary", handleBinary)
 
 	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
@@ -40,6 +69,41 @@ func RegisterDebugHandlers(r *mux.Router) {
 	r.HandleFunc("/debug/pprof/{h:.*}", pprof.Index)
 }
 
+func SetLogFilter(name string, include string, exclude string) error {
+	if err := tail.NewTailHandler("", "").SetFilter(include, exclude); err != nil {
+		return err
+	}
+
+	logFiltersMu.Lock()
+	defer logFiltersMu.Unlock()
+
+	logFilters[name] = [2]string{include, exclude}
+	return nil
+}
+
+func newLogHandler(name string, path string, unit string, container string, format string) http.Handler {
+	var h *tail.TailHandler
+	if container != "" {
+		h = tail.NewDockerHandler(container, format)
+	} else if unit != "" {
+		h = tail.NewJournalHandler(unit, format)
+	} else {
+		h = tail.NewMultiTailHandler(strings.Split(path, ","), format)
+	}
+
+	logFiltersMu.Lock()
+	filter, ok := logFilters[name]
+	logFiltersMu.Unlock()
+	if !ok {
+		prefix := "PPROTEIN_" + strings.ToUpper(name)
+		filter = [2]string{os.Getenv(prefix + "_INCLUDE"), os.Getenv(prefix + "_EXCLUDE")}
+	}
+	if err := h.SetFilter(filter[0], filter[1]); err != nil {
+		log.Printf("[!] ignoring %v filter: %v", name, err)
+	}
+	return h
+}
+
 func gitRepositoryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		defer next.ServeHTTP(rw, r)
@@ -0,0 +1,130 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/goccy/go-json"
+)
+
+type (
+	slowlogControl struct {
+		DSN           string
+		Enable        *bool
+		LongQueryTime *float64
+		Flush         bool
+	}
+
+	slowlogState struct {
+		SlowQueryLog     bool
+		LongQueryTime    float64
+		SlowQueryLogFile string
+	}
+)
+
+const mysqlTimeout = 10 * time.Second
+
+var mysqlDSN = getEnvOrDefault("PPROTEIN_MYSQL_DSN", "")
+
+func handleSlowlogControl(w http.ResponseWriter, r *http.Request) {
+	ctl := &slowlogControl{}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(ctl); err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ctl.DSN == "" {
+		ctl.DSN = mysqlDSN
+	}
+	if ctl.DSN == "" {
+		http.Error(w, "DSN is required (or set PPROTEIN_MYSQL_DSN)", http.StatusBadRequest)
+		return
+	}
+
+	state, err := controlSlowlog(r.Context(), ctl)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+func controlSlowlog(ctx context.Context, ctl *slowlogControl) (*slowlogState, error) {
+	ctx, cancel := context.WithTimeout(ctx, mysqlTimeout)
+	defer cancel()
+
+	db, err := sql.Open("mysql", ctl.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if ctl.LongQueryTime != nil {
+		if _, err := db.ExecContext(ctx, "SET GLOBAL long_query_time = ?", *ctl.LongQueryTime); err != nil {
+			return nil, fmt.Errorf("failed to set long_query_time: %w", err)
+		}
+	}
+	if ctl.Enable != nil {
+		value := "OFF"
+		if *ctl.Enable {
+			value = "ON"
+		}
+		if _, err := db.ExecContext(ctx, "SET GLOBAL slow_query_log = "+value); err != nil {
+			return nil, fmt.Errorf("failed to set slow_query_log: %w", err)
+		}
+	}
+	if ctl.Flush {
+		if _, err := db.ExecContext(ctx, "FLUSH SLOW LOGS"); err != nil {
+			return nil, fmt.Errorf("failed to flush slow logs: %w", err)
+		}
+	}
+
+	state := &slowlogState{}
+	var enabled string
+	row := db.QueryRowContext(ctx, "SELECT @@GLOBAL.slow_query_log, @@GLOBAL.long_query_time, @@GLOBAL.slow_query_log_file")
+	if err := row.Scan(&enabled, &state.LongQueryTime, &state.SlowQueryLogFile); err != nil {
+		return nil, fmt.Errorf("failed to read slow log settings: %w", err)
+	}
+	state.SlowQueryLog = enabled == "1" || strings.EqualFold(enabled, "ON")
+	return state, nil
+}
+
+func slowlogFlushMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flush := map[string]bool{}
+		for _, v := range strings.Split(r.URL.Query().Get("flush"), ",") {
+			flush[v] = true
+		}
+		if mysqlDSN == "" || (!flush["before"] && !flush["after"]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if flush["before"] {
+			if _, err := controlSlowlog(r.Context(), &slowlogControl{DSN: mysqlDSN, Flush: true}); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if flush["after"] {
+			defer func() {
+				if _, err := controlSlowlog(context.Background(), &slowlogControl{DSN: mysqlDSN, Flush: true}); err != nil {
+					log.Printf("failed to flush slow logs: %v", err)
+				}
+			}()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+package nethttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaz/pprotein/integration"
+)
+
+type (
+	Mux interface {
+		Handle(pattern string, handler http.Handler)
+	}
+
+	accessLogger struct {
+		mu *sync.Mutex
+		w  io.Writer
+	}
+
+	responseRecorder struct {
+		http.ResponseWriter
+		status int
+		size   int
+	}
+)
+
+const debugPrefix = "/debug/"
+
+func Integrate(mux Mux) {
+	EnableDebugHandler(mux)
+}
+
+func EnableDebugHandler(mux Mux) {
+	mux.Handle(debugPrefix, Handler())
+}
+
+func Handler() http.Handler {
+	return integration.NewDebugHandler()
+}
+
+func Middleware(next http.Handler) http.Handler {
+	debug := Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, debugPrefix) {
+			debug.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func AccessLog(w io.Writer) func(http.Handler) http.Handler {
+	l := &accessLogger{mu: &sync.Mutex{}, w: w}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, debugPrefix) || !integration.ShouldLogRequest(r) {
+				next.ServeHTTP(rw, r)
+				return
+			}
+
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: rw, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			l.write(r, rec, start)
+		})
+	}
+}
+
+func (l *accessLogger) write(r *http.Request, rec *responseRecorder, start time.Time) {
+	line := fmt.Sprintf("time:%s\tmethod:%s\turi:%s\tstatus:%d\tsize:%d\treqtime:%.6f\n",
+		start.Format(time.RFC3339Nano), r.Method, integration.RecordedURI(r), rec.status, rec.size, time.Since(start).Seconds())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, line)
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,420 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	PushOptions struct {
+		Server   string
+		Name     string
+		Token    string
+		URL      string
+		Types    []string
+		Tags     map[string]string
+		Duration int
+		Interval time.Duration
+	}
+
+	pushSource struct {
+		Path  string
+		Query url.Values
+		Timed bool
+	}
+
+	pushCommand struct {
+		GroupId    string
+		RunId      string
+		ScheduleId string
+		Types      []string
+		Duration   int
+
+		Control json.RawMessage
+	}
+
+	pushMeta struct {
+		GroupId    string
+		RunId      string
+		ScheduleId string
+		Label      string
+		Tags       map[string]string
+		Duration   int
+	}
+
+	pushClient struct {
+		opts    *PushOptions
+		handler http.Handler
+		client  *http.Client
+		mu      *sync.Mutex
+	}
+
+	fileResponse struct {
+		header http.Header
+		status int
+		file   *os.File
+	}
+)
+
+const (
+	pushPollTimeout   = 30
+	pushRetryDelay    = 5 * time.Second
+	heartbeatInterval = 30 * time.Second
+)
+
+var pushSources = map[string]*pushSource{
+	"pprof":     {Path: "/debug/pprof/profile", Timed: true},
+	"fgprof":    {Path: "/debug/fgprof", Timed: true},
+	"block":     {Path: "/debug/contention/block", Timed: true},
+	"mutex":     {Path: "/debug/contention/mutex", Timed: true},
+	"trace":     {Path: "/debug/pprof/trace", Timed: true},
+	"goroutine": {Path: "/debug/pprof/goroutine", Query: url.Values{"debug": {"2"}}},
+	"system":    {Path: "/debug/system", Timed: true},
+	"httplog":   {Path: "/debug/log/httplog", Timed: true},
+	"slowlog":   {Path: "/debug/log/slowlog", Timed: true},
+	"pgslowlog": {Path: "/debug/log/pgslowlog", Timed: true},
+}
+
+func Push(opts *PushOptions) error {
+	if opts.Server == "" || opts.Name == "" {
+		return fmt.Errorf("server and name are required")
+	}
+	if len(opts.Types) == 0 {
+		opts.Types = []string{"pprof", "httplog", "slowlog"}
+	}
+	for _, typ := range opts.Types {
+		if _, ok := pushSources[typ]; !ok {
+			return fmt.Errorf("unsupported type: %v", typ)
+		}
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = 30
+	}
+
+	p := &pushClient{
+		opts:    opts,
+		handler: NewDebugHandler(),
+		client:  &http.Client{},
+		mu:      &sync.Mutex{},
+	}
+
+	if opts.URL != "" {
+		p.announce()
+		return nil
+	}
+	if opts.Interval > 0 {
+		go p.schedule()
+	}
+	p.poll()
+	return nil
+}
+
+func (p *pushClient) announce() {
+	registered := false
+	for {
+		if !registered {
+			if err := p.register(); err != nil {
+				log.Printf("[!] failed to register to %v: %v", p.opts.Server, err)
+				time.Sleep(pushRetryDelay)
+				continue
+			}
+			registered = true
+			log.Printf("[PUSH] announced %v to %v as %v", p.opts.URL, p.opts.Server, p.opts.Name)
+		}
+
+		time.Sleep(heartbeatInterval)
+		status, err := p.heartbeat()
+		if status == http.StatusNotFound {
+			registered = false
+			continue
+		}
+		if err != nil {
+			log.Printf("[!] failed to send heartbeat to %v: %v", p.opts.Server, err)
+		}
+	}
+}
+
+func (p *pushClient) schedule() {
+	for range time.Tick(p.opts.Interval) {
+		grpId := time.Now().Format("2006-01-02_15-04-05.999999")
+		p.run(&pushCommand{GroupId: grpId, RunId: grpId, Types: p.opts.Types, Duration: p.opts.Duration})
+	}
+}
+
+func (p *pushClient) poll() {
+	registered := false
+	for {
+		if !registered {
+			if err := p.register(); err != nil {
+				log.Printf("[!] failed to register to %v: %v", p.opts.Server, err)
+				time.Sleep(pushRetryDelay)
+				continue
+			}
+			registered = true
+			log.Printf("[PUSH] registered to %v as %v", p.opts.Server, p.opts.Name)
+		}
+
+		cmd, status, err := p.next()
+		if status == http.StatusNotFound {
+			registered = false
+			continue
+		}
+		if err != nil {
+			log.Printf("[!] failed to poll %v: %v", p.opts.Server, err)
+			time.Sleep(pushRetryDelay)
+			continue
+		}
+		if cmd != nil && cmd.Control != nil {
+			ctl := &Control{}
+			if err := json.Unmarshal(cmd.Control, ctl); err != nil {
+				log.Printf("[!] failed to parse control: %v", err)
+			} else if err := ApplyControl(ctl); err != nil {
+				log.Printf("[!] failed to apply control: %v", err)
+			}
+		} else if cmd != nil {
+			go p.run(cmd)
+		}
+	}
+}
+
+func (p *pushClient) register() error {
+	host, _ := os.Hostname()
+	req := map[string]any{
+		"Name":     p.opts.Name,
+		"Host":     host,
+		"Types":    p.opts.Types,
+		"Tags":     p.opts.Tags,
+		"Duration": p.opts.Duration,
+	}
+	if p.opts.URL != "" {
+		endpoints := map[string]string{}
+		for _, typ := range p.opts.Types {
+			source := pushSources[typ]
+			endpoints[typ] = source.Path
+			if len(source.Query) > 0 {
+				endpoints[typ] += "?" + source.Query.Encode()
+			}
+		}
+		req["URL"] = p.opts.URL
+		req["Endpoints"] = endpoints
+		req["AgentToken"] = agentToken.Load().(string)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	resp, err := p.do(context.Background(), http.MethodPost, "/api/agents/register", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return unexpectedStatus(resp)
+	}
+	return nil
+}
+
+func (p *pushClient) heartbeat() (int, error) {
+	resp, err := p.do(context.Background(), http.MethodPost, fmt.Sprintf("/api/agents/%s/heartbeat", url.PathEscape(p.opts.Name)), "", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, unexpectedStatus(resp)
+	}
+	return resp.StatusCode, nil
+}
+
+func (p *pushClient) next() (*pushCommand, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), (pushPollTimeout+30)*time.Second)
+	defer cancel()
+
+	resp, err := p.do(ctx, http.MethodGet, fmt.Sprintf("/api/agents/%s/poll?timeout=%d", url.PathEscape(p.opts.Name), pushPollTimeout), "", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil, resp.StatusCode, nil
+	case http.StatusOK:
+		cmd := &pushCommand{}
+		if err := json.NewDecoder(resp.Body).Decode(cmd); err != nil {
+			return nil, resp.StatusCode, fmt.Errorf("failed to decode command: %w", err)
+		}
+		return cmd, resp.StatusCode, nil
+	}
+	return nil, resp.StatusCode, unexpectedStatus(resp)
+}
+
+func (p *pushClient) run(cmd *pushCommand) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	types := cmd.Types
+	if len(types) == 0 {
+		types = p.opts.Types
+	}
+	duration := cmd.Duration
+	if duration <= 0 {
+		duration = p.opts.Duration
+	}
+
+	wg := &sync.WaitGroup{}
+	for _, typ := range types {
+		source, ok := pushSources[typ]
+		if !ok {
+			log.Printf("[!] skipping unsupported type: %v", typ)
+			continue
+		}
+
+		wg.Add(1)
+		go func(typ string, source *pushSource) {
+			defer wg.Done()
+			if err := p.push(typ, source, cmd, duration); err != nil {
+				log.Printf("[!] failed to push %v: %v", typ, err)
+			}
+		}(typ, source)
+	}
+	wg.Wait()
+}
+
+func (p *pushClient) push(typ string, source *pushSource, cmd *pushCommand, duration int) error {
+	query := url.Values{}
+	for k, v := range source.Query {
+		query[k] = v
+	}
+	if source.Timed {
+		query.Set("seconds", strconv.Itoa(duration))
+	}
+
+	file, err := os.CreateTemp("", "pprotein-push-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	req, err := http.NewRequest(http.MethodGet, source.Path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set(agentTokenHeader, agentToken.Load().(string))
+
+	res := &fileResponse{header: http.Header{}, status: http.StatusOK, file: file}
+	p.handler.ServeHTTP(res, req)
+	if res.status != http.StatusOK {
+		file.Seek(0, io.SeekStart)
+		msg, _ := io.ReadAll(io.LimitReader(file, 1024))
+		return fmt.Errorf("local collection failed: status %d: %s", res.status, strings.TrimSpace(string(msg)))
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind: %w", err)
+	}
+
+	meta, err := json.Marshal(&pushMeta{
+		GroupId:    cmd.GroupId,
+		RunId:      cmd.RunId,
+		ScheduleId: cmd.ScheduleId,
+		Label:      p.opts.Name,
+		Tags:       p.opts.Tags,
+		Duration:   duration,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	body, writer := io.Pipe()
+	mw := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeUpload(mw, typ, meta, res.header.Get("X-Git-Repository"), file))
+	}()
+
+	resp, err := p.do(context.Background(), http.MethodPost, fmt.Sprintf("/api/agents/%s/upload", url.PathEscape(p.opts.Name)), mw.FormDataContentType(), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return unexpectedStatus(resp)
+	}
+	return nil
+}
+
+func writeUpload(mw *multipart.Writer, typ string, meta []byte, repo string, file io.Reader) error {
+	if err := mw.WriteField("type", typ); err != nil {
+		return err
+	}
+	if err := mw.WriteField("meta", string(meta)); err != nil {
+		return err
+	}
+	if repo != "" {
+		if err := mw.WriteField("repository", repo); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("file", typ)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+func (p *pushClient) do(ctx context.Context, method string, path string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(p.opts.Server, "/")+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if p.opts.Token != "" {
+		req.Header.Set(agentTokenHeader, p.opts.Token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+func unexpectedStatus(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+func (r *fileResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *fileResponse) Write(b []byte) (int, error) {
+	return r.file.Write(b)
+}
+
+func (r *fileResponse) WriteHeader(status int) {
+	r.status = status
+}
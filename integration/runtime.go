@@ -0,0 +1,26 @@
+package integration
+
+import (
+	"expvar"
+	"os"
+	"runtime"
+)
+
+func init() {
+	publish("goroutines", func() interface{} {
+		return runtime.NumGoroutine()
+	})
+	publish("fds", func() interface{} {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			return nil
+		}
+		return len(entries)
+	})
+}
+
+func publish(name string, fn func() interface{}) {
+	if expvar.Get(name) == nil {
+		expvar.Publish(name, expvar.Func(fn))
+	}
+}
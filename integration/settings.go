@@ -0,0 +1,115 @@
+package integration
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	AccessLogSettings struct {
+		Disabled     bool
+		SampleRate   float64
+		Exclude      []string
+		MaxURILength int
+	}
+
+	compiledAccessLogSettings struct {
+		*AccessLogSettings
+		exclude []*regexp.Regexp
+	}
+)
+
+var accessLogSettings atomic.Pointer[compiledAccessLogSettings]
+
+func init() {
+	settings := &AccessLogSettings{SampleRate: 1}
+	if v := getEnvOrDefault("PPROTEIN_ACCESSLOG_SAMPLE_RATE", ""); v != "" {
+		settings.SampleRate, _ = strconv.ParseFloat(v, 64)
+	}
+	if v := getEnvOrDefault("PPROTEIN_ACCESSLOG_EXCLUDE", ""); v != "" {
+		settings.Exclude = strings.Split(v, ",")
+	}
+	if v := getEnvOrDefault("PPROTEIN_ACCESSLOG_MAX_URI_LENGTH", ""); v != "" {
+		settings.MaxURILength, _ = strconv.Atoi(v)
+	}
+	if err := SetAccessLogSettings(settings); err != nil {
+		log.Printf("[!] ignoring access log settings from environment: %v", err)
+		SetAccessLogSettings(&AccessLogSettings{SampleRate: 1})
+	}
+}
+
+func SetAccessLogSettings(settings *AccessLogSettings) error {
+	if settings.SampleRate < 0 || settings.SampleRate > 1 {
+		return fmt.Errorf("sample rate must be between 0 and 1: %v", settings.SampleRate)
+	}
+	if settings.MaxURILength < 0 {
+		return fmt.Errorf("max uri length must not be negative: %v", settings.MaxURILength)
+	}
+
+	compiled := &compiledAccessLogSettings{AccessLogSettings: settings}
+	for _, pattern := range settings.Exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+		compiled.exclude = append(compiled.exclude, re)
+	}
+	accessLogSettings.Store(compiled)
+	return nil
+}
+
+func GetAccessLogSettings() *AccessLogSettings {
+	copied := *accessLogSettings.Load().AccessLogSettings
+	return &copied
+}
+
+func ShouldLogRequest(r *http.Request) bool {
+	s := accessLogSettings.Load()
+	if s.Disabled {
+		return false
+	}
+	for _, re := range s.exclude {
+		if re.MatchString(r.URL.Path) {
+			return false
+		}
+	}
+	return s.SampleRate >= 1 || rand.Float64() < s.SampleRate
+}
+
+func RecordedURI(r *http.Request) string {
+	uri := r.URL.RequestURI()
+	if max := accessLogSettings.Load().MaxURILength; max > 0 && len(uri) > max {
+		return uri[:max]
+	}
+	return uri
+}
+
+func handleAccessLogSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		settings := GetAccessLogSettings()
+		if err := json.NewDecoder(r.Body).Decode(settings); err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := SetAccessLogSettings(settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetAccessLogSettings())
+}
@@ -1,15 +1,71 @@
 package standalone
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/kaz/pprotein/integration"
 )
 
+type (
+	TLSOptions struct {
+		CertFile     string
+		KeyFile      string
+		ClientCAFile string
+	}
+)
+
 func Integrate(addr string) {
-	log.Printf("[DEBUG_SERVER] Listening on %v\n", addr)
-	if err := http.ListenAndServe(addr, integration.NewDebugHandler()); err != nil {
+	IntegrateTLS(addr, nil)
+}
+
+func IntegrateTLS(addr string, opts *TLSOptions) {
+	server := &http.Server{Addr: addr, Handler: integration.NewDebugHandler()}
+
+	if opts == nil || opts.CertFile == "" {
+		log.Printf("[DEBUG_SERVER] Listening on %v\n", addr)
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("failed to start debug server: %v\n", err)
+		}
+		return
+	}
+
+	tlsConfig, err := opts.config()
+	if err != nil {
+		log.Printf("failed to configure TLS: %v\n", err)
+		return
+	}
+	server.TLSConfig = tlsConfig
+
+	log.Printf("[DEBUG_SERVER] Listening on %v (TLS, client auth: %v)\n", addr, opts.ClientCAFile != "")
+	if err := server.ListenAndServeTLS(opts.CertFile, opts.KeyFile); err != nil {
 		log.Printf("failed to start debug server: %v\n", err)
 	}
 }
+
+func (o *TLSOptions) config() (*tls.Config, error) {
+	if o.KeyFile == "" {
+		return nil, fmt.Errorf("certificate requires a key file")
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.ClientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(o.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA file: %v", o.ClientCAFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
@@ -0,0 +1,226 @@
+package integration
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	systemHandler struct{}
+
+	systemCounters struct {
+		Time      time.Time
+		CPU       []uint64
+		DiskRead  uint64
+		DiskWrite uint64
+		NetRx     uint64
+		NetTx     uint64
+	}
+
+	systemSample struct {
+		Time      time.Time
+		CPUUser   float64
+		CPUSystem float64
+		CPUIOWait float64
+		CPUSteal  float64
+		CPUIdle   float64
+		Load1     float64
+		MemTotal  uint64
+		MemUsed   uint64
+		DiskRead  float64
+		DiskWrite float64
+		NetRx     float64
+		NetTx     float64
+	}
+)
+
+const (
+	defaultSystemInterval = time.Second
+	minSystemInterval     = 100 * time.Millisecond
+	diskSectorSize        = 512
+)
+
+func (h *systemHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	seconds, err := strconv.Atoi(r.URL.Query().Get("seconds"))
+	if err != nil || seconds <= 0 {
+		seconds = 30
+	}
+	interval := defaultSystemInterval
+	if v := r.URL.Query().Get("interval"); v != "" {
+		if interval, err = time.ParseDuration(v); err != nil || interval < minSystemInterval {
+			http.Error(w, fmt.Sprintf("invalid interval: %v", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	prev, err := readSystemCounters()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	deadline := time.After(time.Duration(seconds) * time.Second)
+	for {
+		select {
+		case <-deadline:
+			return
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := readSystemCounters()
+		if err != nil {
+			log.Printf("failed to sample system metrics: %v", err)
+			continue
+		}
+		sample, err := newSystemSample(prev, cur)
+		if err != nil {
+			log.Printf("failed to sample system metrics: %v", err)
+			continue
+		}
+		prev = cur
+
+		if err := enc.Encode(sample); err != nil {
+			return
+		}
+	}
+}
+
+func newSystemSample(prev *systemCounters, cur *systemCounters) (*systemSample, error) {
+	elapsed := cur.Time.Sub(prev.Time).Seconds()
+	sample := &systemSample{
+		Time:      cur.Time,
+		DiskRead:  rate(prev.DiskRead, cur.DiskRead, elapsed),
+		DiskWrite: rate(prev.DiskWrite, cur.DiskWrite, elapsed),
+		NetRx:     rate(prev.NetRx, cur.NetRx, elapsed),
+		NetTx:     rate(prev.NetTx, cur.NetTx, elapsed),
+	}
+
+	if len(cur.CPU) >= 8 && len(prev.CPU) >= 8 {
+		delta := make([]float64, 8)
+		total := 0.0
+		for i := range delta {
+			if cur.CPU[i] >= prev.CPU[i] {
+				delta[i] = float64(cur.CPU[i] - prev.CPU[i])
+			}
+			total += delta[i]
+		}
+		if total > 0 {
+			sample.CPUUser = 100 * (delta[0] + delta[1]) / total
+			sample.CPUSystem = 100 * (delta[2] + delta[5] + delta[6]) / total
+			sample.CPUIdle = 100 * delta[3] / total
+			sample.CPUIOWait = 100 * delta[4] / total
+			sample.CPUSteal = 100 * delta[7] / total
+		}
+	}
+
+	if load, err := os.ReadFile("/proc/loadavg"); err == nil {
+		if fields := strings.Fields(string(load)); len(fields) > 0 {
+			sample.Load1, _ = strconv.ParseFloat(fields[0], 64)
+		}
+	}
+
+	mem, err := readKeyValues("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	sample.MemTotal = mem["MemTotal"] * 1024
+	if total, avail := mem["MemTotal"], mem["MemAvailable"]; total >= avail {
+		sample.MemUsed = (total - avail) * 1024
+	}
+	return sample, nil
+}
+
+func readSystemCounters() (*systemCounters, error) {
+	c := &systemCounters{Time: time.Now()}
+
+	stat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return nil, fmt.Errorf("system metrics are not available on this host: %w", err)
+	}
+	for _, line := range strings.Split(string(stat), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "cpu" {
+			for _, f := range fields[1:] {
+				v, _ := strconv.ParseUint(f, 10, 64)
+				c.CPU = append(c.CPU, v)
+			}
+			break
+		}
+	}
+
+	if err := scanLines("/proc/diskstats", func(fields []string) {
+		if len(fields) < 10 {
+			return
+		}
+		if _, err := os.Stat("/sys/block/" + fields[2]); err != nil || strings.HasPrefix(fields[2], "loop") || strings.HasPrefix(fields[2], "ram") {
+			return
+		}
+		read, _ := strconv.ParseUint(fields[5], 10, 64)
+		written, _ := strconv.ParseUint(fields[9], 10, 64)
+		c.DiskRead += read * diskSectorSize
+		c.DiskWrite += written * diskSectorSize
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := scanLines("/proc/net/dev", func(fields []string) {
+		if len(fields) < 10 || !strings.HasSuffix(fields[0], ":") || fields[0] == "lo:" {
+			return
+		}
+		rx, _ := strconv.ParseUint(fields[1], 10, 64)
+		tx, _ := strconv.ParseUint(fields[9], 10, 64)
+		c.NetRx += rx
+		c.NetTx += tx
+	}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func readKeyValues(path string) (map[string]uint64, error) {
+	values := map[string]uint64{}
+	err := scanLines(path, func(fields []string) {
+		if len(fields) >= 2 {
+			v, _ := strconv.ParseUint(fields[1], 10, 64)
+			values[strings.TrimSuffix(fields[0], ":")] = v
+		}
+	})
+	return values, err
+}
+
+func scanLines(path string, fn func(fields []string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fn(strings.Fields(scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+func rate(prev uint64, cur uint64, elapsed float64) float64 {
+	if cur < prev || elapsed <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / elapsed
+}
@@ -0,0 +1,351 @@
+package alert
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/event"
+	"github.com/kaz/pprotein/internal/extproc"
+	"github.com/kaz/pprotein/internal/persistent"
+	"github.com/kaz/pprotein/internal/pprof"
+	"github.com/kaz/pprotein/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Evaluator struct {
+		config    *persistent.Handler
+		validator *validator.Validate
+		registry  *collect.Registry
+		eventHub  *event.Hub
+
+		mu         *sync.RWMutex
+		thresholds []*Threshold
+
+		queue chan *collect.Snapshot
+	}
+
+	Threshold struct {
+		Name     string `validate:"required"`
+		Kind     string `validate:"required,oneof=endpoint query function"`
+		Metric   string
+		Match    string
+		Groups   []string
+		Value    float64 `validate:"gt=0"`
+		Disabled bool
+
+		pattern *regexp.Regexp
+	}
+
+	measurement struct {
+		subject string
+		values  map[string]float64
+	}
+
+	alertEvent struct {
+		Type    string
+		ID      string
+		GroupId string
+		Alerts  []*collect.Alert
+	}
+)
+
+const (
+	KindEndpoint = "endpoint"
+	KindQuery    = "query"
+	KindFunction = "function"
+
+	MetricFlat = "flat"
+	MetricCum  = "cum"
+
+	alertEventName = "alert"
+
+	evaluateQueue = 64
+	maxAlerts     = 20
+)
+
+var (
+	kindTypes = map[string]map[string]bool{
+		KindEndpoint: {"httplog": true},
+		KindQuery:    {"slowlog": true, "pgslowlog": true},
+		KindFunction: {"pprof": true, "fgprof": true, "java": true, "node": true},
+	}
+	defaultMetrics = map[string]string{
+		KindEndpoint: "P95",
+		KindQuery:    "Sum",
+		KindFunction: MetricFlat,
+	}
+)
+
+func New(store storage.Storage, registry *collect.Registry, eventHub *event.Hub) (*Evaluator, error) {
+	e := &Evaluator{
+		validator: validator.New(),
+		registry:  registry,
+		eventHub:  eventHub,
+		mu:        &sync.RWMutex{},
+		queue:     make(chan *collect.Snapshot, evaluateQueue),
+	}
+
+	config, err := persistent.New(store, "alerts.json", []byte("[]"), e.sanitize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alerts: %w", err)
+	}
+	e.config = config
+	e.config.OnUpdate(e.reload)
+	e.reload()
+
+	go e.work()
+	return e, nil
+}
+
+func (e *Evaluator) RegisterHandlers(g *echo.Group) {
+	e.config.RegisterHandlers(g)
+}
+
+func (e *Evaluator) sanitize(raw []byte) ([]byte, error) {
+	thresholds := []*Threshold{}
+	if err := json.Unmarshal(raw, &thresholds); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	if err := e.validator.Var(thresholds, "dive"); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, th := range thresholds {
+		if seen[th.Name] {
+			return nil, fmt.Errorf("duplicated threshold name: %v", th.Name)
+		}
+		seen[th.Name] = true
+
+		if th.Metric == "" {
+			th.Metric = defaultMetrics[th.Kind]
+		}
+		if th.Kind == KindFunction && th.Metric != MetricFlat && th.Metric != MetricCum {
+			return nil, fmt.Errorf("unknown metric for %v: %v", th.Name, th.Metric)
+		}
+		if _, err := regexp.Compile(th.Match); err != nil {
+			return nil, fmt.Errorf("invalid match for %v: %w", th.Name, err)
+		}
+		for _, group := range th.Groups {
+			if _, err := path.Match(group, ""); err != nil {
+				return nil, fmt.Errorf("invalid group pattern for %v: %v", th.Name, group)
+			}
+		}
+	}
+
+	res, err := json.MarshalIndent(thresholds, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	return res, nil
+}
+
+func (e *Evaluator) reload() {
+	raw, err := e.config.GetContent()
+	if err != nil {
+		log.Printf("[!] failed to load alerts: %v", err)
+		return
+	}
+
+	thresholds := []*Threshold{}
+	if err := json.Unmarshal(raw, &thresholds); err != nil {
+		log.Printf("[!] failed to load alerts: %v", err)
+		return
+	}
+	for _, th := range thresholds {
+		th.pattern = regexp.MustCompile(th.Match)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.thresholds = thresholds
+}
+
+func (e *Evaluator) Notify(event string, entry *collect.Entry) {
+	if event != collect.EventProcessingCompleted || len(e.matching(entry.Snapshot)) == 0 {
+		return
+	}
+
+	select {
+	case e.queue <- entry.Snapshot:
+	default:
+		log.Printf("[!] alert queue is full, skipping %v", entry.Snapshot.ID)
+	}
+}
+
+func (e *Evaluator) matching(snapshot *collect.Snapshot) []*Threshold {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	thresholds := []*Threshold{}
+	for _, th := range e.thresholds {
+		if th.matches(snapshot) {
+			thresholds = append(thresholds, th)
+		}
+	}
+	return thresholds
+}
+
+func (th *Threshold) matches(snapshot *collect.Snapshot) bool {
+	if th.Disabled || !kindTypes[th.Kind][snapshot.Type] {
+		return false
+	}
+	if len(th.Groups) == 0 {
+		return true
+	}
+
+	group := ""
+	if snapshot.SnapshotTarget != nil {
+		group = snapshot.GroupId
+	}
+	for _, pattern := range th.Groups {
+		if ok, _ := path.Match(pattern, group); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Evaluator) work() {
+	for snapshot := range e.queue {
+		if err := e.evaluate(snapshot); err != nil {
+			log.Printf("[!] failed to evaluate alerts for %v: %v", snapshot.ID, err)
+		}
+	}
+}
+
+func (e *Evaluator) evaluate(snapshot *collect.Snapshot) error {
+	thresholds := e.matching(snapshot)
+	if len(thresholds) == 0 {
+		return nil
+	}
+
+	c, ok := e.registry.Get(snapshot.Type)
+	if !ok {
+		return fmt.Errorf("no such collector: %v", snapshot.Type)
+	}
+
+	var measurements []*measurement
+	var err error
+	if kindTypes[KindFunction][snapshot.Type] {
+		measurements, err = functionMeasurements(snapshot)
+	} else {
+		measurements, err = tableMeasurements(c, snapshot, thresholds)
+	}
+	if err != nil {
+		return err
+	}
+
+	alerts := exceeded(thresholds, measurements)
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	if err := c.SetAlerts(snapshot.ID, alerts); err != nil {
+		return fmt.Errorf("failed to mark entry: %w", err)
+	}
+
+	data := &alertEvent{Type: snapshot.Type, ID: snapshot.ID, Alerts: alerts}
+	if snapshot.SnapshotTarget != nil {
+		data.GroupId = snapshot.GroupId
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	e.eventHub.PublishEvent(alertEventName, raw)
+	return nil
+}
+
+func exceeded(thresholds []*Threshold, measurements []*measurement) []*collect.Alert {
+	alerts := []*collect.Alert{}
+	for _, th := range thresholds {
+		for _, m := range measurements {
+			v, ok := m.values[th.Metric]
+			if !ok || v <= th.Value || !th.pattern.MatchString(m.subject) {
+				continue
+			}
+			alerts = append(alerts, &collect.Alert{Rule: th.Name, Subject: m.subject, Metric: th.Metric, Value: v, Threshold: th.Value})
+		}
+	}
+	if len(alerts) > maxAlerts {
+		alerts = alerts[:maxAlerts]
+	}
+	return alerts
+}
+
+func tableMeasurements(c *collect.Collector, snapshot *collect.Snapshot, thresholds []*Threshold) ([]*measurement, error) {
+	r, err := c.Get(snapshot.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	defer r.Close()
+
+	t, err := extproc.ParseTable(r)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := func(row []interface{}) string {
+		return t.String(row, t.Column("Query", "Fingerprint"))
+	}
+	if method, uri := t.Column("Method"), t.Column("Uri"); uri >= 0 {
+		subject = func(row []interface{}) string {
+			return strings.TrimSpace(t.String(row, method) + " " + t.String(row, uri))
+		}
+	}
+
+	columns := map[string]int{}
+	for _, th := range thresholds {
+		if col := t.Column(th.Metric, th.Metric+"(QueryTime)"); col >= 0 {
+			columns[th.Metric] = col
+		}
+	}
+
+	measurements := make([]*measurement, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		m := &measurement{subject: subject(row), values: map[string]float64{}}
+		for metric, col := range columns {
+			m.values[metric] = t.Number(row, col)
+		}
+		measurements = append(measurements, m)
+	}
+	return measurements, nil
+}
+
+func functionMeasurements(snapshot *collect.Snapshot) ([]*measurement, error) {
+	stats, err := pprof.TopFunctions(snapshot, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, st := range stats {
+		total += st.Flat
+	}
+	if total == 0 {
+		return nil, nil
+	}
+
+	measurements := make([]*measurement, 0, len(stats))
+	for _, st := range stats {
+		measurements = append(measurements, &measurement{
+			subject: st.Name,
+			values: map[string]float64{
+				MetricFlat: float64(st.Flat) * 100 / float64(total),
+				MetricCum:  float64(st.Cum) * 100 / float64(total),
+			},
+		})
+	}
+	return measurements, nil
+}
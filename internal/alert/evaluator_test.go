@@ -0,0 +1,138 @@
+package alert
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+func TestThresholdMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold *Threshold
+		typ       string
+		group     string
+		want      bool
+	}{
+		{"endpoint on httplog", &Threshold{Kind: KindEndpoint}, "httplog", "", true},
+		{"endpoint on slowlog", &Threshold{Kind: KindEndpoint}, "slowlog", "", false},
+		{"query on pgslowlog", &Threshold{Kind: KindQuery}, "pgslowlog", "", true},
+		{"function on fgprof", &Threshold{Kind: KindFunction}, "fgprof", "", true},
+		{"disabled", &Threshold{Kind: KindEndpoint, Disabled: true}, "httplog", "", false},
+		{"group pattern", &Threshold{Kind: KindEndpoint, Groups: []string{"2024*"}}, "httplog", "20240101", true},
+		{"group mismatch", &Threshold{Kind: KindEndpoint, Groups: []string{"2023*", "2022*"}}, "httplog", "20240101", false},
+		{"group required", &Threshold{Kind: KindEndpoint, Groups: []string{"?*"}}, "httplog", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := &collect.Snapshot{
+				SnapshotMeta:   &collect.SnapshotMeta{Type: tt.typ},
+				SnapshotTarget: &collect.SnapshotTarget{GroupId: tt.group},
+			}
+			if got := tt.threshold.matches(snapshot); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceeded(t *testing.T) {
+	measurements := []*measurement{
+		{subject: "GET /api/users", values: map[string]float64{"P95": 0.5, "Sum": 10}},
+		{subject: "POST /api/users", values: map[string]float64{"P95": 1.5, "Sum": 30}},
+		{subject: "GET /api/items", values: map[string]float64{"P95": 2.0}},
+	}
+
+	tests := []struct {
+		name       string
+		thresholds []*Threshold
+		want       []*collect.Alert
+	}{
+		{
+			name:       "below threshold",
+			thresholds: []*Threshold{{Name: "slow", Metric: "P95", Value: 5}},
+			want:       []*collect.Alert{},
+		},
+		{
+			name:       "threshold is exclusive",
+			thresholds: []*Threshold{{Name: "slow", Metric: "P95", Value: 2.0}},
+			want:       []*collect.Alert{},
+		},
+		{
+			name:       "exceeded",
+			thresholds: []*Threshold{{Name: "slow", Metric: "P95", Value: 1}},
+			want: []*collect.Alert{
+				{Rule: "slow", Subject: "POST /api/users", Metric: "P95", Value: 1.5, Threshold: 1},
+				{Rule: "slow", Subject: "GET /api/items", Metric: "P95", Value: 2.0, Threshold: 1},
+			},
+		},
+		{
+			name:       "subject pattern",
+			thresholds: []*Threshold{{Name: "slow", Metric: "P95", Match: "^GET ", Value: 1}},
+			want: []*collect.Alert{
+				{Rule: "slow", Subject: "GET /api/items", Metric: "P95", Value: 2.0, Threshold: 1},
+			},
+		},
+		{
+			name:       "missing metric",
+			thresholds: []*Threshold{{Name: "busy", Metric: "Sum", Value: 20}},
+			want: []*collect.Alert{
+				{Rule: "busy", Subject: "POST /api/users", Metric: "Sum", Value: 30, Threshold: 20},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, th := range tt.thresholds {
+				th.pattern = regexp.MustCompile(th.Match)
+			}
+			if got := exceeded(tt.thresholds, measurements); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exceeded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantMetric string
+		wantErr    bool
+	}{
+		{"default endpoint metric", `[{"Name":"a","Kind":"endpoint","Value":1}]`, "P95", false},
+		{"default query metric", `[{"Name":"a","Kind":"query","Value":1}]`, "Sum", false},
+		{"default function metric", `[{"Name":"a","Kind":"function","Value":1}]`, MetricFlat, false},
+		{"explicit metric", `[{"Name":"a","Kind":"endpoint","Metric":"Max","Value":1}]`, "Max", false},
+		{"unknown function metric", `[{"Name":"a","Kind":"function","Metric":"P95","Value":1}]`, "", true},
+		{"unknown kind", `[{"Name":"a","Kind":"memory","Value":1}]`, "", true},
+		{"non-positive value", `[{"Name":"a","Kind":"endpoint","Value":0}]`, "", true},
+		{"duplicated name", `[{"Name":"a","Kind":"endpoint","Value":1},{"Name":"a","Kind":"query","Value":1}]`, "", true},
+		{"invalid match", `[{"Name":"a","Kind":"endpoint","Match":"(","Value":1}]`, "", true},
+		{"invalid group", `[{"Name":"a","Kind":"endpoint","Groups":["["],"Value":1}]`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Evaluator{validator: validator.New()}
+			res, err := e.sanitize([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sanitize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			thresholds := []*Threshold{}
+			if err := json.Unmarshal(res, &thresholds); err != nil {
+				t.Fatal(err)
+			}
+			if got := thresholds[0].Metric; got != tt.wantMetric {
+				t.Errorf("Metric = %v, want %v", got, tt.wantMetric)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Role int
+
+	Principal struct {
+		Name   string
+		Role   Role
+		Method string
+	}
+
+	Token struct {
+		Name  string
+		Role  Role
+		Token string
+	}
+
+	Config struct {
+		Tokens     []*Token
+		OIDC       *OIDCConfig
+		Anonymous  Role
+		AgentToken string
+
+		SessionKey    []byte
+		SessionTTL    time.Duration
+		SecureCookies bool
+	}
+
+	Authenticator struct {
+		cfg      *Config
+		oidc     *provider
+		internal string
+	}
+
+	Status struct {
+		Enabled       bool
+		Authenticated bool
+		Name          string `json:",omitempty"`
+		Role          Role
+		Methods       []string
+	}
+
+	loginRequest struct {
+		Token string
+	}
+)
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleOperator
+	RoleAdmin
+)
+
+const (
+	MethodToken     = "token"
+	MethodOIDC      = "oidc"
+	MethodAgent     = "agent"
+	MethodInternal  = "internal"
+	MethodAnonymous = "anonymous"
+
+	principalKey = "auth.principal"
+
+	defaultSessionTTL = 24 * time.Hour
+)
+
+var (
+	roleNames = map[Role]string{RoleNone: "", RoleViewer: "viewer", RoleOperator: "operator", RoleAdmin: "admin"}
+
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+func ParseRole(s string) (Role, error) {
+	for role, name := range roleNames {
+		if name == strings.ToLower(strings.TrimSpace(s)) {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role: %v", s)
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+func (r Role) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	role, err := ParseRole(name)
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+func ParseTokens(raw string) ([]*Token, error) {
+	tokens := []*Token{}
+	for _, item := range strings.Split(raw, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSpace(item), ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("token must be formatted as name:role:token")
+		}
+		role, err := ParseRole(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if role == RoleNone {
+			return nil, fmt.Errorf("role is required for token %v", parts[0])
+		}
+		tokens = append(tokens, &Token{Name: parts[0], Role: role, Token: parts[2]})
+	}
+	return tokens, nil
+}
+
+func New(cfg *Config) (*Authenticator, error) {
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = defaultSessionTTL
+	}
+	if len(cfg.SessionKey) == 0 {
+		cfg.SessionKey = make([]byte, 32)
+		if _, err := rand.Read(cfg.SessionKey); err != nil {
+			return nil, fmt.Errorf("failed to generate session key: %w", err)
+		}
+	}
+
+	internal := make([]byte, 32)
+	if _, err := rand.Read(internal); err != nil {
+		return nil, fmt.Errorf("failed to generate internal token: %w", err)
+	}
+
+	a := &Authenticator{cfg: cfg, internal: hex.EncodeToString(internal)}
+	if cfg.OIDC != nil {
+		p, err := newProvider(cfg.OIDC)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure OIDC: %w", err)
+		}
+		a.oidc = p
+	}
+	return a, nil
+}
+
+func (a *Authenticator) Enabled() bool {
+	return len(a.cfg.Tokens) > 0 || a.oidc != nil
+}
+
+func (a *Authenticator) Authorize(req *http.Request) {
+	if a.Enabled() {
+		req.Header.Set("Authorization", "Bearer "+a.internal)
+	}
+}
+
+func (a *Authenticator) RegisterHandlers(g *echo.Group) {
+	g.GET("/me", a.getMe)
+	g.POST("/login", a.postLogin)
+	g.POST("/logout", a.postLogout)
+	if a.oidc != nil {
+		g.GET("/oidc/login", a.getOIDCLogin)
+		g.GET("/oidc/callback", a.getOIDCCallback)
+	}
+}
+
+func (a *Authenticator) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !a.Enabled() || exempt(c.Request().URL.Path) {
+			return next(c)
+		}
+
+		p, err := a.authenticate(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		required, err := requiredRole(c.Request())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if p.Role < required {
+			if p.Method == MethodAnonymous {
+				return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+			}
+			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("%v role is required", required))
+		}
+
+		c.Set(principalKey, p)
+		return next(c)
+	}
+}
+
+func FromContext(c echo.Context) *Principal {
+	p, _ := c.Get(principalKey).(*Principal)
+	return p
+}
+
+func (a *Authenticator) authenticate(c echo.Context) (*Principal, error) {
+	req := c.Request()
+
+	if given := req.Header.Get(collect.AgentTokenHeader); given != "" && a.cfg.AgentToken != "" && strings.HasPrefix(req.URL.Path, "/api/agents/") {
+		if subtle.ConstantTimeCompare([]byte(given), []byte(a.cfg.AgentToken)) == 1 {
+			return &Principal{Name: "agent", Role: RoleOperator, Method: MethodAgent}, nil
+		}
+	}
+
+	if header := req.Header.Get("Authorization"); header != "" {
+		scheme, credential, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return nil, fmt.Errorf("unsupported authorization scheme")
+		}
+		return a.verifyBearer(req, strings.TrimSpace(credential))
+	}
+
+	if p := a.session(req); p != nil {
+		return p, nil
+	}
+	return &Principal{Name: MethodAnonymous, Role: a.cfg.Anonymous, Method: MethodAnonymous}, nil
+}
+
+func (a *Authenticator) verifyBearer(req *http.Request, credential string) (*Principal, error) {
+	if subtle.ConstantTimeCompare([]byte(credential), []byte(a.internal)) == 1 {
+		return &Principal{Name: "pprotein", Role: RoleOperator, Method: MethodInternal}, nil
+	}
+	if t := a.lookupToken(credential); t != nil {
+		return &Principal{Name: t.Name, Role: t.Role, Method: MethodToken}, nil
+	}
+	if a.oidc != nil && strings.Count(credential, ".") == 2 {
+		p, err := a.oidc.verify(req.Context(), credential, "")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		return p, nil
+	}
+	return nil, ErrInvalidToken
+}
+
+func (a *Authenticator) lookupToken(credential string) *Token {
+	var found *Token
+	for _, t := range a.cfg.Tokens {
+		if subtle.ConstantTimeCompare([]byte(credential), []byte(t.Token)) == 1 {
+			found = t
+		}
+	}
+	return found
+}
+
+func (a *Authenticator) methods() []string {
+	methods := []string{}
+	if len(a.cfg.Tokens) > 0 {
+		methods = append(methods, MethodToken)
+	}
+	if a.oidc != nil {
+		methods = append(methods, MethodOIDC)
+	}
+	return methods
+}
+
+func (a *Authenticator) getMe(c echo.Context) error {
+	status := &Status{Enabled: a.Enabled(), Methods: a.methods()}
+	if !status.Enabled {
+		status.Authenticated = true
+		status.Role = RoleAdmin
+		return c.JSON(http.StatusOK, status)
+	}
+
+	p, err := a.authenticate(c)
+	if err == nil {
+		status.Authenticated = p.Method != MethodAnonymous
+		status.Role = p.Role
+		if status.Authenticated {
+			status.Name = p.Name
+		}
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+func (a *Authenticator) postLogin(c echo.Context) error {
+	req := &loginRequest{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+
+	t := a.lookupToken(req.Token)
+	if t == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrInvalidToken.Error())
+	}
+	if err := a.startSession(c, &Principal{Name: t.Name, Role: t.Role, Method: MethodToken}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (a *Authenticator) postLogout(c echo.Context) error {
+	a.clearCookie(c, sessionCookie)
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,420 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	OIDCConfig struct {
+		Issuer       string
+		ClientID     string
+		ClientSecret string
+		RedirectURL  string
+		Scopes       []string
+
+		RoleClaim   string
+		Roles       map[string]Role
+		DefaultRole Role
+	}
+
+	provider struct {
+		cfg    *OIDCConfig
+		client *http.Client
+
+		discovery *discovery
+
+		mu      *sync.Mutex
+		keys    map[string]any
+		fetched time.Time
+	}
+
+	discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+
+	jwks struct {
+		Keys []*jwk `json:"keys"`
+	}
+	jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+
+	tokenResponse struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+)
+
+const (
+	defaultRoleClaim = "roles"
+
+	oidcTimeout     = 10 * time.Second
+	jwksMinInterval = time.Minute
+	clockSkew       = time.Minute
+)
+
+var (
+	defaultScopes = []string{"openid", "email", "profile"}
+
+	signingMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+)
+
+func newProvider(cfg *OIDCConfig) (*provider, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("issuer, client ID and redirect URL are required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = defaultScopes
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = defaultRoleClaim
+	}
+
+	p := &provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: oidcTimeout},
+		mu:     &sync.Mutex{},
+		keys:   map[string]any{},
+	}
+
+	d := &discovery{}
+	if err := p.getJSON(context.Background(), strings.TrimSuffix(cfg.Issuer, "/")+"/.well-known/openid-configuration", d); err != nil {
+		return nil, fmt.Errorf("failed to discover provider: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(cfg.Issuer, "/") {
+		return nil, fmt.Errorf("issuer mismatch: %v", d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("incomplete provider metadata")
+	}
+	p.discovery = d
+	return p, nil
+}
+
+func (p *provider) getJSON(ctx context.Context, target string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (p *provider) key(ctx context.Context, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.fetched) < jwksMinInterval {
+		return nil, fmt.Errorf("unknown key: %v", kid)
+	}
+
+	set := &jwks{}
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, set); err != nil {
+		return nil, fmt.Errorf("failed to fetch keys: %w", err)
+	}
+	p.fetched = time.Now()
+
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			log.Printf("[!] ignoring OIDC key %v: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	p.keys = keys
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key: %v", kid)
+}
+
+func (k *jwk) publicKey() (any, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %v", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %v", k.Kty)
+}
+
+func (p *provider) verify(ctx context.Context, raw string, nonce string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: signingMethods, SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); !ok || now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("unexpected issuer: %v", iss)
+	}
+	if !containsClaim(claims["aud"], p.cfg.ClientID) {
+		return nil, fmt.Errorf("unexpected audience")
+	}
+	if nonce != "" {
+		if given, _ := claims["nonce"].(string); given != nonce {
+			return nil, fmt.Errorf("nonce mismatch")
+		}
+	}
+
+	name := ""
+	for _, key := range []string{"email", "preferred_username", "sub"} {
+		if v, _ := claims[key].(string); v != "" && name == "" {
+			name = v
+		}
+	}
+
+	role := p.role(claims)
+	if role == RoleNone {
+		return nil, fmt.Errorf("no role is assigned to %v", name)
+	}
+	return &Principal{Name: name, Role: role, Method: MethodOIDC}, nil
+}
+
+func (p *provider) role(claims jwt.MapClaims) Role {
+	role := p.cfg.DefaultRole
+	grant := func(r Role) {
+		if r > role {
+			role = r
+		}
+	}
+
+	switch v := claims[p.cfg.RoleClaim].(type) {
+	case string:
+		if r, err := ParseRole(v); err == nil {
+			grant(r)
+		}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				if r, err := ParseRole(s); err == nil {
+					grant(r)
+				}
+			}
+		}
+	}
+	for _, key := range []string{"sub", "email"} {
+		if v, _ := claims[key].(string); v != "" {
+			grant(p.cfg.Roles[v])
+		}
+	}
+	return role
+}
+
+func containsClaim(v any, want string) bool {
+	switch v := v.(type) {
+	case string:
+		return v == want
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *provider) exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	token := &tokenResponse{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.IDToken == "" {
+		return "", fmt.Errorf("token request failed: status=%d, error=%v", resp.StatusCode, token.Error)
+	}
+	return token.IDToken, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (a *Authenticator) getOIDCLogin(c echo.Context) error {
+	state, err := randomString()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	expires := time.Now().Add(stateTTL)
+	value, err := a.sign(&stateData{State: state, Nonce: nonce, Expires: expires.Unix()})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	a.setCookie(c, stateCookie, value, expires)
+
+	p := a.oidc
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+
+	target := p.discovery.AuthorizationEndpoint
+	if strings.Contains(target, "?") {
+		target += "&" + query.Encode()
+	} else {
+		target += "?" + query.Encode()
+	}
+	return c.Redirect(http.StatusFound, target)
+}
+
+func (a *Authenticator) getOIDCCallback(c echo.Context) error {
+	cookie, err := c.Request().Cookie(stateCookie)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "login session not found")
+	}
+	a.clearCookie(c, stateCookie)
+
+	state := &stateData{}
+	if err := a.open(cookie.Value, state); err != nil || time.Now().Unix() > state.Expires {
+		return echo.NewHTTPError(http.StatusBadRequest, "login session expired")
+	}
+	if c.QueryParam("state") != state.State {
+		return echo.NewHTTPError(http.StatusBadRequest, "state mismatch")
+	}
+	if e := c.QueryParam("error"); e != "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("login failed: %v", e))
+	}
+
+	raw, err := a.oidc.exchange(c.Request().Context(), c.QueryParam("code"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("failed to exchange code: %v", err))
+	}
+	p, err := a.oidc.verify(c.Request().Context(), raw, state.Nonce)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("failed to verify token: %v", err))
+	}
+
+	if err := a.startSession(c, p); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.Redirect(http.StatusFound, "/")
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	rule struct {
+		Method  string
+		Pattern string
+		Role    Role
+	}
+)
+
+const maxPeekBody = 1 << 20
+
+var rules = []*rule{
+	{http.MethodGet, "/api/group/collect", RoleOperator},
+	{http.MethodGet, "/api/group/env/*/collect", RoleOperator},
+
+	{http.MethodGet, "/api/group/targets", RoleAdmin},
+	{http.MethodGet, "/api/group/env/*/targets", RoleAdmin},
+	{http.MethodGet, "/api/group/history/*", RoleAdmin},
+	{http.MethodGet, "/api/webhooks", RoleAdmin},
+	{http.MethodGet, "/api/exporters", RoleAdmin},
+
+	{http.MethodPost, "/api/group/environments", RoleAdmin},
+	{http.MethodPost, "/api/group/targets", RoleAdmin},
+	{http.MethodPost, "/api/group/schedules", RoleAdmin},
+	{http.MethodPost, "/api/group/schedules/*/*", RoleAdmin},
+	{http.MethodPost, "/api/group/env/*/targets", RoleAdmin},
+	{http.MethodPost, "/api/group/env/*/schedules", RoleAdmin},
+	{http.MethodPost, "/api/group/env/*/schedules/*/*", RoleAdmin},
+	{http.MethodPost, "/api/group/history/*/rollback", RoleAdmin},
+	{http.MethodPost, "/api/webhooks", RoleAdmin},
+	{http.MethodPost, "/api/webhooks/*/test", RoleAdmin},
+	{http.MethodPost, "/api/exporters", RoleAdmin},
+	{http.MethodPost, "/api/types", RoleAdmin},
+	{http.MethodPost, "/api/*/config", RoleAdmin},
+	{http.MethodPost, "/api/import", RoleAdmin},
+	{http.MethodPost, "/api/projects", RoleAdmin},
+
+	{http.MethodPost, "/api/group/validate", RoleOperator},
+	{http.MethodPost, "/api/group/env/*/validate", RoleOperator},
+
+	{http.MethodDelete, "/api/*/*/pin", RoleOperator},
+}
+
+func exempt(p string) bool {
+	return p == "/api/auth" || strings.HasPrefix(p, "/api/auth/")
+}
+
+func requiredRole(req *http.Request) (Role, error) {
+	p := path.Clean(req.URL.Path)
+	for _, r := range rules {
+		if r.Method == req.Method {
+			if ok, _ := path.Match(r.Pattern, p); ok {
+				return r.Role, nil
+			}
+		}
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleViewer, nil
+	case http.MethodDelete:
+		return RoleAdmin, nil
+	}
+
+	if strings.HasSuffix(p, "/bulk") {
+		return bulkRole(req)
+	}
+	return RoleOperator, nil
+}
+
+func bulkRole(req *http.Request) (Role, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPeekBody))
+	if err != nil {
+		return RoleNone, fmt.Errorf("failed to read body: %w", err)
+	}
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+
+	bulk := &collect.BulkRequest{}
+	if err := json.Unmarshal(body, bulk); err != nil {
+		return RoleNone, fmt.Errorf("failed to parse request body: %w", err)
+	}
+	if bulk.Operation == collect.BulkDelete {
+		return RoleAdmin, nil
+	}
+	return RoleOperator, nil
+}
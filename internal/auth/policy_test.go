@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method  string
+		path    string
+		body    string
+		want    Role
+		wantErr bool
+	}{
+		{http.MethodGet, "/api/pprof", "", RoleViewer, false},
+		{http.MethodHead, "/api/pprof/abc", "", RoleViewer, false},
+		{http.MethodGet, "/api/group/collect", "", RoleOperator, false},
+		{http.MethodGet, "/api/group/env/staging/collect", "", RoleOperator, false},
+		{http.MethodGet, "/api/group/targets", "", RoleAdmin, false},
+		{http.MethodGet, "/api/group/env/staging/targets", "", RoleAdmin, false},
+		{http.MethodGet, "/api/group/targets/../targets", "", RoleAdmin, false},
+		{http.MethodGet, "/api/group/history", "", RoleViewer, false},
+		{http.MethodGet, "/api/group/history/abc", "", RoleAdmin, false},
+		{http.MethodGet, "/api/webhooks", "", RoleAdmin, false},
+		{http.MethodGet, "/api/webhooks/", "", RoleAdmin, false},
+		{http.MethodGet, "/api/exporters", "", RoleAdmin, false},
+		{http.MethodPost, "/api/webhooks/slack/test", "", RoleAdmin, false},
+		{http.MethodPost, "/api/exporters/parca/pprof/abc", "", RoleOperator, false},
+		{http.MethodPost, "/api/group/targets", "", RoleAdmin, false},
+		{http.MethodPost, "/api/group/validate", "", RoleOperator, false},
+		{http.MethodPost, "/api/httplog/config", "", RoleAdmin, false},
+		{http.MethodPost, "/api/pprof", "", RoleOperator, false},
+		{http.MethodDelete, "/api/pprof/abc", "", RoleAdmin, false},
+		{http.MethodDelete, "/api/pprof/abc/pin", "", RoleOperator, false},
+		{http.MethodPost, "/api/pprof/bulk", `{"Operation":"pin"}`, RoleOperator, false},
+		{http.MethodPost, "/api/pprof/bulk", `{"Operation":"delete"}`, RoleAdmin, false},
+		{http.MethodPost, "/api/pprof/bulk", `{`, RoleNone, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			got, err := requiredRole(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("requiredRole() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("requiredRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	sessionData struct {
+		Name    string
+		Role    Role
+		Method  string
+		Expires int64
+	}
+
+	stateData struct {
+		State   string
+		Nonce   string
+		Expires int64
+	}
+)
+
+const (
+	sessionCookie = "pprotein_session"
+	stateCookie   = "pprotein_oidc_state"
+
+	stateTTL = 10 * time.Minute
+)
+
+func (a *Authenticator) sign(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.mac(encoded)), nil
+}
+
+func (a *Authenticator) open(value string, v any) error {
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return fmt.Errorf("malformed value")
+	}
+	given, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(given, a.mac(encoded)) {
+		return fmt.Errorf("invalid signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("malformed value: %w", err)
+	}
+	return json.Unmarshal(payload, v)
+}
+
+func (a *Authenticator) mac(encoded string) []byte {
+	h := hmac.New(sha256.New, a.cfg.SessionKey)
+	h.Write([]byte(encoded))
+	return h.Sum(nil)
+}
+
+func (a *Authenticator) startSession(c echo.Context, p *Principal) error {
+	expires := time.Now().Add(a.cfg.SessionTTL)
+	value, err := a.sign(&sessionData{Name: p.Name, Role: p.Role, Method: p.Method, Expires: expires.Unix()})
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	a.setCookie(c, sessionCookie, value, expires)
+	return nil
+}
+
+func (a *Authenticator) session(req *http.Request) *Principal {
+	cookie, err := req.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	data := &sessionData{}
+	if err := a.open(cookie.Value, data); err != nil || time.Now().Unix() > data.Expires {
+		return nil
+	}
+	return &Principal{Name: data.Name, Role: data.Role, Method: data.Method}
+}
+
+func (a *Authenticator) setCookie(c echo.Context, name string, value string, expires time.Time) {
+	c.SetCookie(&http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   a.cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (a *Authenticator) clearCookie(c echo.Context, name string) {
+	c.SetCookie(&http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,40 @@
+package collect
+
+import "fmt"
+
+type (
+	Alert struct {
+		Rule      string
+		Subject   string
+		Metric    string
+		Value     float64
+		Threshold float64
+	}
+)
+
+func (a *Alert) String() string {
+	return fmt.Sprintf("%s: %s of %s is %g (> %g)", a.Rule, a.Metric, a.Subject, a.Value, a.Threshold)
+}
+
+func (c *Collector) SetAlerts(id string, alerts []*Alert) error {
+	c.mu.Lock()
+	ent, ok := c.data[id]
+	if !ok {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: %v", ErrNoSuchEntry, id)
+	}
+	if ent.Status != StatusOk {
+		c.mu.Unlock()
+		return fmt.Errorf("entry is not ready: %v", ent.Status)
+	}
+
+	updated := *ent
+	updated.Alerts = alerts
+	c.putEntry(&updated)
+	c.mu.Unlock()
+
+	if len(alerts) > 0 {
+		c.notify(EventAlertTriggered, ent.Snapshot)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package collect
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	ImportResult struct {
+		Imported []string
+		Skipped  []string
+	}
+)
+
+const (
+	archiveMetaName = "meta.json"
+	archiveBodyName = "body"
+)
+
+var snapshotIdPattern = regexp.MustCompile(`^[0-9a-z]+$`)
+
+func (r *Registry) RegisterHandlers(g *echo.Group) {
+	g.GET("/export", r.getExport)
+	g.POST("/import", r.postImport)
+}
+
+func (r *Registry) Export(w io.Writer, q *IndexQuery) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, c := range r.Collectors() {
+		for _, ent := range c.List() {
+			if ent.Status != StatusOk || !q.matches(ent) {
+				continue
+			}
+			if err := c.export(tw, ent.Snapshot); err != nil {
+				return fmt.Errorf("failed to export %v: %w", ent.Snapshot.ID, err)
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	return gw.Close()
+}
+
+func (c *Collector) export(tw *tar.Writer, snapshot *Snapshot) error {
+	meta, err := snapshot.marshal()
+	if err != nil {
+		return fmt.Errorf("failed to serialize meta: %w", err)
+	}
+	size, err := c.store.FileSize(snapshot.ID)
+	if err != nil {
+		return fmt.Errorf("failed to stat body: %w", err)
+	}
+	body, err := c.store.OpenFile(snapshot.ID)
+	if err != nil {
+		return fmt.Errorf("failed to open body: %w", err)
+	}
+	defer body.Close()
+
+	dir := path.Join(snapshot.Type, snapshot.ID)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    path.Join(dir, archiveMetaName),
+		Mode:    0644,
+		Size:    int64(len(meta)),
+		ModTime: snapshot.Datetime,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(meta); err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    path.Join(dir, archiveBodyName),
+		Mode:    0644,
+		Size:    size,
+		ModTime: snapshot.Datetime,
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, body)
+	return err
+}
+
+func (r *Registry) Import(src io.Reader) (*ImportResult, error) {
+	gr, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize gzip reader: %w", err)
+	}
+	defer gr.Close()
+
+	res := &ImportResult{Imported: []string{}, Skipped: []string{}}
+	tr := tar.NewReader(gr)
+
+	var pending *Snapshot
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return res, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		id := path.Base(path.Dir(hdr.Name))
+		switch path.Base(hdr.Name) {
+		case archiveMetaName:
+			raw, err := io.ReadAll(tr)
+			if err != nil {
+				return res, fmt.Errorf("failed to read %v: %w", hdr.Name, err)
+			}
+			pending = &Snapshot{}
+			if err := pending.unmarshal(raw); err != nil {
+				return res, fmt.Errorf("failed to parse %v: %w", hdr.Name, err)
+			}
+			continue
+		case archiveBodyName:
+		default:
+			continue
+		}
+
+		snapshot := pending
+		pending = nil
+		if snapshot == nil || snapshot.ID != id {
+			log.Printf("[!] skipping body without meta: %v", hdr.Name)
+			res.Skipped = append(res.Skipped, id)
+			continue
+		}
+
+		c, ok := r.Get(snapshot.Type)
+		if !ok {
+			log.Printf("[!] skipping snapshot of unknown type: %v", snapshot.Type)
+			res.Skipped = append(res.Skipped, snapshot.ID)
+			continue
+		}
+
+		imported, err := c.restore(snapshot, tr)
+		if err != nil {
+			return res, fmt.Errorf("failed to restore %v: %w", snapshot.ID, err)
+		}
+		if !imported {
+			res.Skipped = append(res.Skipped, snapshot.ID)
+			continue
+		}
+		res.Imported = append(res.Imported, snapshot.ID)
+	}
+	return res, nil
+}
+
+func (c *Collector) validSnapshotId(id string) bool {
+	return strings.HasSuffix(id, c.ext) && snapshotIdPattern.MatchString(strings.TrimSuffix(id, c.ext))
+}
+
+func (c *Collector) restore(snapshot *Snapshot, body io.Reader) (bool, error) {
+	if !c.validSnapshotId(snapshot.ID) {
+		return false, fmt.Errorf("invalid snapshot id: %v", snapshot.ID)
+	}
+	snapshot.store = c.store
+
+	c.mu.Lock()
+	if _, ok := c.data[snapshot.ID]; ok {
+		c.mu.Unlock()
+		return false, nil
+	}
+	if ok, err := c.store.ExistsFile(snapshot.ID); err != nil || ok {
+		c.mu.Unlock()
+		if err != nil {
+			return false, fmt.Errorf("failed to check body: %w", err)
+		}
+		return false, nil
+	}
+	c.putEntry(&Entry{Snapshot: snapshot, Status: StatusPending, Message: "Importing"})
+	c.mu.Unlock()
+
+	if err := c.store.PutFileStream(snapshot.ID, body); err != nil {
+		c.updateStatus(snapshot, StatusFail, err.Error())
+		return false, fmt.Errorf("failed to write body: %w", err)
+	}
+	if err := snapshot.saveMeta(); err != nil {
+		c.updateStatus(snapshot, StatusFail, err.Error())
+		return false, err
+	}
+
+	go func() {
+		if err := c.process(snapshot, PriorityLow); err != nil {
+			log.Printf("[!] processing imported snapshot failed: %v", err)
+		}
+	}()
+	return true, nil
+}
+
+func (r *Registry) getExport(c echo.Context) error {
+	q, err := parseIndexQuery(c)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("pprotein-%s.tar.gz", time.Now().Format("20060102-150405"))
+	c.Response().Header().Set(echo.HeaderContentType, "application/gzip")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+	c.Response().WriteHeader(http.StatusOK)
+
+	if err := r.Export(c.Response(), q); err != nil {
+		log.Printf("[!] export aborted: %v", err)
+	}
+	return nil
+}
+
+func (r *Registry) postImport(c echo.Context) error {
+	var src io.Reader = c.Request().Body
+	if fh, err := c.FormFile("file"); err == nil {
+		file, err := fh.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to open file: %v", err))
+		}
+		defer file.Close()
+		src = file
+	}
+
+	if collectors := r.Collectors(); len(collectors) > 0 {
+		if err := collectors[0].EnforceQuota(); err != nil {
+			return echo.NewHTTPError(http.StatusInsufficientStorage, err.Error())
+		}
+	}
+
+	res, err := r.Import(src)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to import archive: %v", err))
+	}
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,81 @@
+package collect
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kaz/pprotein/internal/event"
+	"github.com/kaz/pprotein/internal/storage"
+)
+
+func TestRestoreRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		existing bool
+		wantErr  bool
+	}{
+		{"foreign extension", "lqz3k9a1.json", false, true},
+		{"config file", "webhooks.json", false, true},
+		{"path traversal", "../lqz3k9a1.log", false, true},
+		{"upper case", "LQZ3K9A1.log", false, true},
+		{"extension only", ".log", false, true},
+		{"existing body", "lqz3k9a1.log", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := storage.New(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := New(quotaTestProcessor{}, &Options{Type: "test", Ext: ".log", Store: store, EventHub: event.NewHub()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing {
+				if err := store.PutFile(tt.id, []byte("original")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			snapshot := &Snapshot{
+				SnapshotMeta:   &SnapshotMeta{Type: "test", ID: tt.id},
+				SnapshotTarget: &SnapshotTarget{},
+			}
+			ok, err := c.restore(snapshot, strings.NewReader("imported"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("restore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok {
+				t.Errorf("restore() = true, want false")
+			}
+			if _, found := c.data[tt.id]; found {
+				t.Errorf("entry %v was registered", tt.id)
+			}
+
+			if tt.existing {
+				r, err := store.OpenFile(tt.id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer r.Close()
+				body, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(body) != "original" {
+					t.Errorf("existing body was overwritten: %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestValidSnapshotId(t *testing.T) {
+	c := &Collector{ext: ".log"}
+	snapshot := newSnapshot(nil, "test", c.ext, "", &SnapshotTarget{})
+	if !c.validSnapshotId(snapshot.ID) {
+		t.Errorf("generated id %v was rejected", snapshot.ID)
+	}
+}
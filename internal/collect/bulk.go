@@ -0,0 +1,114 @@
+package collect
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/errgroup"
+)
+
+type (
+	BulkRequest struct {
+		Operation string
+		IDs       []string
+		Filter    *IndexQuery
+
+		Tags map[string]string
+	}
+
+	BulkResult struct {
+		ID    string
+		Error string
+	}
+)
+
+const (
+	BulkDelete    = "delete"
+	BulkReprocess = "reprocess"
+	BulkRetag     = "retag"
+	BulkPin       = "pin"
+	BulkUnpin     = "unpin"
+)
+
+const bulkConcurrency = 4
+
+func (c *Collector) Bulk(req *BulkRequest) ([]*BulkResult, error) {
+	op, err := c.bulkOperation(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := req.IDs
+	if req.Filter != nil {
+		filter := *req.Filter
+		filter.Type = c.typ
+		for _, ent := range c.List() {
+			if filter.matches(ent) {
+				ids = append(ids, ent.Snapshot.ID)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	results := []*BulkResult{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			results = append(results, &BulkResult{ID: id})
+		}
+	}
+
+	eg := &errgroup.Group{}
+	eg.SetLimit(bulkConcurrency)
+	for _, res := range results {
+		res := res
+		eg.Go(func() error {
+			if err := op(res.ID); err != nil {
+				res.Error = err.Error()
+			}
+			return nil
+		})
+	}
+	eg.Wait()
+
+	return results, nil
+}
+
+func (c *Collector) bulkOperation(req *BulkRequest) (func(string) error, error) {
+	switch req.Operation {
+	case BulkDelete:
+		return c.Delete, nil
+	case BulkReprocess:
+		return c.Reprocess, nil
+	case BulkRetag:
+		return func(id string) error {
+			ent, err := c.entry(id)
+			if err != nil {
+				return err
+			}
+			return c.Relabel(id, ent.Snapshot.Label, req.Tags)
+		}, nil
+	case BulkPin:
+		return func(id string) error { return c.Pin(id, true) }, nil
+	case BulkUnpin:
+		return func(id string) error { return c.Pin(id, false) }, nil
+	}
+	return nil, fmt.Errorf("unknown operation: %v", req.Operation)
+}
+
+func (h *handler) postBulk(c echo.Context) error {
+	req := &BulkRequest{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+	if len(req.IDs) == 0 && req.Filter == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "either IDs or Filter is required")
+	}
+
+	results, err := h.collector.Bulk(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,192 @@
+package collect
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	ClientOptions struct {
+		Timeout            int
+		InsecureSkipVerify bool
+		CAFile             string
+		CertFile           string
+		KeyFile            string
+		Proxy              string
+		DisableKeepAlives  bool
+		MaxIdleConns       int
+		IdleConnTimeout    int
+	}
+
+	clientCache struct {
+		mu      *sync.Mutex
+		clients map[string]*http.Client
+	}
+)
+
+func newClientCache() *clientCache {
+	return &clientCache{
+		mu:      &sync.Mutex{},
+		clients: map[string]*http.Client{},
+	}
+}
+
+func (o *ClientOptions) merge(override *ClientOptions) *ClientOptions {
+	merged := &ClientOptions{}
+	if o != nil {
+		*merged = *o
+	}
+	if override == nil {
+		return merged
+	}
+
+	if override.Timeout > 0 {
+		merged.Timeout = override.Timeout
+	}
+	if override.InsecureSkipVerify {
+		merged.InsecureSkipVerify = true
+	}
+	if override.CAFile != "" {
+		merged.CAFile = override.CAFile
+	}
+	if override.CertFile != "" || override.KeyFile != "" {
+		merged.CertFile = override.CertFile
+		merged.KeyFile = override.KeyFile
+	}
+	if override.Proxy != "" {
+		merged.Proxy = override.Proxy
+	}
+	if override.DisableKeepAlives {
+		merged.DisableKeepAlives = true
+	}
+	if override.MaxIdleConns > 0 {
+		merged.MaxIdleConns = override.MaxIdleConns
+	}
+	if override.IdleConnTimeout > 0 {
+		merged.IdleConnTimeout = override.IdleConnTimeout
+	}
+	return merged
+}
+
+func (cc *clientCache) get(opts *ClientOptions) (*http.Client, error) {
+	key, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize client options: %w", err)
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if client, ok := cc.clients[string(key)]; ok {
+		return client, nil
+	}
+
+	client, err := opts.newClient()
+	if err != nil {
+		return nil, err
+	}
+	cc.clients[string(key)] = client
+	return client, nil
+}
+
+func (o *ClientOptions) newClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = o.DisableKeepAlives
+	if o.MaxIdleConns > 0 {
+		transport.MaxIdleConns = o.MaxIdleConns
+		transport.MaxIdleConnsPerHost = o.MaxIdleConns
+	}
+	if o.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Duration(o.IdleConnTimeout) * time.Second
+	}
+
+	if o.Proxy != "" {
+		proxy, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if o.InsecureSkipVerify || o.CAFile != "" || o.CertFile != "" {
+		tlsConfig := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+		if o.CAFile != "" {
+			pem, err := os.ReadFile(o.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA file: %v", o.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if o.CertFile != "" {
+			if o.KeyFile == "" {
+				return nil, fmt.Errorf("client certificate requires a key file")
+			}
+			cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(o.Timeout) * time.Second,
+	}, nil
+}
+
+func (c *Collector) httpClient(target *SnapshotTarget) (*http.Client, error) {
+	var override *ClientOptions
+	if target != nil {
+		override = target.Client
+	}
+	return c.clients.get(c.client.merge(override))
+}
+
+func (c *Collector) Fetch(ctx context.Context, target *SnapshotTarget, path string) (*http.Response, error) {
+	client, err := c.httpClient(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure http client: %w", err)
+	}
+
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	u.Path, u.RawQuery = path, ""
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	target.authorize(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("http error: status=%v, body=%v", resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
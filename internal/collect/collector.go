@@ -1,14 +1,21 @@
 package collect
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/kaz/pprotein/internal/event"
+	"github.com/kaz/pprotein/internal/git"
 	"github.com/kaz/pprotein/internal/storage"
+	"github.com/kaz/pprotein/internal/tracing"
 )
 
 type (
@@ -18,24 +25,75 @@ type (
 
 		Store    storage.Storage
 		EventHub *event.Hub
+		Registry *Registry
+		Index    *Index
+
+		Retention *RetentionPolicy
+		Pool      *WorkerPool
+		Retry     *RetryPolicy
+		Quota     *Quota
+		Client    *ClientOptions
+		Notifier  Notifier
+		Views     []*View
+
+		Compress    bool
+		Deduplicate bool
+		Instant     bool
+		Poll        time.Duration
 	}
 
 	Collector struct {
-		typ string
-		ext string
+		typ      string
+		ext      string
+		encoding string
 
 		store     storage.Storage
 		eventHub  *event.Hub
-		processor Processor
-
-		mu   *sync.RWMutex
-		data map[string]*Entry
+		registry  *Registry
+		index     *Index
+		processor *cachedProcessor
+		views     map[string]*cachedProcessor
+		retention *RetentionPolicy
+		pool      *WorkerPool
+		retry     *RetryPolicy
+		quota     *Quota
+		client    *ClientOptions
+		clients   *clientCache
+		notifier  Notifier
+		stats     *collectorStats
+		dedup     bool
+		instant   bool
+		poll      time.Duration
+
+		mu        *sync.RWMutex
+		data      map[string]*Entry
+		published map[string][]byte
+
+		inflightMu *sync.Mutex
+		inflight   map[string]context.CancelFunc
+
+		accessMu *sync.Mutex
+		accessed map[string]time.Time
+
+		commentMu *sync.Mutex
 	}
 
 	Entry struct {
 		Snapshot *Snapshot
 		Status   Status
 		Message  string
+		Progress *Progress
+		Alerts   []*Alert `json:",omitempty"`
+	}
+	EntryDelta struct {
+		Action   string
+		ID       string
+		Type     string
+		Snapshot *Snapshot `json:",omitempty"`
+		Status   Status
+		Message  string
+		Progress *Progress `json:",omitempty"`
+		Alerts   []*Alert  `json:",omitempty"`
 	}
 	Status string
 )
@@ -44,36 +102,150 @@ const (
 	StatusOk      Status = "ok"
 	StatusFail    Status = "fail"
 	StatusPending Status = "pending"
+	StatusDeleted Status = "deleted"
+	StatusCorrupt Status = "corrupt"
+)
+
+const statusTypeKey = "status"
+
+var (
+	ErrNoSuchEntry = errors.New("no such entry")
+	ErrCorrupt     = errors.New("snapshot body is corrupt")
 )
 
 func New(processor Processor, opts *Options) (*Collector, error) {
+	stats := newCollectorStats()
 	c := &Collector{
 		typ: opts.Type,
 		ext: opts.Ext,
 
 		store:     opts.Store,
 		eventHub:  opts.EventHub,
-		processor: newCachedProcessor(processor, opts.Store),
+		registry:  opts.Registry,
+		index:     opts.Index,
+		processor: newCachedProcessor(processor, opts.Store, stats),
+		retention: opts.Retention,
+		pool:      opts.Pool,
+		retry:     opts.Retry,
+		quota:     opts.Quota,
+		client:    opts.Client,
+		clients:   newClientCache(),
+		notifier:  opts.Notifier,
+		stats:     stats,
+		dedup:     opts.Deduplicate,
+		instant:   opts.Instant,
+		poll:      opts.Poll,
+
+		mu:        &sync.RWMutex{},
+		data:      map[string]*Entry{},
+		published: map[string][]byte{},
+
+		inflightMu: &sync.Mutex{},
+		inflight:   map[string]context.CancelFunc{},
+
+		accessMu: &sync.Mutex{},
+		accessed: map[string]time.Time{},
+
+		commentMu: &sync.Mutex{},
+	}
 
-		mu:   &sync.RWMutex{},
-		data: map[string]*Entry{},
+	if c.pool == nil {
+		c.pool = NewWorkerPool(0)
+	}
+	c.initViews(processor, opts.Views)
+	if opts.Compress {
+		c.encoding = EncodingGzip
 	}
 
-	rawSnapshots, err := c.store.GetAll(c.typ)
+	entries, err := c.loadEntries()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get snapshots: %w", err)
 	}
+	for _, ent := range entries {
+		if ent.Status == StatusFail || ent.Status == StatusCorrupt {
+			c.updateStatus(ent.Snapshot, ent.Status, ent.Message)
+			continue
+		}
+		go c.process(ent.Snapshot, PriorityLow)
+	}
+
+	if c.retention.enabled() {
+		go c.runRetention()
+	}
+
+	if c.registry != nil {
+		c.registry.add(c)
+	}
+
+	return c, nil
+}
+
+func (c *Collector) loadEntries() ([]*Entry, error) {
+	if c.index != nil {
+		entries, err := c.index.Query(&IndexQuery{Type: c.typ})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query index: %w", err)
+		}
+
+		if len(entries) > 0 {
+			for _, ent := range entries {
+				ent.Snapshot.store = c.store
+			}
+			return entries, nil
+		}
+	}
+
+	rawSnapshots, err := c.store.GetAll(c.typ)
+	if err != nil {
+		return nil, err
+	}
 
+	entries := make([]*Entry, 0, len(rawSnapshots))
 	for _, raw := range rawSnapshots {
 		snapshot := &Snapshot{store: c.store}
 		if err := snapshot.unmarshal(raw); err != nil {
 			log.Printf("[!] unmarshalling snapshot failed: %v", err)
 			continue
 		}
-		go c.runProcessor(snapshot)
+
+		ent, err := c.loadStatus(snapshot)
+		if err != nil {
+			log.Printf("[!] loading status failed: %v", err)
+			ent = &Entry{Snapshot: snapshot}
+		}
+		entries = append(entries, ent)
+	}
+	return entries, nil
+}
+
+func (c *Collector) loadStatus(snapshot *Snapshot) (*Entry, error) {
+	ent := &Entry{Snapshot: snapshot}
+
+	raw, err := c.store.Get(statusTypeKey, snapshot.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status: %w", err)
+	}
+	if raw == nil {
+		return ent, nil
 	}
 
-	return c, nil
+	if err := json.Unmarshal(raw, ent); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status: %w", err)
+	}
+	ent.Snapshot = snapshot
+	return ent, nil
+}
+
+func (c *Collector) saveStatus(entry *Entry) error {
+	raw, err := json.Marshal(&Entry{Status: entry.Status, Message: entry.Message, Alerts: entry.Alerts})
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+	return c.store.Put(statusTypeKey, entry.Snapshot.ID, raw)
+}
+
+func (c *Collector) Type() string {
+	return c.typ
 }
 
 func (c *Collector) updateStatus(snapshot *Snapshot, status Status, msg string) {
@@ -83,48 +255,276 @@ func (c *Collector) updateStatus(snapshot *Snapshot, status Status, msg string)
 		Message:  msg,
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.putEntry(entry)
+}
+
+func (c *Collector) putEntry(entry *Entry) {
+	prev := c.data[entry.Snapshot.ID]
+	c.data[entry.Snapshot.ID] = entry
+	c.publish(prev, entry)
+
+	if entry.Status == StatusOk || entry.Status == StatusFail || entry.Status == StatusCorrupt {
+		if err := c.saveStatus(entry); err != nil {
+			log.Printf("[!] failed to persist status: %v", err)
+		}
+	}
+
+	if c.index != nil {
+		if err := c.index.put(entry); err != nil {
+			log.Printf("[!] failed to update index: %v", err)
+		}
+	}
+}
+
+func (c *Collector) publish(prev *Entry, entry *Entry) {
 	eventData, err := json.Marshal(entry)
 	if err != nil {
 		log.Printf("failed to serialize event: %v", err)
+		return
+	}
+	snapshotData, err := json.Marshal(entry.Snapshot)
+	if err != nil {
+		log.Printf("failed to serialize event: %v", err)
+		return
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	delta := entryDelta(prev, entry)
+	if delta.Action == "updated" && !bytes.Equal(c.published[entry.Snapshot.ID], snapshotData) {
+		delta.Snapshot = entry.Snapshot
+	}
+	if delta.Action == "deleted" {
+		delete(c.published, entry.Snapshot.ID)
+	} else {
+		c.published[entry.Snapshot.ID] = snapshotData
+	}
+
+	deltaData, err := json.Marshal(delta)
+	if err != nil {
+		log.Printf("failed to serialize event: %v", err)
+		return
+	}
+	c.eventHub.PublishEntry(entry.Snapshot.Type, eventData, deltaData)
+}
 
-	c.data[snapshot.ID] = entry
+func entryDelta(prev *Entry, entry *Entry) *EntryDelta {
+	delta := &EntryDelta{
+		Action:   "updated",
+		ID:       entry.Snapshot.ID,
+		Type:     entry.Snapshot.Type,
+		Status:   entry.Status,
+		Message:  entry.Message,
+		Progress: entry.Progress,
+		Alerts:   entry.Alerts,
+	}
 
-	if eventData != nil {
-		c.eventHub.Publish(eventData)
+	switch {
+	case entry.Status == StatusDeleted:
+		delta.Action = "deleted"
+	case prev == nil:
+		delta.Action = "created"
+		delta.Snapshot = entry.Snapshot
 	}
+	return delta
+}
+
+func (c *Collector) process(snapshot *Snapshot, priority Priority) error {
+	ctx, done := c.track(context.Background(), snapshot.ID)
+	defer done()
+
+	return c.runProcessor(ctx, snapshot, priority)
 }
 
-func (c *Collector) runProcessor(snapshot *Snapshot) error {
-	c.updateStatus(snapshot, StatusPending, "Processing")
+func (c *Collector) runProcessor(ctx context.Context, snapshot *Snapshot, priority Priority) error {
+	c.updateStatus(snapshot, StatusPending, "Queued")
+
+	var r io.ReadCloser
+	err := c.pool.Do(ctx, priority, func() (err error) {
+		c.updateStatus(snapshot, StatusPending, "Processing")
+		start := time.Now()
+		defer func() { c.stats.processed(time.Since(start)) }()
+
+		if err := snapshot.Verify(); err != nil {
+			return err
+		}
+
+		ctx, span := tracing.Start(ctx, "Processor.Process", snapshotAttributes(snapshot)...)
+		defer func() { span.Finish(err) }()
 
-	r, err := c.processor.Process(snapshot)
+		if r, err = c.processor.Process(ctx, snapshot); err == nil {
+			c.processViews(ctx, snapshot)
+		}
+		return err
+	})
+	if errors.Is(err, ErrCorrupt) {
+		c.updateStatus(snapshot, StatusCorrupt, err.Error())
+		c.notify(EventProcessingFailed, snapshot)
+		return fmt.Errorf("processor aborted: %w", err)
+	}
 	if err != nil {
-		go snapshot.Prune()
-		c.updateStatus(snapshot, StatusFail, err.Error())
+		c.updateStatus(snapshot, StatusFail, failureMessage(ctx, err))
+		c.notify(EventProcessingFailed, snapshot)
 		return fmt.Errorf("processor aborted: %w", err)
 	}
+	c.indexOutput(snapshot, r)
 	if r != nil {
 		r.Close()
 	}
 
 	c.updateStatus(snapshot, StatusOk, "Ready")
+	c.notify(EventProcessingCompleted, snapshot)
 	return nil
 }
 
-func (c *Collector) Get(id string) (io.ReadCloser, error) {
+func (c *Collector) entry(id string) (*Entry, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	ent, ok := c.data[id]
 	if !ok {
-		return nil, fmt.Errorf("no such entry: %v", ent)
+		return nil, fmt.Errorf("%w: %v", ErrNoSuchEntry, id)
+	}
+	return ent, nil
+}
+
+func (c *Collector) Snapshot(id string) (*Snapshot, error) {
+	ent, err := c.entry(id)
+	if err != nil {
+		return nil, err
+	}
+	if ent.Status != StatusOk {
+		return nil, fmt.Errorf("entry is not ready: %v", ent.Status)
+	}
+	return ent.Snapshot, nil
+}
+
+func (c *Collector) Get(id string) (io.ReadCloser, error) {
+	ent, err := c.entry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if ent.Status == StatusCorrupt {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, id)
+	}
+
+	c.touch(id)
+	return c.processor.Process(context.Background(), ent.Snapshot)
+}
+
+func (c *Collector) Raw(id string) (io.ReadCloser, error) {
+	ent, err := c.entry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := ent.Snapshot.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	return r, nil
+}
+
+func (c *Collector) Verify(id string) (*Entry, error) {
+	ent, err := c.entry(id)
+	if err != nil {
+		return nil, err
+	}
+	if ent.Status == StatusPending {
+		return nil, fmt.Errorf("entry is busy: %v", ent.Message)
+	}
+
+	if err := ent.Snapshot.Verify(); err != nil {
+		if !errors.Is(err, ErrCorrupt) {
+			return nil, err
+		}
+		c.updateStatus(ent.Snapshot, StatusCorrupt, err.Error())
+	}
+	return c.entry(id)
+}
+
+func (c *Collector) Relabel(id string, label string, tags map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.data[id]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchEntry, id)
+	}
+
+	if err := ent.Snapshot.Relabel(label, tags); err != nil {
+		return fmt.Errorf("failed to relabel snapshot: %w", err)
+	}
+
+	c.putEntry(ent)
+	return nil
+}
+
+func (c *Collector) Pin(id string, pinned bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.data[id]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchEntry, id)
+	}
+
+	if err := ent.Snapshot.Pin(pinned); err != nil {
+		return fmt.Errorf("failed to pin snapshot: %w", err)
+	}
+
+	c.putEntry(ent)
+	return nil
+}
+
+func (c *Collector) Delete(id string) error {
+	return c.remove(id, "Deleted")
+}
+
+func (c *Collector) remove(id string, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.data[id]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchEntry, id)
+	}
+	if ent.Status == StatusPending {
+		return fmt.Errorf("entry is busy: %v", ent.Message)
+	}
+
+	if err := c.purge(ent.Snapshot); err != nil {
+		return fmt.Errorf("failed to purge cache: %w", err)
+	}
+	if err := c.store.Delete(statusTypeKey, id); err != nil {
+		return fmt.Errorf("failed to delete status: %w", err)
+	}
+	if err := c.purgeComments(id); err != nil {
+		return fmt.Errorf("failed to delete comments: %w", err)
+	}
+	if err := ent.Snapshot.Delete(); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	delete(c.data, id)
+
+	c.accessMu.Lock()
+	delete(c.accessed, id)
+	c.accessMu.Unlock()
+
+	if c.index != nil {
+		if err := c.index.remove(id); err != nil {
+			log.Printf("[!] failed to update index: %v", err)
+		}
 	}
 
-	return c.processor.Process(ent.Snapshot)
+	c.publish(ent, &Entry{
+		Snapshot: ent.Snapshot,
+		Status:   StatusDeleted,
+		Message:  reason,
+	})
+	return nil
 }
 
 func (c *Collector) List() []*Entry {
@@ -135,40 +535,295 @@ func (c *Collector) List() []*Entry {
 	for _, ent := range c.data {
 		resp = append(resp, ent)
 	}
+	sortEntries(resp, false)
 	return resp
 }
 
-func (c *Collector) Collect(target *SnapshotTarget) error {
-	if target.URL == "" || target.Duration == 0 {
+func (c *Collector) Query(q *IndexQuery) ([]*Entry, int) {
+	c.mu.RLock()
+	resp := []*Entry{}
+	for _, ent := range c.data {
+		if q.matches(ent) {
+			resp = append(resp, ent)
+		}
+	}
+	c.mu.RUnlock()
+
+	sortEntries(resp, q.ascending())
+
+	total := len(resp)
+	if q.Offset > 0 {
+		if q.Offset >= len(resp) {
+			return []*Entry{}, total
+		}
+		resp = resp[q.Offset:]
+	}
+	if q.Limit > 0 && q.Limit < len(resp) {
+		resp = resp[:q.Limit]
+	}
+	return resp, total
+}
+
+func sortEntries(entries []*Entry, ascending bool) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Snapshot, entries[j].Snapshot
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		if ascending {
+			return a.Datetime.Before(b.Datetime)
+		}
+		return a.Datetime.After(b.Datetime)
+	})
+}
+
+func (c *Collector) Collect(target *SnapshotTarget) (err error) {
+	urls := target.urls()
+	if len(urls) == 0 || (target.Duration == 0 && !c.instant) {
 		return fmt.Errorf("URL and Duration cannot be nil")
 	}
+	if c.instant {
+		instant := *target
+		instant.Duration = 0
+		target = &instant
+	}
+	if err := c.EnforceQuota(); err != nil {
+		return err
+	}
+
+	ctx, span := tracing.Start(context.Background(), "Collect",
+		tracing.String("pprotein.type", c.typ),
+		tracing.Int("pprotein.targets", len(urls)),
+		tracing.String("pprotein.group_id", target.GroupId),
+		tracing.String("pprotein.run_id", target.RunId),
+	)
+	defer func() { span.Finish(err) }()
+
+	if len(urls) == 1 {
+		single := *target
+		single.URL, single.URLs = urls[0], nil
+		return c.collect(ctx, newSnapshot(c.store, c.typ, c.ext, c.encoding, &single))
+	}
+
+	base := *target
+	if base.GroupId == "" {
+		base.GroupId = NewGroupId()
+	}
+	if base.RunId == "" {
+		base.RunId = base.GroupId
+	}
+
+	var (
+		mu     = &sync.Mutex{}
+		wg     = &sync.WaitGroup{}
+		failed = []error{}
+	)
+	for _, u := range urls {
+		sibling := base
+		sibling.URL, sibling.URLs = u, nil
+
+		snapshot := newSnapshot(c.store, c.typ, c.ext, c.encoding, &sibling)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := c.collect(ctx, snapshot); err != nil {
+				mu.Lock()
+				failed = append(failed, fmt.Errorf("%v: %w", snapshot.URL, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to collect %d of %d targets: %w", len(failed), len(urls), errors.Join(failed...))
+	}
+	return nil
+}
+
+func (c *Collector) collect(parent context.Context, snapshot *Snapshot) error {
+	ctx, done := c.track(parent, snapshot.ID)
+	defer done()
 
-	snapshot := newSnapshot(c.store, c.typ, c.ext, target)
 	c.updateStatus(snapshot, StatusPending, "Collecting")
+	c.notify(EventCollectionStarted, snapshot)
 
-	if err := snapshot.Collect(); err != nil {
-		c.updateStatus(snapshot, StatusFail, err.Error())
+	if err := c.collectWithRetry(ctx, snapshot); err != nil {
+		if err := snapshot.saveMeta(); err != nil {
+			log.Printf("[!] failed to save meta of failed snapshot: %v", err)
+		}
+		c.updateStatus(snapshot, StatusFail, failureMessage(ctx, err))
+		c.notify(EventCollectionFailed, snapshot)
 		return fmt.Errorf("failed to collect: %w", err)
 	}
+	c.linkDuplicate(snapshot)
+	c.notify(EventCollectionSucceeded, snapshot)
+
+	if err := c.runProcessor(ctx, snapshot, PriorityHigh); err != nil {
+		return fmt.Errorf("failed to process: %w", err)
+	}
+	return nil
+}
+
+func (c *Collector) linkDuplicate(snapshot *Snapshot) {
+	if !c.dedup || snapshot.Hash == "" {
+		return
+	}
+
+	c.mu.RLock()
+	var original *Snapshot
+	for _, ent := range c.data {
+		s := ent.Snapshot
+		if s.ID != snapshot.ID && s.Hash == snapshot.Hash && s.DuplicateOf == "" && ent.Status == StatusOk {
+			original = s
+			break
+		}
+	}
+	c.mu.RUnlock()
+
+	if original == nil {
+		return
+	}
+
+	snapshot.DuplicateOf = original.ID
+	if err := snapshot.saveMeta(); err != nil {
+		log.Printf("[!] failed to link duplicate snapshot: %v", err)
+	}
+}
+
+func (c *Collector) Retry(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.data[id]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchEntry, id)
+	}
+	discard := ent.Status == StatusCorrupt
+	if ent.Status != StatusFail && !discard {
+		return fmt.Errorf("entry is not failed: %v", ent.Status)
+	}
+	if discard && (ent.Snapshot.SnapshotTarget == nil || ent.Snapshot.URL == "") {
+		return fmt.Errorf("no source to collect from")
+	}
+	c.putEntry(&Entry{Snapshot: ent.Snapshot, Status: StatusPending, Message: "Retrying"})
+
+	go func() {
+		if err := c.rerun(ent.Snapshot, discard); err != nil {
+			log.Printf("[!] retry aborted: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (c *Collector) rerun(snapshot *Snapshot, discard bool) error {
+	if discard {
+		if err := c.purge(snapshot); err != nil {
+			c.updateStatus(snapshot, StatusFail, err.Error())
+			return fmt.Errorf("failed to purge cache: %w", err)
+		}
+		if err := c.store.DeleteFile(snapshot.ID); err != nil {
+			c.updateStatus(snapshot, StatusFail, err.Error())
+			return fmt.Errorf("failed to discard corrupt body: %w", err)
+		}
+	}
+
+	collected, err := c.store.ExistsFile(snapshot.ID)
+	if err != nil {
+		c.updateStatus(snapshot, StatusFail, err.Error())
+		return fmt.Errorf("failed to check snapshot body: %w", err)
+	}
+
+	if !collected {
+		if snapshot.SnapshotTarget == nil || snapshot.URL == "" {
+			c.updateStatus(snapshot, StatusFail, "no source to collect from")
+			return fmt.Errorf("no source to collect from")
+		}
+		return c.collect(context.Background(), snapshot)
+	}
 
-	if err := c.runProcessor(snapshot); err != nil {
+	if err := snapshot.saveMeta(); err != nil {
 		c.updateStatus(snapshot, StatusFail, err.Error())
+		return fmt.Errorf("failed to restore meta: %w", err)
+	}
+	if err := c.process(snapshot, PriorityHigh); err != nil {
 		return fmt.Errorf("failed to process: %w", err)
 	}
 	return nil
 }
 
+func (c *Collector) Reprocess(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.data[id]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchEntry, id)
+	}
+	if ent.Status != StatusOk {
+		return fmt.Errorf("entry is not ready: %v", ent.Status)
+	}
+
+	snapshot := ent.Snapshot
+	if err := c.purge(snapshot); err != nil {
+		return fmt.Errorf("failed to purge cache: %w", err)
+	}
+	if snapshot.DuplicateOf != "" {
+		snapshot.DuplicateOf = ""
+		if err := snapshot.saveMeta(); err != nil {
+			return fmt.Errorf("failed to unlink duplicate: %w", err)
+		}
+	}
+	c.putEntry(&Entry{Snapshot: snapshot, Status: StatusPending, Message: "Reprocessing"})
+
+	go func() {
+		if err := c.process(snapshot, PriorityHigh); err != nil {
+			log.Printf("[!] reprocessing failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (c *Collector) Upload(target *SnapshotTarget, repo *git.RepositoryInfo, r io.Reader) (*Snapshot, error) {
+	if err := c.EnforceQuota(); err != nil {
+		return nil, err
+	}
+
+	snapshot := newSnapshot(c.store, c.typ, c.ext, c.encoding, target)
+	snapshot.Repository = repo
+	c.updateStatus(snapshot, StatusPending, "Uploading")
+	c.notify(EventCollectionStarted, snapshot)
+
+	if err := snapshot.Import(r); err != nil {
+		c.updateStatus(snapshot, StatusFail, err.Error())
+		c.notify(EventCollectionFailed, snapshot)
+		return nil, fmt.Errorf("failed to import: %w", err)
+	}
+	c.linkDuplicate(snapshot)
+	c.notify(EventCollectionSucceeded, snapshot)
+
+	go func() {
+		if err := c.process(snapshot, PriorityHigh); err != nil {
+			log.Printf("[!] processing uploaded snapshot failed: %v", err)
+		}
+	}()
+	return snapshot, nil
+}
+
 func (c *Collector) Add(target *SnapshotTarget, content []byte) (*Snapshot, error) {
-	snapshot := newSnapshot(c.store, c.typ, c.ext, target)
+	snapshot := newSnapshot(c.store, c.typ, c.ext, c.encoding, target)
 	c.updateStatus(snapshot, StatusPending, "Collecting")
+	c.notify(EventCollectionStarted, snapshot)
 
 	if err := snapshot.Add(content); err != nil {
 		c.updateStatus(snapshot, StatusFail, err.Error())
+		c.notify(EventCollectionFailed, snapshot)
 		return nil, fmt.Errorf("failed to collect: %w", err)
 	}
+	c.notify(EventCollectionSucceeded, snapshot)
 
-	if err := c.runProcessor(snapshot); err != nil {
-		c.updateStatus(snapshot, StatusFail, err.Error())
+	if err := c.process(snapshot, PriorityHigh); err != nil {
 		return nil, fmt.Errorf("failed to process: %w", err)
 	}
 	return snapshot, nil
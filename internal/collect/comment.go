@@ -0,0 +1,145 @@
+package collect
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	Comment struct {
+		ID         string
+		SnapshotID string
+		Author     string
+		Body       string
+		Datetime   time.Time
+	}
+
+	CommentEvent struct {
+		Action  string
+		Comment *Comment
+	}
+)
+
+const commentTypeKey = "comment"
+
+var (
+	ErrNoSuchComment = errors.New("no such comment")
+)
+
+func (c *Collector) loadComments(id string) ([]*Comment, error) {
+	raw, err := c.store.Get(commentTypeKey, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read comments: %w", err)
+	}
+
+	comments := []*Comment{}
+	if raw == nil {
+		return comments, nil
+	}
+	if err := json.Unmarshal(raw, &comments); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal comments: %w", err)
+	}
+	return comments, nil
+}
+
+func (c *Collector) saveComments(id string, comments []*Comment) error {
+	if len(comments) == 0 {
+		return c.store.Delete(commentTypeKey, id)
+	}
+
+	raw, err := json.Marshal(comments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comments: %w", err)
+	}
+	return c.store.Put(commentTypeKey, id, raw)
+}
+
+func (c *Collector) Comments(id string) ([]*Comment, error) {
+	if _, err := c.entry(id); err != nil {
+		return nil, err
+	}
+
+	c.commentMu.Lock()
+	defer c.commentMu.Unlock()
+
+	return c.loadComments(id)
+}
+
+func (c *Collector) AddComment(id string, author string, body string) (*Comment, error) {
+	if _, err := c.entry(id); err != nil {
+		return nil, err
+	}
+	if body == "" {
+		return nil, fmt.Errorf("comment body cannot be empty")
+	}
+
+	c.commentMu.Lock()
+	defer c.commentMu.Unlock()
+
+	comments, err := c.loadComments(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := uniqueTime()
+	comment := &Comment{
+		ID:         strconv.FormatInt(ts.UnixNano(), 36),
+		SnapshotID: id,
+		Author:     author,
+		Body:       body,
+		Datetime:   ts,
+	}
+	if err := c.saveComments(id, append(comments, comment)); err != nil {
+		return nil, fmt.Errorf("failed to save comment: %w", err)
+	}
+
+	c.publishComment("created", comment)
+	return comment, nil
+}
+
+func (c *Collector) DeleteComment(id string, commentId string) error {
+	if _, err := c.entry(id); err != nil {
+		return err
+	}
+
+	c.commentMu.Lock()
+	defer c.commentMu.Unlock()
+
+	comments, err := c.loadComments(id)
+	if err != nil {
+		return err
+	}
+
+	for i, comment := range comments {
+		if comment.ID != commentId {
+			continue
+		}
+		if err := c.saveComments(id, append(comments[:i], comments[i+1:]...)); err != nil {
+			return fmt.Errorf("failed to save comments: %w", err)
+		}
+		c.publishComment("deleted", comment)
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrNoSuchComment, commentId)
+}
+
+func (c *Collector) purgeComments(id string) error {
+	c.commentMu.Lock()
+	defer c.commentMu.Unlock()
+
+	return c.store.Delete(commentTypeKey, id)
+}
+
+func (c *Collector) publishComment(action string, comment *Comment) {
+	eventData, err := json.Marshal(&CommentEvent{Action: action, Comment: comment})
+	if err != nil {
+		log.Printf("failed to serialize event: %v", err)
+		return
+	}
+	c.eventHub.PublishEvent(commentTypeKey, eventData)
+}
@@ -0,0 +1,80 @@
+package collect
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	Secret  string
+	Headers map[string]string
+
+	BasicAuth struct {
+		Username string
+		Password Secret
+	}
+)
+
+const (
+	Redacted = "REDACTED"
+
+	AgentTokenHeader = "X-Pprotein-Token"
+)
+
+var sensitiveHeaderWords = []string{"authorization", "cookie", "token", "secret", "key", "password"}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	if s == "" {
+		return json.Marshal("")
+	}
+	return json.Marshal(Redacted)
+}
+
+func (s Secret) usable() bool {
+	return s != "" && s != Redacted
+}
+
+func (h Headers) MarshalJSON() ([]byte, error) {
+	if h == nil {
+		return []byte("null"), nil
+	}
+
+	masked := make(map[string]string, len(h))
+	for name, value := range h {
+		if isSensitiveHeader(name) {
+			value = Redacted
+		}
+		masked[name] = value
+	}
+	return json.Marshal(masked)
+}
+
+func isSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *SnapshotTarget) authorize(req *http.Request) {
+	for name, value := range t.Headers {
+		if isSensitiveHeader(name) && value == Redacted {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	if t.BasicAuth != nil && t.BasicAuth.Password.usable() {
+		req.SetBasicAuth(t.BasicAuth.Username, string(t.BasicAuth.Password))
+	}
+	if t.BearerToken.usable() {
+		req.Header.Set("Authorization", "Bearer "+string(t.BearerToken))
+	}
+	if t.AgentToken.usable() {
+		req.Header.Set(AgentTokenHeader, string(t.AgentToken))
+	}
+}
@@ -0,0 +1,56 @@
+package collect
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	ProcessorFactory func(options json.RawMessage) (Processor, error)
+)
+
+var (
+	factoriesMu = &sync.RWMutex{}
+	factories   = map[string]ProcessorFactory{}
+)
+
+func RegisterProcessor(name string, factory ProcessorFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("processor %q is already registered", name))
+	}
+	factories[name] = factory
+}
+
+func NewProcessor(name string, options json.RawMessage) (Processor, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown processor: %v", name)
+	}
+
+	processor, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v processor: %w", name, err)
+	}
+	return processor, nil
+}
+
+func Processors() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	_ "embed"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
-	"time"
+	"sync"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/goccy/go-json"
@@ -18,18 +20,65 @@ import (
 
 type (
 	Collector struct {
-		port string
+		endpoint string
 
 		store     storage.Storage
 		validator *validator.Validate
-		targets   *persistent.Handler
+
+		environments *persistent.Handler
+		envMu        *sync.RWMutex
+		envs         map[string]*environment
+		active       string
+
+		history *history
+
+		typesMu *sync.RWMutex
+		types   map[string][]*Tool
+
+		dispatchers []func(*collect.SnapshotTarget)
+		observers   []func(*collect.SnapshotTarget)
+		authorizers []func(*http.Request)
 	}
 
 	CollectTarget struct {
 		Type     string `validate:"required"`
 		Label    string `validate:"required"`
-		URL      string `validate:"required,url"`
-		Duration int    `validate:"required,gt=0"`
+		Tags     map[string]string
+		URL      string   `validate:"required_without=URLs,omitempty,url"`
+		URLs     []string `validate:"dive,url"`
+		Duration int      `validate:"gte=0"`
+
+		Headers     map[string]string
+		BasicAuth   *basicAuth
+		BearerToken string
+		AgentToken  string
+
+		Client *collect.ClientOptions
+
+		ProcessorOptions json.RawMessage
+	}
+
+	basicAuth struct {
+		Username string
+		Password string
+	}
+
+	publicTarget struct {
+		*CollectTarget
+
+		Headers     collect.Headers
+		BasicAuth   *collect.BasicAuth
+		BearerToken collect.Secret
+		AgentToken  collect.Secret
+	}
+
+	collectRequest struct {
+		*collect.SnapshotTarget
+
+		Headers     map[string]string
+		BasicAuth   *basicAuth
+		BearerToken string
+		AgentToken  string
 	}
 
 	GroupMeta struct {
@@ -43,26 +92,60 @@ type (
 //go:embed targets.json
 var defaultTargets []byte
 
-func NewCollector(store storage.Storage, port string) (*Collector, error) {
+func NewCollector(store storage.Storage, endpoint string) (*Collector, error) {
 	c := &Collector{
-		port:      port,
+		endpoint:  endpoint,
 		store:     store,
 		validator: validator.New(),
+		envMu:     &sync.RWMutex{},
+		envs:      map[string]*environment{},
+		history:   newHistory(store),
+		typesMu:   &sync.RWMutex{},
+		types:     map[string][]*Tool{},
 	}
 
-	targets, err := persistent.New(store, "targets.json", defaultTargets, c.sanitize)
+	environments, err := persistent.New(store, "environments.json", defaultEnvironments, c.sanitizeEnvironments)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create targets: %w", err)
+		return nil, fmt.Errorf("failed to create environments: %w", err)
+	}
+	c.environments = environments
+	c.environments.OnUpdate(func() {
+		if err := c.reloadEnvironments(); err != nil {
+			log.Printf("[!] failed to reload environments: %v", err)
+		}
+	})
+	if err := c.reloadEnvironments(); err != nil {
+		return nil, fmt.Errorf("failed to load environments: %w", err)
 	}
-	c.targets = targets
 
 	return c, nil
 }
 
 func (cl *Collector) RegisterHandlers(g *echo.Group) {
-	cl.targets.RegisterHandlers(g.Group("/targets"))
+	eg := g.Group("/environments")
+	eg.GET("", cl.environments.HandleGet)
+	eg.POST("", func(c echo.Context) error {
+		return cl.recordChange(cl.environments, "", fileEnvironments, requestAuthor(c), "", func() error {
+			return cl.environments.HandlePost(c)
+		})
+	})
+
+	cl.registerHistoryHandlers(g.Group("/history"))
+
+	cl.registerEnvironmentHandlers(g, func(echo.Context) string { return "" })
+	cl.registerEnvironmentHandlers(g.Group("/env/:env"), func(c echo.Context) string { return c.Param("env") })
+}
 
-	g.GET("/collect", cl.collectAll)
+func (cl *Collector) OnCollect(fn func(*collect.SnapshotTarget)) {
+	cl.dispatchers = append(cl.dispatchers, fn)
+}
+
+func (cl *Collector) OnRun(fn func(*collect.SnapshotTarget)) {
+	cl.observers = append(cl.observers, fn)
+}
+
+func (cl *Collector) OnRequest(fn func(*http.Request)) {
+	cl.authorizers = append(cl.authorizers, fn)
 }
 
 func (cl *Collector) sanitize(raw []byte) ([]byte, error) {
@@ -83,51 +166,251 @@ func (cl *Collector) sanitize(raw []byte) ([]byte, error) {
 	return res, nil
 }
 
-func (cl *Collector) collectAll(c echo.Context) error {
-	raw, err := cl.targets.GetContent()
+func (env *environment) getTargets() ([]*CollectTarget, error) {
+	raw, err := env.targets.GetContent()
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to get config: %v", err))
+		return nil, fmt.Errorf("failed to get config: %w", err)
 	}
 
 	targets := []*CollectTarget{}
 	if err := json.Unmarshal(raw, &targets); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to unmarshal: %v", err))
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
 	}
+	return targets, nil
+}
 
-	grpId := time.Now().Format("2006-01-02_15-04-05.999999")
-	eg := &errgroup.Group{}
+func (env *environment) getPublicTargets(c echo.Context) error {
+	targets, err := env.getTargets()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := make([]*publicTarget, 0, len(targets))
+	for _, target := range targets {
+		pt := &publicTarget{
+			CollectTarget: target,
+			Headers:       target.Headers,
+			BearerToken:   collect.Secret(target.BearerToken),
+			AgentToken:    collect.Secret(target.AgentToken),
+		}
+		if target.BasicAuth != nil {
+			pt.BasicAuth = &collect.BasicAuth{Username: target.BasicAuth.Username, Password: collect.Secret(target.BasicAuth.Password)}
+		}
+		resp = append(resp, pt)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (env *environment) postTargets(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to read body: %v", err))
+	}
 
-	ch := make(chan error, len(targets))
-	defer close(ch)
+	targets := []*CollectTarget{}
+	if err := json.Unmarshal(body, &targets); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse body: %v", err))
+	}
 
+	env.targetsMu.Lock()
+	defer env.targetsMu.Unlock()
+
+	current, err := env.getTargets()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	stored := map[string]*CollectTarget{}
+	for _, target := range current {
+		if _, ok := stored[target.Type+"/"+target.Label]; !ok {
+			stored[target.Type+"/"+target.Label] = target
+		}
+	}
 	for _, target := range targets {
-		target := *target
-		eg.Go(func() error {
-			return cl.makeInternalRequest(grpId, target)
-		})
+		if prev, ok := stored[target.Type+"/"+target.Label]; ok {
+			keepSecrets(target, prev)
+		}
 	}
 
-	if err := eg.Wait(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to collect: %v", err))
+	raw, err := json.Marshal(targets)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to marshal: %v", err))
+	}
+	pretty, err := env.cl.sanitize(raw)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse body: %v", err))
+	}
+	if err := env.targets.SetContent(pretty); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 	return c.NoContent(http.StatusOK)
 }
-func (cl *Collector) makeInternalRequest(grpId string, target CollectTarget) error {
-	body, err := json.Marshal(&collect.SnapshotTarget{
-		GroupId:  grpId,
-		Label:    target.Label,
-		URL:      target.URL,
-		Duration: target.Duration,
+
+func keepSecrets(target *CollectTarget, prev *CollectTarget) {
+	for name, value := range target.Headers {
+		if stored, ok := prev.Headers[name]; ok && value == collect.Redacted {
+			target.Headers[name] = stored
+		}
+	}
+	if target.BasicAuth != nil && prev.BasicAuth != nil && target.BasicAuth.Password == collect.Redacted {
+		target.BasicAuth.Password = prev.BasicAuth.Password
+	}
+	if target.BearerToken == collect.Redacted {
+		target.BearerToken = prev.BearerToken
+	}
+	if target.AgentToken == collect.Redacted {
+		target.AgentToken = prev.AgentToken
+	}
+}
+
+func (cl *Collector) SyncAgentTargets(agent string, targets []*CollectTarget) error {
+	env, ok := cl.environment(DefaultEnvironment)
+	if !ok {
+		return fmt.Errorf("no such environment: %v", DefaultEnvironment)
+	}
+
+	env.targetsMu.Lock()
+	defer env.targetsMu.Unlock()
+
+	current, err := env.getTargets()
+	if err != nil {
+		return err
+	}
+
+	updated := []*CollectTarget{}
+	for _, target := range current {
+		if target.Tags["agent"] != agent {
+			updated = append(updated, target)
+		}
+	}
+	for _, target := range targets {
+		if target.Tags == nil {
+			target.Tags = map[string]string{}
+		}
+		target.Tags["agent"] = agent
+		updated = append(updated, target)
+	}
+
+	before, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	raw, err := json.Marshal(updated)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	if bytes.Equal(raw, before) {
+		return nil
+	}
+	return cl.recordChange(env.targets, env.name, fileTargets, "agent:"+agent, "", func() error {
+		return env.targets.SetContent(raw)
 	})
+}
+
+func (env *environment) collectAll(c echo.Context) error {
+	targets, err := env.getTargets()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	grpId := collect.NewGroupId()
+	if err := env.collect(&collect.SnapshotTarget{GroupId: grpId, RunId: grpId}, targets); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to collect: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (env *environment) collect(base *collect.SnapshotTarget, targets []*CollectTarget) error {
+	for _, observe := range env.cl.observers {
+		observe(base)
+	}
+	if env.name == DefaultEnvironment {
+		for _, dispatch := range env.cl.dispatchers {
+			dispatch(base)
+		}
+	}
+
+	eg := &errgroup.Group{}
+
+	for _, target := range targets {
+		target := target
+
+		tags := map[string]string{}
+		for k, v := range target.Tags {
+			tags[k] = v
+		}
+		tags["env"] = env.name
+
+		eg.Go(func() error {
+			return env.cl.makeInternalRequest(target.Type, &collectRequest{
+				SnapshotTarget: &collect.SnapshotTarget{
+					GroupId:    base.GroupId,
+					RunId:      base.RunId,
+					ScheduleId: base.ScheduleId,
+					Label:      target.Label,
+					Tags:       tags,
+					URL:        target.URL,
+					URLs:       target.URLs,
+					Duration:   target.Duration,
+					Client:     target.Client,
+
+					ProcessorOptions: target.ProcessorOptions,
+				},
+				Headers:     target.Headers,
+				BasicAuth:   target.BasicAuth,
+				BearerToken: target.BearerToken,
+				AgentToken:  target.AgentToken,
+			})
+		})
+	}
+
+	return eg.Wait()
+}
+
+func (cl *Collector) CollectTargets(name string, types []string) (string, []*CollectTarget, error) {
+	env, ok := cl.environment(name)
+	if !ok {
+		return "", nil, fmt.Errorf("no such environment: %v", name)
+	}
+
+	targets, err := env.getTargets()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(types) == 0 {
+		return env.name, targets, nil
+	}
+
+	filtered := []*CollectTarget{}
+	for _, target := range targets {
+		if contains(types, target.Type) {
+			filtered = append(filtered, target)
+		}
+	}
+	return env.name, filtered, nil
+}
+
+func (cl *Collector) Collect(name string, base *collect.SnapshotTarget, targets []*CollectTarget) error {
+	env, ok := cl.environment(name)
+	if !ok {
+		return fmt.Errorf("no such environment: %v", name)
+	}
+	return env.collect(base, targets)
+}
+
+func (cl *Collector) makeInternalRequest(typ string, target *collectRequest) error {
+	body, err := json.Marshal(target)
 	if err != nil {
 		return fmt.Errorf("failed to marshal: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%s/api/%s", cl.port, target.Type), bytes.NewBuffer(body))
+	req, err := http.NewRequest(http.MethodPost, cl.endpoint+"/"+typ, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	for _, authorize := range cl.authorizers {
+		authorize(req)
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -0,0 +1,219 @@
+package group
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/persistent"
+	"github.com/labstack/echo/v4"
+	"github.com/robfig/cron/v3"
+)
+
+type (
+	Environments struct {
+		Active string   `validate:"required"`
+		Names  []string `validate:"required,dive,required"`
+	}
+
+	environment struct {
+		name string
+		cl   *Collector
+
+		targets   *persistent.Handler
+		targetsMu *sync.Mutex
+		schedules *persistent.Handler
+
+		cronMu *sync.Mutex
+		cron   *cron.Cron
+		jobs   map[string]cron.EntryID
+	}
+)
+
+const DefaultEnvironment = "default"
+
+var environmentNamePattern = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+
+var defaultEnvironments = []byte(`{"Active":"` + DefaultEnvironment + `","Names":["` + DefaultEnvironment + `"]}`)
+
+func (cl *Collector) sanitizeEnvironments(raw []byte) ([]byte, error) {
+	envs := &Environments{}
+	if err := json.Unmarshal(raw, envs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	if err := cl.validator.Struct(envs); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, name := range envs.Names {
+		if !environmentNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment name: %v", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicated environment name: %v", name)
+		}
+		seen[name] = true
+	}
+	if !seen[DefaultEnvironment] {
+		return nil, fmt.Errorf("environment %v must not be removed", DefaultEnvironment)
+	}
+	if !seen[envs.Active] {
+		return nil, fmt.Errorf("no such environment: %v", envs.Active)
+	}
+
+	res, err := json.MarshalIndent(envs, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	return res, nil
+}
+
+func (cl *Collector) getEnvironments() (*Environments, error) {
+	raw, err := cl.environments.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	envs := &Environments{}
+	if err := json.Unmarshal(raw, envs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return envs, nil
+}
+
+func (cl *Collector) reloadEnvironments() error {
+	envs, err := cl.getEnvironments()
+	if err != nil {
+		return err
+	}
+
+	cl.envMu.Lock()
+	defer cl.envMu.Unlock()
+
+	names := map[string]bool{}
+	for _, name := range envs.Names {
+		names[name] = true
+		if _, ok := cl.envs[name]; ok {
+			continue
+		}
+
+		env, err := cl.newEnvironment(name)
+		if err != nil {
+			return fmt.Errorf("failed to load environment %v: %w", name, err)
+		}
+		cl.envs[name] = env
+	}
+	for name, env := range cl.envs {
+		if !names[name] {
+			env.stopSchedules()
+			delete(cl.envs, name)
+		}
+	}
+
+	cl.active = envs.Active
+	return nil
+}
+
+func (cl *Collector) newEnvironment(name string) (*environment, error) {
+	env := &environment{
+		name:      name,
+		cl:        cl,
+		targetsMu: &sync.Mutex{},
+		cronMu:    &sync.Mutex{},
+	}
+
+	targetsFile, schedulesFile := "targets.json", "schedules.json"
+	initialTargets := defaultTargets
+	if name != DefaultEnvironment {
+		targetsFile, schedulesFile = "targets."+name+".json", "schedules."+name+".json"
+		initialTargets = []byte("[]")
+	}
+
+	targets, err := persistent.New(cl.store, targetsFile, initialTargets, cl.sanitize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create targets: %w", err)
+	}
+	env.targets = targets
+
+	schedules, err := persistent.New(cl.store, schedulesFile, defaultSchedules, cl.sanitizeSchedules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schedules: %w", err)
+	}
+	env.schedules = schedules
+	env.schedules.OnUpdate(env.reloadSchedules)
+	env.reloadSchedules()
+
+	return env, nil
+}
+
+func (cl *Collector) environment(name string) (*environment, bool) {
+	cl.envMu.RLock()
+	defer cl.envMu.RUnlock()
+
+	if name == "" {
+		name = cl.active
+	}
+	env, ok := cl.envs[name]
+	return env, ok
+}
+
+func (cl *Collector) ActiveTargets() (string, []*CollectTarget, error) {
+	env, ok := cl.environment("")
+	if !ok {
+		return "", nil, fmt.Errorf("no active environment")
+	}
+
+	targets, err := env.getTargets()
+	if err != nil {
+		return "", nil, err
+	}
+	return env.name, targets, nil
+}
+
+func (cl *Collector) registerEnvironmentHandlers(g *echo.Group, name func(echo.Context) string) {
+	with := func(fn func(*environment, echo.Context) error) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			env, ok := cl.environment(name(c))
+			if !ok {
+				return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no such environment: %v", name(c)))
+			}
+			return fn(env, c)
+		}
+	}
+
+	g.GET("/targets", with((*environment).getPublicTargets))
+	g.POST("/targets", with(func(env *environment, c echo.Context) error {
+		return cl.recordChange(env.targets, env.name, fileTargets, requestAuthor(c), "", func() error {
+			return env.postTargets(c)
+		})
+	}))
+
+	g.GET("/collect", with((*environment).collectAll))
+	g.POST("/validate", with((*environment).validateTargets))
+
+	sg := g.Group("/schedules")
+	sg.GET("", with(func(env *environment, c echo.Context) error { return env.schedules.HandleGet(c) }))
+	sg.POST("", with(func(env *environment, c echo.Context) error {
+		return cl.recordChange(env.schedules, env.name, fileSchedules, requestAuthor(c), "", func() error {
+			return env.schedules.HandlePost(c)
+		})
+	}))
+	sg.GET("/status", with((*environment).getScheduleStatus))
+	sg.POST("/:id/start", with((*environment).startSchedule))
+	sg.POST("/:id/stop", with((*environment).stopSchedule))
+}
+
+func (env *environment) stopSchedules() {
+	env.cronMu.Lock()
+	defer env.cronMu.Unlock()
+
+	if env.cron != nil {
+		env.cron.Stop()
+	}
+	env.cron = nil
+	env.jobs = map[string]cron.EntryID{}
+}
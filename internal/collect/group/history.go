@@ -0,0 +1,292 @@
+package group
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/persistent"
+	"github.com/kaz/pprotein/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	history struct {
+		mu    *sync.Mutex
+		store storage.Storage
+	}
+
+	Version struct {
+		ID          string
+		Datetime    time.Time
+		Environment string
+		File        string
+		Author      string
+		Comment     string
+		Added       int
+		Removed     int
+
+		Previous string `json:",omitempty"`
+		Content  string `json:",omitempty"`
+		Diff     string `json:",omitempty"`
+	}
+)
+
+const (
+	historyTypeKey = "group-history"
+	historyLimit   = 200
+
+	fileTargets      = "targets"
+	fileSchedules    = "schedules"
+	fileEnvironments = "environments"
+)
+
+func newHistory(store storage.Storage) *history {
+	return &history{mu: &sync.Mutex{}, store: store}
+}
+
+func (h *history) record(v *Version) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	v.ID = strconv.FormatInt(time.Now().UnixNano(), 10)
+	v.Datetime = time.Now()
+	v.Added, v.Removed = diffStat(v.Previous, v.Content)
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	if err := h.store.Put(historyTypeKey, v.ID, raw); err != nil {
+		return fmt.Errorf("failed to save version: %w", err)
+	}
+
+	versions, err := h.list()
+	if err != nil {
+		return err
+	}
+	for _, old := range versions[min(len(versions), historyLimit):] {
+		if err := h.store.Delete(historyTypeKey, old.ID); err != nil {
+			return fmt.Errorf("failed to delete version: %w", err)
+		}
+	}
+	return nil
+}
+
+func (h *history) list() ([]*Version, error) {
+	raws, err := h.store.GetAll(historyTypeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	versions := make([]*Version, 0, len(raws))
+	for _, raw := range raws {
+		v := &Version{}
+		if err := json.Unmarshal(raw, v); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID > versions[j].ID
+	})
+	return versions, nil
+}
+
+func (h *history) get(id string) (*Version, error) {
+	raw, err := h.store.Get(historyTypeKey, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version: %w", err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("no such version: %v", id)
+	}
+
+	v := &Version{}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return v, nil
+}
+
+func (cl *Collector) recordChange(handler *persistent.Handler, env string, file string, author string, comment string, fn func() error) error {
+	prev, err := handler.GetContent()
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	next, err := handler.GetContent()
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(prev, next) {
+		return nil
+	}
+
+	return cl.history.record(&Version{
+		Environment: env,
+		File:        file,
+		Author:      author,
+		Comment:     comment,
+		Previous:    string(prev),
+		Content:     string(next),
+	})
+}
+
+func (cl *Collector) configHandler(env string, file string) (*persistent.Handler, error) {
+	if file == fileEnvironments {
+		return cl.environments, nil
+	}
+
+	e, ok := cl.environment(env)
+	if !ok {
+		return nil, fmt.Errorf("no such environment: %v", env)
+	}
+	switch file {
+	case fileTargets:
+		return e.targets, nil
+	case fileSchedules:
+		return e.schedules, nil
+	}
+	return nil, fmt.Errorf("unknown config file: %v", file)
+}
+
+func (cl *Collector) registerHistoryHandlers(g *echo.Group) {
+	g.GET("", cl.getHistory)
+	g.GET("/:id", cl.getVersion)
+	g.POST("/:id/rollback", cl.rollbackVersion)
+}
+
+func (cl *Collector) getHistory(c echo.Context) error {
+	versions, err := cl.history.list()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := []*Version{}
+	for _, v := range versions {
+		if env := c.QueryParam("env"); env != "" && v.Environment != env {
+			continue
+		}
+		if file := c.QueryParam("file"); file != "" && v.File != file {
+			continue
+		}
+
+		v.Previous, v.Content = "", ""
+		resp = append(resp, v)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (cl *Collector) getVersion(c echo.Context) error {
+	v, err := cl.history.get(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	v.Diff = unifiedDiff(v.Previous, v.Content)
+	return c.JSON(http.StatusOK, v)
+}
+
+func (cl *Collector) rollbackVersion(c echo.Context) error {
+	v, err := cl.history.get(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	content := v.Content
+	if c.QueryParam("before") == "true" {
+		content = v.Previous
+	}
+
+	handler, err := cl.configHandler(v.Environment, v.File)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	err = cl.recordChange(handler, v.Environment, v.File, requestAuthor(c), "rollback to "+v.ID, func() error {
+		return handler.SetContent([]byte(content))
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to rollback: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func requestAuthor(c echo.Context) string {
+	if author := c.QueryParam("author"); author != "" {
+		return author
+	}
+	return c.RealIP()
+}
+
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+"+b[j])
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func unifiedDiff(prev, next string) string {
+	return strings.Join(diffLines(splitLines(prev), splitLines(next)), "\n")
+}
+
+func diffStat(prev, next string) (int, int) {
+	added, removed := 0, 0
+	for _, line := range diffLines(splitLines(prev), splitLines(next)) {
+		switch line[0] {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,186 @@
+package group
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/labstack/echo/v4"
+	"github.com/robfig/cron/v3"
+)
+
+type (
+	Schedule struct {
+		ID      string `validate:"required"`
+		Spec    string `validate:"required"`
+		Enabled bool
+		Targets []*CollectTarget `validate:"dive"`
+	}
+
+	ScheduleStatus struct {
+		ID      string
+		Enabled bool
+		Prev    time.Time
+		Next    time.Time
+	}
+)
+
+//go:embed schedules.json
+var defaultSchedules []byte
+
+func (cl *Collector) sanitizeSchedules(raw []byte) ([]byte, error) {
+	schedules := []*Schedule{}
+	if err := json.Unmarshal(raw, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	if err := cl.validator.Var(schedules, "dive"); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, s := range schedules {
+		if seen[s.ID] {
+			return nil, fmt.Errorf("duplicated schedule id: %v", s.ID)
+		}
+		seen[s.ID] = true
+
+		if _, err := cron.ParseStandard(s.Spec); err != nil {
+			return nil, fmt.Errorf("invalid spec for %v: %w", s.ID, err)
+		}
+	}
+
+	res, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	return res, nil
+}
+
+func (env *environment) getSchedules() ([]*Schedule, error) {
+	raw, err := env.schedules.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	schedules := []*Schedule{}
+	if err := json.Unmarshal(raw, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return schedules, nil
+}
+
+func (env *environment) reloadSchedules() {
+	schedules, err := env.getSchedules()
+	if err != nil {
+		log.Printf("[!] failed to load schedules for %v: %v", env.name, err)
+		return
+	}
+
+	env.cronMu.Lock()
+	defer env.cronMu.Unlock()
+
+	if env.cron != nil {
+		env.cron.Stop()
+	}
+	env.cron = cron.New()
+	env.jobs = map[string]cron.EntryID{}
+
+	for _, s := range schedules {
+		if !s.Enabled {
+			continue
+		}
+
+		s := s
+		id, err := env.cron.AddFunc(s.Spec, func() { env.runSchedule(s) })
+		if err != nil {
+			log.Printf("[!] failed to register schedule %v: %v", s.ID, err)
+			continue
+		}
+		env.jobs[s.ID] = id
+	}
+
+	env.cron.Start()
+}
+
+func (env *environment) runSchedule(s *Schedule) {
+	targets := s.Targets
+	if len(targets) == 0 {
+		var err error
+		if targets, err = env.getTargets(); err != nil {
+			log.Printf("[!] schedule %v aborted: %v", s.ID, err)
+			return
+		}
+	}
+
+	grpId := collect.NewGroupId()
+	if err := env.collect(&collect.SnapshotTarget{GroupId: grpId, RunId: grpId, ScheduleId: s.ID}, targets); err != nil {
+		log.Printf("[!] schedule %v aborted: %v", s.ID, err)
+	}
+}
+
+func (env *environment) setScheduleEnabled(id string, enabled bool, author string) error {
+	schedules, err := env.getSchedules()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, s := range schedules {
+		if s.ID == id {
+			s.Enabled = enabled
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no such schedule: %v", id)
+	}
+
+	raw, err := json.Marshal(schedules)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	return env.cl.recordChange(env.schedules, env.name, fileSchedules, author, "", func() error {
+		return env.schedules.SetContent(raw)
+	})
+}
+
+func (env *environment) getScheduleStatus(c echo.Context) error {
+	schedules, err := env.getSchedules()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	env.cronMu.Lock()
+	defer env.cronMu.Unlock()
+
+	resp := make([]*ScheduleStatus, 0, len(schedules))
+	for _, s := range schedules {
+		status := &ScheduleStatus{ID: s.ID, Enabled: s.Enabled}
+		if id, ok := env.jobs[s.ID]; ok {
+			ent := env.cron.Entry(id)
+			status.Prev = ent.Prev
+			status.Next = ent.Next
+		}
+		resp = append(resp, status)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (env *environment) startSchedule(c echo.Context) error {
+	if err := env.setScheduleEnabled(c.Param("id"), true, requestAuthor(c)); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to start schedule: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (env *environment) stopSchedule(c echo.Context) error {
+	if err := env.setScheduleEnabled(c.Param("id"), false, requestAuthor(c)); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to stop schedule: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
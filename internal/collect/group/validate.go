@@ -0,0 +1,252 @@
+package group
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/errgroup"
+)
+
+type (
+	ValidationReport struct {
+		Valid   bool
+		Error   string `json:",omitempty"`
+		Targets []*TargetReport
+		Tools   []*ToolReport
+	}
+
+	TargetReport struct {
+		Index    int
+		Type     string
+		Label    string
+		Errors   []string
+		Warnings []string
+		URLs     []*URLReport
+	}
+
+	URLReport struct {
+		URL       string
+		Reachable bool
+		Status    int
+		Latency   float64
+		Error     string `json:",omitempty"`
+	}
+
+	ToolReport struct {
+		Name     string
+		Path     string `json:",omitempty"`
+		Types    []string
+		Found    bool
+		Optional bool
+	}
+
+	Tool struct {
+		Name     string
+		Optional bool
+	}
+)
+
+const (
+	validateTimeout     = 5 * time.Second
+	validateConcurrency = 8
+)
+
+func (cl *Collector) RegisterType(typ string, tools ...*Tool) {
+	cl.typesMu.Lock()
+	defer cl.typesMu.Unlock()
+
+	cl.types[typ] = tools
+}
+
+func (cl *Collector) typeTools(typ string) ([]*Tool, bool) {
+	cl.typesMu.RLock()
+	defer cl.typesMu.RUnlock()
+
+	if len(cl.types) == 0 {
+		return nil, true
+	}
+	tools, ok := cl.types[typ]
+	return tools, ok
+}
+
+func (env *environment) validateTargets(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to read body: %v", err))
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		if body, err = env.targets.GetContent(); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, env.cl.validate(c.Request().Context(), body))
+}
+
+func (cl *Collector) validate(ctx context.Context, raw []byte) *ValidationReport {
+	report := &ValidationReport{Targets: []*TargetReport{}, Tools: []*ToolReport{}}
+
+	if _, err := cl.sanitize(raw); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	targets := []*CollectTarget{}
+	if err := json.Unmarshal(raw, &targets); err != nil {
+		report.Error = fmt.Sprintf("failed to unmarshal: %v", err)
+		return report
+	}
+
+	tools := map[string]*ToolReport{}
+	eg := &errgroup.Group{}
+	eg.SetLimit(validateConcurrency)
+
+	for i, target := range targets {
+		tr := &TargetReport{Index: i, Type: target.Type, Label: target.Label, Errors: []string{}, Warnings: []string{}, URLs: []*URLReport{}}
+		report.Targets = append(report.Targets, tr)
+
+		required, ok := cl.typeTools(target.Type)
+		if !ok {
+			tr.Errors = append(tr.Errors, fmt.Sprintf("unknown type: %v", target.Type))
+		}
+		for _, req := range required {
+			tool, ok := tools[req.Name]
+			if !ok {
+				tool = &ToolReport{Name: req.Name, Types: []string{}, Optional: true}
+				if path, err := exec.LookPath(req.Name); err == nil {
+					tool.Path = path
+					tool.Found = true
+				}
+				tools[req.Name] = tool
+			}
+			if !contains(tool.Types, target.Type) {
+				tool.Types = append(tool.Types, target.Type)
+			}
+			tool.Optional = tool.Optional && req.Optional
+
+			if tool.Found {
+				continue
+			}
+			if req.Optional {
+				tr.Warnings = append(tr.Warnings, fmt.Sprintf("optional tool is not installed: %v", req.Name))
+			} else {
+				tr.Errors = append(tr.Errors, fmt.Sprintf("required tool is not installed: %v", req.Name))
+			}
+		}
+
+		for i, url := range TargetURLs(target) {
+			tr.URLs = append(tr.URLs, nil)
+
+			i, url, target := i, url, target
+			eg.Go(func() error {
+				tr.URLs[i] = CheckURL(ctx, target, url, validateTimeout)
+				return nil
+			})
+		}
+	}
+	eg.Wait()
+
+	for _, tool := range tools {
+		report.Tools = append(report.Tools, tool)
+	}
+	sort.Slice(report.Tools, func(i, j int) bool {
+		return report.Tools[i].Name < report.Tools[j].Name
+	})
+
+	report.Valid = true
+	for _, tr := range report.Targets {
+		if len(tr.Errors) > 0 {
+			report.Valid = false
+		}
+		for _, ur := range tr.URLs {
+			if !ur.Reachable || ur.Error != "" {
+				report.Valid = false
+			}
+		}
+	}
+	return report
+}
+
+func CheckURL(ctx context.Context, target *CollectTarget, url string, timeout time.Duration) *URLReport {
+	report := &URLReport{URL: url}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			report.Reachable = true
+			report.Latency = time.Since(start).Seconds()
+		},
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to create request: %v", err)
+		return report
+	}
+	for name, value := range target.Headers {
+		req.Header.Set(name, value)
+	}
+	if target.BasicAuth != nil {
+		req.SetBasicAuth(target.BasicAuth.Username, target.BasicAuth.Password)
+	}
+	if target.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+target.BearerToken)
+	}
+	if target.AgentToken != "" {
+		req.Header.Set(collect.AgentTokenHeader, target.AgentToken)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: target.Client != nil && target.Client.InsecureSkipVerify},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if report.Reachable && ctx.Err() != nil {
+			return report
+		}
+		report.Error = fmt.Sprintf("failed to send request: %v", err)
+		return report
+	}
+	resp.Body.Close()
+
+	report.Status = resp.StatusCode
+	report.Latency = time.Since(start).Seconds()
+	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode != http.StatusMethodNotAllowed {
+		report.Error = fmt.Sprintf("unexpected status code: %d", resp.StatusCode)
+	}
+	return report
+}
+
+func TargetURLs(target *CollectTarget) []string {
+	urls := target.URLs
+	if target.URL != "" {
+		urls = append([]string{target.URL}, urls...)
+	}
+	return urls
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,326 @@
+package collect
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/git"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	handler struct {
+		collector *Collector
+	}
+
+	labelRequest struct {
+		Label string
+		Tags  map[string]string
+	}
+
+	commentRequest struct {
+		Author string
+		Body   string
+	}
+)
+
+func (c *Collector) RegisterHandlers(g *echo.Group) {
+	h := &handler{collector: c}
+
+	g.POST("/upload", h.postUpload)
+	g.POST("/bulk", h.postBulk)
+	g.DELETE("/:id", h.deleteId)
+	g.PUT("/:id/label", h.putLabel)
+	g.PUT("/:id/pin", h.putPin)
+	g.DELETE("/:id/pin", h.deletePin)
+	g.POST("/:id/retry", h.postRetry)
+	g.POST("/:id/cancel", h.postCancel)
+	g.POST("/:id/reprocess", h.postReprocess)
+	g.GET("/:id/raw", h.getRaw)
+	g.POST("/:id/verify", h.postVerify)
+	g.GET("/:id/comments", h.getComments)
+	g.POST("/:id/comments", h.postComment)
+	g.DELETE("/:id/comments/:commentId", h.deleteComment)
+}
+
+func ServeContent(c echo.Context, contentType string, r io.ReadCloser) error {
+	defer r.Close()
+
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return c.Stream(http.StatusOK, contentType, r)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	http.ServeContent(c.Response(), c.Request(), "", time.Time{}, rs)
+	return nil
+}
+
+func ServeEntries(c echo.Context, collector *Collector) error {
+	q, err := parseIndexQuery(c)
+	if err != nil {
+		return err
+	}
+	q.Type = ""
+
+	entries, total := collector.Query(q)
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(total))
+	return c.JSON(http.StatusOK, entries)
+}
+
+func (h *handler) postUpload(c echo.Context) error {
+	target := &SnapshotTarget{}
+	if meta := c.FormValue("meta"); meta != "" {
+		if err := json.Unmarshal([]byte(meta), target); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse meta: %v", err))
+		}
+	}
+
+	repo, err := ParseRepository(c.FormValue("repository"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse repository: %v", err))
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to read file: %v", err))
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to open file: %v", err))
+	}
+	defer file.Close()
+
+	snapshot, err := h.collector.Upload(target, repo, file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to upload snapshot: %v", err))
+	}
+	return c.JSON(http.StatusAccepted, snapshot)
+}
+
+func ParseRepository(raw string) (*git.RepositoryInfo, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	repo := &git.RepositoryInfo{}
+	if err := json.Unmarshal([]byte(raw), repo); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (h *handler) deleteId(c echo.Context) error {
+	if err := h.collector.Delete(c.Param("id")); err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to delete entry: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) putLabel(c echo.Context) error {
+	req := &labelRequest{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+
+	if err := h.collector.Relabel(c.Param("id"), req.Label, req.Tags); err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to relabel entry: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) putPin(c echo.Context) error {
+	return h.pin(c, true)
+}
+
+func (h *handler) deletePin(c echo.Context) error {
+	return h.pin(c, false)
+}
+
+func (h *handler) pin(c echo.Context, pinned bool) error {
+	if err := h.collector.Pin(c.Param("id"), pinned); err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to pin entry: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) postRetry(c echo.Context) error {
+	if err := h.collector.Retry(c.Param("id")); err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("failed to retry entry: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) postCancel(c echo.Context) error {
+	if err := h.collector.Cancel(c.Param("id")); err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("failed to cancel entry: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) postReprocess(c echo.Context) error {
+	if err := h.collector.Reprocess(c.Param("id")); err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("failed to reprocess entry: %v", err))
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (h *handler) postVerify(c echo.Context) error {
+	ent, err := h.collector.Verify(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("failed to verify entry: %v", err))
+	}
+	return c.JSON(http.StatusOK, ent)
+}
+
+func (h *handler) getComments(c echo.Context) error {
+	comments, err := h.collector.Comments(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to get comments: %v", err))
+	}
+	return c.JSON(http.StatusOK, comments)
+}
+
+func (h *handler) postComment(c echo.Context) error {
+	req := &commentRequest{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+
+	comment, err := h.collector.AddComment(c.Param("id"), req.Author, req.Body)
+	if err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to add comment: %v", err))
+	}
+	return c.JSON(http.StatusCreated, comment)
+}
+
+func (h *handler) deleteComment(c echo.Context) error {
+	if err := h.collector.DeleteComment(c.Param("id"), c.Param("commentId")); err != nil {
+		if errors.Is(err, ErrNoSuchEntry) || errors.Is(err, ErrNoSuchComment) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to delete comment: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+const maxPreviewLines = 10000
+
+func (h *handler) getRaw(c echo.Context) error {
+	r, err := h.collector.Raw(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrNoSuchEntry) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to read entry: %v", err))
+	}
+
+	v := c.QueryParam("lines")
+	if v == "" {
+		return ServeContent(c, "text/plain", r)
+	}
+	defer r.Close()
+
+	lines, err := strconv.Atoi(v)
+	if err != nil || lines <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid lines: %v", v))
+	}
+	if lines > maxPreviewLines {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("too many lines: %v (max %v)", lines, maxPreviewLines))
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain")
+	c.Response().WriteHeader(http.StatusOK)
+
+	if c.QueryParam("from") == "tail" {
+		err = writeTail(c.Response(), r, lines)
+	} else {
+		err = writeHead(c.Response(), r, lines)
+	}
+	if err != nil {
+		log.Printf("[!] failed to write preview: %v", err)
+	}
+	return nil
+}
+
+func writeHead(w io.Writer, r io.Reader, lines int) error {
+	br := bufio.NewReader(r)
+	for i := 0; i < lines; i++ {
+		line, err := br.ReadBytes('\n')
+		if _, werr := w.Write(line); werr != nil {
+			return werr
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTail(w io.Writer, r io.Reader, lines int) error {
+	ring := make([][]byte, lines)
+	count := 0
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			ring[count%lines] = line
+			count++
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	start := 0
+	if count > lines {
+		start = count - lines
+	}
+	for i := start; i < count; i++ {
+		if _, err := w.Write(ring[i%lines]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
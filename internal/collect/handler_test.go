@@ -0,0 +1,60 @@
+package collect
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteHead(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		lines int
+		want  string
+	}{
+		{"empty", "", 3, ""},
+		{"fewer lines than requested", "a\nb\n", 3, "a\nb\n"},
+		{"exact", "a\nb\nc\n", 3, "a\nb\nc\n"},
+		{"truncated", "a\nb\nc\nd\n", 2, "a\nb\n"},
+		{"no trailing newline", "a\nb", 5, "a\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := writeHead(w, strings.NewReader(tt.input), tt.lines); err != nil {
+				t.Fatalf("writeHead() error = %v", err)
+			}
+			if got := w.String(); got != tt.want {
+				t.Errorf("writeHead() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTail(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		lines int
+		want  string
+	}{
+		{"empty", "", 3, ""},
+		{"fewer lines than requested", "a\nb\n", 3, "a\nb\n"},
+		{"exact", "a\nb\nc\n", 3, "a\nb\nc\n"},
+		{"truncated", "a\nb\nc\nd\n", 2, "c\nd\n"},
+		{"wraps ring", "a\nb\nc\nd\ne\n", 3, "c\nd\ne\n"},
+		{"no trailing newline", "a\nb\nc", 2, "b\nc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			if err := writeTail(w, strings.NewReader(tt.input), tt.lines); err != nil {
+				t.Fatalf("writeTail() error = %v", err)
+			}
+			if got := w.String(); got != tt.want {
+				t.Errorf("writeTail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/collect/group"
+	"github.com/kaz/pprotein/internal/collect/push"
+	"github.com/kaz/pprotein/internal/event"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/errgroup"
+)
+
+type (
+	Prober struct {
+		registry *collect.Registry
+		targets  *group.Collector
+		agents   *push.Hub
+		eventHub *event.Hub
+		interval time.Duration
+
+		probeMu *sync.Mutex
+		mu      *sync.RWMutex
+		states  map[string]*Health
+	}
+
+	Health struct {
+		Key         string
+		Environment string
+		Type        string
+		Label       string
+		URL         string
+
+		Healthy   bool
+		Reachable bool
+		Status    int
+		Latency   float64
+		LastError string
+
+		LastChecked time.Time
+		LastSuccess time.Time
+		LastFailure time.Time
+	}
+)
+
+const (
+	healthEvent      = "health"
+	probeConcurrency = 8
+	probeTimeout     = 2 * time.Second
+	agentTargetType  = "agent"
+)
+
+func NewProber(registry *collect.Registry, targets *group.Collector, agents *push.Hub, eventHub *event.Hub, interval time.Duration) *Prober {
+	return &Prober{
+		registry: registry,
+		targets:  targets,
+		agents:   agents,
+		eventHub: eventHub,
+		interval: interval,
+		probeMu:  &sync.Mutex{},
+		mu:       &sync.RWMutex{},
+		states:   map[string]*Health{},
+	}
+}
+
+func (p *Prober) Start() {
+	go func() {
+		for {
+			if !p.collecting() {
+				p.Probe(context.Background())
+			}
+			time.Sleep(p.interval)
+		}
+	}()
+}
+
+func (p *Prober) RegisterHandlers(g *echo.Group) {
+	g.GET("", p.getIndex)
+	g.POST("/probe", p.postProbe)
+}
+
+func (p *Prober) List() []*Health {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	resp := make([]*Health, 0, len(p.states))
+	for _, h := range p.states {
+		copied := *h
+		resp = append(resp, &copied)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Key < resp[j].Key
+	})
+	return resp
+}
+
+func (p *Prober) Probe(ctx context.Context) {
+	p.probeMu.Lock()
+	defer p.probeMu.Unlock()
+
+	results := p.probeTargets(ctx)
+	results = append(results, p.probeAgents()...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	states := map[string]*Health{}
+	for _, h := range results {
+		prev, ok := p.states[h.Key]
+		if ok {
+			if h.LastSuccess.IsZero() {
+				h.LastSuccess = prev.LastSuccess
+			}
+			if h.LastFailure.IsZero() {
+				h.LastFailure = prev.LastFailure
+			}
+		}
+		states[h.Key] = h
+
+		if !ok || prev.Healthy != h.Healthy || prev.LastError != h.LastError {
+			p.publish(h)
+		}
+	}
+	p.states = states
+}
+
+func (p *Prober) collecting() bool {
+	for _, ent := range p.registry.List() {
+		if ent.Status == collect.StatusPending && ent.Message == "Collecting" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Prober) probeTargets(ctx context.Context) []*Health {
+	env, targets, err := p.targets.ActiveTargets()
+	if err != nil {
+		log.Printf("[!] failed to load targets for health check: %v", err)
+		return nil
+	}
+
+	results := []*Health{}
+	eg := &errgroup.Group{}
+	eg.SetLimit(probeConcurrency)
+
+	for _, target := range targets {
+		for _, url := range group.TargetURLs(target) {
+			h := &Health{
+				Key:         fmt.Sprintf("%s/%s/%s/%s", env, target.Type, target.Label, url),
+				Environment: env,
+				Type:        target.Type,
+				Label:       target.Label,
+				URL:         url,
+			}
+			results = append(results, h)
+
+			target, url := target, url
+			eg.Go(func() error {
+				report := group.CheckURL(ctx, target, url, probeTimeout)
+
+				h.LastChecked = time.Now()
+				h.Reachable = report.Reachable
+				h.Status = report.Status
+				h.Latency = report.Latency
+				h.LastError = report.Error
+				h.Healthy = report.Reachable && report.Error == ""
+				if h.Healthy {
+					h.LastSuccess = h.LastChecked
+				} else {
+					h.LastFailure = h.LastChecked
+				}
+				return nil
+			})
+		}
+	}
+	eg.Wait()
+
+	return results
+}
+
+func (p *Prober) probeAgents() []*Health {
+	results := []*Health{}
+	for _, a := range p.agents.List() {
+		h := &Health{
+			Key:         fmt.Sprintf("%s/%s", agentTargetType, a.Name),
+			Type:        agentTargetType,
+			Label:       a.Name,
+			URL:         a.URL,
+			Healthy:     a.Live,
+			Reachable:   a.Live,
+			LastChecked: time.Now(),
+			LastSuccess: a.LastSeen,
+		}
+		if !a.Live {
+			h.LastError = fmt.Sprintf("no heartbeat since %s", a.LastSeen.Format(time.RFC3339))
+			h.LastFailure = h.LastChecked
+		}
+		results = append(results, h)
+	}
+	return results
+}
+
+func (p *Prober) publish(h *Health) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		log.Printf("[!] failed to marshal health: %v", err)
+		return
+	}
+	p.eventHub.PublishEvent(healthEvent, data)
+}
+
+func (p *Prober) getIndex(c echo.Context) error {
+	return c.JSON(http.StatusOK, p.List())
+}
+
+func (p *Prober) postProbe(c echo.Context) error {
+	p.Probe(c.Request().Context())
+	return c.JSON(http.StatusOK, p.List())
+}
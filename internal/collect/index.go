@@ -0,0 +1,286 @@
+package collect
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/labstack/echo/v4"
+	_ "modernc.org/sqlite"
+)
+
+type (
+	Index struct {
+		mu *sync.Mutex
+		db *sql.DB
+	}
+
+	IndexQuery struct {
+		Type    string
+		Status  Status
+		Label   string
+		GroupId string
+		RunId   string
+		URL     string
+		Since   time.Time
+		Until   time.Time
+		Limit   int
+		Offset  int
+		Order   string
+	}
+)
+
+const indexSchema = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	id         TEXT PRIMARY KEY,
+	type       TEXT NOT NULL,
+	group_id   TEXT NOT NULL,
+	run_id     TEXT NOT NULL,
+	label      TEXT NOT NULL,
+	url        TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	message    TEXT NOT NULL,
+	tags       TEXT NOT NULL,
+	meta       TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS snapshots_type_created_at ON snapshots (type, created_at);
+CREATE INDEX IF NOT EXISTS snapshots_run_id ON snapshots (run_id);
+`
+
+func NewIndex(workdir string) (*Index, error) {
+	db, err := sql.Open("sqlite", path.Join(workdir, "index.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(indexSchema); err != nil {
+		return nil, fmt.Errorf("failed to initialize index: %w", err)
+	}
+	if _, err := db.Exec(searchSchema); err != nil {
+		return nil, fmt.Errorf("failed to initialize search index: %w", err)
+	}
+	return &Index{mu: &sync.Mutex{}, db: db}, nil
+}
+
+func (i *Index) put(entry *Entry) error {
+	s := entry.Snapshot
+
+	meta, err := s.marshal()
+	if err != nil {
+		return fmt.Errorf("failed to serialize meta: %w", err)
+	}
+
+	target := s.SnapshotTarget
+	if target == nil {
+		target = &SnapshotTarget{}
+	}
+	tags, err := json.Marshal(target.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to serialize tags: %w", err)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	_, err = i.db.Exec(
+		`INSERT INTO snapshots (id, type, group_id, run_id, label, url, status, message, tags, meta, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			group_id = excluded.group_id, run_id = excluded.run_id, label = excluded.label, url = excluded.url,
+			status = excluded.status, message = excluded.message, tags = excluded.tags, meta = excluded.meta,
+			updated_at = excluded.updated_at`,
+		s.ID, s.Type, target.GroupId, target.RunId, target.Label, target.URL,
+		entry.Status, entry.Message, string(tags), string(meta),
+		s.Datetime.UnixNano(), time.Now().UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert: %w", err)
+	}
+	return i.replaceText(s.ID, SearchFieldLabel, labelText(target))
+}
+
+func (i *Index) remove(id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, err := i.db.Exec(`DELETE FROM snapshots WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+	if _, err := i.db.Exec(`DELETE FROM search WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete text: %w", err)
+	}
+	return nil
+}
+
+func (i *Index) Query(q *IndexQuery) ([]*Entry, error) {
+	conds := []string{}
+	args := []interface{}{}
+
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"type", q.Type},
+		{"status", string(q.Status)},
+		{"label", q.Label},
+		{"group_id", q.GroupId},
+		{"run_id", q.RunId},
+		{"url", q.URL},
+	} {
+		if c.value != "" {
+			conds = append(conds, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, q.Until.UnixNano())
+	}
+
+	query := `SELECT meta, status, message FROM snapshots`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	if q.ascending() {
+		query += ` ORDER BY created_at ASC`
+	} else {
+		query += ` ORDER BY created_at DESC`
+	}
+	if q.Limit > 0 || q.Offset > 0 {
+		limit := q.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, q.Offset)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rows, err := i.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+
+	resp := []*Entry{}
+	for rows.Next() {
+		var meta string
+		ent := &Entry{Snapshot: &Snapshot{}}
+		if err := rows.Scan(&meta, &ent.Status, &ent.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		if err := ent.Snapshot.unmarshal([]byte(meta)); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal meta: %w", err)
+		}
+		resp = append(resp, ent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	return resp, nil
+}
+
+func (i *Index) RegisterHandlers(g *echo.Group) {
+	g.GET("", i.handleGet)
+}
+
+func parseIndexQuery(c echo.Context) (*IndexQuery, error) {
+	q := &IndexQuery{
+		Type:    c.QueryParam("type"),
+		Status:  Status(c.QueryParam("status")),
+		Label:   c.QueryParam("label"),
+		GroupId: c.QueryParam("group"),
+		RunId:   c.QueryParam("run"),
+		URL:     c.QueryParam("url"),
+		Order:   c.QueryParam("order"),
+	}
+	if q.Order != "" && q.Order != "asc" && q.Order != "desc" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid order: %v", q.Order))
+	}
+
+	var err error
+	if v := c.QueryParam("since"); v != "" {
+		if q.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid since: %v", err))
+		}
+	}
+	if v := c.QueryParam("until"); v != "" {
+		if q.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid until: %v", err))
+		}
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid limit: %v", err))
+		}
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid offset: %v", err))
+		}
+	}
+	return q, nil
+}
+
+func (q *IndexQuery) matches(ent *Entry) bool {
+	s := ent.Snapshot
+	target := s.SnapshotTarget
+	if target == nil {
+		target = &SnapshotTarget{}
+	}
+
+	for _, c := range []struct {
+		want string
+		have string
+	}{
+		{q.Type, s.Type},
+		{string(q.Status), string(ent.Status)},
+		{q.Label, target.Label},
+		{q.GroupId, target.GroupId},
+		{q.RunId, target.RunId},
+		{q.URL, target.URL},
+	} {
+		if c.want != "" && c.want != c.have {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && s.Datetime.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !s.Datetime.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+func (q *IndexQuery) ascending() bool {
+	return q.Order == "asc"
+}
+
+func (i *Index) handleGet(c echo.Context) error {
+	q, err := parseIndexQuery(c)
+	if err != nil {
+		return err
+	}
+
+	entries, err := i.Query(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to query index: %v", err))
+	}
+	return c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,46 @@
+package collect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+func (c *Collector) track(parent context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	c.inflightMu.Lock()
+	c.inflight[id] = cancel
+	c.inflightMu.Unlock()
+
+	return ctx, func() {
+		c.inflightMu.Lock()
+		delete(c.inflight, id)
+		c.inflightMu.Unlock()
+
+		cancel()
+	}
+}
+
+func (c *Collector) Cancel(id string) error {
+	if _, err := c.entry(id); err != nil {
+		return err
+	}
+
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	cancel, ok := c.inflight[id]
+	if !ok {
+		return fmt.Errorf("entry is not in progress: %v", id)
+	}
+	cancel()
+	return nil
+}
+
+func failureMessage(ctx context.Context, err error) string {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return "Canceled"
+	}
+	return err.Error()
+}
@@ -0,0 +1,49 @@
+package collect
+
+type (
+	Notifier interface {
+		Notify(event string, entry *Entry)
+	}
+
+	Notifiers []Notifier
+)
+
+const (
+	EventCollectionStarted   = "collection.started"
+	EventCollectionSucceeded = "collection.succeeded"
+	EventCollectionFailed    = "collection.failed"
+	EventProcessingCompleted = "processing.completed"
+	EventProcessingFailed    = "processing.failed"
+	EventAlertTriggered      = "alert.triggered"
+)
+
+var Events = []string{
+	EventCollectionStarted,
+	EventCollectionSucceeded,
+	EventCollectionFailed,
+	EventProcessingCompleted,
+	EventProcessingFailed,
+	EventAlertTriggered,
+}
+
+func (n Notifiers) Notify(event string, entry *Entry) {
+	for _, notifier := range n {
+		notifier.Notify(event, entry)
+	}
+}
+
+func (c *Collector) notify(event string, snapshot *Snapshot) {
+	c.stats.event(event)
+	if c.notifier == nil {
+		return
+	}
+
+	c.mu.RLock()
+	ent, ok := c.data[snapshot.ID]
+	c.mu.RUnlock()
+
+	if !ok {
+		ent = &Entry{Snapshot: snapshot}
+	}
+	c.notifier.Notify(event, ent)
+}
@@ -0,0 +1,99 @@
+package collect
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/git"
+	"github.com/kaz/pprotein/internal/tracing"
+)
+
+type (
+	PollSample struct {
+		Time time.Time
+		Data json.RawMessage
+	}
+)
+
+func (s *Snapshot) Poll(ctx context.Context, client *http.Client, interval time.Duration, progress ProgressFunc) (err error) {
+	ctx, span := tracing.Start(ctx, "Snapshot.Poll", snapshotAttributes(s)...)
+	defer func() { span.Finish(err) }()
+
+	cr := &countingReader{}
+	stop := s.watchProgress(ctx, cr, progress)
+	defer stop()
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	deadline := time.Now().Add(time.Duration(s.Duration) * time.Second)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		data, err := s.sample(ctx, client)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(&PollSample{Time: time.Now(), Data: data}); err != nil {
+			return fmt.Errorf("failed to encode sample: %w", err)
+		}
+		cr.n.Store(int64(buf.Len()))
+
+		if time.Now().Add(interval).After(deadline) {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return s.Import(buf)
+}
+
+func (s *Snapshot) sample(ctx context.Context, client *http.Client) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	tracing.Inject(ctx, req.Header)
+	s.authorize(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http error: status=%v, body=%v", resp.StatusCode, string(body))
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("response is not valid json")
+	}
+
+	if s.Repository == nil {
+		s.Repository = &git.RepositoryInfo{}
+		if err := json.Unmarshal([]byte(resp.Header.Get("X-Git-Repository")), s.Repository); err != nil {
+			log.Printf("failed to parse git repository: %v", err)
+		}
+	}
+
+	compact := &bytes.Buffer{}
+	if err := json.Compact(compact, body); err != nil {
+		return nil, fmt.Errorf("failed to compact response: %w", err)
+	}
+	return compact.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package collect
+
+import (
+	"container/heap"
+	"context"
+	"runtime"
+	"sync"
+)
+
+type (
+	WorkerPool struct {
+		mu      *sync.Mutex
+		size    int
+		running int
+		seq     uint64
+		queue   waitQueue
+	}
+
+	Priority int
+
+	poolWaiter struct {
+		priority Priority
+		seq      uint64
+		index    int
+		ready    chan struct{}
+	}
+	waitQueue []*poolWaiter
+)
+
+const (
+	PriorityLow Priority = iota
+	PriorityHigh
+)
+
+func NewWorkerPool(size int) *WorkerPool {
+	if size <= 0 {
+		size = runtime.NumCPU()
+	}
+	return &WorkerPool{mu: &sync.Mutex{}, size: size}
+}
+
+func (p *WorkerPool) Do(ctx context.Context, priority Priority, fn func() error) error {
+	if err := p.acquire(ctx, priority); err != nil {
+		return err
+	}
+	defer p.release()
+
+	return fn()
+}
+
+func (p *WorkerPool) acquire(ctx context.Context, priority Priority) error {
+	p.mu.Lock()
+	if p.running < p.size && p.queue.Len() == 0 {
+		p.running++
+		p.mu.Unlock()
+		return nil
+	}
+
+	p.seq++
+	w := &poolWaiter{priority: priority, seq: p.seq, ready: make(chan struct{})}
+	heap.Push(&p.queue, w)
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	if w.index >= 0 {
+		heap.Remove(&p.queue, w.index)
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+	p.mu.Unlock()
+
+	// the slot was handed over while we were giving up
+	p.release()
+	return ctx.Err()
+}
+
+func (p *WorkerPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.queue.Len() == 0 {
+		p.running--
+		return
+	}
+	w := heap.Pop(&p.queue).(*poolWaiter)
+	close(w.ready)
+}
+
+func (q waitQueue) Len() int {
+	return len(q)
+}
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *waitQueue) Push(x any) {
+	w := x.(*poolWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+func (q *waitQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
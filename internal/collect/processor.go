@@ -2,6 +2,7 @@ package collect
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 
@@ -12,37 +13,56 @@ const cacheTypeKey = "cache"
 
 type (
 	Processor interface {
-		Process(snapshot *Snapshot) (io.ReadCloser, error)
+		Process(ctx context.Context, snapshot *Snapshot) (io.ReadCloser, error)
 		Cacheable() bool
 	}
 
+	Purger interface {
+		Purge(snapshot *Snapshot) error
+	}
+
+	CacheScoped interface {
+		CacheScope() string
+	}
+
 	cachedProcessor struct {
 		internal Processor
 		store    storage.Storage
+		stats    *collectorStats
+	}
+
+	cachedContent struct {
+		*bytes.Reader
 	}
 )
 
-func newCachedProcessor(internal Processor, store storage.Storage) Processor {
-	return &cachedProcessor{internal, store}
+func newCachedProcessor(internal Processor, store storage.Storage, stats *collectorStats) *cachedProcessor {
+	return &cachedProcessor{internal, store, stats}
 }
 
-func (p *cachedProcessor) Process(snapshot *Snapshot) (io.ReadCloser, error) {
-	if ok, err := p.store.Exists(cacheTypeKey, snapshot.ID); err != nil {
-		return nil, fmt.Errorf("failed to check cache status: %w", err)
-	} else if ok {
-		return p.serveCached(snapshot)
+func (p *cachedProcessor) Process(ctx context.Context, snapshot *Snapshot) (io.ReadCloser, error) {
+	for _, key := range p.cacheKeys(snapshot) {
+		if ok, err := p.store.Exists(cacheTypeKey, key); err != nil {
+			return nil, fmt.Errorf("failed to check cache status: %w", err)
+		} else if ok {
+			p.stats.cacheHits.Add(1)
+			return p.serveCached(key)
+		}
 	}
-	return p.serveGenerated(snapshot)
+	if p.internal.Cacheable() {
+		p.stats.cacheMisses.Add(1)
+	}
+	return p.serveGenerated(ctx, snapshot)
 }
-func (p *cachedProcessor) serveCached(snapshot *Snapshot) (io.ReadCloser, error) {
-	cache, err := p.store.Get(cacheTypeKey, snapshot.ID)
+func (p *cachedProcessor) serveCached(key string) (io.ReadCloser, error) {
+	cache, err := p.store.Get(cacheTypeKey, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read cache: %w", err)
 	}
-	return io.NopCloser(bytes.NewBuffer(cache)), nil
+	return &cachedContent{bytes.NewReader(cache)}, nil
 }
-func (p *cachedProcessor) serveGenerated(snapshot *Snapshot) (io.ReadCloser, error) {
-	r, err := p.internal.Process(snapshot)
+func (p *cachedProcessor) serveGenerated(ctx context.Context, snapshot *Snapshot) (io.ReadCloser, error) {
+	r, err := p.internal.Process(ctx, snapshot)
 	if err != nil {
 		return nil, fmt.Errorf("internal error: %w", err)
 	}
@@ -56,10 +76,36 @@ func (p *cachedProcessor) serveGenerated(snapshot *Snapshot) (io.ReadCloser, err
 		return nil, fmt.Errorf("failed to read data: %w", err)
 	}
 
-	if err := p.store.Put(cacheTypeKey, snapshot.ID, cacheContent); err != nil {
+	key := p.cacheKeys(snapshot)[0]
+	if err := p.store.Put(cacheTypeKey, key, cacheContent); err != nil {
 		return nil, fmt.Errorf("failed to create cache: %w", err)
 	}
-	return p.serveCached(snapshot)
+	return p.serveCached(key)
+}
+
+func (c *cachedContent) Close() error {
+	return nil
+}
+
+func (p *cachedProcessor) Purge(snapshot *Snapshot) error {
+	if purger, ok := p.internal.(Purger); ok {
+		if err := purger.Purge(snapshot); err != nil {
+			return err
+		}
+	}
+	return p.store.Delete(cacheTypeKey, p.cacheKeys(snapshot)[0])
+}
+
+func (p *cachedProcessor) cacheKeys(snapshot *Snapshot) []string {
+	keys := snapshot.cacheKeys()
+	scoped, ok := p.internal.(CacheScoped)
+	if !ok || scoped.CacheScope() == "" {
+		return keys
+	}
+	for i := range keys {
+		keys[i] += "." + scoped.CacheScope()
+	}
+	return keys
 }
 
 func (p *cachedProcessor) Cacheable() bool {
@@ -0,0 +1,83 @@
+package collect
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	Progress struct {
+		Bytes     int64
+		Elapsed   float64
+		Remaining float64
+	}
+	ProgressFunc func(*Progress)
+)
+
+const progressInterval = 1 * time.Second
+
+func (s *Snapshot) watchProgress(ctx context.Context, cr *countingReader, fn ProgressFunc) func() {
+	if fn == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	report := func() {
+		elapsed := time.Since(start).Seconds()
+		remaining := float64(s.Duration) - elapsed
+		if remaining < 0 {
+			remaining = 0
+		}
+		fn(&Progress{
+			Bytes:     cr.n.Load(),
+			Elapsed:   elapsed,
+			Remaining: remaining,
+		})
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				report()
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+func (c *Collector) reportProgress(snapshot *Snapshot) ProgressFunc {
+	return func(p *Progress) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		ent, ok := c.data[snapshot.ID]
+		if !ok || ent.Status != StatusPending {
+			return
+		}
+
+		entry := &Entry{
+			Snapshot: ent.Snapshot,
+			Status:   ent.Status,
+			Message:  ent.Message,
+			Progress: p,
+		}
+		c.data[snapshot.ID] = entry
+		c.publish(ent, entry)
+	}
+}
@@ -0,0 +1,105 @@
+package push
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	ControlRequest struct {
+		Names   []string
+		Control json.RawMessage
+	}
+
+	ControlResult struct {
+		Name   string
+		Queued bool
+		Error  string
+	}
+)
+
+const controlTimeout = 10 * time.Second
+
+func (h *Hub) Control(names []string, control json.RawMessage) []*ControlResult {
+	h.mu.Lock()
+	targets := []*agent{}
+	if len(names) == 0 {
+		for _, a := range h.agents {
+			targets = append(targets, a)
+		}
+	} else {
+		for _, name := range names {
+			if a, ok := h.agents[name]; ok {
+				targets = append(targets, a)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Name < targets[j].Name
+	})
+
+	results := []*ControlResult{}
+	for _, a := range targets {
+		res := &ControlResult{Name: a.Name}
+		if a.URL == "" {
+			h.mu.Lock()
+			select {
+			case a.commands <- &Command{Control: control}:
+				a.Pending++
+				res.Queued = true
+			default:
+				res.Error = "command queue is full"
+			}
+			h.mu.Unlock()
+		} else if err := sendControl(a.Agent, control); err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func sendControl(a *Agent, control json.RawMessage) error {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(a.URL, "/")+"/debug/control", bytes.NewReader(control))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.AgentToken != "" {
+		req.Header.Set(collect.AgentTokenHeader, a.AgentToken)
+	}
+
+	resp, err := (&http.Client{Timeout: controlTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (h *Hub) postControl(c echo.Context) error {
+	req := &ControlRequest{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+	if len(req.Control) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Control is required")
+	}
+	return c.JSON(http.StatusOK, h.Control(req.Names, req.Control))
+}
@@ -0,0 +1,371 @@
+package push
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/collect/group"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Hub struct {
+		registry *collect.Registry
+		token    string
+		targets  *group.Collector
+
+		mu     *sync.Mutex
+		agents map[string]*agent
+	}
+
+	Agent struct {
+		Name      string
+		Host      string
+		URL       string
+		Types     []string
+		Endpoints map[string]string
+		Tags      map[string]string
+		Duration  int
+
+		AgentToken string `json:",omitempty"`
+
+		Registered time.Time
+		LastSeen   time.Time
+		Live       bool
+		Pending    int
+	}
+
+	Command struct {
+		GroupId    string
+		RunId      string
+		ScheduleId string
+		Types      []string
+		Duration   int
+
+		Control json.RawMessage `json:",omitempty"`
+	}
+
+	agent struct {
+		*Agent
+		commands chan *Command
+	}
+)
+
+const (
+	commandBuffer      = 8
+	defaultPollTimeout = 30 * time.Second
+	maxPollTimeout     = 120 * time.Second
+	heartbeatTimeout   = 90 * time.Second
+)
+
+func NewHub(registry *collect.Registry, token string) *Hub {
+	return &Hub{
+		registry: registry,
+		token:    token,
+		mu:       &sync.Mutex{},
+		agents:   map[string]*agent{},
+	}
+}
+
+func (h *Hub) SyncTargets(targets *group.Collector) {
+	h.targets = targets
+}
+
+func (h *Hub) RegisterHandlers(g *echo.Group) {
+	g.Use(h.authenticate)
+
+	g.GET("", h.getIndex)
+	g.POST("/register", h.postRegister)
+	g.POST("/collect", h.postCollect)
+	g.POST("/control", h.postControl)
+	g.DELETE("/:name", h.deleteName)
+	g.POST("/:name/heartbeat", h.postHeartbeat)
+	g.GET("/:name/poll", h.getPoll)
+	g.POST("/:name/upload", h.postUpload)
+}
+
+func (h *Hub) Dispatch(base *collect.SnapshotTarget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, a := range h.agents {
+		if a.URL != "" {
+			continue
+		}
+		cmd := &Command{
+			GroupId:    base.GroupId,
+			RunId:      base.RunId,
+			ScheduleId: base.ScheduleId,
+			Types:      a.Types,
+			Duration:   a.Duration,
+		}
+		if base.Duration > 0 {
+			cmd.Duration = base.Duration
+		}
+		select {
+		case a.commands <- cmd:
+			a.Pending++
+		default:
+			log.Printf("[!] command queue of agent %v is full, dropping collection %v", a.Name, cmd.GroupId)
+		}
+	}
+}
+
+func (h *Hub) List() []*Agent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	resp := make([]*Agent, 0, len(h.agents))
+	for _, a := range h.agents {
+		copied := *a.Agent
+		copied.AgentToken = ""
+		copied.Live = time.Since(copied.LastSeen) < heartbeatTimeout
+		resp = append(resp, &copied)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Name < resp[j].Name
+	})
+	return resp
+}
+
+func (h *Hub) register(req *Agent) *Agent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	a, ok := h.agents[req.Name]
+	if !ok {
+		a = &agent{Agent: &Agent{Name: req.Name, Registered: now}, commands: make(chan *Command, commandBuffer)}
+		h.agents[req.Name] = a
+	}
+	a.Host = req.Host
+	a.URL = req.URL
+	a.Types = req.Types
+	a.Endpoints = req.Endpoints
+	a.Tags = req.Tags
+	a.Duration = req.Duration
+	a.AgentToken = req.AgentToken
+	a.LastSeen = now
+
+	copied := *a.Agent
+	copied.Live = true
+	return &copied
+}
+
+func (h *Hub) unregister(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, ok := h.agents[name]
+	delete(h.agents, name)
+	return ok
+}
+
+func (h *Hub) syncTargets(a *Agent) error {
+	if h.targets == nil {
+		return nil
+	}
+
+	targets := []*group.CollectTarget{}
+	if a.URL != "" {
+		types := make([]string, 0, len(a.Endpoints))
+		for typ := range a.Endpoints {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+
+		for _, typ := range types {
+			if _, ok := h.registry.Get(typ); !ok {
+				continue
+			}
+			tags := map[string]string{}
+			for k, v := range a.Tags {
+				tags[k] = v
+			}
+			targets = append(targets, &group.CollectTarget{
+				Type:     typ,
+				Label:    a.Name,
+				Tags:     tags,
+				URL:      strings.TrimSuffix(a.URL, "/") + a.Endpoints[typ],
+				Duration: a.Duration,
+
+				AgentToken: a.AgentToken,
+			})
+		}
+	}
+	return h.targets.SyncAgentTargets(a.Name, targets)
+}
+
+func (h *Hub) agent(name string) (*agent, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	a, ok := h.agents[name]
+	if ok {
+		a.LastSeen = time.Now()
+	}
+	return a, ok
+}
+
+func (h *Hub) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.token == "" {
+			return next(c)
+		}
+		given := c.Request().Header.Get(collect.AgentTokenHeader)
+		if given == "" || subtle.ConstantTimeCompare([]byte(given), []byte(h.token)) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or missing agent token")
+		}
+		return next(c)
+	}
+}
+
+func (h *Hub) getIndex(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.List())
+}
+
+func (h *Hub) postRegister(c echo.Context) error {
+	req := &Agent{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+	if req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+	for _, typ := range req.Types {
+		if _, ok := h.registry.Get(typ); !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown type: %v", typ))
+		}
+	}
+	if req.URL != "" {
+		if u, err := url.Parse(req.URL); err != nil || u.Host == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid URL: %v", req.URL))
+		}
+	}
+
+	a := h.register(req)
+	if err := h.syncTargets(a); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to update targets: %v", err))
+	}
+	a.AgentToken = ""
+	return c.JSON(http.StatusOK, a)
+}
+
+func (h *Hub) deleteName(c echo.Context) error {
+	if !h.unregister(c.Param("name")) {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("agent is not registered: %v", c.Param("name")))
+	}
+	if err := h.syncTargets(&Agent{Name: c.Param("name")}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to update targets: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *Hub) postHeartbeat(c echo.Context) error {
+	if _, ok := h.agent(c.Param("name")); !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("agent is not registered: %v", c.Param("name")))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *Hub) postCollect(c echo.Context) error {
+	grpId := collect.NewGroupId()
+	h.Dispatch(&collect.SnapshotTarget{GroupId: grpId, RunId: grpId})
+	return c.JSON(http.StatusOK, map[string]string{"GroupId": grpId})
+}
+
+func (h *Hub) getPoll(c echo.Context) error {
+	a, ok := h.agent(c.Param("name"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("agent is not registered: %v", c.Param("name")))
+	}
+
+	timeout := defaultPollTimeout
+	if v := c.QueryParam("timeout"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid timeout: %v", v))
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout > maxPollTimeout {
+		timeout = maxPollTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case cmd := <-a.commands:
+		h.mu.Lock()
+		a.Pending--
+		h.mu.Unlock()
+		return c.JSON(http.StatusOK, cmd)
+	case <-timer.C:
+		return c.NoContent(http.StatusNoContent)
+	case <-c.Request().Context().Done():
+		return nil
+	}
+}
+
+func (h *Hub) postUpload(c echo.Context) error {
+	a, ok := h.agent(c.Param("name"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("agent is not registered: %v", c.Param("name")))
+	}
+
+	collector, ok := h.registry.Get(c.FormValue("type"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown type: %v", c.FormValue("type")))
+	}
+
+	target := &collect.SnapshotTarget{}
+	if meta := c.FormValue("meta"); meta != "" {
+		if err := json.Unmarshal([]byte(meta), target); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse meta: %v", err))
+		}
+	}
+	if target.Label == "" {
+		target.Label = a.Name
+	}
+	if target.Tags == nil {
+		target.Tags = map[string]string{}
+	}
+	for k, v := range a.Tags {
+		if _, ok := target.Tags[k]; !ok {
+			target.Tags[k] = v
+		}
+	}
+	target.Tags["agent"] = a.Name
+
+	repo, err := collect.ParseRepository(c.FormValue("repository"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse repository: %v", err))
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to read file: %v", err))
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to open file: %v", err))
+	}
+	defer file.Close()
+
+	snapshot, err := collector.Upload(target, repo, file)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to upload snapshot: %v", err))
+	}
+	return c.JSON(http.StatusAccepted, snapshot)
+}
@@ -0,0 +1,142 @@
+package collect
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	Quota struct {
+		MaxBytes int64
+		Evict    bool
+
+		mu *sync.Mutex
+	}
+
+	quotaCandidate struct {
+		collector *Collector
+		id        string
+		size      int64
+		lastUsed  time.Time
+	}
+)
+
+func NewQuota(maxBytes int64, evict bool) *Quota {
+	return &Quota{
+		MaxBytes: maxBytes,
+		Evict:    evict,
+		mu:       &sync.Mutex{},
+	}
+}
+
+func (q *Quota) enforce(collectors []*Collector) error {
+	if q == nil || q.MaxBytes <= 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	used, candidates, err := q.usage(collectors)
+	if err != nil {
+		return fmt.Errorf("failed to calculate disk usage: %w", err)
+	}
+
+	for used >= q.MaxBytes {
+		if !q.Evict {
+			return fmt.Errorf("disk quota exceeded: %d/%d bytes used", used, q.MaxBytes)
+		}
+		if len(candidates) == 0 {
+			return fmt.Errorf("disk quota exceeded and nothing to evict: %d/%d bytes used", used, q.MaxBytes)
+		}
+
+		victim := candidates[0]
+		candidates = candidates[1:]
+
+		if err := victim.collector.remove(victim.id, "Evicted by disk quota"); err != nil {
+			return fmt.Errorf("failed to evict snapshot: %w", err)
+		}
+		used -= victim.size
+	}
+	return nil
+}
+
+func (q *Quota) usage(collectors []*Collector) (int64, []*quotaCandidate, error) {
+	var used int64
+	candidates := []*quotaCandidate{}
+
+	for _, c := range collectors {
+		for _, ent := range c.List() {
+			size, err := c.footprint(ent.Snapshot)
+			if err != nil {
+				return 0, nil, err
+			}
+			used += size
+
+			if ent.Status != StatusPending && !ent.Snapshot.Pinned {
+				candidates = append(candidates, &quotaCandidate{
+					collector: c,
+					id:        ent.Snapshot.ID,
+					size:      size,
+					lastUsed:  c.lastUsed(ent.Snapshot),
+				})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lastUsed.Before(candidates[j].lastUsed)
+	})
+	return used, candidates, nil
+}
+
+func (c *Collector) footprint(snapshot *Snapshot) (int64, error) {
+	size, err := c.store.FileSize(snapshot.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get file size: %w", err)
+	}
+
+	processors := map[*cachedProcessor]bool{c.processor: true}
+	for _, p := range c.views {
+		processors[p] = true
+	}
+	for p := range processors {
+		if p == nil {
+			continue
+		}
+
+		cached, err := c.store.Size(cacheTypeKey, p.cacheKeys(snapshot)[0])
+		if err != nil {
+			return 0, fmt.Errorf("failed to get cache size: %w", err)
+		}
+		size += cached
+	}
+	return size, nil
+}
+
+func (c *Collector) lastUsed(snapshot *Snapshot) time.Time {
+	c.accessMu.Lock()
+	defer c.accessMu.Unlock()
+
+	if t, ok := c.accessed[snapshot.ID]; ok {
+		return t
+	}
+	return snapshot.Datetime
+}
+
+func (c *Collector) touch(id string) {
+	c.accessMu.Lock()
+	defer c.accessMu.Unlock()
+
+	c.accessed[id] = time.Now()
+}
+
+func (c *Collector) EnforceQuota() error {
+	collectors := []*Collector{c}
+	if c.registry != nil {
+		collectors = c.registry.Collectors()
+	}
+	return c.quota.enforce(collectors)
+}
@@ -0,0 +1,147 @@
+package collect
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaz/pprotein/internal/event"
+	"github.com/kaz/pprotein/internal/storage"
+)
+
+type quotaTestProcessor struct{}
+
+func (quotaTestProcessor) Process(ctx context.Context, snapshot *Snapshot) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+func (quotaTestProcessor) Cacheable() bool {
+	return false
+}
+
+type quotaTestEntry struct {
+	id       string
+	size     int
+	lastUsed time.Duration
+	status   Status
+	pinned   bool
+}
+
+func TestQuotaEnforce(t *testing.T) {
+	entries := []*quotaTestEntry{
+		{id: "old", size: 40, lastUsed: 1 * time.Hour, status: StatusOk},
+		{id: "mid", size: 40, lastUsed: 2 * time.Hour, status: StatusOk},
+		{id: "new", size: 40, lastUsed: 3 * time.Hour, status: StatusOk},
+		{id: "pinned", size: 40, status: StatusOk, pinned: true},
+		{id: "pending", size: 40, status: StatusPending},
+	}
+
+	tests := []struct {
+		name     string
+		maxBytes int64
+		evict    bool
+		wantErr  bool
+		want     []string
+	}{
+		{"disabled", 0, true, false, []string{"mid", "new", "old", "pending", "pinned"}},
+		{"within quota", 300, true, false, []string{"mid", "new", "old", "pending", "pinned"}},
+		{"exceeded without eviction", 200, false, true, []string{"mid", "new", "old", "pending", "pinned"}},
+		{"evicts least recently used", 200, true, false, []string{"mid", "new", "pending", "pinned"}},
+		{"evicts until below quota", 130, true, false, []string{"new", "pending", "pinned"}},
+		{"skips pinned and pending", 50, true, true, []string{"pending", "pinned"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := storage.New(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := New(quotaTestProcessor{}, &Options{Type: "test", Ext: ".log", Store: store, EventHub: event.NewHub()})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			base := time.Now().Add(-24 * time.Hour)
+			for _, e := range entries {
+				if err := store.PutFile(e.id, []byte(strings.Repeat("x", e.size))); err != nil {
+					t.Fatal(err)
+				}
+				snapshot := &Snapshot{
+					store:          store,
+					SnapshotMeta:   &SnapshotMeta{Type: "test", ID: e.id, Datetime: base, Pinned: e.pinned},
+					SnapshotTarget: &SnapshotTarget{},
+				}
+				c.data[e.id] = &Entry{Snapshot: snapshot, Status: e.status}
+				if e.lastUsed > 0 {
+					c.accessed[e.id] = base.Add(e.lastUsed)
+				}
+			}
+
+			err = NewQuota(tt.maxBytes, tt.evict).enforce([]*Collector{c})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("enforce() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got := []string{}
+			for id := range c.data {
+				got = append(got, id)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remaining = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectorFootprint(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   int
+		cached int
+		want   int64
+	}{
+		{"body only", 40, 0, 40},
+		{"body and cache", 40, 25, 65},
+		{"missing body", 0, 25, 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := storage.New(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := New(quotaTestProcessor{}, &Options{Type: "test", Ext: ".log", Store: store, EventHub: event.NewHub()})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			snapshot := &Snapshot{
+				store:          store,
+				SnapshotMeta:   &SnapshotMeta{Type: "test", ID: "snapshot"},
+				SnapshotTarget: &SnapshotTarget{},
+			}
+			if tt.body > 0 {
+				if err := store.PutFile(snapshot.ID, []byte(strings.Repeat("x", tt.body))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.cached > 0 {
+				if err := store.Put(cacheTypeKey, c.processor.cacheKeys(snapshot)[0], []byte(strings.Repeat("x", tt.cached))); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := c.footprint(snapshot)
+			if err != nil {
+				t.Fatalf("footprint() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("footprint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
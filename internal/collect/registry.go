@@ -0,0 +1,57 @@
+package collect
+
+import (
+	"sort"
+	"sync"
+)
+
+type (
+	Registry struct {
+		mu         *sync.RWMutex
+		collectors map[string]*Collector
+	}
+)
+
+func NewRegistry() *Registry {
+	return &Registry{
+		mu:         &sync.RWMutex{},
+		collectors: map[string]*Collector{},
+	}
+}
+
+func (r *Registry) add(c *Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors[c.typ] = c
+}
+
+func (r *Registry) Get(typ string) (*Collector, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.collectors[typ]
+	return c, ok
+}
+
+func (r *Registry) Collectors() []*Collector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resp := make([]*Collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		resp = append(resp, c)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].typ < resp[j].typ
+	})
+	return resp
+}
+
+func (r *Registry) List() []*Entry {
+	resp := []*Entry{}
+	for _, c := range r.Collectors() {
+		resp = append(resp, c.List()...)
+	}
+	return resp
+}
@@ -0,0 +1,62 @@
+package collect
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+type (
+	RetentionPolicy struct {
+		MaxCount int
+		MaxAge   time.Duration
+		Interval time.Duration
+	}
+)
+
+const defaultRetentionInterval = 1 * time.Minute
+
+func (p *RetentionPolicy) enabled() bool {
+	return p != nil && (p.MaxCount > 0 || p.MaxAge > 0)
+}
+
+func (c *Collector) runRetention() {
+	interval := c.retention.Interval
+	if interval <= 0 {
+		interval = defaultRetentionInterval
+	}
+
+	for range time.Tick(interval) {
+		for _, id := range c.expiredEntries(time.Now()) {
+			if err := c.Delete(id); err != nil {
+				log.Printf("[!] failed to expire snapshot: %v", err)
+			}
+		}
+	}
+}
+
+func (c *Collector) expiredEntries(now time.Time) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := make([]*Entry, 0, len(c.data))
+	for _, ent := range c.data {
+		if ent.Status == StatusPending || ent.Snapshot.Pinned {
+			continue
+		}
+		entries = append(entries, ent)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Snapshot.Datetime.After(entries[j].Snapshot.Datetime)
+	})
+
+	expired := []string{}
+	for i, ent := range entries {
+		if c.retention.MaxCount > 0 && i >= c.retention.MaxCount {
+			expired = append(expired, ent.Snapshot.ID)
+		} else if c.retention.MaxAge > 0 && now.Sub(ent.Snapshot.Datetime) > c.retention.MaxAge {
+			expired = append(expired, ent.Snapshot.ID)
+		}
+	}
+	return expired
+}
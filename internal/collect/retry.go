@@ -0,0 +1,74 @@
+package collect
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type (
+	RetryPolicy struct {
+		Attempts   int
+		Backoff    time.Duration
+		MaxBackoff time.Duration
+	}
+)
+
+const (
+	defaultRetryBackoff    = 1 * time.Second
+	defaultRetryMaxBackoff = 1 * time.Minute
+)
+
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.Attempts <= 0 {
+		return 1
+	}
+	return p.Attempts
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base, limit := defaultRetryBackoff, defaultRetryMaxBackoff
+	if p.Backoff > 0 {
+		base = p.Backoff
+	}
+	if p.MaxBackoff > 0 {
+		limit = p.MaxBackoff
+	}
+
+	delay := base << (attempt - 1)
+	if delay <= 0 || delay > limit {
+		return limit
+	}
+	return delay
+}
+
+func (c *Collector) collectWithRetry(ctx context.Context, snapshot *Snapshot) error {
+	client, err := c.httpClient(snapshot.SnapshotTarget)
+	if err != nil {
+		return fmt.Errorf("failed to configure http client: %w", err)
+	}
+	attempts := c.retry.attempts()
+
+	for attempt := 1; ; attempt++ {
+		var err error
+		if c.poll > 0 {
+			err = snapshot.Poll(ctx, client, c.poll, c.reportProgress(snapshot))
+		} else {
+			err = snapshot.Collect(ctx, client, c.reportProgress(snapshot))
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt >= attempts {
+			return err
+		}
+
+		delay := c.retry.backoff(attempt)
+		c.updateStatus(snapshot, StatusPending, fmt.Sprintf("Retrying in %v (%d/%d): %v", delay, attempt, attempts, err))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,479 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/git"
+	"github.com/kaz/pprotein/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Commits struct {
+		runs     *Handler
+		scores   *Scores
+		store    storage.Storage
+		client   *http.Client
+		endpoint string
+		mu       *sync.Mutex
+	}
+
+	Commit struct {
+		git.RepositoryInfo
+		RunId    string
+		Source   string
+		Datetime time.Time
+	}
+
+	CommitSummary struct {
+		Hash    string
+		Ref     string
+		Author  string
+		Message string
+		Runs    []string
+		Scores  []float64
+
+		BestScore   *float64 `json:",omitempty"`
+		LatestScore *float64 `json:",omitempty"`
+		LastRun     time.Time
+	}
+
+	Comparison struct {
+		Base       *CommitSummary
+		Target     *CommitSummary
+		BaseRun    string
+		TargetRun  string
+		ScoreDelta *float64 `json:",omitempty"`
+		Endpoints  []*EndpointDelta
+		Functions  []*FunctionDelta
+		Queries    []*QueryDelta
+	}
+
+	EndpointDelta struct {
+		Method string
+		Uri    string
+		Base   float64
+		Target float64
+		Delta  float64
+	}
+
+	FunctionDelta struct {
+		Name   string
+		Unit   string
+		Base   int64
+		Target int64
+		Delta  int64
+	}
+
+	QueryDelta struct {
+		QueryID string
+		Query   string
+		Base    float64
+		Target  float64
+		Delta   float64
+	}
+)
+
+const (
+	commitTypeKey  = "run-commit"
+	commitTimeout  = 5 * time.Second
+	compareBreadth = 100
+
+	CommitSourceEndpoint = "endpoint"
+	CommitSourceAgent    = "agent"
+	CommitSourceSnapshot = "snapshot"
+	CommitSourceManual   = "manual"
+)
+
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+func NewCommits(runs *Handler, scores *Scores, store storage.Storage, endpoint string) *Commits {
+	return &Commits{
+		runs:     runs,
+		scores:   scores,
+		store:    store,
+		client:   &http.Client{Timeout: commitTimeout},
+		endpoint: endpoint,
+		mu:       &sync.Mutex{},
+	}
+}
+
+func (cm *Commits) RegisterHandlers(g *echo.Group) {
+	g.GET("", cm.getIndex)
+	g.POST("", cm.postIndex)
+	g.GET("/compare", cm.getCompare)
+}
+
+func (cm *Commits) Observe(base *collect.SnapshotTarget) {
+	if cm.endpoint == "" || base.RunId == "" {
+		return
+	}
+
+	go func() {
+		repo, err := cm.scrape()
+		if err != nil {
+			log.Printf("[!] failed to get commit for run %v: %v", base.RunId, err)
+			return
+		}
+		if err := cm.Record(&Commit{RepositoryInfo: *repo, RunId: base.RunId, Source: CommitSourceEndpoint}); err != nil {
+			log.Printf("[!] failed to record commit for run %v: %v", base.RunId, err)
+		}
+	}()
+}
+
+func (cm *Commits) scrape() (*git.RepositoryInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cm.endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := cm.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http error: status=%v", resp.StatusCode)
+	}
+
+	repo := &git.RepositoryInfo{}
+	if header := resp.Header.Get("X-Git-Repository"); header != "" {
+		if err := json.Unmarshal([]byte(header), repo); err != nil {
+			return nil, fmt.Errorf("failed to parse git repository: %w", err)
+		}
+		return repo, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if err := json.Unmarshal(body, repo); err == nil && repo.Hash != "" {
+		return repo, nil
+	}
+
+	hash := strings.TrimSpace(strings.SplitN(string(body), "\n", 2)[0])
+	if !hashPattern.MatchString(hash) {
+		return nil, fmt.Errorf("response does not contain a commit hash")
+	}
+	return &git.RepositoryInfo{Hash: hash}, nil
+}
+
+func (cm *Commits) Record(commit *Commit) error {
+	if commit.RunId == "" || commit.Hash == "" {
+		return fmt.Errorf("RunId and Hash are required")
+	}
+	commit.Datetime = time.Now()
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	raw, err := json.Marshal(commit)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	if err := cm.store.Put(commitTypeKey, commit.RunId, raw); err != nil {
+		return fmt.Errorf("failed to save commit: %w", err)
+	}
+	return nil
+}
+
+func (cm *Commits) Of(r *Run) (*Commit, error) {
+	raw, err := cm.store.Get(commitTypeKey, r.RunId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit: %w", err)
+	}
+	if raw != nil {
+		commit := &Commit{}
+		if err := json.Unmarshal(raw, commit); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal: %w", err)
+		}
+		return commit, nil
+	}
+
+	for _, ent := range r.Entries {
+		repo := ent.Snapshot.Repository
+		if repo == nil || repo.Hash == "" {
+			continue
+		}
+
+		commit := &Commit{RepositoryInfo: *repo, RunId: r.RunId, Source: CommitSourceSnapshot, Datetime: ent.Snapshot.Datetime}
+		if ent.Snapshot.SnapshotTarget != nil && ent.Snapshot.Tags["agent"] != "" {
+			commit.Source = CommitSourceAgent
+		}
+		return commit, nil
+	}
+	return nil, nil
+}
+
+func (cm *Commits) Summaries() ([]*CommitSummary, map[string][]*Run, error) {
+	scores, err := cm.scores.History("")
+	if err != nil {
+		return nil, nil, err
+	}
+	byRun := map[string][]*ScorePoint{}
+	for _, p := range scores {
+		byRun[p.RunId] = append(byRun[p.RunId], p)
+	}
+
+	summaries := map[string]*CommitSummary{}
+	latest := map[string]time.Time{}
+	runs := map[string][]*Run{}
+	for _, r := range cm.runs.Runs() {
+		commit, err := cm.Of(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if commit == nil {
+			continue
+		}
+
+		s, ok := summaries[commit.Hash]
+		if !ok {
+			s = &CommitSummary{
+				Hash:    commit.Hash,
+				Ref:     commit.Ref,
+				Author:  commit.Author,
+				Message: commit.Message,
+				Runs:    []string{},
+				Scores:  []float64{},
+				LastRun: r.Datetime,
+			}
+			summaries[commit.Hash] = s
+		}
+		s.Runs = append(s.Runs, r.RunId)
+		runs[commit.Hash] = append(runs[commit.Hash], r)
+
+		for _, p := range byRun[r.RunId] {
+			score := p.Score.Score
+			s.Scores = append(s.Scores, score)
+			if s.LatestScore == nil || p.Datetime.After(latest[s.Hash]) {
+				s.LatestScore = &score
+				latest[s.Hash] = p.Datetime
+			}
+			if s.BestScore == nil || score > *s.BestScore {
+				s.BestScore = &score
+			}
+		}
+	}
+
+	resp := make([]*CommitSummary, 0, len(summaries))
+	for _, s := range summaries {
+		resp = append(resp, s)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].LastRun.After(resp[j].LastRun)
+	})
+	return resp, runs, nil
+}
+
+func (cm *Commits) Compare(base, target string, top int) (*Comparison, error) {
+	summaries, runs, err := cm.Summaries()
+	if err != nil {
+		return nil, err
+	}
+
+	baseSummary, err := findCommit(summaries, base)
+	if err != nil {
+		return nil, err
+	}
+	targetSummary, err := findCommit(summaries, target)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &Comparison{
+		Base:      baseSummary,
+		Target:    targetSummary,
+		Endpoints: []*EndpointDelta{},
+		Functions: []*FunctionDelta{},
+		Queries:   []*QueryDelta{},
+	}
+	if baseSummary.LatestScore != nil && targetSummary.LatestScore != nil {
+		delta := *targetSummary.LatestScore - *baseSummary.LatestScore
+		cmp.ScoreDelta = &delta
+	}
+
+	baseRun, targetRun := runs[baseSummary.Hash][0], runs[targetSummary.Hash][0]
+	cmp.BaseRun, cmp.TargetRun = baseRun.RunId, targetRun.RunId
+
+	baseReport, err := cm.runs.RunReport(baseRun, compareBreadth)
+	if err != nil {
+		return nil, err
+	}
+	targetReport, err := cm.runs.RunReport(targetRun, compareBreadth)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp.Endpoints = truncate(endpointDeltas(baseReport, targetReport), top)
+	cmp.Functions = truncate(functionDeltas(baseReport, targetReport), top)
+	cmp.Queries = truncate(queryDeltas(baseReport, targetReport), top)
+	return cmp, nil
+}
+
+func findCommit(summaries []*CommitSummary, hash string) (*CommitSummary, error) {
+	if hash == "" {
+		return nil, fmt.Errorf("commit hash is required")
+	}
+
+	var found *CommitSummary
+	for _, s := range summaries {
+		if !strings.HasPrefix(s.Hash, hash) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("ambiguous commit: %v", hash)
+		}
+		found = s
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no runs recorded for commit: %v", hash)
+	}
+	return found, nil
+}
+
+func endpointDeltas(base, target *Report) []*EndpointDelta {
+	deltas := map[string]*EndpointDelta{}
+	get := func(ep *EndpointReport) *EndpointDelta {
+		key := ep.Method + " " + ep.Uri
+		if _, ok := deltas[key]; !ok {
+			deltas[key] = &EndpointDelta{Method: ep.Method, Uri: ep.Uri}
+		}
+		return deltas[key]
+	}
+	for _, ep := range base.Endpoints {
+		get(ep).Base += ep.Sum
+	}
+	for _, ep := range target.Endpoints {
+		get(ep).Target += ep.Sum
+	}
+
+	resp := make([]*EndpointDelta, 0, len(deltas))
+	for _, d := range deltas {
+		d.Delta = d.Target - d.Base
+		resp = append(resp, d)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return math.Abs(resp[i].Delta) > math.Abs(resp[j].Delta)
+	})
+	return resp
+}
+
+func functionDeltas(base, target *Report) []*FunctionDelta {
+	deltas := map[string]*FunctionDelta{}
+	get := func(fn *FunctionReport) *FunctionDelta {
+		key := fn.Unit + " " + fn.Name
+		if _, ok := deltas[key]; !ok {
+			deltas[key] = &FunctionDelta{Name: fn.Name, Unit: fn.Unit}
+		}
+		return deltas[key]
+	}
+	for _, fn := range base.Functions {
+		get(fn).Base += fn.Flat
+	}
+	for _, fn := range target.Functions {
+		get(fn).Target += fn.Flat
+	}
+
+	resp := make([]*FunctionDelta, 0, len(deltas))
+	for _, d := range deltas {
+		d.Delta = d.Target - d.Base
+		resp = append(resp, d)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return abs(resp[i].Delta) > abs(resp[j].Delta)
+	})
+	return resp
+}
+
+func queryDeltas(base, target *Report) []*QueryDelta {
+	deltas := map[string]*QueryDelta{}
+	get := func(q *QueryReport) *QueryDelta {
+		key := q.QueryID
+		if key == "" {
+			key = q.Query
+		}
+		if _, ok := deltas[key]; !ok {
+			deltas[key] = &QueryDelta{QueryID: q.QueryID, Query: q.Query}
+		}
+		return deltas[key]
+	}
+	for _, q := range base.Queries {
+		get(q).Base += q.Sum
+	}
+	for _, q := range target.Queries {
+		get(q).Target += q.Sum
+	}
+
+	resp := make([]*QueryDelta, 0, len(deltas))
+	for _, d := range deltas {
+		d.Delta = d.Target - d.Base
+		resp = append(resp, d)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return math.Abs(resp[i].Delta) > math.Abs(resp[j].Delta)
+	})
+	return resp
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (cm *Commits) getIndex(c echo.Context) error {
+	summaries, _, err := cm.Summaries()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, summaries)
+}
+
+func (cm *Commits) postIndex(c echo.Context) error {
+	commit := &Commit{}
+	if err := c.Bind(commit); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+	if _, ok := cm.runs.Get(commit.RunId); !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no such run: %v", commit.RunId))
+	}
+	if commit.Source == "" {
+		commit.Source = CommitSourceManual
+	}
+
+	if err := cm.Record(commit); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to record commit: %v", err))
+	}
+	return c.JSON(http.StatusOK, commit)
+}
+
+func (cm *Commits) getCompare(c echo.Context) error {
+	top, err := reportTop(c)
+	if err != nil {
+		return err
+	}
+
+	cmp, err := cm.Compare(c.QueryParam("base"), c.QueryParam("target"), top)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to compare: %v", err))
+	}
+	return c.JSON(http.StatusOK, cmp)
+}
@@ -0,0 +1,321 @@
+package run
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"text/template"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	RunDiff struct {
+		Base        string
+		Target      string
+		BaseScore   *float64 `json:",omitempty"`
+		TargetScore *float64 `json:",omitempty"`
+		ScoreDelta  *float64 `json:",omitempty"`
+		Endpoints   []*EndpointChange
+		Queries     []*QueryChange
+		Functions   []*FunctionChange
+	}
+
+	EndpointChange struct {
+		Method string
+		Uri    string
+		Base   float64
+		Target float64
+		Delta  float64
+		Change Change
+	}
+
+	QueryChange struct {
+		QueryID string
+		Query   string
+		Base    float64
+		Target  float64
+		Delta   float64
+		Change  Change
+	}
+
+	FunctionChange struct {
+		Name   string
+		Unit   string
+		Base   float64
+		Target float64
+		Delta  float64
+		Change Change
+	}
+
+	Change string
+
+	endpointP95 struct {
+		method string
+		uri    string
+		count  float64
+		p95    float64
+	}
+)
+
+const (
+	ChangeImproved  Change = "improved"
+	ChangeRegressed Change = "regressed"
+	ChangeUnchanged Change = "unchanged"
+	ChangeAdded     Change = "added"
+	ChangeRemoved   Change = "removed"
+
+	diffTolerance = 0.05
+	shareEpsilon  = 0.005
+)
+
+var (
+	//go:embed diff.md.tmpl
+	diffTemplate string
+
+	diffMarkdown = template.Must(template.New("diff.md").Funcs(renderFuncs).Parse(diffTemplate))
+)
+
+func (h *Handler) Diff(baseId, targetId string, top int) (*RunDiff, error) {
+	base, ok := h.Get(baseId)
+	if !ok {
+		return nil, fmt.Errorf("no such run: %v", baseId)
+	}
+	target, ok := h.Get(targetId)
+	if !ok {
+		return nil, fmt.Errorf("no such run: %v", targetId)
+	}
+
+	baseReport, err := h.RunReport(base, 0)
+	if err != nil {
+		return nil, err
+	}
+	targetReport, err := h.RunReport(target, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &RunDiff{
+		Base:      base.RunId,
+		Target:    target.RunId,
+		Endpoints: truncate(endpointChanges(baseReport, targetReport), top),
+		Queries:   truncate(queryChanges(baseReport, targetReport), top),
+		Functions: truncate(functionChanges(baseReport, targetReport), top),
+	}
+
+	if h.scores != nil {
+		if diff.BaseScore, err = h.latestScore(base.RunId); err != nil {
+			return nil, err
+		}
+		if diff.TargetScore, err = h.latestScore(target.RunId); err != nil {
+			return nil, err
+		}
+		if diff.BaseScore != nil && diff.TargetScore != nil {
+			delta := *diff.TargetScore - *diff.BaseScore
+			diff.ScoreDelta = &delta
+		}
+	}
+	return diff, nil
+}
+
+func (h *Handler) latestScore(runId string) (*float64, error) {
+	points, err := h.scores.History(runId)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, nil
+	}
+	score := points[len(points)-1].Score.Score
+	return &score, nil
+}
+
+func endpointChanges(base, target *Report) []*EndpointChange {
+	aggregate := func(report *Report) map[string]*endpointP95 {
+		stats := map[string]*endpointP95{}
+		for _, ep := range report.Endpoints {
+			key := ep.Method + " " + ep.Uri
+			st, ok := stats[key]
+			if !ok {
+				st = &endpointP95{method: ep.Method, uri: ep.Uri}
+				stats[key] = st
+			}
+			if total := st.count + ep.Count; total > 0 {
+				st.p95 = (st.p95*st.count + ep.P95*ep.Count) / total
+			}
+			st.count += ep.Count
+		}
+		return stats
+	}
+	baseStats, targetStats := aggregate(base), aggregate(target)
+
+	changes := []*EndpointChange{}
+	for key, st := range baseStats {
+		ch := &EndpointChange{Method: st.method, Uri: st.uri, Base: st.p95}
+		if t, ok := targetStats[key]; ok {
+			ch.Target = t.p95
+			ch.Change = classify(ch.Base, ch.Target, diffTolerance*ch.Base)
+		} else {
+			ch.Change = ChangeRemoved
+		}
+		ch.Delta = ch.Target - ch.Base
+		changes = append(changes, ch)
+	}
+	for key, st := range targetStats {
+		if _, ok := baseStats[key]; !ok {
+			changes = append(changes, &EndpointChange{Method: st.method, Uri: st.uri, Target: st.p95, Delta: st.p95, Change: ChangeAdded})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if a, b := math.Abs(changes[i].Delta), math.Abs(changes[j].Delta); a != b {
+			return a > b
+		}
+		return changes[i].Method+changes[i].Uri < changes[j].Method+changes[j].Uri
+	})
+	return changes
+}
+
+func queryChanges(base, target *Report) []*QueryChange {
+	aggregate := func(report *Report) map[string]*QueryChange {
+		sums := map[string]*QueryChange{}
+		for _, q := range report.Queries {
+			key := q.QueryID
+			if key == "" {
+				key = q.Query
+			}
+			if _, ok := sums[key]; !ok {
+				sums[key] = &QueryChange{QueryID: q.QueryID, Query: q.Query}
+			}
+			sums[key].Base += q.Sum
+		}
+		return sums
+	}
+	baseSums, targetSums := aggregate(base), aggregate(target)
+
+	changes := []*QueryChange{}
+	for key, ch := range baseSums {
+		if t, ok := targetSums[key]; ok {
+			ch.Target = t.Base
+			ch.Change = classify(ch.Base, ch.Target, diffTolerance*ch.Base)
+		} else {
+			ch.Change = ChangeRemoved
+		}
+		ch.Delta = ch.Target - ch.Base
+		changes = append(changes, ch)
+	}
+	for key, ch := range targetSums {
+		if _, ok := baseSums[key]; !ok {
+			ch.Target, ch.Base = ch.Base, 0
+			ch.Delta, ch.Change = ch.Target, ChangeAdded
+			changes = append(changes, ch)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if a, b := math.Abs(changes[i].Delta), math.Abs(changes[j].Delta); a != b {
+			return a > b
+		}
+		return changes[i].Query < changes[j].Query
+	})
+	return changes
+}
+
+func functionChanges(base, target *Report) []*FunctionChange {
+	aggregate := func(report *Report) map[string]*FunctionChange {
+		totals := map[string]int64{}
+		for _, fn := range report.Functions {
+			totals[fn.Unit] += fn.Flat
+		}
+		shares := map[string]*FunctionChange{}
+		for _, fn := range report.Functions {
+			if totals[fn.Unit] == 0 {
+				continue
+			}
+			key := fn.Unit + " " + fn.Name
+			if _, ok := shares[key]; !ok {
+				shares[key] = &FunctionChange{Name: fn.Name, Unit: fn.Unit}
+			}
+			shares[key].Base += float64(fn.Flat) / float64(totals[fn.Unit])
+		}
+		return shares
+	}
+	baseShares, targetShares := aggregate(base), aggregate(target)
+
+	changes := []*FunctionChange{}
+	for key, ch := range baseShares {
+		if t, ok := targetShares[key]; ok {
+			ch.Target = t.Base
+			ch.Change = classify(ch.Base, ch.Target, shareEpsilon)
+		} else {
+			ch.Change = ChangeRemoved
+		}
+		ch.Delta = ch.Target - ch.Base
+		if math.Abs(ch.Delta) >= shareEpsilon {
+			changes = append(changes, ch)
+		}
+	}
+	for key, ch := range targetShares {
+		if _, ok := baseShares[key]; !ok && ch.Base >= shareEpsilon {
+			ch.Target, ch.Base = ch.Base, 0
+			ch.Delta, ch.Change = ch.Target, ChangeAdded
+			changes = append(changes, ch)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if a, b := math.Abs(changes[i].Delta), math.Abs(changes[j].Delta); a != b {
+			return a > b
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+func classify(base, target, tolerance float64) Change {
+	switch {
+	case target > base+tolerance:
+		return ChangeRegressed
+	case target < base-tolerance:
+		return ChangeImproved
+	default:
+		return ChangeUnchanged
+	}
+}
+
+func (d *RunDiff) Markdown(w io.Writer) error {
+	if err := diffMarkdown.Execute(w, d); err != nil {
+		return fmt.Errorf("failed to render markdown: %w", err)
+	}
+	return nil
+}
+
+func (h *Handler) getDiff(c echo.Context) error {
+	top, err := reportTop(c)
+	if err != nil {
+		return err
+	}
+
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "markdown" && format != "md" {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unsupported format: %v", format))
+	}
+
+	diff, err := h.Diff(c.QueryParam("base"), c.QueryParam("target"), top)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to diff runs: %v", err))
+	}
+	if format == "" || format == "json" {
+		return c.JSON(http.StatusOK, diff)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := diff.Markdown(buf); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.Blob(http.StatusOK, "text/markdown; charset=utf-8", buf.Bytes())
+}
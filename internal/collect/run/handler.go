@@ -0,0 +1,108 @@
+package run
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Handler struct {
+		registry *collect.Registry
+		scores   *Scores
+	}
+
+	Run struct {
+		RunId    string
+		Datetime time.Time
+		Status   collect.Status
+		Entries  []*collect.Entry
+	}
+)
+
+func NewHandler(registry *collect.Registry) *Handler {
+	return &Handler{registry: registry}
+}
+
+func (h *Handler) UseScores(scores *Scores) {
+	h.scores = scores
+}
+
+func (h *Handler) RegisterHandlers(g *echo.Group) {
+	g.GET("", h.getIndex)
+	g.GET("/report", h.getWindowReport)
+	g.GET("/diff", h.getDiff)
+	g.GET("/:id", h.getId)
+	g.GET("/:id/report", h.getRunReport)
+	g.GET("/:id/hints", h.getRunHints)
+}
+
+func (h *Handler) Runs() []*Run {
+	runs := map[string]*Run{}
+	for _, ent := range h.registry.List() {
+		id := ent.Snapshot.RunId
+		if id == "" {
+			continue
+		}
+
+		r, ok := runs[id]
+		if !ok {
+			r = &Run{RunId: id, Datetime: ent.Snapshot.Datetime}
+			runs[id] = r
+		}
+		if ent.Snapshot.Datetime.Before(r.Datetime) {
+			r.Datetime = ent.Snapshot.Datetime
+		}
+		r.Entries = append(r.Entries, ent)
+	}
+
+	resp := make([]*Run, 0, len(runs))
+	for _, r := range runs {
+		sort.Slice(r.Entries, func(i, j int) bool {
+			return r.Entries[i].Snapshot.Type < r.Entries[j].Snapshot.Type
+		})
+		r.Status = aggregateStatus(r.Entries)
+		resp = append(resp, r)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Datetime.After(resp[j].Datetime)
+	})
+	return resp
+}
+
+func aggregateStatus(entries []*collect.Entry) collect.Status {
+	status := collect.StatusOk
+	for _, ent := range entries {
+		switch ent.Status {
+		case collect.StatusPending:
+			return collect.StatusPending
+		case collect.StatusFail:
+			status = collect.StatusFail
+		}
+	}
+	return status
+}
+
+func (h *Handler) Get(id string) (*Run, bool) {
+	for _, r := range h.Runs() {
+		if r.RunId == id {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (h *Handler) getIndex(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.Runs())
+}
+
+func (h *Handler) getId(c echo.Context) error {
+	r, ok := h.Get(c.Param("id"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "no such run")
+	}
+	return c.JSON(http.StatusOK, r)
+}
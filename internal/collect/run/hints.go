@@ -0,0 +1,221 @@
+package run
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Hint struct {
+		Kind       string
+		Subject    string
+		Source     string
+		Share      float64
+		Message    string
+		Suggestion string
+	}
+
+	hotspot struct {
+		kind       string
+		name       string
+		pattern    *regexp.Regexp
+		suggestion string
+	}
+)
+
+const (
+	HintDominantQuery    = "dominant-query"
+	HintUnindexedQuery   = "unindexed-query"
+	HintRepeatedQuery    = "repeated-query"
+	HintDominantEndpoint = "dominant-endpoint"
+	HintStaticFiles      = "static-files"
+
+	dominantQueryShare    = 0.5
+	dominantEndpointShare = 0.5
+	staticRequestShare    = 0.1
+	hotspotShare          = 0.2
+	repeatedQueryRatio    = 5
+	repeatedQueryMinimum  = 100
+)
+
+var (
+	staticPattern = regexp.MustCompile(`(?i)(^/(static|assets|public|images?|img|css|js|fonts?)/|\.(css|js|map|png|jpe?g|gif|svg|ico|webp|woff2?|ttf|html?)$)`)
+	fullScanPlan  = regexp.MustCompile(`\btype=ALL\b|Seq Scan`)
+
+	hotspots = []*hotspot{
+		{"json", "JSON encoding", regexp.MustCompile(`encoding/json\.|goccy/go-json\.|json-iterator|easyjson`), "cache encoded responses, shrink payloads or switch to a faster encoder"},
+		{"gc", "memory allocation and GC", regexp.MustCompile(`^runtime\.(mallocgc|gcBgMarkWorker|gcDrain|scanobject|gcAssistAlloc)`), "reduce allocations (reuse buffers, preallocate slices) or raise GOGC"},
+		{"template", "template rendering", regexp.MustCompile(`^(html|text)/template\.`), "cache rendered output or precompute static fragments"},
+		{"regexp", "regular expressions", regexp.MustCompile(`^regexp\.`), "compile patterns once or replace them with strings functions"},
+		{"crypto", "hashing and crypto", regexp.MustCompile(`^(crypto/|golang\.org/x/crypto/)`), "lower hashing cost (e.g. bcrypt cost) or cache verified results"},
+		{"sql", "database driver", regexp.MustCompile(`^(database/sql\.|github\.com/go-sql-driver/mysql\.|github\.com/jackc/pgx|github\.com/lib/pq\.)`), "batch queries and avoid fetching unused rows"},
+	}
+)
+
+func (r *Report) hint(allFunctions []*FunctionReport) {
+	labels := map[string]string{}
+	for _, src := range r.Sources {
+		labels[src.ID] = src.Type
+		if src.Label != "" {
+			labels[src.ID] += " " + src.Label
+		}
+	}
+
+	r.Hints = append(r.Hints, queryHints(r.Queries, r.Endpoints)...)
+	r.Hints = append(r.Hints, endpointHints(r.Endpoints)...)
+	r.Hints = append(r.Hints, functionHints(allFunctions, labels)...)
+
+	sort.SliceStable(r.Hints, func(i, j int) bool { return r.Hints[i].Share > r.Hints[j].Share })
+}
+
+func queryHints(queries []*QueryReport, endpoints []*EndpointReport) []*Hint {
+	hints := []*Hint{}
+
+	totals := map[string]float64{}
+	for _, q := range queries {
+		totals[q.Source] += q.Sum
+	}
+	requests := 0.0
+	for _, ep := range endpoints {
+		requests += ep.Count
+	}
+
+	for _, q := range queries {
+		if total := totals[q.Source]; total > 0 && q.Sum/total >= dominantQueryShare {
+			share := q.Sum / total
+			hint := &Hint{
+				Kind:       HintDominantQuery,
+				Subject:    q.Query,
+				Source:     q.Source,
+				Share:      share,
+				Message:    fmt.Sprintf("%.0f%% of DB time is one query", share*100),
+				Suggestion: "check its plan with EXPLAIN and add an index or cache the result",
+			}
+			if fullScanPlan.MatchString(q.Plan) {
+				hint.Kind = HintUnindexedQuery
+				hint.Message = fmt.Sprintf("%.0f%% of DB time is one unindexed query", share*100)
+				hint.Suggestion = "add an index covering its WHERE and ORDER BY columns"
+			}
+			hints = append(hints, hint)
+		}
+
+		if requests > 0 && q.Count >= repeatedQueryMinimum && q.Count/requests >= repeatedQueryRatio {
+			var share float64
+			if total := totals[q.Source]; total > 0 {
+				share = q.Sum / total
+			}
+			hints = append(hints, &Hint{
+				Kind:       HintRepeatedQuery,
+				Subject:    q.Query,
+				Source:     q.Source,
+				Share:      share,
+				Message:    fmt.Sprintf("one query runs %.1f times per HTTP request", q.Count/requests),
+				Suggestion: "look for an N+1 pattern; fetch rows in bulk with IN or JOIN",
+			})
+		}
+	}
+	return hints
+}
+
+func endpointHints(endpoints []*EndpointReport) []*Hint {
+	hints := []*Hint{}
+
+	totals := map[string]float64{}
+	requests := map[string]float64{}
+	statics := map[string]float64{}
+	for _, ep := range endpoints {
+		totals[ep.Source] += ep.Sum
+		requests[ep.Source] += ep.Count
+		if staticPattern.MatchString(ep.Uri) {
+			statics[ep.Source] += ep.Count
+		}
+	}
+
+	for _, ep := range endpoints {
+		if total := totals[ep.Source]; total > 0 && len(endpoints) > 1 && ep.Sum/total >= dominantEndpointShare {
+			share := ep.Sum / total
+			hints = append(hints, &Hint{
+				Kind:       HintDominantEndpoint,
+				Subject:    strings.TrimSpace(ep.Method + " " + ep.Uri),
+				Source:     ep.Source,
+				Share:      share,
+				Message:    fmt.Sprintf("%.0f%% of response time is spent in one endpoint", share*100),
+				Suggestion: "profile this handler first",
+			})
+		}
+	}
+
+	for source, count := range statics {
+		if share := count / requests[source]; share >= staticRequestShare {
+			hints = append(hints, &Hint{
+				Kind:       HintStaticFiles,
+				Source:     source,
+				Share:      share,
+				Message:    fmt.Sprintf("%.0f%% of requests are static files served by the app", share*100),
+				Suggestion: "serve static files from nginx with cache headers",
+			})
+		}
+	}
+	return hints
+}
+
+func functionHints(functions []*FunctionReport, labels map[string]string) []*Hint {
+	hints := []*Hint{}
+
+	totals := map[string]int64{}
+	for _, fn := range functions {
+		totals[fn.Source] += fn.Flat
+	}
+
+	type key struct{ source, kind string }
+	peaks := map[key]*FunctionReport{}
+	for _, fn := range functions {
+		for _, hs := range hotspots {
+			if !hs.pattern.MatchString(fn.Name) {
+				continue
+			}
+			k := key{fn.Source, hs.kind}
+			if peak, ok := peaks[k]; !ok || fn.Cum > peak.Cum {
+				peaks[k] = fn
+			}
+		}
+	}
+
+	for _, hs := range hotspots {
+		for source, total := range totals {
+			peak, ok := peaks[key{source, hs.kind}]
+			if !ok || total == 0 {
+				continue
+			}
+			if share := float64(peak.Cum) / float64(total); share >= hotspotShare {
+				hints = append(hints, &Hint{
+					Kind:       hs.kind,
+					Subject:    peak.Name,
+					Source:     source,
+					Share:      share,
+					Message:    fmt.Sprintf("%s spends %.0f%% in %s", labels[source], share*100, hs.name),
+					Suggestion: hs.suggestion,
+				})
+			}
+		}
+	}
+	return hints
+}
+
+func (h *Handler) getRunHints(c echo.Context) error {
+	r, ok := h.Get(c.Param("id"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "no such run")
+	}
+
+	report, err := h.RunReport(r, defaultReportTop)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, report.Hints)
+}
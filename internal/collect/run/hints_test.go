@@ -0,0 +1,166 @@
+package run
+
+import (
+	"reflect"
+	"testing"
+)
+
+func hintKinds(hints []*Hint) []string {
+	kinds := []string{}
+	for _, h := range hints {
+		kinds = append(kinds, h.Kind+":"+h.Subject)
+	}
+	return kinds
+}
+
+func TestQueryHints(t *testing.T) {
+	tests := []struct {
+		name      string
+		queries   []*QueryReport
+		endpoints []*EndpointReport
+		want      []string
+	}{
+		{
+			name: "balanced",
+			queries: []*QueryReport{
+				{Source: "db", Query: "SELECT a", Count: 10, Sum: 40},
+				{Source: "db", Query: "SELECT b", Count: 10, Sum: 60},
+				{Source: "db", Query: "SELECT c", Count: 10, Sum: 60},
+			},
+			want: []string{},
+		},
+		{
+			name: "dominant",
+			queries: []*QueryReport{
+				{Source: "db", Query: "SELECT a", Count: 10, Sum: 10},
+				{Source: "db", Query: "SELECT b", Count: 10, Sum: 90},
+			},
+			want: []string{HintDominantQuery + ":SELECT b"},
+		},
+		{
+			name: "unindexed",
+			queries: []*QueryReport{
+				{Source: "db", Query: "SELECT a", Count: 10, Sum: 90, Plan: "id=1 type=ALL rows=1000"},
+				{Source: "db", Query: "SELECT b", Count: 10, Sum: 10},
+			},
+			want: []string{HintUnindexedQuery + ":SELECT a"},
+		},
+		{
+			name: "shares are per source",
+			queries: []*QueryReport{
+				{Source: "db1", Query: "SELECT a", Count: 10, Sum: 90},
+				{Source: "db2", Query: "SELECT b", Count: 10, Sum: 10},
+				{Source: "db2", Query: "SELECT c", Count: 10, Sum: 10},
+				{Source: "db2", Query: "SELECT d", Count: 10, Sum: 10},
+			},
+			want: []string{HintDominantQuery + ":SELECT a"},
+		},
+		{
+			name: "repeated",
+			queries: []*QueryReport{
+				{Source: "db", Query: "SELECT a", Count: 1000, Sum: 10},
+				{Source: "db", Query: "SELECT b", Count: 10, Sum: 20},
+				{Source: "db", Query: "SELECT c", Count: 10, Sum: 20},
+			},
+			endpoints: []*EndpointReport{{Source: "web", Uri: "/", Count: 100}},
+			want:      []string{HintRepeatedQuery + ":SELECT a"},
+		},
+		{
+			name: "repeated needs a minimum count",
+			queries: []*QueryReport{
+				{Source: "db", Query: "SELECT a", Count: 50, Sum: 10},
+				{Source: "db", Query: "SELECT b", Count: 10, Sum: 20},
+				{Source: "db", Query: "SELECT c", Count: 10, Sum: 20},
+			},
+			endpoints: []*EndpointReport{{Source: "web", Uri: "/", Count: 1}},
+			want:      []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hintKinds(queryHints(tt.queries, tt.endpoints)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queryHints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointHints(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []*EndpointReport
+		want      []string
+	}{
+		{
+			name:      "single endpoint",
+			endpoints: []*EndpointReport{{Source: "web", Method: "GET", Uri: "/api", Count: 10, Sum: 100}},
+			want:      []string{},
+		},
+		{
+			name: "dominant",
+			endpoints: []*EndpointReport{
+				{Source: "web", Method: "GET", Uri: "/api/a", Count: 10, Sum: 80},
+				{Source: "web", Method: "GET", Uri: "/api/b", Count: 10, Sum: 20},
+			},
+			want: []string{HintDominantEndpoint + ":GET /api/a"},
+		},
+		{
+			name: "static files",
+			endpoints: []*EndpointReport{
+				{Source: "web", Method: "GET", Uri: "/api/a", Count: 80, Sum: 40},
+				{Source: "web", Method: "GET", Uri: "/assets/app.js", Count: 10, Sum: 30},
+				{Source: "web", Method: "GET", Uri: "/favicon.ico", Count: 10, Sum: 30},
+			},
+			want: []string{HintStaticFiles + ":"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hintKinds(endpointHints(tt.endpoints)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("endpointHints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFunctionHints(t *testing.T) {
+	tests := []struct {
+		name      string
+		functions []*FunctionReport
+		want      []string
+	}{
+		{
+			name: "no hotspot",
+			functions: []*FunctionReport{
+				{Source: "app", Name: "main.handler", Flat: 90, Cum: 100},
+				{Source: "app", Name: "encoding/json.Marshal", Flat: 10, Cum: 10},
+			},
+			want: []string{},
+		},
+		{
+			name: "picks the peak by cum",
+			functions: []*FunctionReport{
+				{Source: "app", Name: "main.handler", Flat: 50, Cum: 100},
+				{Source: "app", Name: "encoding/json.Marshal", Flat: 10, Cum: 40},
+				{Source: "app", Name: "encoding/json.(*encodeState).marshal", Flat: 40, Cum: 30},
+			},
+			want: []string{"json:encoding/json.Marshal"},
+		},
+		{
+			name: "multiple kinds",
+			functions: []*FunctionReport{
+				{Source: "app", Name: "runtime.mallocgc", Flat: 30, Cum: 30},
+				{Source: "app", Name: "regexp.(*Regexp).doExecute", Flat: 30, Cum: 30},
+				{Source: "app", Name: "main.handler", Flat: 40, Cum: 100},
+			},
+			want: []string{"gc:runtime.mallocgc", "regexp:regexp.(*Regexp).doExecute"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hintKinds(functionHints(tt.functions, map[string]string{})); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("functionHints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
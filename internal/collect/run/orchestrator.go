@@ -0,0 +1,190 @@
+package run
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/collect/group"
+	"github.com/kaz/pprotein/internal/event"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Orchestrator struct {
+		runs     *Handler
+		targets  *group.Collector
+		eventHub *event.Hub
+	}
+
+	CollectAllRequest struct {
+		Environment string
+		Label       string
+		Duration    int
+		Types       []string
+		Tags        map[string]string
+	}
+
+	CollectAllResponse struct {
+		GroupId     string
+		RunId       string
+		Environment string
+		Targets     int
+	}
+
+	RunProgress struct {
+		RunId    string
+		Label    string
+		Expected int
+		Total    int
+		Pending  int
+		Ok       int
+		Fail     int
+		Bytes    int64
+		Elapsed  float64
+		Status   collect.Status
+		Done     bool
+		Error    string `json:",omitempty"`
+	}
+)
+
+const (
+	runProgressEvent    = "run"
+	runProgressInterval = 1 * time.Second
+	runProgressGrace    = 10 * time.Minute
+)
+
+func NewOrchestrator(runs *Handler, targets *group.Collector, eventHub *event.Hub) *Orchestrator {
+	return &Orchestrator{runs: runs, targets: targets, eventHub: eventHub}
+}
+
+func (o *Orchestrator) RegisterHandlers(g *echo.Group) {
+	g.POST("/all", o.postAll)
+}
+
+func (o *Orchestrator) CollectAll(req *CollectAllRequest) (*CollectAllResponse, error) {
+	env, targets, err := o.targets.CollectTargets(req.Environment, req.Types)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets to collect in environment %v", env)
+	}
+
+	prepared := make([]*group.CollectTarget, 0, len(targets))
+	for _, target := range targets {
+		copied := *target
+		copied.Tags = map[string]string{}
+		for k, v := range target.Tags {
+			copied.Tags[k] = v
+		}
+		for k, v := range req.Tags {
+			copied.Tags[k] = v
+		}
+		if req.Label != "" {
+			copied.Tags["run"] = req.Label
+		}
+		if req.Duration > 0 {
+			copied.Duration = req.Duration
+		}
+		prepared = append(prepared, &copied)
+	}
+
+	grpId := collect.NewGroupId()
+	base := &collect.SnapshotTarget{GroupId: grpId, RunId: grpId, Duration: req.Duration}
+
+	progress := &RunProgress{RunId: grpId, Label: req.Label, Expected: len(prepared), Status: collect.StatusPending}
+	go func() {
+		if err := o.targets.Collect(env, base, prepared); err != nil {
+			log.Printf("[!] collection of run %v aborted: %v", grpId, err)
+			progress.Error = err.Error()
+		}
+		o.track(progress, maxDuration(prepared))
+	}()
+
+	return &CollectAllResponse{GroupId: grpId, RunId: grpId, Environment: env, Targets: len(prepared)}, nil
+}
+
+func (o *Orchestrator) track(progress *RunProgress, duration int) {
+	start := time.Now()
+	deadline := start.Add(time.Duration(duration)*time.Second + runProgressGrace)
+
+	ticker := time.NewTicker(runProgressInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		o.update(progress, start)
+		if !progress.Done && time.Now().After(deadline) {
+			progress.Done = true
+			progress.Error = "timed out waiting for collections to finish"
+		}
+		o.publish(progress)
+
+		if progress.Done {
+			return
+		}
+	}
+}
+
+func (o *Orchestrator) update(progress *RunProgress, start time.Time) {
+	progress.Total, progress.Pending, progress.Ok, progress.Fail, progress.Bytes = 0, 0, 0, 0, 0
+	progress.Elapsed = time.Since(start).Seconds()
+
+	r, ok := o.runs.Get(progress.RunId)
+	if !ok {
+		return
+	}
+
+	for _, ent := range r.Entries {
+		progress.Total++
+		switch ent.Status {
+		case collect.StatusPending:
+			progress.Pending++
+			if ent.Progress != nil {
+				progress.Bytes += ent.Progress.Bytes
+			}
+		case collect.StatusFail:
+			progress.Fail++
+		default:
+			progress.Ok++
+		}
+	}
+	progress.Status = r.Status
+	progress.Done = progress.Pending == 0 && (progress.Total >= progress.Expected || progress.Error != "")
+}
+
+func (o *Orchestrator) publish(progress *RunProgress) {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		log.Printf("[!] failed to marshal run progress: %v", err)
+		return
+	}
+	o.eventHub.PublishEvent(runProgressEvent, data)
+}
+
+func (o *Orchestrator) postAll(c echo.Context) error {
+	req := &CollectAllRequest{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+	if req.Duration < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Duration must not be negative")
+	}
+
+	resp, err := o.CollectAll(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to collect: %v", err))
+	}
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+func maxDuration(targets []*group.CollectTarget) int {
+	duration := 0
+	for _, target := range targets {
+		duration = max(duration, target.Duration)
+	}
+	return duration
+}
@@ -0,0 +1,157 @@
+package run
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/memo"
+	"github.com/kaz/pprotein/internal/pprof"
+)
+
+type (
+	Document struct {
+		Run       *Run
+		Report    *Report
+		Scores    []*Score
+		Profiles  []*ProfileSection
+		Memos     []*memo.Memo
+		Artifacts map[string][]string
+		Generated time.Time
+	}
+
+	ProfileSection struct {
+		Type       string
+		ID         string
+		Label      string
+		Functions  []*pprof.FunctionStat
+		FlameGraph []byte `json:"-"`
+	}
+)
+
+var (
+	//go:embed report.md.tmpl
+	markdownTemplate string
+	//go:embed report.html.tmpl
+	htmlTemplate string
+
+	renderFuncs = map[string]any{
+		"label":   entryLabel,
+		"float":   func(v float64) string { return fmt.Sprintf("%.3f", v) },
+		"delta":   func(v float64) string { return fmt.Sprintf("%+.3f", v) },
+		"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+		"shift":   func(v float64) string { return fmt.Sprintf("%+.1f%%", v*100) },
+		"join":    strings.Join,
+		"safe":    func(s string) htmltemplate.HTML { return htmltemplate.HTML(s) },
+		"svg": func(b []byte) htmltemplate.URL {
+			return htmltemplate.URL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(b))
+		},
+		"cell": func(s string) string {
+			return strings.NewReplacer("|", `\|`, "\r", "", "\n", " ").Replace(s)
+		},
+	}
+
+	markdown = template.Must(template.New("report.md").Funcs(renderFuncs).Parse(markdownTemplate))
+	html     = htmltemplate.Must(htmltemplate.New("report.html").Funcs(renderFuncs).Parse(htmlTemplate))
+)
+
+func (h *Handler) Document(r *Run, top int) (*Document, error) {
+	report, err := h.RunReport(r, top)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{
+		Run:       r,
+		Report:    report,
+		Scores:    []*Score{},
+		Profiles:  []*ProfileSection{},
+		Artifacts: map[string][]string{},
+		Generated: time.Now(),
+	}
+
+	if h.scores != nil {
+		points, err := h.scores.History(r.RunId)
+		if err != nil {
+			return nil, err
+		}
+		for _, point := range points {
+			doc.Scores = append(doc.Scores, point.Score)
+		}
+	}
+
+	for _, ent := range r.Entries {
+		if ent.Status != collect.StatusOk || !profileTypes[ent.Snapshot.Type] {
+			continue
+		}
+		section, err := profileSection(ent, top)
+		if err != nil {
+			log.Printf("[!] failed to include %v in document: %v", ent.Snapshot.ID, err)
+			continue
+		}
+		doc.Profiles = append(doc.Profiles, section)
+	}
+
+	if doc.Memos, err = h.memos(report); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func profileSection(ent *collect.Entry, top int) (*ProfileSection, error) {
+	functions, err := pprof.TopFunctions(ent.Snapshot, top)
+	if err != nil {
+		return nil, err
+	}
+	svg, err := pprof.FlameGraphSVG(ent.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &ProfileSection{
+		Type:       ent.Snapshot.Type,
+		ID:         ent.Snapshot.ID,
+		Label:      entryLabel(ent),
+		Functions:  functions,
+		FlameGraph: svg,
+	}, nil
+}
+
+func (h *Handler) memos(report *Report) ([]*memo.Memo, error) {
+	c, ok := h.registry.Get("memo")
+	if !ok {
+		return []*memo.Memo{}, nil
+	}
+	return memo.List(c, func(m *memo.Memo) bool {
+		if m.RunId != "" {
+			return m.RunId == report.RunId
+		}
+		return !m.Datetime.Before(report.From) && !m.Datetime.After(report.To)
+	})
+}
+
+func (d *Document) Markdown(w io.Writer) error {
+	if err := markdown.Execute(w, d); err != nil {
+		return fmt.Errorf("failed to render markdown: %w", err)
+	}
+	return nil
+}
+
+func (d *Document) HTML(w io.Writer) error {
+	if err := html.Execute(w, d); err != nil {
+		return fmt.Errorf("failed to render html: %w", err)
+	}
+	return nil
+}
+
+func entryLabel(ent *collect.Entry) string {
+	if ent.Snapshot.SnapshotTarget == nil {
+		return ""
+	}
+	return ent.Snapshot.Label
+}
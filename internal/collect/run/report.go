@@ -0,0 +1,359 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/extproc"
+	"github.com/kaz/pprotein/internal/pprof"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Report struct {
+		RunId     string
+		From      time.Time
+		To        time.Time
+		Sources   []*ReportSource
+		Endpoints []*EndpointReport
+		Functions []*FunctionReport
+		Queries   []*QueryReport
+		Hints     []*Hint
+	}
+
+	ReportSource struct {
+		Type  string
+		ID    string
+		Label string
+	}
+
+	EndpointReport struct {
+		Source string
+		Method string
+		Uri    string
+		Count  float64
+		Sum    float64
+		Avg    float64
+		P95    float64
+
+		Functions []string
+		Queries   []string
+	}
+
+	FunctionReport struct {
+		Source string
+		Name   string
+		Unit   string
+		Flat   int64
+		Cum    int64
+	}
+
+	QueryReport struct {
+		Source  string
+		QueryID string
+		Query   string
+		Count   float64
+		Sum     float64
+		Plan    string `json:",omitempty"`
+	}
+)
+
+const defaultReportTop = 10
+
+var (
+	endpointTypes = map[string]bool{"httplog": true}
+	profileTypes  = map[string]bool{"pprof": true, "fgprof": true, "java": true, "node": true}
+	queryTypes    = map[string]bool{"slowlog": true, "pgslowlog": true}
+
+	tokenPattern = regexp.MustCompile(`[A-Za-z]{3,}`)
+)
+
+func (h *Handler) Report(id string, from, to time.Time, top int) (*Report, error) {
+	report := &Report{
+		RunId:     id,
+		From:      from,
+		To:        to,
+		Sources:   []*ReportSource{},
+		Endpoints: []*EndpointReport{},
+		Functions: []*FunctionReport{},
+		Queries:   []*QueryReport{},
+		Hints:     []*Hint{},
+	}
+
+	allFunctions := []*FunctionReport{}
+	for _, ent := range h.registry.List() {
+		if ent.Status != collect.StatusOk || !report.covers(ent.Snapshot) {
+			continue
+		}
+
+		snapshot := ent.Snapshot
+		var err error
+		switch {
+		case endpointTypes[snapshot.Type]:
+			err = h.addEndpoints(report, snapshot)
+		case profileTypes[snapshot.Type]:
+			var functions []*FunctionReport
+			functions, err = profileFunctions(snapshot)
+			allFunctions = append(allFunctions, functions...)
+		case queryTypes[snapshot.Type]:
+			err = h.addQueries(report, snapshot)
+		default:
+			continue
+		}
+		if err != nil {
+			log.Printf("[!] failed to include %v in report: %v", snapshot.ID, err)
+			continue
+		}
+		report.Sources = append(report.Sources, &ReportSource{Type: snapshot.Type, ID: snapshot.ID, Label: snapshot.Label})
+	}
+
+	sort.SliceStable(report.Endpoints, func(i, j int) bool { return report.Endpoints[i].Sum > report.Endpoints[j].Sum })
+	sort.SliceStable(report.Queries, func(i, j int) bool { return report.Queries[i].Sum > report.Queries[j].Sum })
+	sort.SliceStable(allFunctions, func(i, j int) bool { return allFunctions[i].Flat > allFunctions[j].Flat })
+	report.hint(allFunctions)
+
+	report.Endpoints = truncate(report.Endpoints, top)
+	report.Queries = truncate(report.Queries, top)
+	report.Functions = truncate(allFunctions, top)
+
+	for _, ep := range report.Endpoints {
+		ep.correlate(allFunctions, report.Queries)
+	}
+	return report, nil
+}
+
+func (r *Report) covers(snapshot *collect.Snapshot) bool {
+	if r.RunId != "" {
+		return snapshot.RunId == r.RunId
+	}
+	end := snapshot.Datetime.Add(time.Duration(snapshot.Duration) * time.Second)
+	return !snapshot.Datetime.After(r.To) && !end.Before(r.From)
+}
+
+func (h *Handler) table(snapshot *collect.Snapshot) (*extproc.Table, error) {
+	c, ok := h.registry.Get(snapshot.Type)
+	if !ok {
+		return nil, fmt.Errorf("no such collector: %v", snapshot.Type)
+	}
+	r, err := c.Get(snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return extproc.ParseTable(r)
+}
+
+func (h *Handler) addEndpoints(report *Report, snapshot *collect.Snapshot) error {
+	t, err := h.table(snapshot)
+	if err != nil {
+		return err
+	}
+
+	method, uri := t.Column("Method"), t.Column("Uri")
+	count, sum, avg, p95 := t.Column("Count"), t.Column("Sum"), t.Column("Avg"), t.Column("P95")
+	if uri < 0 || sum < 0 {
+		return fmt.Errorf("unexpected access log table")
+	}
+	for _, row := range t.Rows {
+		report.Endpoints = append(report.Endpoints, &EndpointReport{
+			Source:    snapshot.ID,
+			Method:    t.String(row, method),
+			Uri:       t.String(row, uri),
+			Count:     t.Number(row, count),
+			Sum:       t.Number(row, sum),
+			Avg:       t.Number(row, avg),
+			P95:       t.Number(row, p95),
+			Functions: []string{},
+			Queries:   []string{},
+		})
+	}
+	return nil
+}
+
+func (h *Handler) addQueries(report *Report, snapshot *collect.Snapshot) error {
+	t, err := h.table(snapshot)
+	if err != nil {
+		return err
+	}
+
+	id, query := t.Column("QueryID"), t.Column("Query", "Fingerprint")
+	count, sum, plan := t.Column("Count"), t.Column("Sum", "Sum(QueryTime)"), t.Column("Plan")
+	if query < 0 {
+		return fmt.Errorf("unexpected slow log table")
+	}
+	for _, row := range t.Rows {
+		report.Queries = append(report.Queries, &QueryReport{
+			Source:  snapshot.ID,
+			QueryID: t.String(row, id),
+			Query:   t.String(row, query),
+			Count:   t.Number(row, count),
+			Sum:     t.Number(row, sum),
+			Plan:    t.String(row, plan),
+		})
+	}
+	return nil
+}
+
+func profileFunctions(snapshot *collect.Snapshot) ([]*FunctionReport, error) {
+	stats, err := pprof.TopFunctions(snapshot, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	functions := make([]*FunctionReport, 0, len(stats))
+	for _, st := range stats {
+		functions = append(functions, &FunctionReport{
+			Source: snapshot.ID,
+			Name:   st.Name,
+			Unit:   st.Unit,
+			Flat:   st.Flat,
+			Cum:    st.Cum,
+		})
+	}
+	return functions, nil
+}
+
+func (ep *EndpointReport) correlate(functions []*FunctionReport, queries []*QueryReport) {
+	tokens := []string{}
+	for _, token := range tokenPattern.FindAllString(ep.Uri, -1) {
+		tokens = append(tokens, strings.ToLower(strings.TrimSuffix(token, "s")))
+	}
+	if len(tokens) == 0 {
+		return
+	}
+	matches := func(s string) bool {
+		s = strings.ToLower(s)
+		for _, token := range tokens {
+			if token != "api" && strings.Contains(s, token) {
+				return true
+			}
+		}
+		return false
+	}
+
+	seen := map[string]bool{}
+	for _, fn := range functions {
+		if !seen[fn.Name] && !isStdlib(fn.Name) && matches(fn.Name) {
+			seen[fn.Name] = true
+			ep.Functions = append(ep.Functions, fn.Name)
+		}
+	}
+	for _, q := range queries {
+		if !seen[q.Query] && matches(q.Query) {
+			seen[q.Query] = true
+			ep.Queries = append(ep.Queries, q.Query)
+		}
+	}
+}
+
+func isStdlib(name string) bool {
+	if first, _, ok := strings.Cut(name, "/"); ok {
+		return !strings.Contains(first, ".")
+	}
+	pkg, _, _ := strings.Cut(name, ".")
+	return pkg != "main"
+}
+
+func truncate[T any](items []T, n int) []T {
+	if n > 0 && len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+func reportTop(c echo.Context) (int, error) {
+	raw := c.QueryParam("top")
+	if raw == "" {
+		return defaultReportTop, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid top: %v", raw))
+	}
+	return n, nil
+}
+
+func (h *Handler) getRunReport(c echo.Context) error {
+	r, ok := h.Get(c.Param("id"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "no such run")
+	}
+	top, err := reportTop(c)
+	if err != nil {
+		return err
+	}
+
+	format := c.QueryParam("format")
+	if format == "" || format == "json" {
+		report, err := h.RunReport(r, top)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, report)
+	}
+
+	var render func(*Document, io.Writer) error
+	var contentType, ext string
+	switch format {
+	case "markdown", "md":
+		render, contentType, ext = (*Document).Markdown, "text/markdown; charset=utf-8", ".md"
+	case "html":
+		render, contentType, ext = (*Document).HTML, echo.MIMETextHTMLCharsetUTF8, ".html"
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unsupported format: %v", format))
+	}
+
+	doc, err := h.Document(r, top)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	buf := &bytes.Buffer{}
+	if err := render(doc, buf); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", r.RunId+"-report"+ext))
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+func (h *Handler) RunReport(r *Run, top int) (*Report, error) {
+	from, to := r.Datetime, r.Datetime
+	for _, ent := range r.Entries {
+		if end := ent.Snapshot.Datetime.Add(time.Duration(ent.Snapshot.Duration) * time.Second); end.After(to) {
+			to = end
+		}
+	}
+	return h.Report(r.RunId, from, to, top)
+}
+
+func (h *Handler) getWindowReport(c echo.Context) error {
+	from, err := time.Parse(time.RFC3339, c.QueryParam("from"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid from: %v", err))
+	}
+	to, err := time.Parse(time.RFC3339, c.QueryParam("to"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid to: %v", err))
+	}
+	if to.Before(from) {
+		return echo.NewHTTPError(http.StatusBadRequest, "to must not be before from")
+	}
+	top, err := reportTop(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.Report("", from, to, top)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,192 @@
+package run
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/event"
+	"github.com/kaz/pprotein/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Scores struct {
+		runs     *Handler
+		store    storage.Storage
+		eventHub *event.Hub
+		mu       *sync.Mutex
+	}
+
+	Score struct {
+		ID       string
+		RunId    string
+		Datetime time.Time
+		Score    float64
+		Detail   json.RawMessage `json:",omitempty"`
+	}
+
+	ScoreRequest struct {
+		RunId    string
+		Datetime *time.Time
+		Score    *float64
+		Detail   json.RawMessage
+	}
+
+	ScorePoint struct {
+		*Score
+		Status    collect.Status `json:",omitempty"`
+		Snapshots []*SnapshotLink
+	}
+
+	SnapshotLink struct {
+		Type    string
+		ID      string
+		Label   string
+		GroupId string
+		Status  collect.Status
+	}
+)
+
+const (
+	scoreTypeKey = "score"
+	scoreEvent   = "score"
+)
+
+func NewScores(runs *Handler, store storage.Storage, eventHub *event.Hub) *Scores {
+	return &Scores{runs: runs, store: store, eventHub: eventHub, mu: &sync.Mutex{}}
+}
+
+func (s *Scores) RegisterHandlers(g *echo.Group) {
+	g.GET("", s.getIndex)
+	g.POST("", s.postIndex)
+	g.DELETE("/:id", s.deleteId)
+}
+
+func (s *Scores) Record(req *ScoreRequest) (*Score, error) {
+	if req.Score == nil {
+		return nil, fmt.Errorf("score is required")
+	}
+
+	score := &Score{RunId: req.RunId, Datetime: time.Now(), Score: *req.Score, Detail: req.Detail}
+	if req.Datetime != nil {
+		score.Datetime = *req.Datetime
+	}
+
+	if score.RunId != "" {
+		if _, ok := s.runs.Get(score.RunId); !ok {
+			return nil, fmt.Errorf("no such run: %v", score.RunId)
+		}
+	} else {
+		score.RunId = s.runAt(score.Datetime)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	score.ID = strconv.FormatInt(time.Now().UnixNano(), 10)
+	raw, err := json.Marshal(score)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	if err := s.store.Put(scoreTypeKey, score.ID, raw); err != nil {
+		return nil, fmt.Errorf("failed to save score: %w", err)
+	}
+
+	s.eventHub.PublishEvent(scoreEvent, raw)
+	return score, nil
+}
+
+func (s *Scores) History(runId string) ([]*ScorePoint, error) {
+	raws, err := s.store.GetAll(scoreTypeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scores: %w", err)
+	}
+
+	runs := map[string]*Run{}
+	for _, r := range s.runs.Runs() {
+		runs[r.RunId] = r
+	}
+
+	points := make([]*ScorePoint, 0, len(raws))
+	for _, raw := range raws {
+		score := &Score{}
+		if err := json.Unmarshal(raw, score); err != nil {
+			log.Printf("[!] failed to unmarshal score: %v", err)
+			continue
+		}
+		if runId != "" && score.RunId != runId {
+			continue
+		}
+
+		point := &ScorePoint{Score: score, Snapshots: []*SnapshotLink{}}
+		if r, ok := runs[score.RunId]; ok {
+			point.Status = r.Status
+			for _, ent := range r.Entries {
+				link := &SnapshotLink{Type: ent.Snapshot.Type, ID: ent.Snapshot.ID, Status: ent.Status}
+				if ent.Snapshot.SnapshotTarget != nil {
+					link.Label = ent.Snapshot.Label
+					link.GroupId = ent.Snapshot.GroupId
+				}
+				point.Snapshots = append(point.Snapshots, link)
+			}
+		}
+		points = append(points, point)
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Datetime.Before(points[j].Datetime)
+	})
+	return points, nil
+}
+
+func (s *Scores) runAt(t time.Time) string {
+	for _, r := range s.runs.Runs() {
+		if !r.Datetime.After(t) {
+			return r.RunId
+		}
+	}
+	return ""
+}
+
+func (s *Scores) getIndex(c echo.Context) error {
+	points, err := s.History(c.QueryParam("run"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, points)
+}
+
+func (s *Scores) postIndex(c echo.Context) error {
+	req := &ScoreRequest{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+
+	score, err := s.Record(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to record score: %v", err))
+	}
+	return c.JSON(http.StatusOK, score)
+}
+
+func (s *Scores) deleteId(c echo.Context) error {
+	exists, err := s.store.Exists(scoreTypeKey, c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if !exists {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no such score: %v", c.Param("id")))
+	}
+
+	if err := s.store.Delete(scoreTypeKey, c.Param("id")); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to delete score: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,193 @@
+package collect
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Searchable interface {
+		SearchText(snapshot *Snapshot) ([]byte, error)
+	}
+
+	SearchQuery struct {
+		Text  string
+		Type  string
+		Limit int
+	}
+
+	SearchHit struct {
+		Entry   *Entry
+		Field   string
+		Snippet string
+		Rank    float64
+	}
+)
+
+const (
+	searchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS search USING fts5(id UNINDEXED, field UNINDEXED, body);
+`
+
+	SearchFieldLabel  = "label"
+	SearchFieldOutput = "output"
+
+	searchTextLimit    = 1 << 20
+	defaultSearchLimit = 50
+)
+
+func (i *Index) putText(id string, field string, body string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.replaceText(id, field, body)
+}
+
+func (i *Index) replaceText(id string, field string, body string) error {
+	tx, err := i.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM search WHERE id = ? AND field = ?`, id, field); err != nil {
+		return fmt.Errorf("failed to delete text: %w", err)
+	}
+	if body != "" {
+		if _, err := tx.Exec(`INSERT INTO search (id, field, body) VALUES (?, ?, ?)`, id, field, body); err != nil {
+			return fmt.Errorf("failed to insert text: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func labelText(target *SnapshotTarget) string {
+	parts := []string{}
+	for _, v := range []string{target.Label, target.URL} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	keys := make([]string, 0, len(target.Tags))
+	for k := range target.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+target.Tags[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+func (i *Index) Search(q *SearchQuery) ([]*SearchHit, error) {
+	match := matchExpression(q.Text)
+	if match == "" {
+		return []*SearchHit{}, nil
+	}
+
+	query := `SELECT s.meta, s.status, s.message, f.field, snippet(search, 2, '[', ']', '...', 16), bm25(search)
+		FROM search f JOIN snapshots s ON s.id = f.id
+		WHERE search MATCH ?`
+	args := []interface{}{match}
+	if q.Type != "" {
+		query += ` AND s.type = ?`
+		args = append(args, q.Type)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	query += ` ORDER BY bm25(search) LIMIT ?`
+	args = append(args, limit)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rows, err := i.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	resp := []*SearchHit{}
+	for rows.Next() {
+		var meta string
+		hit := &SearchHit{Entry: &Entry{Snapshot: &Snapshot{}}}
+		if err := rows.Scan(&meta, &hit.Entry.Status, &hit.Entry.Message, &hit.Field, &hit.Snippet, &hit.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		if err := hit.Entry.Snapshot.unmarshal([]byte(meta)); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal meta: %w", err)
+		}
+		resp = append(resp, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	return resp, nil
+}
+
+func matchExpression(text string) string {
+	terms := []string{}
+	for _, term := range strings.Fields(text) {
+		terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+func (c *Collector) indexOutput(snapshot *Snapshot, r io.Reader) {
+	if c.index == nil {
+		return
+	}
+
+	var text []byte
+	var err error
+	if searchable, ok := c.processor.internal.(Searchable); ok {
+		text, err = searchable.SearchText(snapshot)
+	} else if r != nil {
+		text, err = io.ReadAll(io.LimitReader(r, searchTextLimit))
+	}
+	if err != nil {
+		log.Printf("[!] failed to extract search text of %v: %v", snapshot.ID, err)
+		return
+	}
+	if !utf8.Valid(text) || bytes.IndexByte(text, 0) >= 0 {
+		return
+	}
+
+	if err := c.index.putText(snapshot.ID, SearchFieldOutput, string(text)); err != nil {
+		log.Printf("[!] failed to update index: %v", err)
+	}
+}
+
+func (i *Index) RegisterSearchHandlers(g *echo.Group) {
+	g.GET("", i.handleSearch)
+}
+
+func (i *Index) handleSearch(c echo.Context) error {
+	q := &SearchQuery{Text: c.QueryParam("q"), Type: c.QueryParam("type")}
+	if strings.TrimSpace(q.Text) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is required")
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid limit: %v", err))
+		}
+	}
+
+	hits, err := i.Search(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to search index: %v", err))
+	}
+	return c.JSON(http.StatusOK, hits)
+}
@@ -1,18 +1,25 @@
 package collect
 
 import (
+	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/kaz/pprotein/internal/git"
 	"github.com/kaz/pprotein/internal/storage"
+	"github.com/kaz/pprotein/internal/tracing"
 )
 
 type (
@@ -23,21 +30,56 @@ type (
 		*SnapshotTarget
 	}
 	SnapshotMeta struct {
-		Type       string
-		ID         string
-		Datetime   time.Time
-		Repository *git.RepositoryInfo
+		Type        string
+		ID          string
+		Datetime    time.Time
+		Repository  *git.RepositoryInfo
+		Encoding    string
+		Hash        string
+		DuplicateOf string
+		Pinned      bool
 	}
 	SnapshotTarget struct {
-		GroupId  string
-		Label    string
-		URL      string
-		Duration int
+		GroupId    string
+		RunId      string
+		ScheduleId string
+		Label      string
+		Tags       map[string]string
+		URL        string
+		URLs       []string
+		Duration   int
+
+		Headers     Headers
+		BasicAuth   *BasicAuth
+		BearerToken Secret
+		AgentToken  Secret
+
+		Client *ClientOptions
+
+		ProcessorOptions json.RawMessage
+	}
+
+	countingReader struct {
+		r io.Reader
+		n atomic.Int64
+	}
+
+	gzipBody struct {
+		*gzip.Reader
+		file io.Closer
 	}
 )
 
-func newSnapshot(store storage.Storage, typ string, ext string, target *SnapshotTarget) *Snapshot {
-	ts := time.Now()
+const EncodingGzip = "gzip"
+
+var lastSnapshotTime atomic.Int64
+
+func NewGroupId() string {
+	return time.Now().Format("2006-01-02_15-04-05.999999")
+}
+
+func newSnapshot(store storage.Storage, typ string, ext string, encoding string, target *SnapshotTarget) *Snapshot {
+	ts := uniqueTime()
 	id := strconv.FormatInt(ts.UnixNano(), 36) + ext
 
 	return &Snapshot{
@@ -48,11 +90,37 @@ func newSnapshot(store storage.Storage, typ string, ext string, target *Snapshot
 			ID:         id,
 			Datetime:   ts,
 			Repository: nil,
+			Encoding:   encoding,
 		},
 		SnapshotTarget: target,
 	}
 }
 
+func uniqueTime() time.Time {
+	for {
+		ts := time.Now()
+		last := lastSnapshotTime.Load()
+		if ts.UnixNano() <= last {
+			ts = time.Unix(0, last+1)
+		}
+		if lastSnapshotTime.CompareAndSwap(last, ts.UnixNano()) {
+			return ts
+		}
+	}
+}
+
+func (t *SnapshotTarget) urls() []string {
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, u := range append([]string{t.URL}, t.URLs...) {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
 func (s *Snapshot) unmarshal(raw []byte) error {
 	return json.Unmarshal(raw, s)
 }
@@ -60,14 +128,53 @@ func (s *Snapshot) marshal() ([]byte, error) {
 	return json.Marshal(s)
 }
 
-func (s *Snapshot) Collect() error {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s?seconds=%d", s.URL, s.Duration), nil)
+func (s *Snapshot) saveMeta() error {
+	serialized, err := s.marshal()
+	if err != nil {
+		return fmt.Errorf("failed to serialize: %w", err)
+	}
+	if err := s.store.Put(s.Type, s.ID, serialized); err != nil {
+		return fmt.Errorf("failed to write meta: %w", err)
+	}
+	return nil
+}
+
+func (s *Snapshot) requestURL() (string, error) {
+	if s.Duration == 0 {
+		return s.URL, nil
+	}
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("seconds", strconv.Itoa(s.Duration))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (s *Snapshot) Collect(ctx context.Context, client *http.Client, progress ProgressFunc) (err error) {
+	ctx, span := tracing.Start(ctx, "Snapshot.Collect", snapshotAttributes(s)...)
+	defer func() { span.Finish(err) }()
+
+	cr := &countingReader{}
+	stop := s.watchProgress(ctx, cr, progress)
+	defer stop()
+
+	target, err := s.requestURL()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Accept-Encoding", "gzip")
+	tracing.Inject(ctx, req.Header)
+	s.authorize(req)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("http error: %w", err)
 	}
@@ -84,54 +191,192 @@ func (s *Snapshot) Collect() error {
 		r = cr
 	}
 
-	bodyContent, err := io.ReadAll(r)
-	if err != nil {
-		return fmt.Errorf("failed to read body: %w", err)
-	}
-
 	s.Repository = &git.RepositoryInfo{}
 	if err := json.Unmarshal([]byte(resp.Header.Get("X-Git-Repository")), s.Repository); err != nil {
 		log.Printf("failed to parse git repository: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		bodyContent, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read body: %w", err)
+		}
 		return fmt.Errorf("http error: status=%v, body=%v", resp.StatusCode, string(bodyContent))
 	}
-	if len(bodyContent) == 0 {
-		return fmt.Errorf("received empty content")
+
+	cr.r = r
+	if err := s.Import(cr); err != nil {
+		return err
 	}
+	span.SetAttributes(tracing.Int64("pprotein.bytes", cr.n.Load()))
+	return nil
+}
 
-	serialized, err := s.marshal()
-	if err != nil {
-		return fmt.Errorf("failed to serialize: %w", err)
+func snapshotAttributes(s *Snapshot) []tracing.Attribute {
+	attrs := []tracing.Attribute{
+		tracing.String("pprotein.type", s.Type),
+		tracing.String("pprotein.snapshot_id", s.ID),
 	}
-	if err := s.store.Put(s.Type, s.ID, serialized); err != nil {
-		return fmt.Errorf("failed to write meta: %w", err)
+	if s.SnapshotTarget != nil {
+		attrs = append(attrs,
+			tracing.String("pprotein.label", s.Label),
+			tracing.String("pprotein.group_id", s.GroupId),
+			tracing.String("pprotein.run_id", s.RunId),
+			tracing.Int("pprotein.duration", s.Duration),
+		)
+		if u, err := url.Parse(s.URL); err == nil && s.URL != "" {
+			attrs = append(attrs, tracing.String("url.full", u.Redacted()))
+		}
+	}
+	return attrs
+}
+
+func (s *Snapshot) Import(r io.Reader) error {
+	h := sha256.New()
+	cr := &countingReader{r: io.TeeReader(r, h)}
+	if err := s.writeBody(cr); err != nil {
+		return fmt.Errorf("failed to write body: %w", err)
+	}
+	if cr.n.Load() == 0 {
+		if err := s.store.DeleteFile(s.ID); err != nil {
+			log.Printf("failed to remove empty body: %v", err)
+		}
+		return fmt.Errorf("received empty content")
+	}
+
+	s.Hash = hex.EncodeToString(h.Sum(nil))
+	return s.saveMeta()
+}
+
+func (s *Snapshot) Add(content []byte) error {
+	sum := sha256.Sum256(content)
+	s.Hash = hex.EncodeToString(sum[:])
+
+	if err := s.saveMeta(); err != nil {
+		return err
 	}
-	if err := s.store.PutFile(s.ID, bodyContent); err != nil {
+	if err := s.writeBody(bytes.NewReader(content)); err != nil {
 		return fmt.Errorf("failed to write body: %w", err)
 	}
 	return nil
 }
 
-func (s *Snapshot) Add(content []byte) error {
-	serialized, err := s.marshal()
+func (s *Snapshot) writeBody(r io.Reader) error {
+	if s.Encoding != EncodingGzip {
+		return s.store.PutFileStream(s.ID, r)
+	}
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	go func() {
+		gw := gzip.NewWriter(pw)
+		_, err := io.Copy(gw, r)
+		if err == nil {
+			err = gw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return s.store.PutFileStream(s.ID, pr)
+}
+
+func (s *Snapshot) Verify() error {
+	if s.Hash == "" {
+		return nil
+	}
+
+	r, err := s.Open()
 	if err != nil {
-		return fmt.Errorf("failed to serialize: %w", err)
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
 	}
-	if err := s.store.Put(s.ID, s.Type, serialized); err != nil {
-		return fmt.Errorf("failed to write meta: %w", err)
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
 	}
-	if err := s.store.PutFile(s.ID, content); err != nil {
-		return fmt.Errorf("failed to write body: %w", err)
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != s.Hash {
+		return fmt.Errorf("%w: checksum mismatch: expected=%v, actual=%v", ErrCorrupt, s.Hash, sum)
 	}
 	return nil
 }
 
+func (s *Snapshot) cacheKeys() []string {
+	suffix := s.optionsKey()
+	if s.DuplicateOf == "" {
+		return []string{s.ID + suffix}
+	}
+	return []string{s.ID + suffix, s.DuplicateOf + suffix}
+}
+
+func (s *Snapshot) optionsKey() string {
+	if len(s.ProcessorOptions) == 0 {
+		return ""
+	}
+
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, s.ProcessorOptions); err != nil {
+		buf.Reset()
+		buf.Write(s.ProcessorOptions)
+	}
+	if buf.String() == "null" || buf.String() == "{}" {
+		return ""
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	return "." + hex.EncodeToString(sum[:8])
+}
+
+func (s *Snapshot) Relabel(label string, tags map[string]string) error {
+	s.Label = label
+	s.Tags = tags
+	return s.saveMeta()
+}
+
+func (s *Snapshot) Pin(pinned bool) error {
+	s.Pinned = pinned
+	return s.saveMeta()
+}
+
+func (s *Snapshot) Open() (io.ReadCloser, error) {
+	file, err := s.store.OpenFile(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	if s.Encoding != EncodingGzip {
+		return file, nil
+	}
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to initialize gzip reader: %w", err)
+	}
+	return &gzipBody{gr, file}, nil
+}
+
 func (s *Snapshot) BodyPath() (string, error) {
 	return s.store.GetFilePath(s.ID)
 }
 
-func (s *Snapshot) Prune() error {
-	return s.store.Delete(s.Type, s.ID)
+func (s *Snapshot) Delete() error {
+	if err := s.store.Delete(s.Type, s.ID); err != nil {
+		return fmt.Errorf("failed to delete meta: %w", err)
+	}
+	if err := s.store.DeleteFile(s.ID); err != nil {
+		return fmt.Errorf("failed to delete body: %w", err)
+	}
+	return nil
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.file.Close()
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+	return n, err
 }
@@ -0,0 +1,98 @@
+package collect
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	Stats struct {
+		Type string
+
+		Events map[string]uint64
+
+		ProcessingCount   uint64
+		ProcessingSeconds float64
+		ProcessingBuckets []uint64
+
+		CacheHits   uint64
+		CacheMisses uint64
+	}
+
+	collectorStats struct {
+		mu      *sync.Mutex
+		events  map[string]uint64
+		count   uint64
+		seconds float64
+		buckets []uint64
+
+		cacheHits   *atomic.Uint64
+		cacheMisses *atomic.Uint64
+	}
+)
+
+var ProcessingBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+func newCollectorStats() *collectorStats {
+	return &collectorStats{
+		mu:          &sync.Mutex{},
+		events:      map[string]uint64{},
+		buckets:     make([]uint64, len(ProcessingBuckets)),
+		cacheHits:   &atomic.Uint64{},
+		cacheMisses: &atomic.Uint64{},
+	}
+}
+
+func (s *collectorStats) event(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events[event]++
+}
+
+func (s *collectorStats) processed(elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	s.seconds += elapsed.Seconds()
+	for i, le := range ProcessingBuckets {
+		if elapsed.Seconds() <= le {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (c *Collector) Stats() *Stats {
+	s := c.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make(map[string]uint64, len(s.events))
+	for k, v := range s.events {
+		events[k] = v
+	}
+
+	return &Stats{
+		Type:              c.typ,
+		Events:            events,
+		ProcessingCount:   s.count,
+		ProcessingSeconds: s.seconds,
+		ProcessingBuckets: append([]uint64{}, s.buckets...),
+		CacheHits:         s.cacheHits.Load(),
+		CacheMisses:       s.cacheMisses.Load(),
+	}
+}
+
+func (c *Collector) StorageBytes() (int64, error) {
+	var used int64
+	for _, ent := range c.List() {
+		size, err := c.store.FileSize(ent.Snapshot.ID)
+		if err != nil {
+			return 0, err
+		}
+		used += size
+	}
+	return used, nil
+}
@@ -0,0 +1,137 @@
+package collect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+)
+
+type (
+	View struct {
+		Name      string
+		Processor Processor
+		Deriver   Deriver
+	}
+
+	Deriver interface {
+		Derive(ctx context.Context, snapshot *Snapshot, processed io.Reader) (io.ReadCloser, error)
+	}
+
+	viewProcessor struct {
+		view   *View
+		source Processor
+	}
+)
+
+const viewScopePrefix = "view-"
+
+var ErrNoSuchView = errors.New("no such view")
+
+func (p *viewProcessor) Cacheable() bool {
+	return p.view.Deriver != nil || p.view.Processor.Cacheable()
+}
+
+func (p *viewProcessor) CacheScope() string {
+	if p.view.Processor != nil {
+		if scoped, ok := p.view.Processor.(CacheScoped); ok && scoped.CacheScope() != "" {
+			return scoped.CacheScope() + "." + viewScopePrefix + p.view.Name
+		}
+	}
+	return viewScopePrefix + p.view.Name
+}
+
+func (p *viewProcessor) Process(ctx context.Context, snapshot *Snapshot) (io.ReadCloser, error) {
+	if p.view.Deriver == nil {
+		return p.view.Processor.Process(ctx, snapshot)
+	}
+
+	r, err := p.source.Process(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return p.view.Deriver.Derive(ctx, snapshot, r)
+}
+
+func (p *viewProcessor) Purge(snapshot *Snapshot) error {
+	if purger, ok := p.view.Processor.(Purger); ok {
+		return purger.Purge(snapshot)
+	}
+	return nil
+}
+
+func (c *Collector) initViews(processor Processor, views []*View) {
+	c.views = map[string]*cachedProcessor{}
+	for _, v := range views {
+		if v.Processor == processor && v.Deriver == nil {
+			c.views[v.Name] = c.processor
+			continue
+		}
+		c.views[v.Name] = newCachedProcessor(&viewProcessor{view: v, source: c.processor}, c.store, c.stats)
+	}
+}
+
+func (c *Collector) Views() []string {
+	names := make([]string, 0, len(c.views))
+	for name := range c.views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Collector) HasView(name string) bool {
+	_, ok := c.views[name]
+	return ok
+}
+
+func (c *Collector) View(id string, name string) (io.ReadCloser, error) {
+	p, ok := c.views[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNoSuchView, name)
+	}
+
+	ent, err := c.entry(id)
+	if err != nil {
+		return nil, err
+	}
+	if ent.Status == StatusCorrupt {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, id)
+	}
+
+	c.touch(id)
+	return p.Process(context.Background(), ent.Snapshot)
+}
+
+func (c *Collector) processViews(ctx context.Context, snapshot *Snapshot) {
+	for name, p := range c.views {
+		if p == c.processor || !p.internal.Cacheable() {
+			continue
+		}
+
+		r, err := p.Process(ctx, snapshot)
+		if err != nil {
+			log.Printf("[!] failed to process view %v of %v: %v", name, snapshot.ID, err)
+			continue
+		}
+		r.Close()
+	}
+}
+
+func (c *Collector) purge(snapshot *Snapshot) error {
+	if err := c.processor.Purge(snapshot); err != nil {
+		return err
+	}
+	for name, p := range c.views {
+		if p == c.processor {
+			continue
+		}
+		if err := p.Purge(snapshot); err != nil {
+			return fmt.Errorf("failed to purge view %v: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -1,25 +1,71 @@
 package event
 
 import (
+	"sync"
+
 	"github.com/alexandrevicenzi/go-sse"
 	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
 )
 
 type (
 	Hub struct {
 		server *sse.Server
+		deltas *sse.Server
+
+		mu      *sync.RWMutex
+		sockets map[*socket]struct{}
 	}
 )
 
+const (
+	EventMessage = "message"
+	EventEntry   = "entry"
+
+	FormatDelta = "delta"
+)
+
 func NewHub() *Hub {
 	return &Hub{
-		server: sse.NewServer(&sse.Options{}),
+		server:  sse.NewServer(&sse.Options{}),
+		deltas:  sse.NewServer(&sse.Options{}),
+		mu:      &sync.RWMutex{},
+		sockets: map[*socket]struct{}{},
 	}
 }
 
 func (h *Hub) RegisterHandlers(g *echo.Group) {
-	g.GET("", echo.WrapHandler(h.server))
+	g.GET("", h.serveEvents)
+	g.GET("/ws", echo.WrapHandler(websocket.Server{Handler: h.serveSocket}))
+}
+func (h *Hub) serveEvents(c echo.Context) error {
+	if c.QueryParam("format") == FormatDelta {
+		h.deltas.ServeHTTP(c.Response(), c.Request())
+	} else {
+		h.server.ServeHTTP(c.Response(), c.Request())
+	}
+	return nil
 }
+
 func (h *Hub) Publish(message []byte) {
 	h.server.SendMessage("", sse.SimpleMessage(string(message)))
+	h.deltas.SendMessage("", sse.SimpleMessage(string(message)))
+	h.broadcast(&Message{Event: EventMessage, Data: message})
+}
+func (h *Hub) PublishEvent(event string, message []byte) {
+	h.server.SendMessage("", sse.NewMessage("", string(message), event))
+	h.deltas.SendMessage("", sse.NewMessage("", string(message), event))
+	h.broadcast(&Message{Event: event, Data: message})
+}
+func (h *Hub) PublishEntry(typ string, message []byte, delta []byte) {
+	h.server.SendMessage("", sse.SimpleMessage(string(message)))
+	h.deltas.SendMessage("", sse.NewMessage("", string(delta), EventEntry))
+	h.broadcast(&Message{Event: EventEntry, Type: typ, Data: delta})
+}
+
+func (h *Hub) Clients() (int, int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.server.ClientCount() + h.deltas.ClientCount(), len(h.sockets)
 }
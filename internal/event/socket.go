@@ -0,0 +1,139 @@
+package event
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"golang.org/x/net/websocket"
+)
+
+type (
+	Message struct {
+		Event string
+		Type  string `json:",omitempty"`
+		Data  json.RawMessage
+	}
+
+	Subscription struct {
+		Events []string
+		Types  []string
+	}
+
+	socket struct {
+		send chan []byte
+
+		mu     *sync.RWMutex
+		events map[string]bool
+		types  map[string]bool
+	}
+)
+
+const socketBuffer = 64
+
+func (h *Hub) serveSocket(ws *websocket.Conn) {
+	defer ws.Close()
+
+	query := ws.Request().URL.Query()
+	s := &socket{send: make(chan []byte, socketBuffer), mu: &sync.RWMutex{}}
+	s.subscribe(&Subscription{Events: splitList(query.Get("events")), Types: splitList(query.Get("types"))})
+
+	h.mu.Lock()
+	h.sockets[s] = struct{}{}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			sub := &Subscription{}
+			if err := websocket.JSON.Receive(ws, sub); err != nil {
+				return
+			}
+			s.subscribe(sub)
+		}
+	}()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.sockets, s)
+		h.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case data, ok := <-s.send:
+			if !ok {
+				return
+			}
+			if _, err := ws.Write(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (h *Hub) broadcast(msg *Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.sockets) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("failed to serialize event: %v", err)
+		return
+	}
+
+	for s := range h.sockets {
+		if !s.wants(msg) {
+			continue
+		}
+		select {
+		case s.send <- data:
+		default:
+			log.Printf("[!] websocket client is too slow, dropping %v event", msg.Event)
+		}
+	}
+}
+
+func (s *socket) subscribe(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = toSet(sub.Events)
+	s.types = toSet(sub.Types)
+}
+
+func (s *socket) wants(msg *Message) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.events) > 0 && !s.events[msg.Event] {
+		return false
+	}
+	if len(s.types) > 0 && msg.Type != "" && !s.types[msg.Type] {
+		return false
+	}
+	return true
+}
+
+func toSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
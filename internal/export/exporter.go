@@ -0,0 +1,440 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/persistent"
+	"github.com/kaz/pprotein/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Exporter struct {
+		config    *persistent.Handler
+		validator *validator.Validate
+		registry  *collect.Registry
+		client    *http.Client
+
+		mu      *sync.RWMutex
+		targets []*Target
+
+		queue chan *export
+	}
+
+	Target struct {
+		Name        string `validate:"required"`
+		Kind        string `validate:"required,oneof=pyroscope parca"`
+		URL         string `validate:"required,url"`
+		Application string
+		BearerToken string
+		Types       []string
+		Labels      map[string]string
+		Disabled    bool
+	}
+
+	publicTarget struct {
+		*Target
+
+		BearerToken collect.Secret
+	}
+
+	export struct {
+		target   *Target
+		snapshot *collect.Snapshot
+	}
+
+	parcaWriteRaw struct {
+		Series []*parcaSeries `json:"series"`
+	}
+	parcaSeries struct {
+		Labels  *parcaLabelSet `json:"labels"`
+		Samples []*parcaSample `json:"samples"`
+	}
+	parcaLabelSet struct {
+		Labels []*parcaLabel `json:"labels"`
+	}
+	parcaLabel struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	parcaSample struct {
+		RawProfile string `json:"rawProfile"`
+	}
+)
+
+const (
+	KindPyroscope = "pyroscope"
+	KindParca     = "parca"
+
+	defaultApplication = "pprotein"
+
+	exportQueue    = 64
+	exportWorkers  = 1
+	exportAttempts = 3
+	exportBackoff  = 2 * time.Second
+	exportTimeout  = 30 * time.Second
+)
+
+var (
+	profileTypes = map[string]bool{"pprof": true, "fgprof": true, "block": true, "mutex": true}
+
+	pyroscopeInvalid = regexp.MustCompile(`[^0-9A-Za-z_.\-]`)
+)
+
+func New(store storage.Storage, registry *collect.Registry) (*Exporter, error) {
+	e := &Exporter{
+		validator: validator.New(),
+		registry:  registry,
+		client:    &http.Client{Timeout: exportTimeout},
+		mu:        &sync.RWMutex{},
+		queue:     make(chan *export, exportQueue),
+	}
+
+	config, err := persistent.New(store, "exporters.json", []byte("[]"), e.sanitize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporters: %w", err)
+	}
+	e.config = config
+	e.config.OnUpdate(e.reload)
+	e.reload()
+
+	for i := 0; i < exportWorkers; i++ {
+		go e.work()
+	}
+	return e, nil
+}
+
+func (e *Exporter) RegisterHandlers(g *echo.Group) {
+	g.GET("", e.getTargets)
+	g.POST("", e.config.HandlePost)
+	g.POST("/:name/:type/:id", e.postExport)
+}
+
+func (e *Exporter) getTargets(c echo.Context) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	resp := make([]*publicTarget, 0, len(e.targets))
+	for _, target := range e.targets {
+		resp = append(resp, &publicTarget{Target: target, BearerToken: collect.Secret(target.BearerToken)})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (e *Exporter) sanitize(raw []byte) ([]byte, error) {
+	targets := []*Target{}
+	if err := json.Unmarshal(raw, &targets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	if err := e.validator.Var(targets, "dive"); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	stored := map[string]string{}
+	e.mu.RLock()
+	for _, target := range e.targets {
+		stored[target.Name] = target.BearerToken
+	}
+	e.mu.RUnlock()
+
+	seen := map[string]bool{}
+	for _, target := range targets {
+		if seen[target.Name] {
+			return nil, fmt.Errorf("duplicated exporter name: %v", target.Name)
+		}
+		seen[target.Name] = true
+
+		if target.BearerToken == collect.Redacted {
+			target.BearerToken = stored[target.Name]
+		}
+
+		for _, typ := range target.Types {
+			if !profileTypes[typ] {
+				return nil, fmt.Errorf("type cannot be exported by %v: %v", target.Name, typ)
+			}
+		}
+	}
+
+	res, err := json.MarshalIndent(targets, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	return res, nil
+}
+
+func (e *Exporter) reload() {
+	raw, err := e.config.GetContent()
+	if err != nil {
+		log.Printf("[!] failed to load exporters: %v", err)
+		return
+	}
+
+	targets := []*Target{}
+	if err := json.Unmarshal(raw, &targets); err != nil {
+		log.Printf("[!] failed to load exporters: %v", err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.targets = targets
+}
+
+func (e *Exporter) Notify(event string, entry *collect.Entry) {
+	if event != collect.EventProcessingCompleted || !profileTypes[entry.Snapshot.Type] {
+		return
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, target := range e.targets {
+		if target.matches(entry.Snapshot.Type) {
+			e.enqueue(&export{target: target, snapshot: entry.Snapshot})
+		}
+	}
+}
+
+func (e *Exporter) enqueue(ex *export) {
+	select {
+	case e.queue <- ex:
+	default:
+		log.Printf("[!] export queue is full, dropping %v for %v", ex.snapshot.ID, ex.target.Name)
+	}
+}
+
+func (e *Exporter) work() {
+	for ex := range e.queue {
+		var err error
+		for attempt := 1; attempt <= exportAttempts; attempt++ {
+			if err = e.Export(ex.target, ex.snapshot); err == nil {
+				break
+			}
+			if attempt < exportAttempts {
+				time.Sleep(exportBackoff * time.Duration(attempt))
+			}
+		}
+		if err != nil {
+			log.Printf("[!] failed to export %v to %v: %v", ex.snapshot.ID, ex.target.Name, err)
+		}
+	}
+}
+
+func (e *Exporter) Export(target *Target, snapshot *collect.Snapshot) error {
+	collector, ok := e.registry.Get(snapshot.Type)
+	if !ok {
+		return fmt.Errorf("unknown type: %v", snapshot.Type)
+	}
+
+	r, err := collector.Raw(snapshot.ID)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	profile, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var req *http.Request
+	switch target.Kind {
+	case KindPyroscope:
+		req, err = pyroscopeRequest(target, snapshot, profile)
+	case KindParca:
+		req, err = parcaRequest(target, snapshot, profile)
+	default:
+		err = fmt.Errorf("unknown exporter kind: %v", target.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	if target.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+target.BearerToken)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func pyroscopeRequest(target *Target, snapshot *collect.Snapshot, profile []byte) (*http.Request, error) {
+	labels := exportLabels(target, snapshot)
+	pairs := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		pairs = append(pairs, pyroscopeInvalid.ReplaceAllString(k, "_")+"="+pyroscopeInvalid.ReplaceAllString(labels[k], "_"))
+	}
+	name := fmt.Sprintf("%s{%s}", pyroscopeInvalid.ReplaceAllString(application(target), "_"), strings.Join(pairs, ","))
+
+	from, until := profileWindow(snapshot)
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("from", strconv.FormatInt(from.Unix(), 10))
+	query.Set("until", strconv.FormatInt(until.Unix(), 10))
+	query.Set("format", "pprof")
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form: %w", err)
+	}
+	if _, err := part.Write(profile); err != nil {
+		return nil, fmt.Errorf("failed to write form: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write form: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(target.URL, "/")+"/ingest?"+query.Encode(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req, nil
+}
+
+func parcaRequest(target *Target, snapshot *collect.Snapshot, profile []byte) (*http.Request, error) {
+	labels := exportLabels(target, snapshot)
+	labels["__name__"] = snapshot.Type
+	labels["job"] = application(target)
+
+	set := &parcaLabelSet{Labels: []*parcaLabel{}}
+	for _, k := range sortedKeys(labels) {
+		set.Labels = append(set.Labels, &parcaLabel{Name: k, Value: labels[k]})
+	}
+
+	body, err := json.Marshal(&parcaWriteRaw{Series: []*parcaSeries{{
+		Labels:  set,
+		Samples: []*parcaSample{{RawProfile: base64.StdEncoding.EncodeToString(profile)}},
+	}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(target.URL, "/")+"/profiles/writeraw", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func exportLabels(target *Target, snapshot *collect.Snapshot) map[string]string {
+	labels := map[string]string{"type": snapshot.Type}
+	if t := snapshot.SnapshotTarget; t != nil {
+		for k, v := range t.Tags {
+			labels[k] = v
+		}
+		if t.Label != "" {
+			labels["target"] = t.Label
+		}
+		if t.RunId != "" {
+			labels["run_id"] = t.RunId
+		}
+		if t.GroupId != "" {
+			labels["group_id"] = t.GroupId
+		}
+	}
+	for k, v := range target.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+func profileWindow(snapshot *collect.Snapshot) (time.Time, time.Time) {
+	duration := time.Second
+	if snapshot.SnapshotTarget != nil && snapshot.Duration > 0 {
+		duration = time.Duration(snapshot.Duration) * time.Second
+	}
+	return snapshot.Datetime, snapshot.Datetime.Add(duration)
+}
+
+func application(target *Target) string {
+	if target.Application != "" {
+		return target.Application
+	}
+	return defaultApplication
+}
+
+func (t *Target) matches(typ string) bool {
+	if t.Disabled {
+		return false
+	}
+	if len(t.Types) == 0 {
+		return true
+	}
+	for _, v := range t.Types {
+		if v == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Exporter) postExport(c echo.Context) error {
+	e.mu.RLock()
+	var target *Target
+	for _, t := range e.targets {
+		if t.Name == c.Param("name") {
+			target = t
+		}
+	}
+	e.mu.RUnlock()
+
+	if target == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no such exporter: %v", c.Param("name")))
+	}
+	if !profileTypes[c.Param("type")] {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("type cannot be exported: %v", c.Param("type")))
+	}
+
+	collector, ok := e.registry.Get(c.Param("type"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("unknown type: %v", c.Param("type")))
+	}
+	snapshot, err := collector.Snapshot(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	if err := e.Export(target, snapshot); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("failed to export: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,226 @@
+package accesslog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	Config struct {
+		Sort           string   `yaml:"sort"`
+		Reverse        bool     `yaml:"reverse"`
+		Percentiles    []int    `yaml:"percentiles"`
+		MatchingGroups []string `yaml:"matching_groups"`
+
+		TemplateRoutes bool `yaml:"-"`
+	}
+
+	stat struct {
+		method string
+		uri    string
+		status [5]int
+		times  []float64
+		bodies []float64
+	}
+)
+
+var defaultPercentiles = []int{90, 95, 99}
+
+func Aggregate(r io.Reader, format string, cfg *Config) ([]byte, error) {
+	p, err := newParser(format)
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := newRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	percentiles := cfg.Percentiles
+	if len(percentiles) == 0 {
+		percentiles = defaultPercentiles
+	}
+
+	index := map[string]*stat{}
+	stats := []*stat{}
+	err = parse(r, p, func(rec *record) {
+		uri := route(rec.URI)
+
+		key := rec.Method + " " + uri
+		st, ok := index[key]
+		if !ok {
+			st = &stat{method: rec.Method, uri: uri}
+			index[key] = st
+			stats = append(stats, st)
+		}
+		if class := rec.Status/100 - 1; class >= 0 && class < len(st.status) {
+			st.status[class]++
+		}
+		st.times = append(st.times, rec.ResponseTime)
+		st.bodies = append(st.bodies, rec.BodyBytes)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse access log: %w", err)
+	}
+
+	for _, st := range stats {
+		sort.Float64s(st.times)
+		sort.Float64s(st.bodies)
+	}
+	if err := sortStats(stats, cfg.Sort, cfg.Reverse); err != nil {
+		return nil, err
+	}
+	return render(stats, percentiles), nil
+}
+
+func newRouter(cfg *Config) (func(string) string, error) {
+	groups := make([]*regexp.Regexp, 0, len(cfg.MatchingGroups))
+	for _, pattern := range cfg.MatchingGroups {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matching group %q: %w", pattern, err)
+		}
+		groups = append(groups, re)
+	}
+
+	return func(uri string) string {
+		for _, re := range groups {
+			if re.MatchString(uri) {
+				return re.String()
+			}
+		}
+		if cfg.TemplateRoutes {
+			return templateRoute(uri)
+		}
+		return uri
+	}, nil
+}
+
+func sortStats(stats []*stat, key string, reverse bool) error {
+	value, err := sortValue(key)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if reverse {
+			a, b = b, a
+		}
+		if key == "uri" {
+			return a.uri < b.uri
+		}
+		if key == "method" {
+			return a.method < b.method
+		}
+		return value(a) < value(b)
+	})
+	return nil
+}
+
+func sortValue(key string) (func(*stat) float64, error) {
+	switch key {
+	case "", "count":
+		return func(s *stat) float64 { return float64(len(s.times)) }, nil
+	case "uri", "method":
+		return nil, nil
+	case "min":
+		return func(s *stat) float64 { return s.times[0] }, nil
+	case "max":
+		return func(s *stat) float64 { return s.times[len(s.times)-1] }, nil
+	case "sum":
+		return func(s *stat) float64 { return sum(s.times) }, nil
+	case "avg":
+		return func(s *stat) float64 { return sum(s.times) / float64(len(s.times)) }, nil
+	case "stddev":
+		return func(s *stat) float64 { return stddev(s.times) }, nil
+	case "min-body":
+		return func(s *stat) float64 { return s.bodies[0] }, nil
+	case "max-body":
+		return func(s *stat) float64 { return s.bodies[len(s.bodies)-1] }, nil
+	case "sum-body":
+		return func(s *stat) float64 { return sum(s.bodies) }, nil
+	case "avg-body":
+		return func(s *stat) float64 { return sum(s.bodies) / float64(len(s.bodies)) }, nil
+	}
+
+	if n, err := strconv.Atoi(strings.TrimPrefix(key, "p")); err == nil && strings.HasPrefix(key, "p") {
+		return func(s *stat) float64 { return percentile(s.times, n) }, nil
+	}
+	return nil, fmt.Errorf("unsupported sort key: %v", key)
+}
+
+func render(stats []*stat, percentiles []int) []byte {
+	header := []string{"Count", "1xx", "2xx", "3xx", "4xx", "5xx", "Method", "Uri", "Min", "Max", "Sum", "Avg"}
+	for _, p := range percentiles {
+		header = append(header, fmt.Sprintf("P%d", p))
+	}
+	header = append(header, "Stddev", "Min(Body)", "Max(Body)", "Sum(Body)", "Avg(Body)")
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(strings.Join(header, "\t") + "\n")
+	for _, st := range stats {
+		n := float64(len(st.times))
+		row := []string{strconv.Itoa(len(st.times))}
+		for _, c := range st.status {
+			row = append(row, strconv.Itoa(c))
+		}
+		row = append(row, st.method, st.uri,
+			seconds(st.times[0]),
+			seconds(st.times[len(st.times)-1]),
+			seconds(sum(st.times)),
+			seconds(sum(st.times)/n),
+		)
+		for _, p := range percentiles {
+			row = append(row, seconds(percentile(st.times, p)))
+		}
+		row = append(row,
+			seconds(stddev(st.times)),
+			seconds(st.bodies[0]),
+			seconds(st.bodies[len(st.bodies)-1]),
+			seconds(sum(st.bodies)),
+			seconds(sum(st.bodies)/n),
+		)
+		buf.WriteString(strings.Join(row, "\t") + "\n")
+	}
+	return buf.Bytes()
+}
+
+func seconds(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func stddev(values []float64) float64 {
+	avg := sum(values) / float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - avg) * (v - avg)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
+
+func percentile(sorted []float64, p int) float64 {
+	idx := int(math.Ceil(float64(len(sorted))*float64(p)/100)) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,94 @@
+package accesslog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	echoRecord struct {
+		Time     string   `json:"time"`
+		Method   string   `json:"method"`
+		URI      string   `json:"uri"`
+		Status   int      `json:"status"`
+		Latency  *float64 `json:"latency"`
+		BytesOut float64  `json:"bytes_out"`
+	}
+)
+
+const (
+	PresetEcho = "echo"
+	PresetGin  = "gin"
+)
+
+var (
+	ginPattern  = regexp.MustCompile(`^\[GIN\] ([^|]+?)\s*\|\s*(\d{3})\s*\|\s*(\S+)\s*\|[^|]*\|\s*(\S+)\s+"([^"]*)"`)
+	ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+	idSegmentPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^\d+$`),
+		regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+		regexp.MustCompile(`^[0-9a-fA-F]{16,}$`),
+	}
+)
+
+func IsMiddleware(format string) bool {
+	return format == PresetEcho || format == PresetGin
+}
+
+func parseEcho(line string) (*record, error) {
+	entry := &echoRecord{}
+	if err := json.Unmarshal([]byte(line), entry); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if entry.Latency == nil {
+		return nil, fmt.Errorf("latency is missing")
+	}
+
+	rec, err := newRecord(entry.Method, entry.URI, strconv.Itoa(entry.Status), "", "")
+	if err != nil {
+		return nil, err
+	}
+	rec.ResponseTime = *entry.Latency / float64(time.Second)
+	rec.BodyBytes = entry.BytesOut
+	rec.Time = parseTime(entry.Time)
+	return rec, nil
+}
+
+func parseGin(line string) (*record, error) {
+	m := ginPattern.FindStringSubmatch(ansiPattern.ReplaceAllString(line, ""))
+	if m == nil {
+		return nil, fmt.Errorf("line does not match gin log format")
+	}
+
+	latency, err := time.ParseDuration(m[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid latency: %v", m[3])
+	}
+
+	rec, err := newRecord(m[4], m[5], m[2], "", "")
+	if err != nil {
+		return nil, err
+	}
+	rec.ResponseTime = latency.Seconds()
+	rec.Time = parseTime(m[1])
+	return rec, nil
+}
+
+func templateRoute(uri string) string {
+	segments := strings.Split(uri, "/")
+	for i, seg := range segments {
+		for _, re := range idSegmentPatterns {
+			if re.MatchString(seg) {
+				segments[i] = ":id"
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
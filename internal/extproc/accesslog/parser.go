@@ -0,0 +1,177 @@
+package accesslog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	record struct {
+		Method       string
+		URI          string
+		Status       int
+		ResponseTime float64
+		BodyBytes    float64
+		Time         time.Time
+	}
+
+	parser func(line string) (*record, error)
+)
+
+var combinedPattern = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "(\S*) ?(\S*)[^"]*" (\d{3}) (\S+)(?: "[^"]*" "[^"]*")?(?: (\S+))?`)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"02/Jan/2006:15:04:05 -0700",
+	"2006/01/02 - 15:04:05",
+}
+
+func newParser(format string) (parser, error) {
+	switch format {
+	case "", "ltsv", PresetNginxLTSV:
+		return parseLTSV, nil
+	case "json":
+		return parseJSON, nil
+	case "regexp", "combined", PresetNginxCombined:
+		return parseCombined, nil
+	case PresetEnvoy:
+		return parseEnvoy, nil
+	case PresetCaddy:
+		return parseCaddy, nil
+	case PresetEcho:
+		return parseEcho, nil
+	case PresetGin:
+		return parseGin, nil
+	}
+	return nil, fmt.Errorf("unsupported log format: %v", format)
+}
+
+func parseLTSV(line string) (*record, error) {
+	fields := map[string]string{}
+	for _, field := range strings.Split(line, "\t") {
+		if k, v, ok := strings.Cut(field, ":"); ok {
+			fields[k] = v
+		}
+	}
+
+	reqtime := fields["reqtime"]
+	if reqtime == "" {
+		reqtime = fields["apptime"]
+	}
+
+	rec, err := newRecord(fields["method"], fields["uri"], fields["status"], reqtime, fields["size"])
+	if err != nil {
+		return nil, err
+	}
+	rec.Time = parseTime(fields["time"])
+	return rec, nil
+}
+
+func parseJSON(line string) (*record, error) {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	str := func(key string) string {
+		switch v := fields[key].(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return ""
+	}
+	rec, err := newRecord(str("method"), str("uri"), str("status"), str("response_time"), str("body_bytes"))
+	if err != nil {
+		return nil, err
+	}
+	rec.Time = parseTime(str("time"))
+	return rec, nil
+}
+
+func parseCombined(line string) (*record, error) {
+	m := combinedPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("line does not match combined log format")
+	}
+	rec, err := newRecord(m[2], m[3], m[4], m[6], m[5])
+	if err != nil {
+		return nil, err
+	}
+	rec.Time = parseTime(m[1])
+	return rec, nil
+}
+
+func newRecord(method, uri, status, reqtime, size string) (*record, error) {
+	if uri == "" {
+		return nil, fmt.Errorf("uri is missing")
+	}
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		uri = uri[:i]
+	}
+
+	r := &record{Method: method, URI: uri}
+
+	var err error
+	if r.Status, err = strconv.Atoi(status); err != nil {
+		return nil, fmt.Errorf("invalid status: %v", status)
+	}
+	if reqtime != "" && reqtime != "-" {
+		if r.ResponseTime, err = strconv.ParseFloat(reqtime, 64); err != nil {
+			return nil, fmt.Errorf("invalid response time: %v", reqtime)
+		}
+	}
+	if size != "" && size != "-" {
+		if r.BodyBytes, err = strconv.ParseFloat(size, 64); err != nil {
+			return nil, fmt.Errorf("invalid body size: %v", size)
+		}
+	}
+	return r, nil
+}
+
+func parseTime(value string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func parse(r io.Reader, p parser, fn func(*record)) error {
+	var parsed, skipped int
+	var lastErr error
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		rec, err := p(line)
+		if err != nil {
+			skipped++
+			lastErr = err
+			continue
+		}
+		parsed++
+		fn(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if parsed == 0 && skipped > 0 {
+		return fmt.Errorf("no parsable lines (%d skipped): %w", skipped, lastErr)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package accesslog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	caddyRecord struct {
+		Timestamp float64 `json:"ts"`
+		Request   *struct {
+			Method string `json:"method"`
+			URI    string `json:"uri"`
+		} `json:"request"`
+		Duration float64 `json:"duration"`
+		Size     float64 `json:"size"`
+		Status   int     `json:"status"`
+	}
+)
+
+const (
+	PresetNginxCombined = "nginx-combined"
+	PresetNginxLTSV     = "nginx-ltsv"
+	PresetEnvoy         = "envoy"
+	PresetCaddy         = "caddy"
+)
+
+const detectLines = 10
+
+var ErrEmptyLog = errors.New("log is empty")
+
+var (
+	envoyPattern = regexp.MustCompile(`^\[([^\]]+)\] "(\S*) (\S*)[^"]*" (\d+) \S+ \S+ (\S+) (\S+)`)
+
+	detectOrder = []string{PresetCaddy, PresetEcho, "json", PresetGin, PresetNginxLTSV, PresetEnvoy, PresetNginxCombined}
+)
+
+func IsPreset(format string) bool {
+	switch format {
+	case PresetNginxCombined, PresetNginxLTSV, PresetEnvoy, PresetCaddy, PresetEcho, PresetGin:
+		return true
+	}
+	return false
+}
+
+func Detect(r io.Reader) (string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() && len(lines) < detectLines {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read log: %w", err)
+	}
+	if len(lines) == 0 {
+		return "", ErrEmptyLog
+	}
+
+	for _, format := range detectOrder {
+		p, _ := newParser(format)
+
+		matched := 0
+		for _, line := range lines {
+			if _, err := p(line); err == nil {
+				matched++
+			}
+		}
+		if matched*2 > len(lines) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown log format")
+}
+
+func parseEnvoy(line string) (*record, error) {
+	m := envoyPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("line does not match envoy log format")
+	}
+
+	rec, err := newRecord(m[2], m[3], m[4], "", m[5])
+	if err != nil {
+		return nil, err
+	}
+	if m[6] != "-" {
+		ms, err := strconv.ParseFloat(m[6], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration: %v", m[6])
+		}
+		rec.ResponseTime = ms / 1000
+	}
+	rec.Time = parseTime(m[1])
+	return rec, nil
+}
+
+func parseCaddy(line string) (*record, error) {
+	entry := &caddyRecord{}
+	if err := json.Unmarshal([]byte(line), entry); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if entry.Request == nil {
+		return nil, fmt.Errorf("not an access log entry")
+	}
+
+	rec, err := newRecord(entry.Request.Method, entry.Request.URI, strconv.Itoa(entry.Status), "", "")
+	if err != nil {
+		return nil, err
+	}
+	rec.ResponseTime = entry.Duration
+	rec.BodyBytes = entry.Size
+	if entry.Timestamp > 0 {
+		sec, frac := math.Modf(entry.Timestamp)
+		rec.Time = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	}
+	return rec, nil
+}
@@ -0,0 +1,135 @@
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+type (
+	TimeSeries struct {
+		Bucket    float64
+		Start     time.Time
+		End       time.Time
+		Total     []*SeriesPoint
+		Endpoints []*EndpointSeries
+	}
+
+	EndpointSeries struct {
+		Method string
+		Uri    string
+		Count  int
+		Points []*SeriesPoint
+	}
+
+	SeriesPoint struct {
+		Time  time.Time
+		Count int
+		RPS   float64
+		P95   float64
+	}
+
+	bucketedStat struct {
+		method  string
+		uri     string
+		count   int
+		buckets map[int64][]float64
+	}
+)
+
+const maxBuckets = 10000
+
+func Series(r io.Reader, format string, cfg *Config, bucket time.Duration) (*TimeSeries, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("bucket must be positive")
+	}
+
+	p, err := newParser(format)
+	if err != nil {
+		return nil, err
+	}
+	route, err := newRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	total := &bucketedStat{buckets: map[int64][]float64{}}
+	index := map[string]*bucketedStat{}
+	stats := []*bucketedStat{}
+	var first, last int64
+	err = parse(r, p, func(rec *record) {
+		if rec.Time.IsZero() {
+			return
+		}
+
+		slot := rec.Time.UnixNano() / int64(bucket)
+		if total.count == 0 || slot < first {
+			first = slot
+		}
+		if total.count == 0 || slot > last {
+			last = slot
+		}
+
+		uri := route(rec.URI)
+		key := rec.Method + " " + uri
+		st, ok := index[key]
+		if !ok {
+			st = &bucketedStat{method: rec.Method, uri: uri, buckets: map[int64][]float64{}}
+			index[key] = st
+			stats = append(stats, st)
+		}
+		for _, s := range []*bucketedStat{st, total} {
+			s.count++
+			s.buckets[slot] = append(s.buckets[slot], rec.ResponseTime)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse access log: %w", err)
+	}
+	if total.count == 0 {
+		return nil, fmt.Errorf("no timestamped requests found")
+	}
+	if n := last - first + 1; n > maxBuckets {
+		return nil, fmt.Errorf("too many buckets: %d", n)
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].count > stats[j].count
+	})
+
+	series := &TimeSeries{
+		Bucket: bucket.Seconds(),
+		Start:  time.Unix(0, first*int64(bucket)).UTC(),
+		End:    time.Unix(0, (last+1)*int64(bucket)).UTC(),
+		Total:  total.points(first, last, bucket),
+	}
+	for _, st := range stats {
+		series.Endpoints = append(series.Endpoints, &EndpointSeries{
+			Method: st.method,
+			Uri:    st.uri,
+			Count:  st.count,
+			Points: st.points(first, last, bucket),
+		})
+	}
+	return series, nil
+}
+
+func (s *bucketedStat) points(first, last int64, bucket time.Duration) []*SeriesPoint {
+	points := make([]*SeriesPoint, 0, last-first+1)
+	for slot := first; slot <= last; slot++ {
+		times := s.buckets[slot]
+		sort.Float64s(times)
+
+		point := &SeriesPoint{
+			Time:  time.Unix(0, slot*int64(bucket)).UTC(),
+			Count: len(times),
+			RPS:   float64(len(times)) / bucket.Seconds(),
+		}
+		if len(times) > 0 {
+			point.P95 = percentile(times, 95)
+		}
+		points = append(points, point)
+	}
+	return points
+}
@@ -0,0 +1,24 @@
+package alp
+
+import (
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/extproc"
+	"github.com/labstack/echo/v4"
+)
+
+var diffSpec = &extproc.DiffSpec{
+	Keys:   []string{"Method", "Uri"},
+	Weight: []string{"Count"},
+	Metrics: []*extproc.DiffMetric{
+		{Columns: []string{"Count"}, Additive: true},
+		{Columns: []string{"Avg"}},
+		{Columns: []string{"P95"}},
+		{Columns: []string{"Sum"}, Additive: true},
+	},
+	Indicator: 2,
+	View:      "diff",
+}
+
+func (p *processor) getDiff(c echo.Context, collector *collect.Collector) error {
+	return extproc.ServeTableDiff(c, collector, diffSpec)
+}
@@ -39,7 +39,16 @@ func NewHandler(opts *collect.Options, store storage.Storage) (*handler, error)
 func (h *handler) Register(g *echo.Group) error {
 	h.config.RegisterHandlers(g.Group("/config"))
 
-	if err := extproc.NewHandler(&processor{confPath: h.config.GetPath()}, h.opts).Register(g); err != nil {
+	p := &processor{config: h.config}
+
+	opts := *h.opts
+	opts.Views = []*collect.View{
+		{Name: "alp", Processor: p},
+		{Name: timeSeriesView, Processor: &timeSeriesProcessor{p}},
+		{Name: diffSpec.View, Deriver: extproc.DiffIndex(diffSpec)},
+	}
+
+	if err := extproc.NewHandler(p, &opts).Register(g); err != nil {
 		return fmt.Errorf("failed to register extproc handlers: %w", err)
 	}
 	return nil
@@ -0,0 +1,147 @@
+package alp
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/extproc/accesslog"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	alpOptions struct {
+		Format         string
+		Sort           string
+		Reverse        *bool
+		Percentiles    []int
+		MatchingGroups []string
+		TemplateRoutes *bool
+	}
+)
+
+const (
+	defaultFormat  = "auto"
+	autoFormat     = "auto"
+	fallbackFormat = "ltsv"
+)
+
+var formats = map[string]bool{
+	"ltsv":                        true,
+	"json":                        true,
+	"regexp":                      true,
+	autoFormat:                    true,
+	accesslog.PresetNginxCombined: true,
+	accesslog.PresetNginxLTSV:     true,
+	accesslog.PresetEnvoy:         true,
+	accesslog.PresetCaddy:         true,
+}
+
+func parseOptions(snapshot *collect.Snapshot) (*alpOptions, error) {
+	opts := &alpOptions{}
+	if len(snapshot.ProcessorOptions) > 0 {
+		if err := json.Unmarshal(snapshot.ProcessorOptions, opts); err != nil {
+			return nil, fmt.Errorf("failed to parse alp options: %w", err)
+		}
+	}
+
+	if opts.Format == "" {
+		opts.Format = defaultFormat
+	}
+	if !formats[opts.Format] {
+		return nil, fmt.Errorf("unsupported alp format: %v", opts.Format)
+	}
+	return opts, nil
+}
+
+func (o *alpOptions) templateRoutes() bool {
+	if o.TemplateRoutes != nil {
+		return *o.TemplateRoutes
+	}
+	return accesslog.IsMiddleware(o.Format)
+}
+
+func (o *alpOptions) subcommand() (string, bool) {
+	if o.templateRoutes() {
+		return "", false
+	}
+	switch o.Format {
+	case "ltsv", "json", "regexp":
+		return o.Format, true
+	case accesslog.PresetNginxLTSV:
+		return "ltsv", true
+	}
+	return "", false
+}
+
+func (o *alpOptions) nativeConfig(basePath string) (*accesslog.Config, error) {
+	raw, err := o.config(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &accesslog.Config{}
+	if err := yaml.Unmarshal(raw, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	config.TemplateRoutes = o.templateRoutes()
+	return config, nil
+}
+
+func (o *alpOptions) overrides() bool {
+	return o.Sort != "" || o.Reverse != nil || len(o.Percentiles) > 0 || len(o.MatchingGroups) > 0
+}
+
+func (o *alpOptions) config(basePath string) ([]byte, error) {
+	raw, err := os.ReadFile(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if !o.overrides() {
+		return raw, nil
+	}
+
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if o.Sort != "" {
+		config["sort"] = o.Sort
+	}
+	if o.Reverse != nil {
+		config["reverse"] = *o.Reverse
+	}
+	if len(o.Percentiles) > 0 {
+		config["percentiles"] = o.Percentiles
+	}
+	if len(o.MatchingGroups) > 0 {
+		config["matching_groups"] = o.MatchingGroups
+	}
+
+	res, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize config: %w", err)
+	}
+	return res, nil
+}
+
+func (o *alpOptions) writeConfig(basePath string) (string, func(), error) {
+	res, err := o.config(basePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	file, err := os.CreateTemp("", "pprotein-alp-*.yml")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary config: %w", err)
+	}
+	defer file.Close()
+
+	cleanup := func() { os.Remove(file.Name()) }
+	if _, err := file.Write(res); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write temporary config: %w", err)
+	}
+	return file.Name(), cleanup, nil
+}
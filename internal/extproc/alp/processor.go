@@ -2,30 +2,103 @@ package alp
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os/exec"
 
+	"github.com/goccy/go-json"
 	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/extproc/accesslog"
+	"github.com/kaz/pprotein/internal/persistent"
 )
 
 type (
 	processor struct {
 		confPath string
+		config   *persistent.Handler
+	}
+
+	processorOptions struct {
+		Config string
 	}
 )
 
+func init() {
+	collect.RegisterProcessor("alp", newProcessor)
+}
+
+func newProcessor(raw json.RawMessage) (collect.Processor, error) {
+	opts := &processorOptions{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, opts); err != nil {
+			return nil, fmt.Errorf("failed to parse options: %w", err)
+		}
+	}
+	if opts.Config == "" {
+		return nil, fmt.Errorf("config path is required")
+	}
+	return &processor{confPath: opts.Config}, nil
+}
+
+func (p *processor) configPath() (string, error) {
+	if p.config != nil {
+		return p.config.GetPath()
+	}
+	return p.confPath, nil
+}
+
 func (p *processor) Cacheable() bool {
 	return true
 }
 
-func (p *processor) Process(snapshot *collect.Snapshot) (io.ReadCloser, error) {
-	bodyPath, err := snapshot.BodyPath()
+func (p *processor) Tabular() bool {
+	return true
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	opts, err := parseOptions(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Format == autoFormat {
+		if opts.Format, err = detectFormat(snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	subcommand, ok := opts.subcommand()
+	if !ok {
+		return p.processNative(snapshot, opts)
+	}
+	if _, err := exec.LookPath("alp"); err != nil {
+		return p.processNative(snapshot, opts)
+	}
+
+	confPath, err := p.configPath()
+	if err != nil {
+		return nil, err
+	}
+	if opts.overrides() {
+		path, cleanup, err := opts.writeConfig(confPath)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		confPath = path
+	}
+
+	body, err := snapshot.Open()
 	if err != nil {
-		return nil, fmt.Errorf("failed to find snapshot body: %w", err)
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
 	}
+	defer body.Close()
 
-	cmd := exec.Command("alp", "ltsv", "--config", p.confPath, "--format", "tsv", "--file", bodyPath)
+	cmd := exec.CommandContext(ctx, "alp", subcommand, "--config", confPath, "--format", "tsv")
+	cmd.Stdin = body
 
 	res, err := cmd.Output()
 	if err != nil {
@@ -34,3 +107,43 @@ func (p *processor) Process(snapshot *collect.Snapshot) (io.ReadCloser, error) {
 
 	return io.NopCloser(bytes.NewBuffer(res)), nil
 }
+
+func detectFormat(snapshot *collect.Snapshot) (string, error) {
+	body, err := snapshot.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	format, err := accesslog.Detect(body)
+	if err != nil {
+		if !errors.Is(err, accesslog.ErrEmptyLog) {
+			log.Printf("[!] failed to detect log format of %v, falling back to %v: %v", snapshot.ID, fallbackFormat, err)
+		}
+		return fallbackFormat, nil
+	}
+	return format, nil
+}
+
+func (p *processor) processNative(snapshot *collect.Snapshot, opts *alpOptions) (io.ReadCloser, error) {
+	confPath, err := p.configPath()
+	if err != nil {
+		return nil, err
+	}
+	config, err := opts.nativeConfig(confPath)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := snapshot.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	res, err := accesslog.Aggregate(body, opts.Format, config)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewBuffer(res)), nil
+}
@@ -0,0 +1,111 @@
+package alp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/extproc/accesslog"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	timeSeriesProcessor struct {
+		*processor
+	}
+)
+
+const (
+	timeSeriesView = "timeseries"
+	defaultBucket  = 5 * time.Second
+)
+
+func (p *processor) RegisterRoutes(g *echo.Group, collector *collect.Collector) {
+	g.GET("/:id/timeseries", func(c echo.Context) error {
+		return p.getTimeSeries(c, collector)
+	})
+	g.GET("/diff/:base/:target", func(c echo.Context) error {
+		return p.getDiff(c, collector)
+	})
+}
+
+func (p *timeSeriesProcessor) Tabular() bool {
+	return false
+}
+
+func (p *timeSeriesProcessor) CacheScope() string {
+	return timeSeriesView
+}
+
+func (p *timeSeriesProcessor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	series, err := p.timeSeries(snapshot, defaultBucket)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(series)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal time series: %w", err)
+	}
+	return io.NopCloser(bytes.NewReader(raw)), nil
+}
+
+func (p *processor) getTimeSeries(c echo.Context, collector *collect.Collector) error {
+	snapshot, err := collector.Snapshot(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	raw := c.QueryParam("bucket")
+	if raw == "" {
+		r, err := collector.View(snapshot.ID, timeSeriesView)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		return collect.ServeContent(c, echo.MIMEApplicationJSON, r)
+	}
+
+	sec, err := strconv.ParseFloat(raw, 64)
+	if err != nil || sec < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid bucket: %v", raw))
+	}
+	series, err := p.timeSeries(snapshot, time.Duration(sec*float64(time.Second)))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, series)
+}
+
+func (p *processor) timeSeries(snapshot *collect.Snapshot, bucket time.Duration) (*accesslog.TimeSeries, error) {
+	opts, err := parseOptions(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Format == autoFormat {
+		if opts.Format, err = detectFormat(snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	confPath, err := p.configPath()
+	if err != nil {
+		return nil, err
+	}
+	config, err := opts.nativeConfig(confPath)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := snapshot.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	return accesslog.Series(body, opts.Format, config, bucket)
+}
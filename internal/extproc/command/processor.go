@@ -0,0 +1,139 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	processor struct {
+		args    []*template.Template
+		stdin   bool
+		tabular bool
+	}
+
+	processorOptions struct {
+		Command []string
+		Stdin   bool
+		Tabular bool
+	}
+
+	commandParams struct {
+		ID   string
+		Type string
+		Path string
+	}
+)
+
+func init() {
+	collect.RegisterProcessor("command", newProcessor)
+}
+
+func newProcessor(raw json.RawMessage) (collect.Processor, error) {
+	opts := &processorOptions{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, opts); err != nil {
+			return nil, fmt.Errorf("failed to parse options: %w", err)
+		}
+	}
+	if len(opts.Command) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	p := &processor{stdin: opts.Stdin, tabular: opts.Tabular}
+	for i, arg := range opts.Command {
+		tmpl, err := template.New(fmt.Sprintf("arg%d", i)).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse argument %q: %w", arg, err)
+		}
+		p.args = append(p.args, tmpl)
+	}
+	return p, nil
+}
+
+func (p *processor) Cacheable() bool {
+	return true
+}
+
+func (p *processor) Tabular() bool {
+	return p.tabular
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	path, cleanup, err := p.bodyPath(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	params := &commandParams{ID: snapshot.ID, Type: snapshot.Type, Path: path}
+	args := make([]string, 0, len(p.args))
+	for _, tmpl := range p.args {
+		buf := &strings.Builder{}
+		if err := tmpl.Execute(buf, params); err != nil {
+			return nil, fmt.Errorf("failed to render command: %w", err)
+		}
+		args = append(args, buf.String())
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if p.stdin {
+		body, err := snapshot.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+		}
+		defer body.Close()
+		cmd.Stdin = body
+	}
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("external process aborted: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return io.NopCloser(stdout), nil
+}
+
+func (p *processor) bodyPath(snapshot *collect.Snapshot) (string, func(), error) {
+	if snapshot.Encoding != collect.EncodingGzip {
+		path, err := snapshot.BodyPath()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to find snapshot body: %w", err)
+		}
+		return path, func() {}, nil
+	}
+
+	body, err := snapshot.Open()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	file, err := os.CreateTemp("", "pprotein-*"+snapshot.ID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+
+	_, err = io.Copy(file, body)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to decompress snapshot body: %w", err)
+	}
+	return file.Name(), cleanup, nil
+}
@@ -0,0 +1,304 @@
+package extproc
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	DiffSpec struct {
+		Keys      []string
+		Labels    [][]string
+		Weight    []string
+		Metrics   []*DiffMetric
+		Indicator int
+		View      string
+	}
+
+	DiffMetric struct {
+		Columns  []string
+		Additive bool
+	}
+
+	diffIndex struct {
+		spec *DiffSpec
+	}
+
+	diffRow struct {
+		keys     []interface{}
+		labels   []interface{}
+		base     []float64
+		target   []float64
+		inBase   bool
+		inTarget bool
+	}
+)
+
+const (
+	ChangeColumn = "Change"
+
+	diffTolerance = 0.05
+)
+
+var changeRank = map[string]int{"regressed": 0, "added": 1, "improved": 2, "removed": 3, "unchanged": 4}
+
+func DiffTables(base, target *Table, spec *DiffSpec) (*Table, error) {
+	rows := map[string]*diffRow{}
+	order := []string{}
+
+	for i, t := range []*Table{base, target} {
+		if err := spec.aggregate(t, i, rows, &order); err != nil {
+			return nil, err
+		}
+	}
+
+	columns := append([]string{}, spec.Keys...)
+	for _, names := range spec.Labels {
+		columns = append(columns, names[0])
+	}
+	columns = append(columns, ChangeColumn)
+	for _, m := range spec.Metrics {
+		name := m.Columns[0]
+		columns = append(columns, name+"(base)", name+"(target)", name+"(delta)")
+	}
+	resp := &Table{Columns: columns, Rows: make([][]interface{}, 0, len(rows))}
+
+	changes := map[string]string{}
+	deltas := map[string]float64{}
+	for _, key := range order {
+		r := rows[key]
+		b, t := r.base[spec.Indicator], r.target[spec.Indicator]
+		deltas[key] = t - b
+
+		switch {
+		case !r.inBase:
+			changes[key] = "added"
+		case !r.inTarget:
+			changes[key] = "removed"
+		case t > b+b*diffTolerance:
+			changes[key] = "regressed"
+		case t < b-b*diffTolerance:
+			changes[key] = "improved"
+		default:
+			changes[key] = "unchanged"
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if a, b := changeRank[changes[order[i]]], changeRank[changes[order[j]]]; a != b {
+			return a < b
+		}
+		return math.Abs(deltas[order[i]]) > math.Abs(deltas[order[j]])
+	})
+
+	for _, key := range order {
+		r := rows[key]
+		row := append(append(append([]interface{}{}, r.keys...), r.labels...), changes[key])
+		for j := range spec.Metrics {
+			row = append(row, roundValue(r.base[j]), roundValue(r.target[j]), roundValue(r.target[j]-r.base[j]))
+		}
+		resp.Rows = append(resp.Rows, row)
+	}
+	return resp, nil
+}
+
+func (spec *DiffSpec) aggregate(t *Table, side int, rows map[string]*diffRow, order *[]string) error {
+	keyCols := make([]int, len(spec.Keys))
+	for j, name := range spec.Keys {
+		if keyCols[j] = t.Column(name); keyCols[j] < 0 {
+			return fmt.Errorf("missing column: %v", name)
+		}
+	}
+	metricCols := make([]int, len(spec.Metrics))
+	for j, m := range spec.Metrics {
+		metricCols[j] = t.Column(m.Columns...)
+	}
+	labelCols := make([]int, len(spec.Labels))
+	for j, names := range spec.Labels {
+		labelCols[j] = t.Column(names...)
+	}
+	weightCol := t.Column(spec.Weight...)
+
+	weights := map[string]float64{}
+	for _, row := range t.Rows {
+		keys := make([]interface{}, len(keyCols))
+		parts := make([]string, len(keyCols))
+		for j, col := range keyCols {
+			keys[j], parts[j] = t.String(row, col), t.String(row, col)
+		}
+		key := strings.Join(parts, "\x00")
+
+		r, ok := rows[key]
+		if !ok {
+			r = &diffRow{keys: keys, labels: make([]interface{}, len(labelCols)), base: make([]float64, len(spec.Metrics)), target: make([]float64, len(spec.Metrics))}
+			rows[key] = r
+			*order = append(*order, key)
+		}
+		for j, col := range labelCols {
+			if r.labels[j] == nil || r.labels[j] == "" {
+				r.labels[j] = t.String(row, col)
+			}
+		}
+		values := r.base
+		if side == 0 {
+			r.inBase = true
+		} else {
+			values, r.inTarget = r.target, true
+		}
+
+		w := 1.0
+		if weightCol >= 0 {
+			w = t.Number(row, weightCol)
+		}
+		prev := weights[key]
+		for j, m := range spec.Metrics {
+			v := t.Number(row, metricCols[j])
+			switch {
+			case m.Additive:
+				values[j] += v
+			case prev+w > 0:
+				values[j] = (values[j]*prev + v*w) / (prev + w)
+			}
+		}
+		weights[key] = prev + w
+	}
+	return nil
+}
+
+func IndexTable(t *Table, spec *DiffSpec) (*Table, error) {
+	rows := map[string]*diffRow{}
+	order := []string{}
+	if err := spec.aggregate(t, 0, rows, &order); err != nil {
+		return nil, err
+	}
+
+	columns := append([]string{}, spec.Keys...)
+	for _, names := range spec.Labels {
+		columns = append(columns, names[0])
+	}
+	for _, m := range spec.Metrics {
+		columns = append(columns, m.Columns[0])
+	}
+	resp := &Table{Columns: columns, Rows: make([][]interface{}, 0, len(rows))}
+
+	for _, key := range order {
+		r := rows[key]
+		row := append(append([]interface{}{}, r.keys...), r.labels...)
+		for j := range spec.Metrics {
+			row = append(row, roundValue(r.base[j]))
+		}
+		resp.Rows = append(resp.Rows, row)
+	}
+	return resp, nil
+}
+
+func DiffIndex(spec *DiffSpec) collect.Deriver {
+	return &diffIndex{spec: spec}
+}
+
+func (d *diffIndex) Tabular() bool {
+	return true
+}
+
+func (d *diffIndex) Derive(ctx context.Context, snapshot *collect.Snapshot, processed io.Reader) (io.ReadCloser, error) {
+	t, err := ParseTable(processed)
+	if err != nil {
+		return nil, err
+	}
+	index, err := IndexTable(t, d.spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index table: %w", err)
+	}
+	tsv, err := index.TSV()
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(tsv)), nil
+}
+
+func roundValue(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+func (t *Table) TSV() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	w.Comma = '\t'
+
+	if err := w.Write(t.Columns); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			if n, ok := v.(float64); ok {
+				record[i] = strconv.FormatFloat(n, 'f', -1, 64)
+			} else {
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func loadTable(collector *collect.Collector, id string, view string) (*Table, error) {
+	if _, err := collector.Snapshot(id); err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("failed to find snapshot: %v", err))
+	}
+
+	get := collector.Get
+	if view != "" && collector.HasView(view) {
+		get = func(id string) (io.ReadCloser, error) { return collector.View(id, view) }
+	}
+	r, err := get(id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to get entry: %v", err))
+	}
+	defer r.Close()
+
+	t, err := ParseTable(r)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return t, nil
+}
+
+func ServeTableDiff(c echo.Context, collector *collect.Collector, spec *DiffSpec) error {
+	base, err := loadTable(collector, c.Param("base"), spec.View)
+	if err != nil {
+		return err
+	}
+	target, err := loadTable(collector, c.Param("target"), spec.View)
+	if err != nil {
+		return err
+	}
+
+	diff, err := DiffTables(base, target, spec)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to diff snapshots: %v", err))
+	}
+
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	if acceptsJSON(c) {
+		return c.JSON(http.StatusOK, diff)
+	}
+	tsv, err := diff.TSV()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.Blob(http.StatusOK, mimeTSV, tsv)
+}
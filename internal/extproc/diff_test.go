@@ -0,0 +1,148 @@
+package extproc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTables(t *testing.T) {
+	spec := &DiffSpec{
+		Keys:   []string{"Key"},
+		Labels: [][]string{{"Label"}},
+		Weight: []string{"Count"},
+		Metrics: []*DiffMetric{
+			{Columns: []string{"Count"}, Additive: true},
+			{Columns: []string{"Avg", "Mean"}},
+		},
+		Indicator: 1,
+	}
+
+	tests := []struct {
+		name    string
+		base    *Table
+		target  *Table
+		want    *Table
+		wantErr bool
+	}{
+		{
+			name: "classifies and orders changes",
+			base: &Table{
+				Columns: []string{"Key", "Label", "Count", "Avg"},
+				Rows: [][]interface{}{
+					{"unchanged", "", 1.0, 10.0},
+					{"removed", "", 1.0, 10.0},
+					{"improved", "", 1.0, 10.0},
+					{"regressed", "", 1.0, 10.0},
+				},
+			},
+			target: &Table{
+				Columns: []string{"Key", "Label", "Count", "Avg"},
+				Rows: [][]interface{}{
+					{"added", "", 1.0, 1.0},
+					{"improved", "", 1.0, 5.0},
+					{"regressed", "", 2.0, 30.0},
+					{"unchanged", "", 1.0, 10.2},
+				},
+			},
+			want: &Table{
+				Columns: []string{"Key", "Label", ChangeColumn, "Count(base)", "Count(target)", "Count(delta)", "Avg(base)", "Avg(target)", "Avg(delta)"},
+				Rows: [][]interface{}{
+					{"regressed", "", "regressed", 1.0, 2.0, 1.0, 10.0, 30.0, 20.0},
+					{"added", "", "added", 0.0, 1.0, 1.0, 0.0, 1.0, 1.0},
+					{"improved", "", "improved", 1.0, 1.0, 0.0, 10.0, 5.0, -5.0},
+					{"removed", "", "removed", 1.0, 0.0, -1.0, 10.0, 0.0, -10.0},
+					{"unchanged", "", "unchanged", 1.0, 1.0, 0.0, 10.0, 10.2, 0.2},
+				},
+			},
+		},
+		{
+			name: "aggregates duplicated keys by weight",
+			base: &Table{
+				Columns: []string{"Key", "Label", "Count", "Mean"},
+				Rows: [][]interface{}{
+					{"a", "", 1.0, 10.0},
+					{"a", "first", 3.0, 30.0},
+					{"a", "second", 0.0, 100.0},
+				},
+			},
+			target: &Table{
+				Columns: []string{"Key", "Label", "Count", "Mean"},
+				Rows: [][]interface{}{
+					{"a", "", 4.0, 25.0},
+				},
+			},
+			want: &Table{
+				Columns: []string{"Key", "Label", ChangeColumn, "Count(base)", "Count(target)", "Count(delta)", "Avg(base)", "Avg(target)", "Avg(delta)"},
+				Rows: [][]interface{}{
+					{"a", "first", "unchanged", 4.0, 4.0, 0.0, 25.0, 25.0, 0.0},
+				},
+			},
+		},
+		{
+			name:    "missing key column",
+			base:    &Table{Columns: []string{"Count", "Avg"}},
+			target:  &Table{Columns: []string{"Key", "Count", "Avg"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffTables(tt.base, tt.target, spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiffTables() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffTables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexTable(t *testing.T) {
+	spec := &DiffSpec{
+		Keys:    []string{"Key"},
+		Weight:  []string{"Count"},
+		Metrics: []*DiffMetric{{Columns: []string{"Count"}, Additive: true}, {Columns: []string{"Max"}}},
+	}
+
+	tests := []struct {
+		name  string
+		table *Table
+		want  *Table
+	}{
+		{
+			name:  "empty",
+			table: &Table{Columns: []string{"Key", "Count", "Max"}},
+			want:  &Table{Columns: []string{"Key", "Count", "Max"}, Rows: [][]interface{}{}},
+		},
+		{
+			name: "keeps first appearance order",
+			table: &Table{
+				Columns: []string{"Key", "Count", "Max"},
+				Rows: [][]interface{}{
+					{"b", 1.0, 2.0},
+					{"a", 2.0, 4.0},
+					{"b", 1.0, 4.0},
+				},
+			},
+			want: &Table{
+				Columns: []string{"Key", "Count", "Max"},
+				Rows: [][]interface{}{
+					{"b", 2.0, 3.0},
+					{"a", 2.0, 4.0},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IndexTable(tt.table, spec)
+			if err != nil {
+				t.Fatalf("IndexTable() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IndexTable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
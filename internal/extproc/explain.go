@@ -0,0 +1,174 @@
+package extproc
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	_ "github.com/lib/pq"
+)
+
+type (
+	explainOptions struct {
+		Explain *ExplainConfig
+	}
+
+	ExplainConfig struct {
+		Driver  string
+		DSN     string
+		Top     int
+		Analyze bool
+	}
+)
+
+const (
+	planColumn     = "Plan"
+	defaultTop     = 5
+	explainTimeout = 10 * time.Second
+)
+
+var placeholders = map[string]string{
+	"mysql":    "1",
+	"postgres": "'1'",
+}
+
+func explainConfig(snapshot *collect.Snapshot) (*ExplainConfig, error) {
+	if len(snapshot.ProcessorOptions) == 0 {
+		return nil, nil
+	}
+
+	opts := &explainOptions{}
+	if err := json.Unmarshal(snapshot.ProcessorOptions, opts); err != nil {
+		return nil, fmt.Errorf("failed to parse explain options: %w", err)
+	}
+	if opts.Explain == nil {
+		return nil, nil
+	}
+
+	if _, ok := placeholders[opts.Explain.Driver]; !ok {
+		return nil, fmt.Errorf("unsupported explain driver: %v", opts.Explain.Driver)
+	}
+	if opts.Explain.DSN == "" {
+		return nil, fmt.Errorf("explain DSN is required")
+	}
+	if opts.Explain.Top <= 0 {
+		opts.Explain.Top = defaultTop
+	}
+	return opts.Explain, nil
+}
+
+func explainTable(ctx context.Context, cfg *ExplainConfig, raw []byte) ([]byte, error) {
+	lines := strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+	col := findColumn(strings.Split(lines[0], "\t"), queryColumns)
+	if col < 0 {
+		return raw, nil
+	}
+
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(lines[0] + "\t" + planColumn + "\n")
+	for i, line := range lines[1:] {
+		plan := ""
+		if fields := strings.Split(line, "\t"); i < cfg.Top && col < len(fields) {
+			plan, err = explain(ctx, db, cfg, unquote(fields[col]))
+			if err != nil {
+				plan = "error: " + err.Error()
+			}
+		}
+		buf.WriteString(line + "\t" + flatten(plan) + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+func explain(ctx context.Context, db *sql.DB, cfg *ExplainConfig, query string) (string, error) {
+	query = strings.ReplaceAll(query, "?+", "?")
+	query = strings.ReplaceAll(query, "?", placeholders[cfg.Driver])
+
+	stmt := "EXPLAIN "
+	if cfg.Analyze && isSelect(query) {
+		stmt = "EXPLAIN ANALYZE "
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, explainTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, stmt+query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	return formatPlan(rows)
+}
+
+func formatPlan(rows *sql.Rows) (string, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	plan := []string{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", fmt.Errorf("failed to scan plan: %w", err)
+		}
+
+		if len(cols) == 1 {
+			plan = append(plan, values[0].String)
+			continue
+		}
+		fields := []string{}
+		for i, v := range values {
+			if v.Valid {
+				fields = append(fields, cols[i]+"="+v.String)
+			}
+		}
+		plan = append(plan, strings.Join(fields, " "))
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to read plan: %w", err)
+	}
+	return strings.Join(plan, "\n"), nil
+}
+
+func isSelect(query string) bool {
+	fields := strings.Fields(query)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "SELECT")
+}
+
+func flatten(plan string) string {
+	parts := []string{}
+	for _, line := range strings.Split(plan, "\n") {
+		if line = strings.TrimSpace(strings.ReplaceAll(line, "\t", " ")); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
@@ -1,8 +1,11 @@
 package extproc
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/kaz/pprotein/internal/collect"
 	"github.com/labstack/echo/v4"
@@ -10,6 +13,10 @@ import (
 )
 
 type (
+	Routable interface {
+		RegisterRoutes(g *echo.Group, collector *collect.Collector)
+	}
+
 	handler struct {
 		processor collect.Processor
 		opts      *collect.Options
@@ -34,12 +41,23 @@ func (h *handler) Register(g *echo.Group) error {
 	g.GET("", h.getIndex)
 	g.POST("", h.postIndex)
 	g.GET("/:id", h.getId)
+	g.GET("/:id/views", h.getViews)
+	g.GET("/:id/views/:name", h.getView)
+	if _, ok := h.processor.(*queryStage); ok {
+		g.GET("/queries/:fingerprint", h.getQueryHistory)
+		g.GET("/diff/:base/:target", h.getQueryDiff)
+	}
+	if routable, ok := h.processor.(Routable); ok {
+		routable.RegisterRoutes(g, h.collector)
+	}
+
+	h.collector.RegisterHandlers(g)
 
 	return nil
 }
 
 func (h *handler) getIndex(c echo.Context) error {
-	return c.JSON(http.StatusOK, h.collector.List())
+	return collect.ServeEntries(c, h.collector)
 }
 
 func (h *handler) postIndex(c echo.Context) error {
@@ -47,6 +65,9 @@ func (h *handler) postIndex(c echo.Context) error {
 	if err := c.Bind(target); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
 	}
+	if err := h.collector.EnforceQuota(); err != nil {
+		return echo.NewHTTPError(http.StatusInsufficientStorage, err.Error())
+	}
 
 	go func() {
 		if err := h.collector.Collect(target); err != nil {
@@ -62,7 +83,59 @@ func (h *handler) getId(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("failed to get entry: %w", err))
 	}
+	return serveOutput(c, r, isTabular(h.processor))
+}
+
+func (h *handler) getViews(c echo.Context) error {
+	if _, err := h.collector.Snapshot(c.Param("id")); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	return c.JSON(http.StatusOK, h.collector.Views())
+}
+
+func (h *handler) getView(c echo.Context) error {
+	r, err := h.collector.View(c.Param("id"), c.Param("name"))
+	if err != nil {
+		if errors.Is(err, collect.ErrNoSuchEntry) || errors.Is(err, collect.ErrNoSuchView) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to get view: %v", err))
+	}
+	return serveOutput(c, r, h.tabularView(c.Param("name")))
+}
+
+func (h *handler) tabularView(name string) bool {
+	for _, v := range h.opts.Views {
+		if v.Name == name {
+			return isTabular(v.Processor) || isTabular(v.Deriver)
+		}
+	}
+	return isTabular(h.processor)
+}
+
+func serveOutput(c echo.Context, r io.ReadCloser, tabular bool) error {
+	if !tabular {
+		return collect.ServeContent(c, "application/json", r)
+	}
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	if !acceptsJSON(c) {
+		return collect.ServeContent(c, mimeTSV, r)
+	}
+
 	defer r.Close()
+	table, err := ParseTable(r)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, table)
+}
 
-	return c.Stream(http.StatusOK, "application/json", r)
+func acceptsJSON(c echo.Context) bool {
+	for _, accept := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mime, _, _ := strings.Cut(strings.TrimSpace(accept), ";")
+		if mime == echo.MIMEApplicationJSON {
+			return true
+		}
+	}
+	return false
 }
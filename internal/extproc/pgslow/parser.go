@@ -0,0 +1,121 @@
+package pgslow
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	statement struct {
+		Duration float64
+		Query    string
+		Plan     bool
+	}
+
+	pending struct {
+		duration float64
+		plan     bool
+		lines    []string
+	}
+)
+
+var (
+	durationPattern = regexp.MustCompile(`duration: ([\d.]+) ms\s+(?:(?:statement|(?:parse|bind|execute)[^:]*): (.*)|(plan):\s*(.*))$`)
+	entryPattern    = regexp.MustCompile(`\b(?:LOG|ERROR|WARNING|NOTICE|INFO|DEBUG\d?|FATAL|PANIC|DETAIL|HINT|CONTEXT|STATEMENT):\s`)
+)
+
+func parse(r io.Reader, fn func(*statement)) error {
+	var cur *pending
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		if st := cur.statement(); st != nil {
+			fn(st)
+		}
+		cur = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := durationPattern.FindStringSubmatch(line); m != nil {
+			flush()
+
+			duration, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				continue
+			}
+			cur = &pending{duration: duration / 1000}
+			if m[3] != "" {
+				cur.plan = true
+				cur.lines = append(cur.lines, m[4])
+			} else {
+				cur.lines = append(cur.lines, m[2])
+			}
+			continue
+		}
+		if entryPattern.MatchString(line) {
+			flush()
+			continue
+		}
+		if cur != nil {
+			cur.lines = append(cur.lines, line)
+		}
+	}
+	flush()
+	return scanner.Err()
+}
+
+func (p *pending) statement() *statement {
+	if !p.plan {
+		query := strings.TrimSpace(strings.Join(p.lines, "\n"))
+		if query == "" {
+			return nil
+		}
+		return &statement{Duration: p.duration, Query: query}
+	}
+
+	query := planQuery(p.lines)
+	if query == "" {
+		return nil
+	}
+	return &statement{Duration: p.duration, Query: query, Plan: true}
+}
+
+func planQuery(lines []string) string {
+	body := strings.TrimSpace(strings.Join(lines, "\n"))
+	if strings.HasPrefix(body, "{") {
+		plan := struct {
+			QueryText string `json:"Query Text"`
+		}{}
+		if err := json.Unmarshal([]byte(body), &plan); err == nil {
+			return plan.QueryText
+		}
+	}
+
+	query := []string{}
+	inQuery := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if text, ok := strings.CutPrefix(trimmed, "Query Text:"); ok {
+			inQuery = true
+			query = append(query, strings.TrimSpace(text))
+			continue
+		}
+		if !inQuery {
+			continue
+		}
+		if trimmed == "" || strings.Contains(trimmed, "(cost=") || strings.Contains(trimmed, "(actual time=") {
+			break
+		}
+		query = append(query, trimmed)
+	}
+	return strings.Join(query, " ")
+}
@@ -0,0 +1,101 @@
+package pgslow
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/sqlnorm"
+)
+
+type (
+	processor struct{}
+
+	queryStat struct {
+		query     string
+		plans     int
+		durations []float64
+	}
+)
+
+func init() {
+	collect.RegisterProcessor("pgslow", func(json.RawMessage) (collect.Processor, error) {
+		return New(), nil
+	})
+}
+
+func New() collect.Processor {
+	return &processor{}
+}
+
+func (p *processor) Cacheable() bool {
+	return true
+}
+
+func (p *processor) Tabular() bool {
+	return true
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	body, err := snapshot.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	index := map[string]*queryStat{}
+	stats := []*queryStat{}
+	err = parse(body, func(st *statement) {
+		query := sqlnorm.Normalize(st.Query)
+		qs, ok := index[query]
+		if !ok {
+			qs = &queryStat{query: query}
+			index[query] = qs
+			stats = append(stats, qs)
+		}
+		qs.durations = append(qs.durations, st.Duration)
+		if st.Plan {
+			qs.plans++
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgres log: %w", err)
+	}
+
+	for _, qs := range stats {
+		sort.Float64s(qs.durations)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].sum() > stats[j].sum()
+	})
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("Count\tSum\tAvg\tMin\tMax\tP95\tPlans\tQuery\n")
+	for _, qs := range stats {
+		n := len(qs.durations)
+		fmt.Fprintf(buf, "%d\t%.6f\t%.6f\t%.6f\t%.6f\t%.6f\t%d\t%s\n",
+			n, qs.sum(), qs.sum()/float64(n), qs.durations[0], qs.durations[n-1], qs.percentile(95), qs.plans, qs.query)
+	}
+	return io.NopCloser(buf), nil
+}
+
+func (qs *queryStat) sum() float64 {
+	total := 0.0
+	for _, d := range qs.durations {
+		total += d
+	}
+	return total
+}
+
+func (qs *queryStat) percentile(p int) float64 {
+	idx := int(math.Ceil(float64(len(qs.durations))*float64(p)/100)) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return qs.durations[idx]
+}
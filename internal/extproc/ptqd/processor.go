@@ -0,0 +1,139 @@
+package ptqd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	processor struct {
+		args []string
+	}
+
+	processorOptions struct {
+		Args []string
+	}
+
+	report struct {
+		Classes []*class `json:"classes"`
+	}
+
+	class struct {
+		Checksum    string              `json:"checksum"`
+		Fingerprint string              `json:"fingerprint"`
+		QueryCount  number              `json:"query_count"`
+		Metrics     map[string]*metrics `json:"metrics"`
+	}
+
+	metrics struct {
+		Sum    number `json:"sum"`
+		Min    number `json:"min"`
+		Max    number `json:"max"`
+		Avg    number `json:"avg"`
+		Median number `json:"median"`
+		Pct95  number `json:"pct_95"`
+	}
+
+	number float64
+)
+
+func init() {
+	collect.RegisterProcessor("pt-query-digest", func(raw json.RawMessage) (collect.Processor, error) {
+		opts := &processorOptions{}
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, opts); err != nil {
+				return nil, fmt.Errorf("failed to parse options: %w", err)
+			}
+		}
+		return New(opts.Args), nil
+	})
+}
+
+func New(args []string) collect.Processor {
+	return &processor{args: args}
+}
+
+func (p *processor) Cacheable() bool {
+	return true
+}
+
+func (p *processor) Tabular() bool {
+	return true
+}
+
+func (p *processor) CacheScope() string {
+	return "ptqd"
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	body, err := snapshot.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	args := append([]string{"--output", "json", "--no-version-check"}, p.args...)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "pt-query-digest", args...)
+	cmd.Stdin = body
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("external process aborted: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	rep := &report{}
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+		if err := json.Unmarshal(out, rep); err != nil {
+			return nil, fmt.Errorf("failed to parse pt-query-digest output: %w", err)
+		}
+	}
+	return io.NopCloser(bytes.NewReader(rep.table())), nil
+}
+
+func (r *report) table() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("Count\tSum\tAvg\tMedian\tP95\tMax\tLockSum\tRowsSentAvg\tRowsExaminedAvg\tChecksum\tFingerprint\n")
+	for _, c := range r.Classes {
+		qt, lt := c.metric("Query_time"), c.metric("Lock_time")
+		fmt.Fprintf(buf, "%d\t%.6f\t%.6f\t%.6f\t%.6f\t%.6f\t%.6f\t%.1f\t%.1f\t%s\t%s\n",
+			int64(c.QueryCount), qt.Sum, qt.Avg, qt.Median, qt.Pct95, qt.Max, lt.Sum,
+			c.metric("Rows_sent").Avg, c.metric("Rows_examined").Avg,
+			c.Checksum, strings.Join(strings.Fields(c.Fingerprint), " "),
+		)
+	}
+	return buf.Bytes()
+}
+
+func (c *class) metric(name string) *metrics {
+	if m, ok := c.Metrics[name]; ok && m != nil {
+		return m
+	}
+	return &metrics{}
+}
+
+func (n *number) UnmarshalJSON(raw []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case float64:
+		*n = number(v)
+	case string:
+		var f float64
+		if _, err := fmt.Sscan(v, &f); err == nil {
+			*n = number(f)
+		}
+	}
+	return nil
+}
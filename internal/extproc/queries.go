@@ -0,0 +1,219 @@
+package extproc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/sqlnorm"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	queryStage struct {
+		internal collect.Processor
+	}
+
+	QueryPoint struct {
+		SnapshotID string
+		GroupId    string
+		Label      string
+		Datetime   time.Time
+		Query      string
+		Count      float64
+		Sum        float64
+	}
+)
+
+const queryIDColumn = "QueryID"
+
+var (
+	queryColumns = []string{"Query", "Fingerprint"}
+	countColumns = []string{"Count"}
+	sumColumns   = []string{"Sum", "Sum(QueryTime)"}
+)
+
+var queryDiffSpec = &DiffSpec{
+	Keys:   []string{queryIDColumn},
+	Labels: [][]string{queryColumns},
+	Weight: countColumns,
+	Metrics: []*DiffMetric{
+		{Columns: sumColumns, Additive: true},
+		{Columns: countColumns, Additive: true},
+		{Columns: []string{"Avg", "Avg(QueryTime)"}},
+	},
+}
+
+func QueryStage(internal collect.Processor) collect.Processor {
+	return &queryStage{internal: internal}
+}
+
+func (s *queryStage) Cacheable() bool {
+	return s.internal.Cacheable()
+}
+
+func (s *queryStage) Tabular() bool {
+	return true
+}
+
+func (s *queryStage) CacheScope() string {
+	if scoped, ok := s.internal.(collect.CacheScoped); ok && scoped.CacheScope() != "" {
+		return scoped.CacheScope() + ".qid"
+	}
+	return "qid"
+}
+
+func (s *queryStage) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	r, err := s.internal.Process(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processed output: %w", err)
+	}
+	raw = fingerprintTable(raw)
+
+	cfg, err := explainConfig(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil {
+		raw, err = explainTable(ctx, cfg, raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain queries: %w", err)
+		}
+	}
+	return io.NopCloser(bytes.NewReader(raw)), nil
+}
+
+func fingerprintTable(raw []byte) []byte {
+	lines := strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+	if len(lines) == 0 {
+		return raw
+	}
+
+	header := strings.Split(lines[0], "\t")
+	col := findColumn(header, queryColumns)
+	if col < 0 {
+		return raw
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(lines[0] + "\t" + queryIDColumn + "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		id := ""
+		if col < len(fields) {
+			id = sqlnorm.Fingerprint(unquote(fields[col]))
+		}
+		buf.WriteString(line + "\t" + id + "\n")
+	}
+	return buf.Bytes()
+}
+
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func unquote(field string) string {
+	if len(field) >= 2 && strings.HasPrefix(field, `"`) && strings.HasSuffix(field, `"`) {
+		return strings.ReplaceAll(field[1:len(field)-1], `""`, `"`)
+	}
+	return field
+}
+
+func (h *handler) QueryHistory(fingerprint string) ([]*QueryPoint, error) {
+	entries := []*collect.Entry{}
+	for _, ent := range h.collector.List() {
+		if ent.Status == collect.StatusOk {
+			entries = append(entries, ent)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Snapshot.Datetime.Before(entries[j].Snapshot.Datetime)
+	})
+
+	points := []*QueryPoint{}
+	for _, ent := range entries {
+		r, err := h.collector.Get(ent.Snapshot.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %v: %w", ent.Snapshot.ID, err)
+		}
+		table, err := ParseTable(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %w", ent.Snapshot.ID, err)
+		}
+
+		if point := table.queryPoint(fingerprint); point != nil {
+			point.SnapshotID = ent.Snapshot.ID
+			point.GroupId = ent.Snapshot.GroupId
+			point.Label = ent.Snapshot.Label
+			point.Datetime = ent.Snapshot.Datetime
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
+func (t *Table) queryPoint(fingerprint string) *QueryPoint {
+	idCol := findColumn(t.Columns, []string{queryIDColumn})
+	queryCol := findColumn(t.Columns, queryColumns)
+	countCol := findColumn(t.Columns, countColumns)
+	sumCol := findColumn(t.Columns, sumColumns)
+	if idCol < 0 {
+		return nil
+	}
+
+	var point *QueryPoint
+	for _, row := range t.Rows {
+		if idCol >= len(row) || fmt.Sprint(row[idCol]) != fingerprint {
+			continue
+		}
+		if point == nil {
+			point = &QueryPoint{}
+			if queryCol >= 0 && queryCol < len(row) {
+				point.Query = fmt.Sprint(row[queryCol])
+			}
+		}
+		point.Count += numberAt(row, countCol)
+		point.Sum += numberAt(row, sumCol)
+	}
+	return point
+}
+
+func numberAt(row []interface{}, col int) float64 {
+	if col < 0 || col >= len(row) {
+		return 0
+	}
+	n, _ := row[col].(float64)
+	return n
+}
+
+func (h *handler) getQueryHistory(c echo.Context) error {
+	points, err := h.QueryHistory(c.Param("fingerprint"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, points)
+}
+
+func (h *handler) getQueryDiff(c echo.Context) error {
+	return ServeTableDiff(c, h.collector, queryDiffSpec)
+}
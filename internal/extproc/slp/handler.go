@@ -6,6 +6,7 @@ import (
 
 	"github.com/kaz/pprotein/internal/collect"
 	"github.com/kaz/pprotein/internal/extproc"
+	"github.com/kaz/pprotein/internal/extproc/ptqd"
 	"github.com/kaz/pprotein/internal/persistent"
 	"github.com/kaz/pprotein/internal/storage"
 	"github.com/labstack/echo/v4"
@@ -14,17 +15,23 @@ import (
 
 type (
 	handler struct {
-		opts   *collect.Options
-		config *persistent.Handler
+		opts     *collect.Options
+		analyzer string
+		config   *persistent.Handler
 	}
 )
 
 //go:embed slp.yml
 var defaultConfig []byte
 
-func NewHandler(opts *collect.Options, store storage.Storage) (*handler, error) {
+func NewHandler(opts *collect.Options, store storage.Storage, analyzer string) (*handler, error) {
+	if analyzer != "" && analyzer != "slp" && analyzer != "pt-query-digest" {
+		return nil, fmt.Errorf("unknown slow log analyzer: %v", analyzer)
+	}
+
 	h := &handler{
-		opts: opts,
+		opts:     opts,
+		analyzer: analyzer,
 	}
 
 	config, err := persistent.New(store, "slp.yml", defaultConfig, h.sanitize)
@@ -39,7 +46,12 @@ func NewHandler(opts *collect.Options, store storage.Storage) (*handler, error)
 func (h *handler) Register(g *echo.Group) error {
 	h.config.RegisterHandlers(g.Group("/config"))
 
-	if err := extproc.NewHandler(&processor{confPath: h.config.GetPath()}, h.opts).Register(g); err != nil {
+	var p collect.Processor = &processor{config: h.config}
+	if h.analyzer == "pt-query-digest" {
+		p = ptqd.New(nil)
+	}
+
+	if err := extproc.NewHandler(extproc.QueryStage(p), h.opts).Register(g); err != nil {
 		return fmt.Errorf("failed to register extproc handlers: %w", err)
 	}
 	return nil
@@ -2,30 +2,73 @@ package slp
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os/exec"
 
+	"github.com/goccy/go-json"
 	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/persistent"
 )
 
 type (
 	processor struct {
 		confPath string
+		config   *persistent.Handler
+	}
+
+	processorOptions struct {
+		Config string
 	}
 )
 
+func init() {
+	collect.RegisterProcessor("slp", newProcessor)
+}
+
+func newProcessor(raw json.RawMessage) (collect.Processor, error) {
+	opts := &processorOptions{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, opts); err != nil {
+			return nil, fmt.Errorf("failed to parse options: %w", err)
+		}
+	}
+	if opts.Config == "" {
+		return nil, fmt.Errorf("config path is required")
+	}
+	return &processor{confPath: opts.Config}, nil
+}
+
+func (p *processor) configPath() (string, error) {
+	if p.config != nil {
+		return p.config.GetPath()
+	}
+	return p.confPath, nil
+}
+
 func (p *processor) Cacheable() bool {
 	return true
 }
 
-func (p *processor) Process(snapshot *collect.Snapshot) (io.ReadCloser, error) {
-	bodyPath, err := snapshot.BodyPath()
+func (p *processor) Tabular() bool {
+	return true
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	confPath, err := p.configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := snapshot.Open()
 	if err != nil {
-		return nil, fmt.Errorf("failed to find snapshot body: %w", err)
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
 	}
+	defer body.Close()
 
-	cmd := exec.Command("slp", "my", "--config", p.confPath, "--output", "standard", "--format", "tsv", "--file", bodyPath)
+	cmd := exec.CommandContext(ctx, "slp", "my", "--config", confPath, "--output", "standard", "--format", "tsv")
+	cmd.Stdin = body
 
 	res, err := cmd.Output()
 	if err != nil {
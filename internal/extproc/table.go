@@ -0,0 +1,82 @@
+package extproc
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	Tabular interface {
+		Tabular() bool
+	}
+
+	Table struct {
+		Columns []string
+		Rows    [][]interface{}
+	}
+)
+
+const mimeTSV = "text/tab-separated-values; charset=UTF-8"
+
+func isTabular(p interface{}) bool {
+	t, ok := p.(Tabular)
+	return ok && t.Tabular()
+}
+
+func ParseTable(r io.Reader) (*Table, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = '\t'
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+
+	table := &Table{Columns: []string{}, Rows: [][]interface{}{}}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse table: %w", err)
+		}
+
+		if len(table.Columns) == 0 {
+			for _, col := range record {
+				table.Columns = append(table.Columns, strings.TrimSpace(col))
+			}
+			continue
+		}
+
+		row := make([]interface{}, len(record))
+		for i, field := range record {
+			row[i] = cellValue(strings.TrimSpace(field))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+func cellValue(field string) interface{} {
+	if n, err := strconv.ParseFloat(field, 64); err == nil {
+		return n
+	}
+	return field
+}
+
+func (t *Table) Column(names ...string) int {
+	return findColumn(t.Columns, names)
+}
+
+func (t *Table) Number(row []interface{}, col int) float64 {
+	return numberAt(row, col)
+}
+
+func (t *Table) String(row []interface{}, col int) string {
+	if col < 0 || col >= len(row) {
+		return ""
+	}
+	return fmt.Sprint(row[col])
+}
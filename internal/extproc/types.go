@@ -0,0 +1,164 @@
+package extproc
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/persistent"
+	"github.com/kaz/pprotein/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Types struct {
+		config    *persistent.Handler
+		validator *validator.Validate
+		reserved  map[string]bool
+	}
+
+	TypeConfig struct {
+		Type      string `validate:"required,alphanum,lowercase"`
+		Ext       string
+		Processor string `validate:"required"`
+		Options   json.RawMessage
+		Compress  bool
+		Views     []*ViewConfig `validate:"dive"`
+	}
+
+	ViewConfig struct {
+		Name      string `validate:"required,alphanum"`
+		Processor string `validate:"required"`
+		Options   json.RawMessage
+	}
+)
+
+var internalBuckets = []string{"cache", "status", "comment", "score", "project", "pprof-top", "pprof-binary", "group-history", "run-commit"}
+
+func (cfg *TypeConfig) Extension() string {
+	if cfg.Ext == "" {
+		return "-" + cfg.Type + ".log"
+	}
+	return cfg.Ext
+}
+
+func NewTypes(store storage.Storage, reserved []string) (*Types, error) {
+	t := &Types{
+		validator: validator.New(),
+		reserved:  map[string]bool{},
+	}
+	for _, typ := range append(internalBuckets, reserved...) {
+		t.reserved[typ] = true
+	}
+
+	config, err := persistent.New(store, "types.json", []byte("[]"), t.sanitize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create types: %w", err)
+	}
+	config.OnUpdate(func() {
+		log.Printf("[*] custom types updated; restart to apply")
+	})
+	t.config = config
+
+	return t, nil
+}
+
+func (t *Types) RegisterHandlers(g *echo.Group) {
+	t.config.RegisterHandlers(g)
+}
+
+func (t *Types) sanitize(raw []byte) ([]byte, error) {
+	types := []*TypeConfig{}
+	if err := json.Unmarshal(raw, &types); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	if err := t.validator.Var(types, "dive"); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, name := range collect.Processors() {
+		known[name] = true
+	}
+
+	seen := map[string]bool{}
+	for _, cfg := range types {
+		if !known[cfg.Processor] {
+			return nil, fmt.Errorf("unknown processor: %v", cfg.Processor)
+		}
+		if t.reserved[cfg.Type] || seen[cfg.Type] {
+			return nil, fmt.Errorf("type is already in use: %v", cfg.Type)
+		}
+		seen[cfg.Type] = true
+
+		views := map[string]bool{cfg.Processor: true}
+		for _, view := range cfg.Views {
+			if !known[view.Processor] {
+				return nil, fmt.Errorf("unknown processor for view %v of %v: %v", view.Name, cfg.Type, view.Processor)
+			}
+			if views[view.Name] {
+				return nil, fmt.Errorf("duplicated view of %v: %v", cfg.Type, view.Name)
+			}
+			views[view.Name] = true
+		}
+	}
+
+	res, err := json.MarshalIndent(types, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	return res, nil
+}
+
+func (t *Types) Configs() ([]*TypeConfig, error) {
+	raw, err := t.config.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	types := []*TypeConfig{}
+	if err := json.Unmarshal(raw, &types); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return types, nil
+}
+
+func (t *Types) Register(api *echo.Group, options func(*TypeConfig) (*collect.Options, error)) error {
+	types, err := t.Configs()
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range types {
+		if t.reserved[cfg.Type] {
+			return fmt.Errorf("type is already in use: %v", cfg.Type)
+		}
+
+		processor, err := collect.NewProcessor(cfg.Processor, cfg.Options)
+		if err != nil {
+			return fmt.Errorf("failed to initialize type %v: %w", cfg.Type, err)
+		}
+
+		opts, err := options(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to configure type %v: %w", cfg.Type, err)
+		}
+
+		opts.Views = []*collect.View{{Name: cfg.Processor, Processor: processor}}
+		for _, view := range cfg.Views {
+			p, err := collect.NewProcessor(view.Processor, view.Options)
+			if err != nil {
+				return fmt.Errorf("failed to initialize view %v of %v: %w", view.Name, cfg.Type, err)
+			}
+			opts.Views = append(opts.Views, &collect.View{Name: view.Name, Processor: p})
+		}
+
+		if err := NewHandler(processor, opts).Register(api.Group("/" + cfg.Type)); err != nil {
+			return fmt.Errorf("failed to register type %v: %w", cfg.Type, err)
+		}
+	}
+	return nil
+}
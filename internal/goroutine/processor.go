@@ -0,0 +1,180 @@
+package goroutine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	processor struct{}
+
+	goroutine struct {
+		state     string
+		wait      int
+		stack     []string
+		createdBy string
+	}
+
+	group struct {
+		count     int
+		states    map[string]int
+		wait      int
+		stack     []string
+		createdBy string
+	}
+)
+
+var headerPattern = regexp.MustCompile(`^goroutine \d+ \[(.+)\]:$`)
+
+func init() {
+	collect.RegisterProcessor("goroutine", func(json.RawMessage) (collect.Processor, error) {
+		return &processor{}, nil
+	})
+}
+
+func (p *processor) Cacheable() bool {
+	return true
+}
+
+func (p *processor) Tabular() bool {
+	return true
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	body, err := snapshot.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	goroutines, err := parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse goroutine dump: %w", err)
+	}
+	if len(goroutines) == 0 {
+		return nil, fmt.Errorf("no goroutines found; the dump must be collected with debug=2")
+	}
+	return io.NopCloser(bytes.NewReader(render(aggregate(goroutines)))), nil
+}
+
+func parse(r io.Reader) ([]*goroutine, error) {
+	goroutines := []*goroutine{}
+
+	var cur *goroutine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := headerPattern.FindStringSubmatch(line); m != nil {
+			cur = parseHeader(m[1])
+			goroutines = append(goroutines, cur)
+			continue
+		}
+		if cur == nil || line == "" || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "created by ") {
+			name := strings.TrimPrefix(line, "created by ")
+			if i := strings.Index(name, " in goroutine "); i >= 0 {
+				name = name[:i]
+			}
+			cur.createdBy = name
+			continue
+		}
+		if strings.HasPrefix(line, "...") {
+			continue
+		}
+		cur.stack = append(cur.stack, functionName(line))
+	}
+	return goroutines, scanner.Err()
+}
+
+func parseHeader(header string) *goroutine {
+	parts := strings.Split(header, ", ")
+	g := &goroutine{state: parts[0]}
+	for _, part := range parts[1:] {
+		if v, ok := strings.CutSuffix(part, " minutes"); ok {
+			if n, err := strconv.Atoi(v); err == nil {
+				g.wait = n
+			}
+		}
+	}
+	return g
+}
+
+func functionName(line string) string {
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func aggregate(goroutines []*goroutine) []*group {
+	index := map[string]*group{}
+	groups := []*group{}
+	for _, g := range goroutines {
+		key := strings.Join(g.stack, "\n") + "\n" + g.createdBy
+
+		grp, ok := index[key]
+		if !ok {
+			grp = &group{states: map[string]int{}, stack: g.stack, createdBy: g.createdBy}
+			index[key] = grp
+			groups = append(groups, grp)
+		}
+		grp.count++
+		grp.states[g.state]++
+		if g.wait > grp.wait {
+			grp.wait = g.wait
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].count > groups[j].count
+	})
+	return groups
+}
+
+func render(groups []*group) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("count\tstates\tmax_wait_minutes\ttop\tcreated_by\tstack\n")
+	for _, g := range groups {
+		top := ""
+		if len(g.stack) > 0 {
+			top = g.stack[0]
+		}
+		fmt.Fprintf(buf, "%d\t%s\t%d\t%s\t%s\t%s\n", g.count, g.stateSummary(), g.wait, top, g.createdBy, strings.Join(g.stack, " <- "))
+	}
+	return buf.Bytes()
+}
+
+func (g *group) stateSummary() string {
+	states := make([]string, 0, len(g.states))
+	for state := range g.states {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if g.states[states[i]] != g.states[states[j]] {
+			return g.states[states[i]] > g.states[states[j]]
+		}
+		return states[i] < states[j]
+	})
+
+	summary := make([]string, 0, len(states))
+	for _, state := range states {
+		summary = append(summary, fmt.Sprintf("%s: %d", state, g.states[state]))
+	}
+	return strings.Join(summary, ", ")
+}
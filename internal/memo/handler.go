@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/kaz/pprotein/internal/collect"
@@ -12,13 +14,27 @@ import (
 
 type (
 	textValue struct {
-		Text string
+		Text       string
+		SnapshotId string `json:",omitempty"`
+	}
+
+	Memo struct {
+		ID         string
+		Datetime   time.Time
+		GroupId    string
+		Label      string
+		RunId      string
+		SnapshotId string
+		Text       string
+		HTML       string
 	}
 
 	requestBody struct {
-		GroupId string
-		Label   string
-		Text    string
+		GroupId    string
+		Label      string
+		RunId      string
+		SnapshotId string
+		Text       string
 	}
 
 	handler struct {
@@ -42,29 +58,104 @@ func (h *handler) Register(g *echo.Group) error {
 
 	g.GET("", h.getIndex)
 	g.POST("", h.postIndex)
+	g.GET("/refs", h.getRefs)
 	g.GET("/:id", h.getId)
+	h.collector.RegisterHandlers(g)
 	return nil
 }
 
 func (h *handler) getIndex(c echo.Context) error {
 	list := h.collector.List()
 	for _, m := range list {
-		r, err := h.collector.Get(m.Snapshot.ID)
+		text, err := Text(h.collector, m.Snapshot.ID)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("failed to get entry: %w", err))
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 		}
-		buf, err := ioutil.ReadAll(r)
-		r.Close()
+		m.Message = text
+	}
+	return collect.ServeEntries(c, h.collector)
+}
+
+func (h *handler) getRefs(c echo.Context) error {
+	snapshotId, runId := c.QueryParam("snapshot"), c.QueryParam("run")
+	if snapshotId == "" && runId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "snapshot or run is required")
+	}
+
+	memos, err := List(h.collector, func(m *Memo) bool {
+		return (snapshotId == "" || m.SnapshotId == snapshotId) && (runId == "" || m.RunId == runId)
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, memos)
+}
+
+func Text(c *collect.Collector, id string) (string, error) {
+	v, err := read(c, id)
+	if err != nil {
+		return "", err
+	}
+	return v.Text, nil
+}
+
+func Get(c *collect.Collector, id string) (*Memo, error) {
+	snapshot, err := c.Snapshot(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find memo: %w", err)
+	}
+	v, err := read(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Memo{
+		ID:         snapshot.ID,
+		Datetime:   snapshot.Datetime,
+		SnapshotId: v.SnapshotId,
+		Text:       v.Text,
+		HTML:       RenderMarkdown(v.Text),
+	}
+	if snapshot.SnapshotTarget != nil {
+		m.GroupId = snapshot.GroupId
+		m.Label = snapshot.Label
+		m.RunId = snapshot.RunId
+	}
+	return m, nil
+}
+
+func List(c *collect.Collector, filter func(*Memo) bool) ([]*Memo, error) {
+	memos := []*Memo{}
+	for _, ent := range c.List() {
+		if ent.Status != collect.StatusOk {
+			continue
+		}
+		m, err := Get(c, ent.Snapshot.ID)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("failed to read entry: %v", err))
+			return nil, err
 		}
+		if filter(m) {
+			memos = append(memos, m)
+		}
+	}
+	sort.Slice(memos, func(i, j int) bool { return memos[i].Datetime.Before(memos[j].Datetime) })
+	return memos, nil
+}
 
-		var v textValue
-		json.Unmarshal(buf, &v)
-
-		m.Message = v.Text
+func read(c *collect.Collector, id string) (*textValue, error) {
+	r, err := c.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	buf, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entry: %w", err)
 	}
-	return c.JSON(http.StatusOK, h.collector.List())
+
+	v := &textValue{}
+	json.Unmarshal(buf, v)
+	return v, nil
 }
 
 func (h *handler) postIndex(c echo.Context) error {
@@ -72,14 +163,19 @@ func (h *handler) postIndex(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
 	}
+	if err := h.validateReference(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 
 	target := &collect.SnapshotTarget{
 		GroupId: req.GroupId,
 		Label:   req.Label,
+		RunId:   req.RunId,
 	}
 
 	v := &textValue{
-		Text: req.Text,
+		Text:       req.Text,
+		SnapshotId: req.SnapshotId,
 	}
 	buf, err := json.Marshal(v)
 	if err != nil {
@@ -103,12 +199,36 @@ func (h *handler) postIndex(c echo.Context) error {
 	return c.NoContent(http.StatusAccepted)
 }
 
+func (h *handler) validateReference(req *requestBody) error {
+	snapshotFound, runFound := req.SnapshotId == "", req.RunId == ""
+	if snapshotFound && runFound {
+		return nil
+	}
+
+	for _, ent := range h.opts.Registry.List() {
+		if ent.Snapshot.Type == h.opts.Type {
+			continue
+		}
+		if ent.Snapshot.ID == req.SnapshotId {
+			snapshotFound = true
+		}
+		if ent.Snapshot.SnapshotTarget != nil && ent.Snapshot.RunId == req.RunId {
+			runFound = true
+		}
+	}
+	if !snapshotFound {
+		return fmt.Errorf("no such snapshot: %v", req.SnapshotId)
+	}
+	if !runFound {
+		return fmt.Errorf("no such run: %v", req.RunId)
+	}
+	return nil
+}
+
 func (h *handler) getId(c echo.Context) error {
-	r, err := h.collector.Get(c.Param("id"))
+	m, err := Get(h.collector, c.Param("id"))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("failed to get entry: %w", err))
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
 	}
-	defer r.Close()
-
-	return c.Stream(http.StatusOK, "application/json", r)
+	return c.JSON(http.StatusOK, m)
 }
@@ -0,0 +1,141 @@
+package memo
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+type (
+	markdownRenderer struct {
+		buf  *strings.Builder
+		para []string
+		list string
+	}
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	bulletPattern  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^\s*(-\s*){3,}$|^\s*(\*\s*){3,}$|^\s*(_\s*){3,}$`)
+
+	linkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongPattern = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emPattern     = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+
+	safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+)
+
+func RenderMarkdown(src string) string {
+	r := &markdownRenderer{buf: &strings.Builder{}}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			r.flush()
+			code := []string{}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			r.buf.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			r.flush()
+		case rulePattern.MatchString(line):
+			r.flush()
+			r.buf.WriteString("<hr>\n")
+		case headingPattern.MatchString(trimmed):
+			r.flush()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			r.buf.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+		case strings.HasPrefix(trimmed, ">"):
+			r.flush()
+			r.buf.WriteString("<blockquote>" + renderInline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))) + "</blockquote>\n")
+		case bulletPattern.MatchString(line):
+			r.item("ul", bulletPattern.FindStringSubmatch(line)[1])
+		case orderedPattern.MatchString(line):
+			r.item("ol", orderedPattern.FindStringSubmatch(line)[1])
+		default:
+			r.closeList()
+			r.para = append(r.para, trimmed)
+		}
+	}
+	r.flush()
+	return r.buf.String()
+}
+
+func (r *markdownRenderer) item(list string, text string) {
+	r.flushParagraph()
+	if r.list != list {
+		r.closeList()
+		r.buf.WriteString("<" + list + ">\n")
+		r.list = list
+	}
+	r.buf.WriteString("<li>" + renderInline(text) + "</li>\n")
+}
+
+func (r *markdownRenderer) flush() {
+	r.flushParagraph()
+	r.closeList()
+}
+
+func (r *markdownRenderer) flushParagraph() {
+	if len(r.para) == 0 {
+		return
+	}
+	r.buf.WriteString("<p>" + renderInline(strings.Join(r.para, "\n")) + "</p>\n")
+	r.para = nil
+}
+
+func (r *markdownRenderer) closeList() {
+	if r.list == "" {
+		return
+	}
+	r.buf.WriteString("</" + r.list + ">\n")
+	r.list = ""
+}
+
+func renderInline(text string) string {
+	parts := strings.Split(text, "`")
+	out := &strings.Builder{}
+	for i, part := range parts {
+		if i%2 == 1 && i < len(parts)-1 {
+			out.WriteString("<code>" + html.EscapeString(part) + "</code>")
+			continue
+		}
+		if i%2 == 1 {
+			out.WriteString("`")
+		}
+		out.WriteString(renderSpan(html.EscapeString(part)))
+	}
+	return out.String()
+}
+
+func renderSpan(escaped string) string {
+	escaped = linkPattern.ReplaceAllStringFunc(escaped, func(s string) string {
+		m := linkPattern.FindStringSubmatch(s)
+		if !safeURL(html.UnescapeString(m[2])) {
+			return m[1]
+		}
+		return `<a href="` + m[2] + `" rel="noopener noreferrer">` + m[1] + "</a>"
+	})
+	escaped = strongPattern.ReplaceAllString(escaped, "<strong>$1$2</strong>")
+	escaped = emPattern.ReplaceAllString(escaped, "<em>$1$2</em>")
+	return strings.ReplaceAll(escaped, "\n", "<br>\n")
+}
+
+func safeURL(u string) bool {
+	scheme, _, ok := strings.Cut(u, ":")
+	if !ok || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+	return safeSchemes[strings.ToLower(scheme)]
+}
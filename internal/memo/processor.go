@@ -2,10 +2,11 @@ package memo
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 
+	"github.com/goccy/go-json"
 	"github.com/kaz/pprotein/internal/collect"
 )
 
@@ -17,15 +18,30 @@ func (p *processor) Cacheable() bool {
 	return false
 }
 
-func (p *processor) Process(snapshot *collect.Snapshot) (io.ReadCloser, error) {
-	bodyPath, err := snapshot.BodyPath()
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	body, err := snapshot.Open()
 	if err != nil {
-		return nil, fmt.Errorf("failed to find snapshot body: %w", err)
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
 	}
+	defer body.Close()
 
-	res, err := ioutil.ReadFile(bodyPath)
+	res, err := io.ReadAll(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read snapshot body: %w", err)
 	}
 	return io.NopCloser(bytes.NewBuffer(res)), nil
 }
+
+func (p *processor) SearchText(snapshot *collect.Snapshot) ([]byte, error) {
+	r, err := p.Process(context.Background(), snapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	v := &textValue{}
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return nil, fmt.Errorf("failed to parse memo: %w", err)
+	}
+	return []byte(v.Text), nil
+}
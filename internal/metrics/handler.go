@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/event"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Handler struct {
+		registry *collect.Registry
+		eventHub *event.Hub
+	}
+
+	writer struct {
+		buf *bytes.Buffer
+	}
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func NewHandler(registry *collect.Registry, eventHub *event.Hub) *Handler {
+	return &Handler{registry: registry, eventHub: eventHub}
+}
+
+func (h *Handler) RegisterHandlers(e *echo.Echo, m ...echo.MiddlewareFunc) {
+	e.GET("/metrics", h.getMetrics, m...)
+}
+
+func (h *Handler) getMetrics(c echo.Context) error {
+	w := &writer{buf: &bytes.Buffer{}}
+	collectors := h.registry.Collectors()
+
+	w.help("pprotein_entries", "gauge", "Number of snapshots by type and status.")
+	for _, col := range collectors {
+		counts := map[collect.Status]int{}
+		for _, ent := range col.List() {
+			counts[ent.Status]++
+		}
+		for _, status := range sortedKeys(counts) {
+			w.sample("pprotein_entries", counts[status], "type", col.Type(), "status", string(status))
+		}
+	}
+
+	stats := make([]*collect.Stats, 0, len(collectors))
+	for _, col := range collectors {
+		stats = append(stats, col.Stats())
+	}
+
+	w.help("pprotein_collection_events_total", "counter", "Number of collection lifecycle events by type.")
+	for _, s := range stats {
+		for _, ev := range sortedKeys(s.Events) {
+			w.sample("pprotein_collection_events_total", s.Events[ev], "type", s.Type, "event", ev)
+		}
+	}
+
+	w.help("pprotein_processing_duration_seconds", "histogram", "Time spent processing snapshots.")
+	for _, s := range stats {
+		for i, le := range collect.ProcessingBuckets {
+			w.sample("pprotein_processing_duration_seconds_bucket", s.ProcessingBuckets[i], "type", s.Type, "le", strconv.FormatFloat(le, 'g', -1, 64))
+		}
+		w.sample("pprotein_processing_duration_seconds_bucket", s.ProcessingCount, "type", s.Type, "le", "+Inf")
+		w.sample("pprotein_processing_duration_seconds_sum", s.ProcessingSeconds, "type", s.Type)
+		w.sample("pprotein_processing_duration_seconds_count", s.ProcessingCount, "type", s.Type)
+	}
+
+	w.help("pprotein_cache_hits_total", "counter", "Number of processed results served from cache.")
+	for _, s := range stats {
+		w.sample("pprotein_cache_hits_total", s.CacheHits, "type", s.Type)
+	}
+	w.help("pprotein_cache_misses_total", "counter", "Number of processed results generated because the cache was empty.")
+	for _, s := range stats {
+		w.sample("pprotein_cache_misses_total", s.CacheMisses, "type", s.Type)
+	}
+
+	w.help("pprotein_storage_bytes", "gauge", "Bytes used by snapshot bodies.")
+	for _, col := range collectors {
+		used, err := col.StorageBytes()
+		if err != nil {
+			log.Printf("[!] failed to measure storage of %v: %v", col.Type(), err)
+			continue
+		}
+		w.sample("pprotein_storage_bytes", used, "type", col.Type())
+	}
+
+	sse, ws := h.eventHub.Clients()
+	w.help("pprotein_event_clients", "gauge", "Number of connected event stream clients.")
+	w.sample("pprotein_event_clients", sse, "transport", "sse")
+	w.sample("pprotein_event_clients", ws, "transport", "websocket")
+
+	mem := &runtime.MemStats{}
+	runtime.ReadMemStats(mem)
+	w.help("go_goroutines", "gauge", "Number of goroutines that currently exist.")
+	w.sample("go_goroutines", runtime.NumGoroutine())
+	w.help("go_memstats_heap_alloc_bytes", "gauge", "Number of heap bytes allocated and still in use.")
+	w.sample("go_memstats_heap_alloc_bytes", mem.HeapAlloc)
+
+	return c.Blob(http.StatusOK, contentType, w.buf.Bytes())
+}
+
+func (w *writer) help(name string, typ string, help string) {
+	fmt.Fprintf(w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (w *writer) sample(name string, value any, labels ...string) {
+	w.buf.WriteString(name)
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+		}
+		fmt.Fprintf(w.buf, "{%s}", strings.Join(pairs, ","))
+	}
+	fmt.Fprintf(w.buf, " %v\n", value)
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
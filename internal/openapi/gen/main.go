@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/openapi"
+)
+
+type (
+	document struct {
+		Paths      map[string]map[string]*operation
+		Components *components
+	}
+	components struct {
+		Parameters map[string]*parameter
+		Schemas    map[string]*schema
+	}
+
+	operation struct {
+		OperationId string
+		Summary     string
+		Parameters  []*parameter
+		RequestBody *requestBody
+		Responses   map[string]*response
+	}
+	parameter struct {
+		Ref    string `json:"$ref"`
+		Name   string
+		In     string
+		Schema *schema
+	}
+	requestBody struct {
+		Content map[string]*mediaType
+	}
+	response struct {
+		Ref     string `json:"$ref"`
+		Content map[string]*mediaType
+	}
+	mediaType struct {
+		Schema *schema
+	}
+
+	schema struct {
+		Ref        string `json:"$ref"`
+		Type       string
+		Format     string
+		Nullable   bool
+		Enum       []string
+		Required   []string
+		Items      *schema
+		Properties map[string]*schema
+
+		AdditionalProperties *schema
+	}
+
+	generator struct {
+		doc *document
+		buf *bytes.Buffer
+	}
+)
+
+var (
+	pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+	reserved = map[string]string{"type": "typ", "func": "fn", "default": "def"}
+)
+
+func main() {
+	out := flag.String("o", "client_gen.go", "output file")
+	pkg := flag.String("package", "client", "package name")
+	flag.Parse()
+
+	doc := &document{}
+	if err := json.Unmarshal(openapi.Spec, doc); err != nil {
+		log.Fatalf("failed to parse spec: %v", err)
+	}
+
+	g := &generator{doc: doc, buf: &bytes.Buffer{}}
+	src, err := g.generate(*pkg)
+	if err != nil {
+		log.Fatalf("failed to generate client: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("failed to write client: %v", err)
+	}
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(g.buf, format, args...)
+}
+
+func (g *generator) generate(pkg string) ([]byte, error) {
+	for _, name := range sortedKeys(g.doc.Components.Schemas) {
+		if err := g.schemaType(name, g.doc.Components.Schemas[name]); err != nil {
+			return nil, fmt.Errorf("schema %v: %w", name, err)
+		}
+	}
+
+	ops := map[string]func() error{}
+	for path, methods := range g.doc.Paths {
+		for method, op := range methods {
+			path, method, op := path, method, op
+			if _, ok := ops[op.OperationId]; ok {
+				return nil, fmt.Errorf("duplicated operationId: %v", op.OperationId)
+			}
+			ops[op.OperationId] = func() error { return g.operation(path, strings.ToUpper(method), op) }
+		}
+	}
+	for _, id := range sortedKeys(ops) {
+		if err := ops[id](); err != nil {
+			return nil, fmt.Errorf("operation %v: %w", id, err)
+		}
+	}
+
+	body := g.buf.String()
+	imports := []string{"context", "net/url"}
+	for pkg, ident := range map[string]string{"encoding/json": "json.", "io": "io.", "time": "time."} {
+		if strings.Contains(body, ident) {
+			imports = append(imports, pkg)
+		}
+	}
+	sort.Strings(imports)
+
+	head := &bytes.Buffer{}
+	fmt.Fprintf(head, "// Code generated by internal/openapi/gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	for _, imp := range imports {
+		fmt.Fprintf(head, "%q\n", imp)
+	}
+	fmt.Fprintf(head, ")\n\n%s", body)
+
+	src, err := format.Source(head.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format: %w", err)
+	}
+	return src, nil
+}
+
+func (g *generator) schemaType(name string, s *schema) error {
+	if s.Type == "string" && len(s.Enum) > 0 {
+		g.printf("type %s string\n\nconst (\n", name)
+		for _, v := range s.Enum {
+			g.printf("%s%s %s = %q\n", name, exported(v), name, v)
+		}
+		g.printf(")\n\n")
+		return nil
+	}
+	if s.Type != "object" {
+		typ, err := g.goType(s)
+		if err != nil {
+			return err
+		}
+		g.printf("type %s %s\n\n", name, typ)
+		return nil
+	}
+
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+
+	g.printf("type %s struct {\n", name)
+	for _, prop := range sortedKeys(s.Properties) {
+		typ, err := g.goType(s.Properties[prop])
+		if err != nil {
+			return fmt.Errorf("property %v: %w", prop, err)
+		}
+		tag := prop
+		if !required[prop] {
+			tag += ",omitempty"
+		}
+		g.printf("%s %s `json:%q`\n", exported(prop), typ, tag)
+	}
+	g.printf("}\n\n")
+	return nil
+}
+
+func (g *generator) goType(s *schema) (string, error) {
+	if s.Ref != "" {
+		name, target, err := g.resolve(s.Ref)
+		if err != nil {
+			return "", err
+		}
+		if target.Type == "object" {
+			return "*" + name, nil
+		}
+		return name, nil
+	}
+
+	var typ string
+	switch s.Type {
+	case "":
+		return "json.RawMessage", nil
+	case "string":
+		switch s.Format {
+		case "date-time":
+			typ = "time.Time"
+		case "binary":
+			return "io.Reader", nil
+		default:
+			typ = "string"
+		}
+	case "integer":
+		typ = "int"
+		if s.Format == "int64" {
+			typ = "int64"
+		}
+	case "number":
+		typ = "float64"
+	case "boolean":
+		typ = "bool"
+	case "array":
+		if s.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		elem, err := g.goType(s.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		if s.AdditionalProperties == nil {
+			return "map[string]json.RawMessage", nil
+		}
+		elem, err := g.goType(s.AdditionalProperties)
+		if err != nil {
+			return "", err
+		}
+		return "map[string]" + elem, nil
+	default:
+		return "", fmt.Errorf("unsupported type: %v", s.Type)
+	}
+
+	if s.Nullable {
+		return "*" + typ, nil
+	}
+	return typ, nil
+}
+
+func (g *generator) resolve(ref string) (string, *schema, error) {
+	name := strings.TrimPrefix(ref, "#/components/schemas/")
+	s, ok := g.doc.Components.Schemas[name]
+	if !ok || name == ref {
+		return "", nil, fmt.Errorf("unresolved reference: %v", ref)
+	}
+	return name, s, nil
+}
+
+func (g *generator) parameters(op *operation) ([]*parameter, error) {
+	params := make([]*parameter, 0, len(op.Parameters))
+	for _, p := range op.Parameters {
+		if p.Ref != "" {
+			resolved, ok := g.doc.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+			if !ok {
+				return nil, fmt.Errorf("unresolved reference: %v", p.Ref)
+			}
+			p = resolved
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+func (g *generator) operation(path string, method string, op *operation) error {
+	name := exported(op.OperationId)
+
+	params, err := g.parameters(op)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"ctx context.Context"}
+	pathArgs := map[string]string{}
+	query := []*parameter{}
+	for _, p := range params {
+		switch p.In {
+		case "path":
+			pathArgs[p.Name] = argName(p.Name)
+			args = append(args, pathArgs[p.Name]+" string")
+		case "query":
+			query = append(query, p)
+		}
+	}
+	if len(query) > 0 {
+		g.printf("type %sParams struct {\n", name)
+		for _, p := range query {
+			typ, err := g.goType(p.Schema)
+			if err != nil {
+				return fmt.Errorf("parameter %v: %w", p.Name, err)
+			}
+			g.printf("%s %s\n", exported(p.Name), typ)
+		}
+		g.printf("}\n\n")
+		args = append(args, "params *"+name+"Params")
+	}
+
+	var in, multipart string
+	if op.RequestBody != nil {
+		if media, ok := op.RequestBody.Content["application/json"]; ok {
+			if in, err = g.goType(media.Schema); err != nil {
+				return fmt.Errorf("request body: %w", err)
+			}
+			args = append(args, "body "+in)
+		} else if _, ok := op.RequestBody.Content["multipart/form-data"]; ok {
+			multipart = "multipart/form-data"
+			args = append(args, "body io.Reader", "contentType string")
+		} else {
+			return fmt.Errorf("unsupported request body")
+		}
+	}
+
+	out, binary, err := g.result(op)
+	if err != nil {
+		return err
+	}
+
+	ret := "error"
+	if binary {
+		ret = "(io.ReadCloser, error)"
+	} else if out != "" {
+		ret = "(" + out + ", error)"
+	}
+
+	g.printf("// %s %s: %s\n", method, path, op.Summary)
+	g.printf("func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), ret)
+	g.printf("path := %s\n", pathExpr(path, pathArgs))
+	g.printf("query := url.Values{}\n")
+	if len(query) > 0 {
+		g.printf("if params != nil {\n")
+		for _, p := range query {
+			g.printf("%s\n", g.querySetter(p))
+		}
+		g.printf("}\n")
+	}
+
+	switch {
+	case binary:
+		g.printf("return c.stream(ctx, %q, path, query)\n", method)
+	case out != "":
+		g.printf("var out %s\n", out)
+		if multipart != "" {
+			g.printf("err := c.upload(ctx, %q, path, query, body, contentType, &out)\n", method)
+		} else {
+			g.printf("err := c.call(ctx, %q, path, query, %s, &out)\n", method, bodyArg(in))
+		}
+		g.printf("return out, err\n")
+	case multipart != "":
+		g.printf("return c.upload(ctx, %q, path, query, body, contentType, nil)\n", method)
+	default:
+		g.printf("return c.call(ctx, %q, path, query, %s, nil)\n", method, bodyArg(in))
+	}
+	g.printf("}\n\n")
+	return nil
+}
+
+func (g *generator) result(op *operation) (string, bool, error) {
+	for _, code := range sortedKeys(op.Responses) {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		resp := op.Responses[code]
+		if media, ok := resp.Content["application/json"]; ok && media.Schema.Format != "binary" {
+			typ, err := g.goType(media.Schema)
+			if err != nil {
+				return "", false, fmt.Errorf("response: %w", err)
+			}
+			return typ, false, nil
+		}
+		return "", len(resp.Content) > 0, nil
+	}
+	return "", false, nil
+}
+
+func (g *generator) querySetter(p *parameter) string {
+	field := "params." + exported(p.Name)
+	s := p.Schema
+	if s.Ref != "" {
+		return fmt.Sprintf("setString(query, %q, string(%s))", p.Name, field)
+	}
+	switch {
+	case s.Type == "integer":
+		return fmt.Sprintf("setInt(query, %q, %s)", p.Name, field)
+	case s.Type == "boolean":
+		return fmt.Sprintf("setBool(query, %q, %s)", p.Name, field)
+	case s.Format == "date-time":
+		return fmt.Sprintf("setTime(query, %q, %s)", p.Name, field)
+	}
+	return fmt.Sprintf("setString(query, %q, %s)", p.Name, field)
+}
+
+func bodyArg(in string) string {
+	if in == "" {
+		return "nil"
+	}
+	return "body"
+}
+
+func pathExpr(path string, args map[string]string) string {
+	parts := []string{}
+	last := 0
+	for _, m := range pathParam.FindAllStringSubmatchIndex(path, -1) {
+		parts = append(parts, fmt.Sprintf("%q", path[last:m[0]]))
+		parts = append(parts, "url.PathEscape("+args[path[m[2]:m[3]]]+")")
+		last = m[1]
+	}
+	if last < len(path) {
+		parts = append(parts, fmt.Sprintf("%q", path[last:]))
+	}
+	return strings.Join(parts, " + ")
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func argName(name string) string {
+	if v, ok := reserved[name]; ok {
+		return v
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 
 	"github.com/kaz/pprotein/internal/storage"
 	"github.com/labstack/echo/v4"
@@ -15,9 +14,9 @@ type (
 		store storage.Storage
 
 		sanitize func([]byte) ([]byte, error)
+		onUpdate []func()
 
 		fileName string
-		filePath string
 	}
 )
 
@@ -36,41 +35,71 @@ func New(store storage.Storage, fileName string, defaultContent []byte, sanitize
 		}
 	}
 
-	filePath, err := store.GetFilePath(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find blob path: %v", err)
-	}
-
 	return &Handler{
 		store: store,
 
 		sanitize: sanitize,
 
 		fileName: fileName,
-		filePath: filePath,
 	}, nil
 }
 
 func (h *Handler) RegisterHandlers(g *echo.Group) {
-	g.GET("", h.handleGet)
-	g.POST("", h.handlePost)
+	g.GET("", h.HandleGet)
+	g.POST("", h.HandlePost)
 }
 
-func (h *Handler) GetPath() string {
-	return h.filePath
+func (h *Handler) GetPath() (string, error) {
+	filePath, err := h.store.GetFilePath(h.fileName)
+	if err != nil {
+		return "", fmt.Errorf("failed to find blob path: %w", err)
+	}
+	return filePath, nil
 }
 func (h *Handler) GetContent() ([]byte, error) {
-	content, err := os.ReadFile(h.filePath)
+	file, err := h.store.OpenFile(h.fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 	return content, nil
 }
 
-func (h *Handler) handleGet(c echo.Context) error {
-	return c.File(h.filePath)
+func (h *Handler) SetContent(content []byte) error {
+	pretty, err := h.sanitize(content)
+	if err != nil {
+		return fmt.Errorf("failed to sanitize: %w", err)
+	}
+	return h.save(pretty)
+}
+
+func (h *Handler) OnUpdate(fn func()) {
+	h.onUpdate = append(h.onUpdate, fn)
+}
+
+func (h *Handler) save(content []byte) error {
+	if err := h.store.PutFile(h.fileName, content); err != nil {
+		return fmt.Errorf("failed to save: %w", err)
+	}
+	for _, fn := range h.onUpdate {
+		fn()
+	}
+	return nil
+}
+
+func (h *Handler) HandleGet(c echo.Context) error {
+	filePath, err := h.GetPath()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.File(filePath)
 }
-func (h *Handler) handlePost(c echo.Context) error {
+func (h *Handler) HandlePost(c echo.Context) error {
 	body, err := io.ReadAll(c.Request().Body)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to read body: %v", err))
@@ -81,8 +110,8 @@ func (h *Handler) handlePost(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse body: %v", err))
 	}
 
-	if err := h.store.PutFile(h.fileName, pretty); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to save: %v", err))
+	if err := h.save(pretty); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.NoContent(http.StatusOK)
@@ -0,0 +1,256 @@
+package pprof
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/pprof/profile"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/storage"
+)
+
+type (
+	Binary struct {
+		BuildID   string
+		GoBuildID string `json:",omitempty"`
+		Name      string
+		Target    string `json:",omitempty"`
+		Size      int64
+		Datetime  time.Time
+	}
+
+	binaries struct {
+		store storage.Storage
+	}
+
+	elfNote struct {
+		name string
+		typ  uint32
+		desc []byte
+	}
+)
+
+const (
+	binaryTypeKey   = "pprof-binary"
+	agentBinaryPath = "/debug/binary"
+
+	gnuBuildIDNote = 3
+	goBuildIDNote  = 4
+)
+
+var ErrNoSuchBinary = errors.New("no such binary")
+
+func binaryFileId(buildID string) string {
+	return "binary-" + buildID
+}
+
+func binaryTarget(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return target
+}
+
+func (b *binaries) put(name string, target string, r io.Reader) (*Binary, error) {
+	tmp, err := os.CreateTemp("", "pprotein-binary-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("binary is empty")
+	}
+
+	bin := &Binary{
+		Name:     filepath.Base(name),
+		Target:   binaryTarget(target),
+		Size:     size,
+		Datetime: time.Now(),
+	}
+	bin.BuildID, bin.GoBuildID = readBuildIDs(tmp.Name())
+	if bin.BuildID == "" {
+		bin.BuildID = hex.EncodeToString(hash.Sum(nil))[:40]
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+	if err := b.store.PutFileStream(binaryFileId(bin.BuildID), tmp); err != nil {
+		return nil, fmt.Errorf("failed to save binary: %w", err)
+	}
+
+	raw, err := json.Marshal(bin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	if err := b.store.Put(binaryTypeKey, bin.BuildID, raw); err != nil {
+		return nil, fmt.Errorf("failed to save binary meta: %w", err)
+	}
+	return bin, nil
+}
+
+func (b *binaries) list() ([]*Binary, error) {
+	raws, err := b.store.GetAll(binaryTypeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read binaries: %w", err)
+	}
+
+	resp := make([]*Binary, 0, len(raws))
+	for _, raw := range raws {
+		bin := &Binary{}
+		if err := json.Unmarshal(raw, bin); err != nil {
+			log.Printf("[!] failed to unmarshal binary: %v", err)
+			continue
+		}
+		resp = append(resp, bin)
+	}
+
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Datetime.After(resp[j].Datetime)
+	})
+	return resp, nil
+}
+
+func (b *binaries) delete(buildID string) error {
+	ok, err := b.store.Exists(binaryTypeKey, buildID)
+	if err != nil {
+		return fmt.Errorf("failed to find binary: %w", err)
+	}
+	if !ok {
+		return ErrNoSuchBinary
+	}
+	if err := b.store.DeleteFile(binaryFileId(buildID)); err != nil {
+		return fmt.Errorf("failed to delete binary: %w", err)
+	}
+	return b.store.Delete(binaryTypeKey, buildID)
+}
+
+func (b *binaries) match(prof *profile.Profile, target string) (*Binary, error) {
+	if len(prof.Mapping) == 0 {
+		return nil, nil
+	}
+	exe := prof.Mapping[0]
+
+	bins, err := b.list()
+	if err != nil {
+		return nil, err
+	}
+
+	if exe.BuildID != "" {
+		for _, bin := range bins {
+			if bin.BuildID == exe.BuildID || bin.GoBuildID == exe.BuildID {
+				return bin, nil
+			}
+		}
+		return nil, nil
+	}
+
+	host := binaryTarget(target)
+	var fallback *Binary
+	for _, bin := range bins {
+		if bin.Target == "" || bin.Target != host {
+			continue
+		}
+		if exe.File != "" && bin.Name == filepath.Base(exe.File) {
+			return bin, nil
+		}
+		if fallback == nil {
+			fallback = bin
+		}
+	}
+	return fallback, nil
+}
+
+func (b *binaries) args(snapshot *collect.Snapshot) []string {
+	prof, err := loadProfile(snapshot)
+	if err != nil {
+		return nil
+	}
+	return b.argsFor(snapshot, prof)
+}
+
+func (b *binaries) argsFor(snapshot *collect.Snapshot, prof *profile.Profile) []string {
+	bin, err := b.match(prof, snapshot.URL)
+	if err != nil {
+		log.Printf("[!] failed to match binary of %v: %v", snapshot.ID, err)
+		return nil
+	}
+	if bin == nil {
+		return nil
+	}
+
+	path, err := b.store.GetFilePath(binaryFileId(bin.BuildID))
+	if err != nil {
+		log.Printf("[!] failed to find binary %v: %v", bin.BuildID, err)
+		return nil
+	}
+	return []string{path}
+}
+
+func readBuildIDs(path string) (string, string) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	var gnuID, goID string
+	for _, sect := range f.Sections {
+		if sect.Type != elf.SHT_NOTE {
+			continue
+		}
+		data, err := sect.Data()
+		if err != nil {
+			continue
+		}
+		for _, note := range parseNotes(data, f.ByteOrder) {
+			switch {
+			case note.name == "GNU" && note.typ == gnuBuildIDNote:
+				gnuID = hex.EncodeToString(note.desc)
+			case note.name == "Go" && note.typ == goBuildIDNote:
+				goID = string(note.desc)
+			}
+		}
+	}
+	return gnuID, goID
+}
+
+func parseNotes(data []byte, order binary.ByteOrder) []*elfNote {
+	align := func(n uint32) int {
+		return int((n + 3) &^ 3)
+	}
+
+	notes := []*elfNote{}
+	for len(data) >= 12 {
+		nameLen, descLen, typ := order.Uint32(data), order.Uint32(data[4:]), order.Uint32(data[8:])
+		data = data[12:]
+		if len(data) < align(nameLen)+align(descLen) {
+			break
+		}
+		name := string(bytes.TrimRight(data[:nameLen], "\x00"))
+		desc := data[align(nameLen) : align(nameLen)+int(descLen)]
+		notes = append(notes, &elfNote{name: name, typ: typ, desc: desc})
+		data = data[align(nameLen)+align(descLen):]
+	}
+	return notes
+}
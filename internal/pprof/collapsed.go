@@ -0,0 +1,90 @@
+package pprof
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+var (
+	jfrMagic = []byte("FLR\x00")
+
+	frameAnnotation = regexp.MustCompile(`_\[[jikp0-9]\]$`)
+
+	errJFR = errors.New("JFR recordings are not supported directly; convert them with async-profiler's jfrconv (--collapsed or -o pprof) first")
+)
+
+func decodeProfile(data []byte) (*profile.Profile, bool, error) {
+	prof, err := profile.ParseData(data)
+	if err == nil {
+		return prof, false, nil
+	}
+	if bytes.HasPrefix(data, jfrMagic) {
+		return nil, false, errJFR
+	}
+	if isCPUProfile(data) {
+		prof, err := parseCPUProfile(data)
+		return prof, err == nil, err
+	}
+	if collapsed, cerr := parseCollapsed(bytes.NewReader(data)); cerr == nil {
+		return collapsed, true, nil
+	}
+	return nil, false, fmt.Errorf("failed to parse profile: %w", err)
+}
+
+func parseCollapsed(r io.Reader) (*profile.Profile, error) {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		PeriodType: &profile.ValueType{Type: "samples", Unit: "count"},
+		Period:     1,
+	}
+	locations := map[string]*profile.Location{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		sep := strings.LastIndexByte(line, ' ')
+		if sep <= 0 {
+			return nil, fmt.Errorf("line %d: missing sample count", lineno)
+		}
+		count, err := strconv.ParseInt(line[sep+1:], 10, 64)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("line %d: invalid sample count: %v", lineno, line[sep+1:])
+		}
+
+		frames := strings.Split(line[:sep], ";")
+		sample := &profile.Sample{Value: []int64{count}, Location: make([]*profile.Location, 0, len(frames))}
+		for i := len(frames) - 1; i >= 0; i-- {
+			name := frameAnnotation.ReplaceAllString(frames[i], "")
+			loc, ok := locations[name]
+			if !ok {
+				fn := &profile.Function{ID: uint64(len(prof.Function) + 1), Name: name, SystemName: name}
+				prof.Function = append(prof.Function, fn)
+				loc = &profile.Location{ID: uint64(len(prof.Location) + 1), Line: []profile.Line{{Function: fn}}}
+				prof.Location = append(prof.Location, loc)
+				locations[name] = loc
+			}
+			sample.Location = append(sample.Location, loc)
+		}
+		prof.Sample = append(prof.Sample, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read collapsed stacks: %w", err)
+	}
+	if len(prof.Sample) == 0 {
+		return nil, fmt.Errorf("no samples found")
+	}
+	return prof, prof.CheckValid()
+}
@@ -0,0 +1,128 @@
+package pprof
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/pprof/profile"
+)
+
+type (
+	cpuProfile struct {
+		Nodes      []*cpuProfileNode
+		StartTime  int64
+		EndTime    int64
+		Samples    []int64
+		TimeDeltas []int64
+	}
+
+	cpuProfileNode struct {
+		ID        int64
+		CallFrame struct {
+			FunctionName string
+			URL          string
+			LineNumber   int64
+		}
+		HitCount int64
+		Children []int64
+	}
+)
+
+func isCPUProfile(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return bytes.HasPrefix(data, []byte("{")) && bytes.Contains(data, []byte(`"nodes"`))
+}
+
+func parseCPUProfile(data []byte) (*profile.Profile, error) {
+	cp := &cpuProfile{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("failed to parse cpuprofile: %w", err)
+	}
+	if len(cp.Nodes) == 0 {
+		return nil, fmt.Errorf("cpuprofile has no nodes")
+	}
+
+	nodes := map[int64]*cpuProfileNode{}
+	parents := map[int64]int64{}
+	for _, n := range cp.Nodes {
+		nodes[n.ID] = n
+		for _, child := range n.Children {
+			parents[child] = n.ID
+		}
+	}
+
+	hits := map[int64]int64{}
+	micros := map[int64]int64{}
+	if len(cp.Samples) > 0 {
+		for i, id := range cp.Samples {
+			hits[id]++
+			if i+1 < len(cp.TimeDeltas) {
+				micros[id] += cp.TimeDeltas[i+1]
+			}
+		}
+	} else {
+		for _, n := range cp.Nodes {
+			hits[n.ID] = n.HitCount
+		}
+	}
+
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "microseconds"}},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "microseconds"},
+	}
+	if cp.EndTime > cp.StartTime {
+		prof.DurationNanos = (cp.EndTime - cp.StartTime) * int64(time.Microsecond)
+	}
+	if total := int64(len(cp.Samples)); total > 0 && prof.DurationNanos > 0 {
+		prof.Period = prof.DurationNanos / int64(time.Microsecond) / total
+	}
+
+	functions := map[string]*profile.Function{}
+	locations := map[int64]*profile.Location{}
+	location := func(n *cpuProfileNode) *profile.Location {
+		if loc, ok := locations[n.ID]; ok {
+			return loc
+		}
+
+		name := n.CallFrame.FunctionName
+		if name == "" {
+			name = "(anonymous)"
+		}
+		key := name + "\x00" + n.CallFrame.URL
+		fn, ok := functions[key]
+		if !ok {
+			fn = &profile.Function{ID: uint64(len(prof.Function) + 1), Name: name, SystemName: name, Filename: n.CallFrame.URL}
+			prof.Function = append(prof.Function, fn)
+			functions[key] = fn
+		}
+
+		loc := &profile.Location{ID: uint64(len(prof.Location) + 1), Line: []profile.Line{{Function: fn, Line: n.CallFrame.LineNumber + 1}}}
+		prof.Location = append(prof.Location, loc)
+		locations[n.ID] = loc
+		return loc
+	}
+
+	for _, n := range cp.Nodes {
+		if hits[n.ID] == 0 {
+			continue
+		}
+
+		sample := &profile.Sample{Value: []int64{hits[n.ID], micros[n.ID]}}
+		for id, ok := n.ID, true; ok; id, ok = parents[id] {
+			cur, found := nodes[id]
+			if !found || cur.CallFrame.FunctionName == "(root)" {
+				break
+			}
+			sample.Location = append(sample.Location, location(cur))
+		}
+		if len(sample.Location) > 0 {
+			prof.Sample = append(prof.Sample, sample)
+		}
+	}
+	if len(prof.Sample) == 0 {
+		return nil, fmt.Errorf("cpuprofile has no samples")
+	}
+	return prof, prof.CheckValid()
+}
@@ -0,0 +1,130 @@
+package pprof
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	deltaRow struct {
+		Function string
+		Values   []int64
+	}
+)
+
+var (
+	heapRequiredTypes = []string{"inuse_space", "alloc_space"}
+	heapSampleTypes   = []string{"inuse_space", "alloc_space", "inuse_objects", "alloc_objects"}
+)
+
+func compareHeap(base *collect.Snapshot, target *collect.Snapshot) (*profile.Profile, error) {
+	baseProf, err := loadProfile(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base profile: %w", err)
+	}
+	targetProf, err := loadProfile(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target profile: %w", err)
+	}
+	return heapDelta(baseProf, targetProf)
+}
+
+func heapDelta(base *profile.Profile, target *profile.Profile) (*profile.Profile, error) {
+	for _, prof := range []*profile.Profile{base, target} {
+		for _, st := range heapRequiredTypes {
+			if _, err := sampleIndex(prof, st); err != nil {
+				return nil, fmt.Errorf("not a heap profile: %w", err)
+			}
+		}
+	}
+
+	base = base.Copy()
+	base.Scale(-1)
+	for _, sample := range base.Sample {
+		if sample.Label == nil {
+			sample.Label = map[string][]string{}
+		}
+		sample.Label["pprof::base"] = []string{"true"}
+	}
+
+	delta, err := profile.Merge([]*profile.Profile{base, target.Copy()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge profiles: %w", err)
+	}
+	return delta, nil
+}
+
+func deltaTable(delta *profile.Profile) []byte {
+	header := []string{"function"}
+	cols := []int{}
+	for _, st := range heapSampleTypes {
+		if idx, err := sampleIndex(delta, st); err == nil {
+			header = append(header, st)
+			cols = append(cols, idx)
+		}
+	}
+
+	index := map[string]*deltaRow{}
+	for _, sample := range delta.Sample {
+		stack := sampleStack(sample)
+		if len(stack) == 0 {
+			continue
+		}
+		name := stack[len(stack)-1].Name
+
+		row, ok := index[name]
+		if !ok {
+			row = &deltaRow{Function: name, Values: make([]int64, len(cols))}
+			index[name] = row
+		}
+		for i, idx := range cols {
+			row.Values[i] += sample.Value[idx]
+		}
+	}
+
+	rows := make([]*deltaRow, 0, len(index))
+	for _, row := range index {
+		if !row.zero() {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := abs(rows[i].Values[0]), abs(rows[j].Values[0])
+		if a != b {
+			return a > b
+		}
+		return rows[i].Function < rows[j].Function
+	})
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(strings.Join(header, "\t") + "\n")
+	for _, row := range rows {
+		buf.WriteString(row.Function)
+		for _, v := range row.Values {
+			fmt.Fprintf(buf, "\t%d", v)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func (r *deltaRow) zero() bool {
+	for _, v := range r.Values {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
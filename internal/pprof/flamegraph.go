@@ -0,0 +1,150 @@
+package pprof
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	stackFrame struct {
+		Name string
+		File string
+		Line int64
+	}
+
+	flameNode struct {
+		Name     string       `json:"name"`
+		Value    int64        `json:"value"`
+		Children []*flameNode `json:"children"`
+
+		index map[string]*flameNode
+	}
+)
+
+func loadProfile(snapshot *collect.Snapshot) (*profile.Profile, error) {
+	prof, _, err := readProfile(snapshot)
+	return prof, err
+}
+
+func readProfile(snapshot *collect.Snapshot) (*profile.Profile, bool, error) {
+	body, err := snapshot.Open()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read snapshot body: %w", err)
+	}
+	return decodeProfile(data)
+}
+
+func sampleIndex(prof *profile.Profile, sampleType string) (int, error) {
+	if sampleType == "" {
+		return len(prof.SampleType) - 1, nil
+	}
+	for i, st := range prof.SampleType {
+		if st.Type == sampleType {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no such sample type: %v", sampleType)
+}
+
+func sampleStack(sample *profile.Sample) []*stackFrame {
+	frames := []*stackFrame{}
+	for i := len(sample.Location) - 1; i >= 0; i-- {
+		loc := sample.Location[i]
+		if len(loc.Line) == 0 {
+			frames = append(frames, &stackFrame{Name: fmt.Sprintf("0x%x", loc.Address)})
+			continue
+		}
+		for j := len(loc.Line) - 1; j >= 0; j-- {
+			frame := &stackFrame{Name: "?", Line: loc.Line[j].Line}
+			if fn := loc.Line[j].Function; fn != nil {
+				frame.Name = fn.Name
+				frame.File = fn.Filename
+			}
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+func stackFrames(sample *profile.Sample) []string {
+	names := []string{}
+	for _, frame := range sampleStack(sample) {
+		names = append(names, frame.Name)
+	}
+	return names
+}
+
+func foldedStacks(prof *profile.Profile, idx int) []byte {
+	stacks := map[string]int64{}
+	for _, sample := range prof.Sample {
+		if v := sample.Value[idx]; v != 0 {
+			stacks[strings.Join(stackFrames(sample), ";")] += v
+		}
+	}
+
+	keys := make([]string, 0, len(stacks))
+	for key := range stacks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	for _, key := range keys {
+		fmt.Fprintf(buf, "%s %d\n", key, stacks[key])
+	}
+	return buf.Bytes()
+}
+
+func flameGraph(prof *profile.Profile, idx int) *flameNode {
+	root := newFlameNode("root")
+	for _, sample := range prof.Sample {
+		v := sample.Value[idx]
+		if v == 0 {
+			continue
+		}
+
+		node := root
+		node.Value += v
+		for _, frame := range stackFrames(sample) {
+			node = node.child(frame)
+			node.Value += v
+		}
+	}
+	root.sort()
+	return root
+}
+
+func newFlameNode(name string) *flameNode {
+	return &flameNode{Name: name, Children: []*flameNode{}, index: map[string]*flameNode{}}
+}
+
+func (n *flameNode) child(name string) *flameNode {
+	if c, ok := n.index[name]; ok {
+		return c
+	}
+	c := newFlameNode(name)
+	n.index[name] = c
+	n.Children = append(n.Children, c)
+	return c
+}
+
+func (n *flameNode) sort() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
@@ -1,10 +1,13 @@
 package pprof
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"sync"
 
+	"github.com/google/pprof/profile"
 	"github.com/kaz/pprotein/internal/collect"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
@@ -13,16 +16,26 @@ import (
 type (
 	handler struct {
 		opts      *collect.Options
+		toolOpts  *ToolOptions
 		collector *collect.Collector
+		processor *processor
 	}
 )
 
-func NewHandler(opts *collect.Options) *handler {
-	return &handler{opts: opts}
+func NewHandler(opts *collect.Options, toolOpts *ToolOptions) *handler {
+	return &handler{opts: opts, toolOpts: toolOpts}
 }
 
 func (h *handler) Register(g *echo.Group) error {
-	p := &processor{mu: &sync.Mutex{}, route: g}
+	t := newTool(h.toolOpts)
+	p := &processor{
+		tool:     t,
+		sessions: newSessions(t, h.toolOpts),
+		binaries: &binaries{store: h.opts.Store},
+
+		store: h.opts.Store,
+	}
+	h.processor = p
 
 	var err error
 	h.collector, err = collect.New(p, h.opts)
@@ -32,12 +45,29 @@ func (h *handler) Register(g *echo.Group) error {
 
 	g.GET("", h.getIndex)
 	g.POST("", h.postIndex)
+	g.GET("/binaries", h.getBinaries)
+	g.POST("/binaries", h.postBinary)
+	g.POST("/binaries/fetch", h.fetchBinary)
+	g.DELETE("/binaries/:buildId", h.deleteBinary)
+	g.GET("/sessions", h.getSessions)
+	g.DELETE("/sessions/:key", h.deleteSession)
+	g.GET("/diff/:base/:target", h.getDiff)
+	g.Any("/diff/:base/:target/*", h.serveDiffUI)
+	g.GET("/delta/:base/:target", h.getDelta)
+	g.GET("/delta/:base/:target/profile", h.getDeltaProfile)
+	g.Any("/:id/*", h.serveUI)
+	g.GET("/:id/folded", h.getFolded)
+	g.GET("/:id/flamegraph.json", h.getFlameGraph)
+	g.GET("/:id/speedscope.json", h.getSpeedscope)
+	g.GET("/:id/top", h.getTop)
+
+	h.collector.RegisterHandlers(g)
 
 	return nil
 }
 
 func (h *handler) getIndex(c echo.Context) error {
-	return c.JSON(http.StatusOK, h.collector.List())
+	return collect.ServeEntries(c, h.collector)
 }
 
 func (h *handler) postIndex(c echo.Context) error {
@@ -45,6 +75,9 @@ func (h *handler) postIndex(c echo.Context) error {
 	if err := c.Bind(target); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
 	}
+	if err := h.collector.EnforceQuota(); err != nil {
+		return echo.NewHTTPError(http.StatusInsufficientStorage, err.Error())
+	}
 
 	go func() {
 		if err := h.collector.Collect(target); err != nil {
@@ -54,3 +87,230 @@ func (h *handler) postIndex(c echo.Context) error {
 
 	return c.NoContent(http.StatusOK)
 }
+
+func (h *handler) getDiff(c echo.Context) error {
+	base, target, err := h.diffSnapshots(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.processor.Diff(base, target); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to generate diff: %v", err))
+	}
+	return c.Redirect(http.StatusFound, target.ID+"/")
+}
+
+func (h *handler) diffSnapshots(c echo.Context) (*collect.Snapshot, *collect.Snapshot, error) {
+	base, err := h.collector.Snapshot(c.Param("base"))
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("failed to find base snapshot: %v", err))
+	}
+	target, err := h.collector.Snapshot(c.Param("target"))
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("failed to find target snapshot: %v", err))
+	}
+	return base, target, nil
+}
+
+func (h *handler) loadDelta(c echo.Context) (*profile.Profile, error) {
+	base, err := h.collector.Snapshot(c.Param("base"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("failed to find base snapshot: %v", err))
+	}
+	target, err := h.collector.Snapshot(c.Param("target"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("failed to find target snapshot: %v", err))
+	}
+
+	delta, err := compareHeap(base, target)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to compare snapshots: %v", err))
+	}
+	return delta, nil
+}
+
+func (h *handler) getDelta(c echo.Context) error {
+	delta, err := h.loadDelta(c)
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, "text/tab-separated-values", deltaTable(delta))
+}
+
+func (h *handler) getDeltaProfile(c echo.Context) error {
+	delta, err := h.loadDelta(c)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s-heapdelta.pb.gz", c.Param("base"), c.Param("target"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEOctetStream)
+	c.Response().WriteHeader(http.StatusOK)
+	return delta.Write(c.Response())
+}
+
+func (h *handler) serveUI(c echo.Context) error {
+	snapshot, err := h.collector.Snapshot(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("failed to find snapshot: %v", err))
+	}
+	ui, err := h.processor.View(snapshot)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to open viewer: %v", err))
+	}
+	return h.serve(c, ui)
+}
+
+func (h *handler) serveDiffUI(c echo.Context) error {
+	base, target, err := h.diffSnapshots(c)
+	if err != nil {
+		return err
+	}
+	ui, err := h.processor.Diff(base, target)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to open viewer: %v", err))
+	}
+	return h.serve(c, ui)
+}
+
+func (h *handler) serve(c echo.Context, ui http.Handler) error {
+	req := c.Request().Clone(c.Request().Context())
+	req.URL.Path = "/" + c.Param("*")
+	req.URL.RawPath = ""
+	ui.ServeHTTP(c.Response(), req)
+	return nil
+}
+
+func (h *handler) getBinaries(c echo.Context) error {
+	bins, err := h.processor.binaries.list()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, bins)
+}
+
+func (h *handler) postBinary(c echo.Context) error {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to read file: %v", err))
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to open file: %v", err))
+	}
+	defer file.Close()
+
+	return h.putBinary(c, fh.Filename, c.FormValue("target"), file)
+}
+
+func (h *handler) fetchBinary(c echo.Context) error {
+	target := &collect.SnapshotTarget{}
+	if err := c.Bind(target); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+	if target.URL == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "URL is required")
+	}
+
+	resp, err := h.collector.Fetch(c.Request().Context(), target, agentBinaryPath)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("failed to fetch binary: %v", err))
+	}
+	defer resp.Body.Close()
+
+	name := "binary"
+	if _, params, err := mime.ParseMediaType(resp.Header.Get(echo.HeaderContentDisposition)); err == nil && params["filename"] != "" {
+		name = params["filename"]
+	}
+	return h.putBinary(c, name, target.URL, resp.Body)
+}
+
+func (h *handler) putBinary(c echo.Context, name string, target string, r io.Reader) error {
+	bin, err := h.processor.binaries.put(name, target, r)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to store binary: %v", err))
+	}
+	h.processor.sessions.reset()
+	return c.JSON(http.StatusCreated, bin)
+}
+
+func (h *handler) deleteBinary(c echo.Context) error {
+	if err := h.processor.binaries.delete(c.Param("buildId")); err != nil {
+		if errors.Is(err, ErrNoSuchBinary) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	h.processor.sessions.reset()
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) getSessions(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.processor.Sessions())
+}
+
+func (h *handler) deleteSession(c echo.Context) error {
+	if !h.processor.StopSession(c.Param("key")) {
+		return echo.NewHTTPError(http.StatusNotFound, "no such session")
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) loadProfile(c echo.Context) (*profile.Profile, int, error) {
+	snapshot, err := h.collector.Snapshot(c.Param("id"))
+	if err != nil {
+		return nil, 0, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("failed to find snapshot: %v", err))
+	}
+	prof, err := loadProfile(snapshot)
+	if err != nil {
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	idx, err := sampleIndex(prof, c.QueryParam("sample"))
+	if err != nil {
+		return nil, 0, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return prof, idx, nil
+}
+
+func (h *handler) getFolded(c echo.Context) error {
+	prof, idx, err := h.loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", c.Param("id")+".folded"))
+	return c.Blob(http.StatusOK, "text/plain", foldedStacks(prof, idx))
+}
+
+func (h *handler) getFlameGraph(c echo.Context) error {
+	prof, idx, err := h.loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", c.Param("id")+".json"))
+	return c.JSON(http.StatusOK, flameGraph(prof, idx))
+}
+
+func (h *handler) getSpeedscope(c echo.Context) error {
+	prof, _, err := h.loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", c.Param("id")+".speedscope.json"))
+	return c.JSON(http.StatusOK, speedscope(c.Param("id"), prof))
+}
+
+func (h *handler) getTop(c echo.Context) error {
+	if _, err := h.collector.Snapshot(c.Param("id")); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("failed to find snapshot: %v", err))
+	}
+
+	top, err := h.processor.Top(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("summary is not available: %v", err))
+	}
+	return c.Blob(http.StatusOK, "text/plain; charset=utf-8", top)
+}
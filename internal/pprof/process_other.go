@@ -0,0 +1,13 @@
+//go:build !unix
+
+package pprof
+
+import "os/exec"
+
+func isolateProcess(cmd *exec.Cmd) {}
+
+func killProcess(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+}
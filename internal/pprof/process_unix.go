@@ -0,0 +1,18 @@
+//go:build unix
+
+package pprof
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func isolateProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcess(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -1,19 +1,23 @@
 package pprof
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"sync"
+	"net/http"
 
-	"github.com/google/pprof/driver"
 	"github.com/kaz/pprotein/internal/collect"
-	"github.com/labstack/echo/v4"
+	"github.com/kaz/pprotein/internal/storage"
 )
 
 type (
 	processor struct {
-		mu    *sync.Mutex
-		route *echo.Group
+		tool     *tool
+		sessions *sessions
+		binaries *binaries
+
+		store storage.Storage
 	}
 )
 
@@ -21,38 +25,95 @@ func (p *processor) Cacheable() bool {
 	return false
 }
 
-func (p *processor) Process(snapshot *collect.Snapshot) (io.ReadCloser, error) {
-	registerProfileHandlers := func(args *driver.HTTPServerArgs) error {
-		if args.Hostport != "0:0" {
-			return fmt.Errorf("unxpected hostport: %v", args.Hostport)
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	prof, converted, err := readProfile(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if converted {
+		buf := &bytes.Buffer{}
+		if err := prof.Write(buf); err != nil {
+			return nil, fmt.Errorf("failed to encode profile: %w", err)
 		}
+		if err := p.store.PutFile(convertedFileId(snapshot.ID), buf.Bytes()); err != nil {
+			return nil, fmt.Errorf("failed to save converted profile: %w", err)
+		}
+	}
 
-		p.mu.Lock()
-		defer p.mu.Unlock()
+	bodyPath, err := p.profilePath(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	top, err := p.tool.top(append(p.binaries.argsFor(snapshot, prof), bodyPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize profile: %w", err)
+	}
+	if err := p.store.Put(topTypeKey, snapshot.ID, top); err != nil {
+		return nil, fmt.Errorf("failed to save summary: %w", err)
+	}
+	return nil, nil
+}
 
-		ig := p.route.Group(fmt.Sprintf("/%s", snapshot.ID))
-		for key, handler := range args.Handlers {
-			ig.Any(key, echo.WrapHandler(handler))
-		}
-		return nil
+func (p *processor) Purge(snapshot *collect.Snapshot) error {
+	p.sessions.close(snapshot.ID)
+	if err := p.store.DeleteFile(convertedFileId(snapshot.ID)); err != nil {
+		return err
 	}
+	return p.store.Delete(topTypeKey, snapshot.ID)
+}
+
+func convertedFileId(id string) string {
+	return id + "-converted.pb.gz"
+}
 
+func (p *processor) profilePath(snapshot *collect.Snapshot) (string, error) {
+	if ok, err := p.store.ExistsFile(convertedFileId(snapshot.ID)); err == nil && ok {
+		return p.store.GetFilePath(convertedFileId(snapshot.ID))
+	}
 	bodyPath, err := snapshot.BodyPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to find snapshot body: %w", err)
+		return "", fmt.Errorf("failed to find snapshot body: %w", err)
 	}
+	return bodyPath, nil
+}
+
+func (p *processor) SearchText(snapshot *collect.Snapshot) ([]byte, error) {
+	return p.Top(snapshot.ID)
+}
+
+func (p *processor) Top(id string) ([]byte, error) {
+	return p.store.Get(topTypeKey, id)
+}
 
-	options := &driver.Options{
-		Flagset: NewFlagSet([]string{
-			"-no_browser",
-			"-http", "0:0",
-			bodyPath,
-		}),
-		HTTPServer: registerProfileHandlers,
+func diffKey(base string, target string) string {
+	return fmt.Sprintf("%s..%s", base, target)
+}
+
+func (p *processor) View(snapshot *collect.Snapshot) (http.Handler, error) {
+	bodyPath, err := p.profilePath(snapshot)
+	if err != nil {
+		return nil, err
 	}
+	return p.sessions.open(snapshot.ID, append(p.binaries.args(snapshot), bodyPath)...)
+}
 
-	if err := driver.PProf(options); err != nil {
-		return nil, fmt.Errorf("pprof internal error: %w", err)
+func (p *processor) Diff(base *collect.Snapshot, target *collect.Snapshot) (http.Handler, error) {
+	basePath, err := p.profilePath(base)
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil
+	targetPath, err := p.profilePath(target)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"-diff_base", basePath}, p.binaries.args(target)...)
+	return p.sessions.open(diffKey(base.ID, target.ID), append(args, targetPath)...)
+}
+
+func (p *processor) Sessions() []*SessionStatus {
+	return p.sessions.list()
+}
+
+func (p *processor) StopSession(key string) bool {
+	return p.sessions.stop(key)
 }
@@ -0,0 +1,225 @@
+package pprof
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	SessionStatus struct {
+		Key      string
+		State    SessionState
+		Started  *time.Time `json:",omitempty"`
+		LastUsed *time.Time `json:",omitempty"`
+		Expires  *time.Time `json:",omitempty"`
+		Requests int
+		Restarts int
+	}
+
+	SessionState string
+
+	sessions struct {
+		mu       *sync.Mutex
+		entries  map[string]*session
+		restarts map[string]int
+		tool     *tool
+		idle     time.Duration
+		max      int
+	}
+
+	session struct {
+		mu       *sync.Mutex
+		viewer   *viewer
+		started  time.Time
+		lastUsed time.Time
+		requests int
+	}
+)
+
+const (
+	SessionStarting SessionState = "starting"
+	SessionRunning  SessionState = "running"
+
+	defaultIdleTimeout = 10 * time.Minute
+	defaultMaxSessions = 32
+)
+
+func newSessions(t *tool, opts *ToolOptions) *sessions {
+	s := &sessions{
+		mu:       &sync.Mutex{},
+		entries:  map[string]*session{},
+		restarts: map[string]int{},
+		tool:     t,
+		idle:     defaultIdleTimeout,
+		max:      defaultMaxSessions,
+	}
+	if opts != nil && opts.IdleTimeout > 0 {
+		s.idle = opts.IdleTimeout
+	}
+	if opts != nil && opts.MaxSessions > 0 {
+		s.max = opts.MaxSessions
+	}
+	go s.collectIdle()
+	return s
+}
+
+func (s *sessions) open(key string, args ...string) (http.Handler, error) {
+	s.mu.Lock()
+	sess, ok := s.entries[key]
+	if !ok {
+		sess = &session{mu: &sync.Mutex{}}
+		s.entries[key] = sess
+		s.evict(key)
+	}
+	s.mu.Unlock()
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.viewer != nil && sess.viewer.dead() {
+		log.Printf("[!] pprof viewer for %v exited, respawning", key)
+		sess.viewer.stop()
+		sess.viewer = nil
+
+		s.mu.Lock()
+		s.restarts[key]++
+		s.mu.Unlock()
+	}
+	if sess.viewer == nil {
+		v, err := s.tool.startViewer(args)
+		if err != nil {
+			s.drop(key, sess)
+			return nil, err
+		}
+		sess.viewer = v
+		sess.started = time.Now()
+	}
+	sess.lastUsed = time.Now()
+	sess.requests++
+	return sess.viewer, nil
+}
+
+func (s *sessions) evict(keep string) {
+	for len(s.entries) > s.max {
+		victim := ""
+		var oldest time.Time
+		for key, sess := range s.entries {
+			if key == keep || !sess.mu.TryLock() {
+				continue
+			}
+			if sess.viewer != nil && (victim == "" || sess.lastUsed.Before(oldest)) {
+				victim, oldest = key, sess.lastUsed
+			}
+			sess.mu.Unlock()
+		}
+		if victim == "" {
+			return
+		}
+		s.remove(victim)
+	}
+}
+
+func (s *sessions) remove(key string) {
+	if sess, ok := s.entries[key]; ok {
+		delete(s.entries, key)
+		s.restarts[key]++
+		sess.shutdown()
+	}
+}
+
+func (sess *session) shutdown() {
+	go func() {
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+
+		if sess.viewer != nil {
+			sess.viewer.stop()
+		}
+	}()
+}
+
+func (s *sessions) drop(key string, sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries[key] == sess {
+		delete(s.entries, key)
+	}
+}
+
+func (s *sessions) close(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.entries[key]
+	if ok {
+		delete(s.entries, key)
+		sess.shutdown()
+	}
+	delete(s.restarts, key)
+	return ok
+}
+
+func (s *sessions) stop(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.entries[key]
+	s.remove(key)
+	return ok
+}
+
+func (s *sessions) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.entries {
+		s.remove(key)
+	}
+}
+
+func (s *sessions) list() []*SessionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := make([]*SessionStatus, 0, len(s.entries))
+	for key, sess := range s.entries {
+		status := &SessionStatus{Key: key, State: SessionStarting, Restarts: s.restarts[key]}
+		if sess.mu.TryLock() {
+			if sess.viewer != nil {
+				status.State = SessionRunning
+				started, lastUsed, expires := sess.started, sess.lastUsed, sess.lastUsed.Add(s.idle)
+				status.Started, status.LastUsed, status.Expires = &started, &lastUsed, &expires
+			}
+			status.Requests = sess.requests
+			sess.mu.Unlock()
+		}
+		resp = append(resp, status)
+	}
+
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Key < resp[j].Key
+	})
+	return resp
+}
+
+func (s *sessions) collectIdle() {
+	for range time.Tick(s.idle / 2) {
+		deadline := time.Now().Add(-s.idle)
+
+		s.mu.Lock()
+		for key, sess := range s.entries {
+			if !sess.mu.TryLock() {
+				continue
+			}
+			if sess.viewer != nil && sess.lastUsed.Before(deadline) {
+				s.remove(key)
+			}
+			sess.mu.Unlock()
+		}
+		s.mu.Unlock()
+	}
+}
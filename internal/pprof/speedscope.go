@@ -0,0 +1,91 @@
+package pprof
+
+import (
+	"fmt"
+
+	"github.com/google/pprof/profile"
+)
+
+type (
+	speedscopeFile struct {
+		Schema   string              `json:"$schema"`
+		Name     string              `json:"name"`
+		Exporter string              `json:"exporter"`
+		Shared   speedscopeShared    `json:"shared"`
+		Profiles []*speedscopeSample `json:"profiles"`
+	}
+	speedscopeShared struct {
+		Frames []*speedscopeFrame `json:"frames"`
+	}
+	speedscopeFrame struct {
+		Name string `json:"name"`
+		File string `json:"file,omitempty"`
+		Line int64  `json:"line,omitempty"`
+	}
+	speedscopeSample struct {
+		Type       string  `json:"type"`
+		Name       string  `json:"name"`
+		Unit       string  `json:"unit"`
+		StartValue int64   `json:"startValue"`
+		EndValue   int64   `json:"endValue"`
+		Samples    [][]int `json:"samples"`
+		Weights    []int64 `json:"weights"`
+	}
+)
+
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+func speedscope(name string, prof *profile.Profile) *speedscopeFile {
+	file := &speedscopeFile{
+		Schema:   speedscopeSchema,
+		Name:     name,
+		Exporter: "pprotein",
+		Shared:   speedscopeShared{Frames: []*speedscopeFrame{}},
+		Profiles: []*speedscopeSample{},
+	}
+
+	frameIndex := map[stackFrame]int{}
+	stacks := make([][]int, len(prof.Sample))
+	for i, sample := range prof.Sample {
+		stack := []int{}
+		for _, frame := range sampleStack(sample) {
+			idx, ok := frameIndex[*frame]
+			if !ok {
+				idx = len(file.Shared.Frames)
+				frameIndex[*frame] = idx
+				file.Shared.Frames = append(file.Shared.Frames, &speedscopeFrame{Name: frame.Name, File: frame.File, Line: frame.Line})
+			}
+			stack = append(stack, idx)
+		}
+		stacks[i] = stack
+	}
+
+	for idx, st := range prof.SampleType {
+		p := &speedscopeSample{
+			Type:    "sampled",
+			Name:    fmt.Sprintf("%s (%s)", name, st.Type),
+			Unit:    speedscopeUnit(st.Unit),
+			Samples: [][]int{},
+			Weights: []int64{},
+		}
+		for i, sample := range prof.Sample {
+			v := sample.Value[idx]
+			if v == 0 {
+				continue
+			}
+			p.Samples = append(p.Samples, stacks[i])
+			p.Weights = append(p.Weights, v)
+			p.EndValue += v
+		}
+		file.Profiles = append(file.Profiles, p)
+	}
+	return file
+}
+
+func speedscopeUnit(unit string) string {
+	switch unit {
+	case "nanoseconds", "microseconds", "milliseconds", "seconds", "bytes":
+		return unit
+	}
+	return "none"
+}
@@ -0,0 +1,86 @@
+package pprof
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html"
+
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+const (
+	svgWidth     = 1200
+	svgRowHeight = 16
+	svgMaxDepth  = 64
+	svgCharWidth = 7
+)
+
+func FlameGraphSVG(snapshot *collect.Snapshot) ([]byte, error) {
+	prof, err := loadProfile(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if len(prof.SampleType) == 0 {
+		return nil, fmt.Errorf("profile has no sample types")
+	}
+	return flameGraphSVG(flameGraph(prof, len(prof.SampleType)-1)), nil
+}
+
+func flameGraphSVG(root *flameNode) []byte {
+	depth := root.depth()
+	if depth > svgMaxDepth {
+		depth = svgMaxDepth
+	}
+	height := depth * svgRowHeight
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="11">`, svgWidth, height, svgWidth, height)
+	if root.Value > 0 {
+		writeSVGNode(buf, root, root.Value, 0, 0, svgWidth)
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+func writeSVGNode(buf *bytes.Buffer, n *flameNode, total int64, level int, x float64, width float64) {
+	if width < 1 || level >= svgMaxDepth {
+		return
+	}
+
+	y := level * svgRowHeight
+	name := html.EscapeString(n.Name)
+	fmt.Fprintf(buf, `<g><title>%s (%d, %.2f%%)</title>`, name, n.Value, float64(n.Value)*100/float64(total))
+	fmt.Fprintf(buf, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" stroke="#fff" stroke-width="0.5"/>`, x, y, width, svgRowHeight, svgColor(n.Name))
+	if chars := int(width) / svgCharWidth; chars >= 3 {
+		label := n.Name
+		if len(label) > chars {
+			label = label[:chars-2] + ".."
+		}
+		fmt.Fprintf(buf, `<text x="%.1f" y="%d">%s</text>`, x+2, y+svgRowHeight-4, html.EscapeString(label))
+	}
+	buf.WriteString("</g>")
+
+	for _, c := range n.Children {
+		w := width * float64(c.Value) / float64(n.Value)
+		writeSVGNode(buf, c, total, level+1, x, w)
+		x += w
+	}
+}
+
+func (n *flameNode) depth() int {
+	deepest := 0
+	for _, c := range n.Children {
+		if d := c.depth(); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest + 1
+}
+
+func svgColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, 80+(v>>8)%130, 40+(v>>16)%40)
+}
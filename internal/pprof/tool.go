@@ -0,0 +1,155 @@
+package pprof
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os/exec"
+	"time"
+
+	"github.com/google/pprof/driver"
+)
+
+type (
+	ToolOptions struct {
+		IdleTimeout time.Duration
+		MaxSessions int
+		Command     []string
+		Args        []string
+	}
+
+	tool struct {
+		command []string
+		args    []string
+	}
+
+	viewer struct {
+		http.Handler
+		stop   func()
+		exited <-chan struct{}
+	}
+)
+
+const (
+	viewerStartTimeout = 30 * time.Second
+	externalUIPrefix   = "/ui"
+)
+
+func newTool(opts *ToolOptions) *tool {
+	if opts == nil {
+		return &tool{}
+	}
+	return &tool{command: opts.Command, args: opts.Args}
+}
+
+func (t *tool) flags(flags []string, args []string) []string {
+	return append(append(append([]string{}, flags...), t.args...), args...)
+}
+
+func (t *tool) run(flags []string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(t.command[0], append(t.command[1:], flags...)...)
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pprof command failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out, nil
+}
+
+func (t *tool) startViewer(args []string) (*viewer, error) {
+	if len(t.command) > 0 {
+		return t.spawnViewer(args)
+	}
+
+	var handler http.Handler
+	options := &driver.Options{
+		Flagset: NewFlagSet(t.flags([]string{"-no_browser", "-http", "0:0"}, args)),
+		HTTPServer: func(args *driver.HTTPServerArgs) error {
+			if args.Hostport != "0:0" {
+				return fmt.Errorf("unxpected hostport: %v", args.Hostport)
+			}
+
+			mux := http.NewServeMux()
+			for pattern, h := range args.Handlers {
+				mux.Handle(pattern, h)
+			}
+			handler = mux
+			return nil
+		},
+	}
+
+	if err := driver.PProf(options); err != nil {
+		return nil, fmt.Errorf("pprof internal error: %w", err)
+	}
+	return &viewer{Handler: handler, stop: func() {}}, nil
+}
+
+func (t *tool) spawnViewer(args []string) (*viewer, error) {
+	addr, err := freeAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(t.command[0], append(t.command[1:], t.flags([]string{"-no_browser", "-http", addr}, args)...)...)
+	cmd.Stderr = stderr
+	isolateProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start pprof command: %w", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	stop := func() {
+		killProcess(cmd)
+		<-exited
+	}
+
+	deadline := time.Now().Add(viewerStartTimeout)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+
+		select {
+		case <-exited:
+			return nil, fmt.Errorf("pprof command exited: %s", bytes.TrimSpace(stderr.Bytes()))
+		case <-time.After(100 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			stop()
+			return nil, fmt.Errorf("pprof command did not listen on %v in %v", addr, viewerStartTimeout)
+		}
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: addr, Path: externalUIPrefix})
+	return &viewer{Handler: proxy, stop: stop, exited: exited}, nil
+}
+
+func (v *viewer) dead() bool {
+	select {
+	case <-v.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func freeAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
@@ -0,0 +1,45 @@
+package pprof
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/google/pprof/driver"
+)
+
+type (
+	bufferWriter struct {
+		*bytes.Buffer
+	}
+)
+
+const (
+	topTypeKey   = "pprof-top"
+	topNodeCount = 20
+)
+
+func (w *bufferWriter) Open(name string) (io.WriteCloser, error) {
+	return w, nil
+}
+
+func (w *bufferWriter) Close() error {
+	return nil
+}
+
+func (t *tool) top(args []string) ([]byte, error) {
+	flags := []string{"-top", fmt.Sprintf("-nodecount=%d", topNodeCount)}
+	if len(t.command) > 0 {
+		return t.run(t.flags(flags, args))
+	}
+
+	w := &bufferWriter{&bytes.Buffer{}}
+	options := &driver.Options{
+		Flagset: NewFlagSet(t.flags(append(flags, "-output", "top.txt"), args)),
+		Writer:  w,
+	}
+	if err := driver.PProf(options); err != nil {
+		return nil, fmt.Errorf("pprof internal error: %w", err)
+	}
+	return w.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package pprof
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	FunctionStat struct {
+		Name string
+		Unit string
+		Flat int64
+		Cum  int64
+	}
+)
+
+func TopFunctions(snapshot *collect.Snapshot, n int) ([]*FunctionStat, error) {
+	prof, err := loadProfile(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if len(prof.SampleType) == 0 {
+		return nil, fmt.Errorf("profile has no sample types")
+	}
+	idx := len(prof.SampleType) - 1
+	unit := prof.SampleType[idx].Unit
+
+	index := map[string]*FunctionStat{}
+	for _, sample := range prof.Sample {
+		v := sample.Value[idx]
+		if v == 0 {
+			continue
+		}
+
+		stack := sampleStack(sample)
+		seen := map[string]bool{}
+		for i, frame := range stack {
+			st, ok := index[frame.Name]
+			if !ok {
+				st = &FunctionStat{Name: frame.Name, Unit: unit}
+				index[frame.Name] = st
+			}
+			if i == len(stack)-1 {
+				st.Flat += v
+			}
+			if !seen[frame.Name] {
+				seen[frame.Name] = true
+				st.Cum += v
+			}
+		}
+	}
+
+	stats := make([]*FunctionStat, 0, len(index))
+	for _, st := range index {
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Flat != stats[j].Flat {
+			return stats[i].Flat > stats[j].Flat
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats, nil
+}
@@ -0,0 +1,227 @@
+package project
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Builder func(name string) (http.Handler, error)
+
+	Manager struct {
+		store     storage.Storage
+		validator *validator.Validate
+		build     Builder
+
+		defaultHandler http.Handler
+
+		mu       *sync.RWMutex
+		projects map[string]*Project
+		handlers map[string]http.Handler
+	}
+
+	Project struct {
+		Name        string
+		Description string `json:",omitempty"`
+		Created     time.Time
+	}
+
+	createRequest struct {
+		Name        string `validate:"required,max=64"`
+		Description string
+	}
+)
+
+const (
+	Default = "default"
+	Header  = "X-Pprotein-Project"
+	Cookie  = "pprotein_project"
+
+	projectTypeKey = "project"
+	prefix         = "/api/projects/"
+)
+
+var namePattern = regexp.MustCompile(`^[0-9a-z][0-9a-z_-]*$`)
+
+func NewManager(store storage.Storage, defaultHandler http.Handler, build Builder) (*Manager, error) {
+	m := &Manager{
+		store:          store,
+		validator:      validator.New(),
+		build:          build,
+		defaultHandler: defaultHandler,
+		mu:             &sync.RWMutex{},
+		projects:       map[string]*Project{},
+		handlers:       map[string]http.Handler{},
+	}
+
+	raws, err := store.GetAll(projectTypeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects: %w", err)
+	}
+	for _, raw := range raws {
+		p := &Project{}
+		if err := json.Unmarshal(raw, p); err != nil {
+			log.Printf("[!] failed to unmarshal project: %v", err)
+			continue
+		}
+		if err := m.open(p); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Manager) RegisterHandlers(e *echo.Echo, g *echo.Group) {
+	g.GET("", m.getIndex)
+	g.POST("", m.postIndex)
+
+	e.Any(prefix+":project", m.serve)
+	e.Any(prefix+":project/*", m.serve)
+}
+
+func (m *Manager) Select(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+
+		name := req.Header.Get(Header)
+		if name == "" {
+			if cookie, err := req.Cookie(Cookie); err == nil {
+				name = cookie.Value
+			}
+		}
+		if name != "" && name != Default && selectable(req.URL.Path) {
+			req.URL.Path = prefix + name + strings.TrimPrefix(req.URL.Path, "/api")
+			req.URL.RawPath = ""
+		}
+		return next(c)
+	}
+}
+
+func (m *Manager) Projects() []*Project {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resp := make([]*Project, 0, len(m.projects)+1)
+	for _, p := range m.projects {
+		resp = append(resp, p)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Name < resp[j].Name
+	})
+	return append([]*Project{{Name: Default}}, resp...)
+}
+
+func (m *Manager) Create(req *createRequest) (*Project, error) {
+	if err := m.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	if !namePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("project name must consist of lowercase letters, digits, '_' and '-': %v", req.Name)
+	}
+	if req.Name == Default {
+		return nil, fmt.Errorf("project already exists: %v", req.Name)
+	}
+
+	m.mu.RLock()
+	_, exists := m.projects[req.Name]
+	m.mu.RUnlock()
+	if exists {
+		return nil, fmt.Errorf("project already exists: %v", req.Name)
+	}
+
+	p := &Project{Name: req.Name, Description: req.Description, Created: time.Now()}
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	if err := m.open(p); err != nil {
+		return nil, err
+	}
+	if err := m.store.Put(projectTypeKey, p.Name, raw); err != nil {
+		return nil, fmt.Errorf("failed to save project: %w", err)
+	}
+	return p, nil
+}
+
+func (m *Manager) open(p *Project) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.projects[p.Name]; ok {
+		return fmt.Errorf("project already exists: %v", p.Name)
+	}
+	handler, err := m.build(p.Name)
+	if err != nil {
+		return fmt.Errorf("failed to open project %v: %w", p.Name, err)
+	}
+	m.projects[p.Name] = p
+	m.handlers[p.Name] = handler
+	return nil
+}
+
+func (m *Manager) handler(name string) (http.Handler, bool) {
+	if name == Default {
+		return m.defaultHandler, true
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	h, ok := m.handlers[name]
+	return h, ok
+}
+
+func (m *Manager) getIndex(c echo.Context) error {
+	return c.JSON(http.StatusOK, m.Projects())
+}
+
+func (m *Manager) postIndex(c echo.Context) error {
+	req := &createRequest{}
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+
+	p, err := m.Create(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusCreated, p)
+}
+
+func (m *Manager) serve(c echo.Context) error {
+	name := c.Param("project")
+	h, ok := m.handler(name)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no such project: %v", name))
+	}
+
+	req := c.Request()
+	req.URL.Path = "/api/" + c.Param("*")
+	req.URL.RawPath = ""
+	req.Header.Set(Header, name)
+	h.ServeHTTP(c.Response(), req)
+	return nil
+}
+
+func selectable(p string) bool {
+	if !strings.HasPrefix(p, "/api/") {
+		return false
+	}
+	for _, global := range []string{"/api/projects", "/api/auth"} {
+		if p == global || strings.HasPrefix(p, global+"/") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package runtimestats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	processor struct{}
+
+	vars struct {
+		Memstats *struct {
+			HeapAlloc    uint64
+			HeapInuse    uint64
+			HeapSys      uint64
+			NumGC        uint32
+			PauseTotalNs uint64
+		} `json:"memstats"`
+		Goroutines *int `json:"goroutines"`
+		FDs        *int `json:"fds"`
+	}
+)
+
+var columns = []string{"Time", "Elapsed", "HeapAlloc", "HeapInuse", "HeapSys", "Goroutines", "NumGC", "GCPause", "OpenFDs"}
+
+func init() {
+	collect.RegisterProcessor("runtime", func(json.RawMessage) (collect.Processor, error) {
+		return &processor{}, nil
+	})
+}
+
+func (p *processor) Cacheable() bool {
+	return true
+}
+
+func (p *processor) Tabular() bool {
+	return true
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	body, err := snapshot.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	res, err := render(body)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(res)), nil
+}
+
+func render(r io.Reader) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(strings.Join(columns, "\t") + "\n")
+
+	var start time.Time
+	var lastPause uint64
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		sample := &collect.PollSample{}
+		if err := json.Unmarshal(scanner.Bytes(), sample); err != nil {
+			return nil, fmt.Errorf("failed to parse sample: %w", err)
+		}
+		v := &vars{}
+		if err := json.Unmarshal(sample.Data, v); err != nil {
+			return nil, fmt.Errorf("failed to parse vars: %w", err)
+		}
+
+		if start.IsZero() {
+			start = sample.Time
+		}
+		row := []string{sample.Time.Format(time.RFC3339Nano), fmt.Sprintf("%.3f", sample.Time.Sub(start).Seconds())}
+		if m := v.Memstats; m != nil {
+			pause := uint64(0)
+			if lastPause > 0 && m.PauseTotalNs >= lastPause {
+				pause = m.PauseTotalNs - lastPause
+			}
+			lastPause = m.PauseTotalNs
+			row = append(row,
+				fmt.Sprint(m.HeapAlloc),
+				fmt.Sprint(m.HeapInuse),
+				fmt.Sprint(m.HeapSys),
+				optional(v.Goroutines),
+				fmt.Sprint(m.NumGC),
+				fmt.Sprintf("%.6f", time.Duration(pause).Seconds()),
+			)
+		} else {
+			row = append(row, "", "", "", optional(v.Goroutines), "", "")
+		}
+		row = append(row, optional(v.FDs))
+		buf.WriteString(strings.Join(row, "\t") + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func optional(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(*v)
+}
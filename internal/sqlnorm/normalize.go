@@ -0,0 +1,34 @@
+package sqlnorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+var (
+	stringPattern     = regexp.MustCompile(`'(?:[^']|'')*'`)
+	paramPattern      = regexp.MustCompile(`\$\d+`)
+	numberPattern     = regexp.MustCompile(`([^\w.$])-?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?\b`)
+	listPattern       = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+	valuesPattern     = regexp.MustCompile(`(?i)(values\s*\(\?\))(?:\s*,\s*\(\?\))+`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+func Normalize(query string) string {
+	q := whitespacePattern.ReplaceAllString(strings.TrimSpace(query), " ")
+	q = strings.TrimRight(q, "; ")
+
+	q = stringPattern.ReplaceAllString(q, "?")
+	q = paramPattern.ReplaceAllString(q, "?")
+	q = numberPattern.ReplaceAllString(" "+q, "${1}?")[1:]
+	q = listPattern.ReplaceAllString(q, "(?)")
+	q = valuesPattern.ReplaceAllString(q, "$1")
+	return q
+}
+
+func Fingerprint(query string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(Normalize(query))))
+	return hex.EncodeToString(sum[:8])
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
 )
@@ -32,6 +33,25 @@ func (s *fileStore) PutFile(id string, data []byte) error {
 	}
 	return nil
 }
+func (s *fileStore) PutFileStream(id string, r io.Reader) error {
+	file, err := os.Create(path.Join(s.workdir, id))
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+func (s *fileStore) OpenFile(id string) (io.ReadCloser, error) {
+	file, err := os.Open(path.Join(s.workdir, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
 func (s *fileStore) GetFilePath(id string) (string, error) {
 	return path.Join(s.workdir, id), nil
 }
@@ -39,3 +59,23 @@ func (s *fileStore) ExistsFile(id string) (bool, error) {
 	_, err := os.Stat(path.Join(s.workdir, id))
 	return err == nil, nil
 }
+func (s *fileStore) FileSize(id string) (int64, error) {
+	return localFileSize(path.Join(s.workdir, id))
+}
+func (s *fileStore) DeleteFile(id string) error {
+	if err := os.Remove(path.Join(s.workdir, id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
+}
+
+func localFileSize(name string) (int64, error) {
+	finfo, err := os.Stat(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return finfo.Size(), nil
+}
@@ -42,7 +42,9 @@ func (s *kvStore) Get(typ, id string) (resp []byte, err error) {
 			return nil
 		}
 
-		resp = bucket.Get([]byte(id))
+		if v := bucket.Get([]byte(id)); v != nil {
+			resp = append([]byte{}, v...)
+		}
 		return nil
 	})
 	return resp, err
@@ -57,7 +59,7 @@ func (s *kvStore) GetAll(typ string) (resp [][]byte, err error) {
 		}
 
 		bucket.ForEach(func(k, v []byte) error {
-			resp = append(resp, v)
+			resp = append(resp, append([]byte{}, v...))
 			return nil
 		})
 		return nil
@@ -76,6 +78,18 @@ func (s *kvStore) Exists(typ, id string) (exists bool, err error) {
 	})
 	return exists, err
 }
+func (s *kvStore) Size(typ, id string) (size int64, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(typ))
+		if bucket == nil {
+			return nil
+		}
+
+		size = int64(len(bucket.Get([]byte(id))))
+		return nil
+	})
+	return size, err
+}
 func (s *kvStore) Delete(typ, id string) error {
 	return s.db.Update(func(tx *bbolt.Tx) error {
 		bucket, err := tx.CreateBucketIfNotExists([]byte(typ))
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type (
+	ObjectConfig struct {
+		Provider  string
+		Endpoint  string
+		Region    string
+		Bucket    string
+		Prefix    string
+		AccessKey string
+		SecretKey string
+		Insecure  bool
+		CacheSize int64
+	}
+
+	objectStore struct {
+		client  *minio.Client
+		bucket  string
+		prefix  string
+		workdir string
+
+		cacheMu   *sync.Mutex
+		cacheSize int64
+	}
+)
+
+const (
+	ProviderS3  = "s3"
+	ProviderGCS = "gcs"
+
+	defaultCacheSize = 1 << 30
+	uploadPartSize   = 16 << 20
+)
+
+func NewObject(workdir string, cfg *ObjectConfig) (Storage, error) {
+	workdir = path.Join(workdir, "cache")
+	if err := os.MkdirAll(workdir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create workdir: %w", err)
+	}
+
+	endpoint := cfg.Endpoint
+	switch cfg.Provider {
+	case ProviderS3:
+		if endpoint == "" {
+			endpoint = "s3.amazonaws.com"
+		}
+	case ProviderGCS:
+		if endpoint == "" {
+			endpoint = "storage.googleapis.com"
+		}
+	default:
+		return nil, fmt.Errorf("unknown provider: %v", cfg.Provider)
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Region: cfg.Region,
+		Secure: !cfg.Insecure,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	ok, err := client.BucketExists(context.Background(), cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("no such bucket: %v", cfg.Bucket)
+	}
+
+	cacheSize := cfg.CacheSize
+	if cacheSize <= 0 {
+		cacheSize = defaultCacheSize
+	}
+
+	return &objectStore{
+		client:  client,
+		bucket:  cfg.Bucket,
+		prefix:  strings.Trim(cfg.Prefix, "/"),
+		workdir: workdir,
+
+		cacheMu:   &sync.Mutex{},
+		cacheSize: cacheSize,
+	}, nil
+}
+
+func (s *objectStore) key(elem ...string) string {
+	return path.Join(append([]string{s.prefix}, elem...)...)
+}
+
+func isNotFound(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
+
+func (s *objectStore) Put(typ, id string, data []byte) error {
+	if _, err := s.client.PutObject(context.Background(), s.bucket, s.key("kv", typ, id), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to put object: %w", err)
+	}
+	return nil
+}
+func (s *objectStore) Get(typ, id string) ([]byte, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.key("kv", typ, id), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+func (s *objectStore) GetAll(typ string) ([][]byte, error) {
+	resp := make([][]byte, 0)
+
+	for info := range s.client.ListObjects(context.Background(), s.bucket, minio.ListObjectsOptions{Prefix: s.key("kv", typ) + "/"}) {
+		if info.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", info.Err)
+		}
+
+		data, err := s.Get(typ, path.Base(info.Key))
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			resp = append(resp, data)
+		}
+	}
+	return resp, nil
+}
+func (s *objectStore) Exists(typ, id string) (bool, error) {
+	return s.exists(s.key("kv", typ, id))
+}
+func (s *objectStore) Size(typ, id string) (int64, error) {
+	info, err := s.client.StatObject(context.Background(), s.bucket, s.key("kv", typ, id), minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFound(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return info.Size, nil
+}
+func (s *objectStore) Delete(typ, id string) error {
+	if err := s.client.RemoveObject(context.Background(), s.bucket, s.key("kv", typ, id), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to remove object: %w", err)
+	}
+	return nil
+}
+
+func (s *objectStore) exists(key string) (bool, error) {
+	if _, err := s.client.StatObject(context.Background(), s.bucket, key, minio.StatObjectOptions{}); err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return true, nil
+}
+
+func (s *objectStore) PutFile(id string, data []byte) error {
+	return s.PutFileStream(id, bytes.NewReader(data))
+}
+func (s *objectStore) PutFileStream(id string, r io.Reader) error {
+	if _, err := s.client.PutObject(context.Background(), s.bucket, s.key("blob", id), r, -1, minio.PutObjectOptions{PartSize: uploadPartSize}); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := os.Remove(path.Join(s.workdir, id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale cache: %w", err)
+	}
+	return nil
+}
+func (s *objectStore) OpenFile(id string) (io.ReadCloser, error) {
+	if file, err := os.Open(path.Join(s.workdir, id)); err == nil {
+		return file, nil
+	}
+
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.key("blob", id), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	return obj, nil
+}
+func (s *objectStore) GetFilePath(id string) (string, error) {
+	localPath := path.Join(s.workdir, id)
+	if _, err := os.Stat(localPath); err == nil {
+		now := time.Now()
+		if err := os.Chtimes(localPath, now, now); err != nil {
+			return "", fmt.Errorf("failed to touch cache: %w", err)
+		}
+		return localPath, nil
+	}
+
+	if err := s.client.FGetObject(context.Background(), s.bucket, s.key("blob", id), localPath, minio.GetObjectOptions{}); err != nil && !isNotFound(err) {
+		return "", fmt.Errorf("failed to download file: %w", err)
+	}
+	s.evict(id)
+	return localPath, nil
+}
+func (s *objectStore) ExistsFile(id string) (bool, error) {
+	if _, err := os.Stat(path.Join(s.workdir, id)); err == nil {
+		return true, nil
+	}
+	return s.exists(s.key("blob", id))
+}
+func (s *objectStore) FileSize(id string) (int64, error) {
+	if finfo, err := os.Stat(path.Join(s.workdir, id)); err == nil {
+		return finfo.Size(), nil
+	}
+
+	info, err := s.client.StatObject(context.Background(), s.bucket, s.key("blob", id), minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFound(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return info.Size, nil
+}
+func (s *objectStore) DeleteFile(id string) error {
+	if err := os.Remove(path.Join(s.workdir, id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	if err := s.client.RemoveObject(context.Background(), s.bucket, s.key("blob", id), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to remove object: %w", err)
+	}
+	return nil
+}
+
+func (s *objectStore) evict(keep string) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	entries, err := os.ReadDir(s.workdir)
+	if err != nil {
+		log.Printf("[!] failed to read cache: %v", err)
+		return
+	}
+
+	files := []os.FileInfo{}
+	total := int64(0)
+	for _, entry := range entries {
+		finfo, err := entry.Info()
+		if err != nil || !finfo.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, finfo)
+		total += finfo.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	for _, finfo := range files {
+		if total <= s.cacheSize {
+			return
+		}
+		if finfo.Name() == keep {
+			continue
+		}
+		if err := os.Remove(path.Join(s.workdir, finfo.Name())); err != nil && !os.IsNotExist(err) {
+			log.Printf("[!] failed to evict %v: %v", finfo.Name(), err)
+			continue
+		}
+		total -= finfo.Size()
+	}
+}
@@ -1,5 +1,7 @@
 package storage
 
+import "io"
+
 type (
 	Storage interface {
 		kvStorage
@@ -11,11 +13,16 @@ type (
 		Get(typ, id string) ([]byte, error)
 		GetAll(typ string) ([][]byte, error)
 		Exists(typ, id string) (bool, error)
+		Size(typ, id string) (int64, error)
 		Delete(typ, id string) error
 	}
 	fileStorage interface {
 		PutFile(id string, data []byte) error
+		PutFileStream(id string, r io.Reader) error
+		OpenFile(id string) (io.ReadCloser, error)
 		GetFilePath(id string) (string, error)
 		ExistsFile(id string) (bool, error)
+		FileSize(id string) (int64, error)
+		DeleteFile(id string) error
 	}
 )
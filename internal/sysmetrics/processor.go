@@ -0,0 +1,107 @@
+package sysmetrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	processor struct{}
+
+	sample struct {
+		Time      time.Time
+		CPUUser   float64
+		CPUSystem float64
+		CPUIOWait float64
+		CPUSteal  float64
+		CPUIdle   float64
+		Load1     float64
+		MemTotal  uint64
+		MemUsed   uint64
+		DiskRead  float64
+		DiskWrite float64
+		NetRx     float64
+		NetTx     float64
+	}
+)
+
+var columns = []string{"Time", "Elapsed", "CPUUser", "CPUSystem", "CPUIOWait", "CPUSteal", "CPUIdle", "Load1", "MemUsed", "MemTotal", "DiskRead", "DiskWrite", "NetRx", "NetTx"}
+
+func init() {
+	collect.RegisterProcessor("system", func(json.RawMessage) (collect.Processor, error) {
+		return &processor{}, nil
+	})
+}
+
+func (p *processor) Cacheable() bool {
+	return true
+}
+
+func (p *processor) Tabular() bool {
+	return true
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	body, err := snapshot.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot body: %w", err)
+	}
+	defer body.Close()
+
+	res, err := render(body)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(res)), nil
+}
+
+func render(r io.Reader) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(strings.Join(columns, "\t") + "\n")
+
+	var start time.Time
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		s := &sample{}
+		if err := json.Unmarshal(scanner.Bytes(), s); err != nil {
+			return nil, fmt.Errorf("failed to parse sample: %w", err)
+		}
+		if start.IsZero() {
+			start = s.Time
+		}
+
+		row := []string{
+			s.Time.Format(time.RFC3339Nano),
+			fmt.Sprintf("%.3f", s.Time.Sub(start).Seconds()),
+			fmt.Sprintf("%.1f", s.CPUUser),
+			fmt.Sprintf("%.1f", s.CPUSystem),
+			fmt.Sprintf("%.1f", s.CPUIOWait),
+			fmt.Sprintf("%.1f", s.CPUSteal),
+			fmt.Sprintf("%.1f", s.CPUIdle),
+			fmt.Sprintf("%.2f", s.Load1),
+			fmt.Sprint(s.MemUsed),
+			fmt.Sprint(s.MemTotal),
+			fmt.Sprintf("%.0f", s.DiskRead),
+			fmt.Sprintf("%.0f", s.DiskWrite),
+			fmt.Sprintf("%.0f", s.NetRx),
+			fmt.Sprintf("%.0f", s.NetTx),
+		}
+		buf.WriteString(strings.Join(row, "\t") + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,131 @@
+package tail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	checkpoint struct {
+		Inode  uint64
+		Offset int64
+	}
+)
+
+var (
+	checkpointDir = checkpointDirectory()
+	checkpointMu  = &sync.Mutex{}
+)
+
+func checkpointDirectory() string {
+	if dir := os.Getenv("PPROTEIN_CHECKPOINT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "pprotein-checkpoints")
+}
+
+func checkpointPath(filename, name string) string {
+	sum := sha256.Sum256([]byte(filename + "\x00" + name))
+	return filepath.Join(checkpointDir, hex.EncodeToString(sum[:8]))
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	cp := &checkpoint{}
+	if err := json.Unmarshal(raw, cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+func saveCheckpoint(path string, cp *checkpoint) error {
+	raw, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to serialize checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *fileSource) sinceCheckpoint(w io.Writer, name string) error {
+	checkpointMu.Lock()
+	defer checkpointMu.Unlock()
+
+	path := checkpointPath(s.filename, name) + ".json"
+	cp, err := loadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(s.filename)
+	if err != nil {
+		return fmt.Errorf("failed to open: %w", err)
+	}
+	defer file.Close()
+
+	finfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat: %w", err)
+	}
+	inode := fileID(finfo)
+
+	start := int64(0)
+	if cp != nil {
+		if cp.Inode == inode && cp.Offset <= finfo.Size() {
+			start = cp.Offset
+		} else if err := copyRotated(w, s.filename, cp); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(w, io.NewSectionReader(file, start, finfo.Size()-start)); err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+	return saveCheckpoint(path, &checkpoint{Inode: inode, Offset: finfo.Size()})
+}
+
+func copyRotated(w io.Writer, filename string, cp *checkpoint) error {
+	if cp.Inode == 0 {
+		return nil
+	}
+
+	for _, candidate := range []string{filename + ".1", filename + ".0", filename + "-old"} {
+		finfo, err := os.Stat(candidate)
+		if err != nil || fileID(finfo) != cp.Inode || finfo.Size() < cp.Offset {
+			continue
+		}
+
+		file, err := os.Open(candidate)
+		if err != nil {
+			return fmt.Errorf("failed to open rotated log: %w", err)
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(w, io.NewSectionReader(file, cp.Offset, finfo.Size()-cp.Offset)); err != nil {
+			return fmt.Errorf("failed to copy rotated log: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
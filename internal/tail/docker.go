@@ -0,0 +1,78 @@
+package tail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type (
+	dockerSource struct {
+		container string
+	}
+)
+
+func NewDockerHandler(container string, format string) *TailHandler {
+	return &TailHandler{source: &dockerSource{container}, format: format}
+}
+
+func (s *dockerSource) tail(w io.Writer, duration time.Duration) error {
+	if err := s.inspect(); err != nil {
+		return err
+	}
+	return followCommand(w, duration, "docker", s.args("--follow", "--since", dockerTime(time.Now()))...)
+}
+
+func (s *dockerSource) window(w io.Writer, format string, duration time.Duration) error {
+	if err := s.inspect(); err != nil {
+		return err
+	}
+	return runCommand(context.Background(), w, "docker", s.args("--since", dockerTime(time.Now().Add(-duration)))...)
+}
+
+func (s *dockerSource) sinceCheckpoint(w io.Writer, name string) error {
+	checkpointMu.Lock()
+	defer checkpointMu.Unlock()
+
+	if err := s.inspect(); err != nil {
+		return err
+	}
+
+	path := checkpointPath("docker:"+s.container, name)
+	since, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	until := dockerTime(time.Now())
+	args := []string{"--until", until}
+	if len(since) > 0 {
+		args = append(args, "--since", string(since))
+	}
+	if err := runCommand(context.Background(), w, "docker", s.args(args...)...); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(until), 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *dockerSource) inspect() error {
+	return runCommand(context.Background(), io.Discard, "docker", "inspect", "--type", "container", "--format", "{{.Id}}", s.container)
+}
+
+func (s *dockerSource) args(args ...string) []string {
+	return append(append([]string{"logs"}, args...), s.container)
+}
+
+func dockerTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
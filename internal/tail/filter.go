@@ -0,0 +1,134 @@
+package tail
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+type (
+	lineFilter struct {
+		include *regexp.Regexp
+		exclude *regexp.Regexp
+	}
+
+	filterWriter struct {
+		w       io.Writer
+		filters []*lineFilter
+		start   timestampParser
+
+		line   []byte
+		record []byte
+	}
+)
+
+var multilineFormats = map[string]bool{
+	"slowlog":   true,
+	"pgslowlog": true,
+}
+
+func newLineFilter(include string, exclude string) (*lineFilter, error) {
+	f := &lineFilter{}
+	if include != "" {
+		re, err := regexp.Compile(include)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+		f.include = re
+	}
+	if exclude != "" {
+		re, err := regexp.Compile(exclude)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+		f.exclude = re
+	}
+	if f.include == nil && f.exclude == nil {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func (h *TailHandler) SetFilter(include string, exclude string) error {
+	f, err := newLineFilter(include, exclude)
+	if err != nil {
+		return err
+	}
+	h.filter = f
+	return nil
+}
+
+func (f *lineFilter) match(record []byte) bool {
+	if f.include != nil && !f.include.Match(record) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.Match(record)
+}
+
+func newFilterWriter(w io.Writer, format string, filters []*lineFilter) *filterWriter {
+	fw := &filterWriter{w: w, filters: filters}
+	if multilineFormats[format] {
+		fw.start = timestampParsers[format]
+	}
+	return fw
+}
+
+func (fw *filterWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			fw.line = append(fw.line, p...)
+			break
+		}
+		fw.line = append(fw.line, p[:i+1]...)
+		p = p[i+1:]
+
+		if err := fw.push(fw.line); err != nil {
+			return 0, err
+		}
+		fw.line = fw.line[:0]
+	}
+	return n, nil
+}
+
+func (fw *filterWriter) push(line []byte) error {
+	if fw.start == nil {
+		return fw.emit(line)
+	}
+	if _, ok := fw.start(line); ok && len(fw.record) > 0 {
+		if err := fw.emit(fw.record); err != nil {
+			return err
+		}
+		fw.record = fw.record[:0]
+	}
+	fw.record = append(fw.record, line...)
+	return nil
+}
+
+func (fw *filterWriter) emit(record []byte) error {
+	for _, f := range fw.filters {
+		if !f.match(record) {
+			return nil
+		}
+	}
+	_, err := fw.w.Write(record)
+	return err
+}
+
+func (fw *filterWriter) Flush() error {
+	if len(fw.line) > 0 {
+		if err := fw.push(fw.line); err != nil {
+			return err
+		}
+		fw.line = fw.line[:0]
+	}
+	if len(fw.record) > 0 {
+		if err := fw.emit(fw.record); err != nil {
+			return err
+		}
+		fw.record = fw.record[:0]
+	}
+	return nil
+}
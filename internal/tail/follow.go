@@ -0,0 +1,106 @@
+package tail
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+type (
+	follower struct {
+		filename string
+		file     *os.File
+		pos      int64
+	}
+)
+
+const pollInterval = 500 * time.Millisecond
+
+func newFollower(filename string) (*follower, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open: %w", err)
+	}
+
+	pos, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek: %w", err)
+	}
+	return &follower{filename: filename, file: file, pos: pos}, nil
+}
+
+func (f *follower) Close() error {
+	return f.file.Close()
+}
+
+func (f *follower) follow(w io.Writer, duration time.Duration) error {
+	deadline := time.Now().Add(duration)
+	for {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return f.drain(w)
+		}
+		if wait > pollInterval {
+			wait = pollInterval
+		}
+		time.Sleep(wait)
+
+		if err := f.check(w); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *follower) check(w io.Writer) error {
+	current, err := f.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat: %w", err)
+	}
+	latest, err := os.Stat(f.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f.drain(w)
+		}
+		return fmt.Errorf("failed to stat: %w", err)
+	}
+
+	if !os.SameFile(current, latest) {
+		if err := f.drain(w); err != nil {
+			return err
+		}
+		return f.reopen()
+	}
+	if current.Size() < f.pos {
+		f.pos = 0
+	}
+	return f.drain(w)
+}
+
+func (f *follower) drain(w io.Writer) error {
+	finfo, err := f.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat: %w", err)
+	}
+	if finfo.Size() < f.pos {
+		f.pos = 0
+	}
+
+	n, err := io.Copy(w, io.NewSectionReader(f.file, f.pos, finfo.Size()-f.pos))
+	f.pos += n
+	if err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+	return nil
+}
+
+func (f *follower) reopen() error {
+	file, err := os.Open(f.filename)
+	if err != nil {
+		return fmt.Errorf("failed to reopen: %w", err)
+	}
+	f.file.Close()
+	f.file, f.pos = file, 0
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tail
+
+import "os"
+
+func fileID(finfo os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package tail
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileID(finfo os.FileInfo) uint64 {
+	if st, ok := finfo.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package tail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type (
+	journalSource struct {
+		unit string
+	}
+)
+
+func NewJournalHandler(unit string, format string) *TailHandler {
+	return &TailHandler{source: &journalSource{unit}, format: format}
+}
+
+func (s *journalSource) tail(w io.Writer, duration time.Duration) error {
+	return followCommand(w, duration, "journalctl", s.args("--follow", "--lines", "0")...)
+}
+
+func (s *journalSource) window(w io.Writer, format string, duration time.Duration) error {
+	since := time.Now().Add(-duration).Format("2006-01-02 15:04:05")
+	return runCommand(context.Background(), w, "journalctl", s.args("--since", since)...)
+}
+
+func (s *journalSource) sinceCheckpoint(w io.Writer, name string) error {
+	checkpointMu.Lock()
+	defer checkpointMu.Unlock()
+
+	path := checkpointPath("journal:"+s.unit, name) + ".cursor"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	return runCommand(context.Background(), w, "journalctl", s.args("--cursor-file", path)...)
+}
+
+func (s *journalSource) args(args ...string) []string {
+	return append([]string{"--unit", s.unit, "--output", "cat", "--no-pager", "--quiet"}, args...)
+}
@@ -0,0 +1,204 @@
+package tail
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"golang.org/x/sync/errgroup"
+)
+
+type (
+	multiSource struct {
+		sources []*fileSource
+		format  string
+	}
+
+	logRecord struct {
+		time time.Time
+		data []byte
+	}
+
+	recordReader struct {
+		reader *bufio.Reader
+		parse  timestampParser
+		tag    func([]byte) []byte
+		next   []byte
+	}
+)
+
+func NewMultiTailHandler(filenames []string, format string) *TailHandler {
+	if len(filenames) == 1 {
+		return NewTailHandler(filenames[0], format)
+	}
+
+	sources := make([]*fileSource, 0, len(filenames))
+	for _, filename := range filenames {
+		sources = append(sources, &fileSource{filename})
+	}
+	return &TailHandler{source: &multiSource{sources, format}, format: format}
+}
+
+func (s *multiSource) tail(w io.Writer, duration time.Duration) error {
+	return s.merge(w, s.format, func(src *fileSource, w io.Writer) error {
+		return src.tail(w, duration)
+	})
+}
+
+func (s *multiSource) window(w io.Writer, format string, duration time.Duration) error {
+	return s.merge(w, format, func(src *fileSource, w io.Writer) error {
+		return src.window(w, format, duration)
+	})
+}
+
+func (s *multiSource) sinceCheckpoint(w io.Writer, name string) error {
+	return s.merge(w, s.format, func(src *fileSource, w io.Writer) error {
+		return src.sinceCheckpoint(w, name)
+	})
+}
+
+func (s *multiSource) merge(w io.Writer, format string, read func(*fileSource, io.Writer) error) error {
+	files := make([]*os.File, len(s.sources))
+	defer func() {
+		for _, file := range files {
+			if file != nil {
+				file.Close()
+				os.Remove(file.Name())
+			}
+		}
+	}()
+
+	eg := &errgroup.Group{}
+	for i, src := range s.sources {
+		file, err := os.CreateTemp("", "pprotein-tail-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		files[i] = file
+
+		src := src
+		eg.Go(func() error {
+			if err := read(src, file); err != nil {
+				return fmt.Errorf("%v: %w", src.filename, err)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	parse, ok := timestampParsers[format]
+	readers := make([]*recordReader, len(files))
+	for i, file := range files {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind: %w", err)
+		}
+		if !ok {
+			if _, err := io.Copy(w, file); err != nil {
+				return fmt.Errorf("failed to copy: %w", err)
+			}
+			continue
+		}
+		readers[i] = &recordReader{reader: bufio.NewReader(file), parse: parse, tag: sourceTagger(format, s.sources[i].filename)}
+	}
+	if !ok {
+		return nil
+	}
+	return mergeRecords(w, readers)
+}
+
+func mergeRecords(w io.Writer, readers []*recordReader) error {
+	heads := make([]*logRecord, len(readers))
+	for i, r := range readers {
+		rec, err := r.read()
+		if err != nil {
+			return err
+		}
+		heads[i] = rec
+	}
+
+	for {
+		min := -1
+		for i, rec := range heads {
+			if rec != nil && (min < 0 || rec.time.Before(heads[min].time)) {
+				min = i
+			}
+		}
+		if min < 0 {
+			return nil
+		}
+
+		if _, err := w.Write(heads[min].data); err != nil {
+			return fmt.Errorf("failed to write: %w", err)
+		}
+		rec, err := readers[min].read()
+		if err != nil {
+			return err
+		}
+		heads[min] = rec
+	}
+}
+
+func (r *recordReader) read() (*logRecord, error) {
+	var rec *logRecord
+	for {
+		line := r.next
+		r.next = nil
+		if line == nil {
+			var err error
+			line, err = r.reader.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("failed to read: %w", err)
+			}
+			if len(line) == 0 {
+				return rec, nil
+			}
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+		}
+
+		ts, ok := r.parse(line)
+		if rec == nil {
+			rec = &logRecord{time: ts, data: r.tag(line)}
+			continue
+		}
+		if ok {
+			r.next = line
+			return rec, nil
+		}
+		rec.data = append(rec.data, line...)
+	}
+}
+
+func sourceTagger(format string, filename string) func([]byte) []byte {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+
+	switch format {
+	case "ltsv":
+		return func(line []byte) []byte {
+			return append(bytes.TrimRight(line, "\r\n"), []byte("\tsource:"+name+"\n")...)
+		}
+	case "json":
+		quoted, _ := json.Marshal(name)
+		return func(line []byte) []byte {
+			trimmed := bytes.TrimLeft(line, " \t")
+			if len(trimmed) < 2 || trimmed[0] != '{' {
+				return line
+			}
+			sep := ","
+			if bytes.HasPrefix(bytes.TrimLeft(trimmed[1:], " \t"), []byte("}")) {
+				sep = ""
+			}
+			return append([]byte(`{"source":`+string(quoted)+sep), trimmed[1:]...)
+		}
+	}
+	return func(line []byte) []byte { return line }
+}
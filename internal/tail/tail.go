@@ -6,7 +6,6 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -14,12 +13,24 @@ import (
 
 type (
 	TailHandler struct {
+		source source
+		format string
+		filter *lineFilter
+	}
+
+	source interface {
+		tail(w io.Writer, duration time.Duration) error
+		window(w io.Writer, format string, duration time.Duration) error
+		sinceCheckpoint(w io.Writer, name string) error
+	}
+
+	fileSource struct {
 		filename string
 	}
 )
 
-func NewTailHandler(filename string) *TailHandler {
-	return &TailHandler{filename}
+func NewTailHandler(filename string, format string) *TailHandler {
+	return &TailHandler{source: &fileSource{filename}, format: format}
 }
 
 func (h *TailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +44,24 @@ func (h *TailHandler) serve(w http.ResponseWriter, r *http.Request) error {
 		seconds = 30
 	}
 
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = h.format
+	}
+
+	filters := []*lineFilter{}
+	if h.filter != nil {
+		filters = append(filters, h.filter)
+	}
+	requested, err := newLineFilter(r.URL.Query().Get("include"), r.URL.Query().Get("exclude"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+	if requested != nil {
+		filters = append(filters, requested)
+	}
+
 	var output io.Writer = w
 	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
 		ew, err := gzip.NewWriterLevel(w, gzip.DefaultCompression)
@@ -45,7 +74,26 @@ func (h *TailHandler) serve(w http.ResponseWriter, r *http.Request) error {
 		w.Header().Set("Content-Encoding", "gzip")
 	}
 
-	if err := h.tail(output, time.Duration(seconds)*time.Second); err != nil {
+	var filtered *filterWriter
+	if len(filters) > 0 {
+		filtered = newFilterWriter(output, format, filters)
+		output = filtered
+	}
+
+	read := func() error { return h.source.tail(output, time.Duration(seconds)*time.Second) }
+	if name := r.URL.Query().Get("checkpoint"); name != "" {
+		read = func() error { return h.source.sinceCheckpoint(output, name) }
+	} else if r.URL.Query().Get("mode") == "window" {
+		read = func() error { return h.source.window(output, format, time.Duration(seconds)*time.Second) }
+	}
+
+	err = read()
+	if filtered != nil {
+		if ferr := filtered.Flush(); err == nil {
+			err = ferr
+		}
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		output.Write([]byte(err.Error()))
 
@@ -58,28 +106,12 @@ func (h *TailHandler) serve(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-func (h *TailHandler) tail(w io.Writer, duration time.Duration) error {
-	file, err := os.Open(h.filename)
-	if err != nil {
-		return fmt.Errorf("failed to open: %w", err)
-	}
-	defer file.Close()
-
-	startPos, err := file.Seek(0, io.SeekEnd)
+func (s *fileSource) tail(w io.Writer, duration time.Duration) error {
+	f, err := newFollower(s.filename)
 	if err != nil {
-		return fmt.Errorf("failed to seek: %w", err)
+		return err
 	}
+	defer f.Close()
 
-	time.Sleep(duration)
-
-	finfo, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat: %w", err)
-	}
-
-	r := io.LimitReader(file, finfo.Size()-startPos)
-	if _, err := io.Copy(w, r); err != nil {
-		return fmt.Errorf("failed to copy: %w", err)
-	}
-	return nil
+	return f.follow(w, duration)
 }
@@ -0,0 +1,185 @@
+package tail
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	timestampParser func(line []byte) (time.Time, bool)
+
+	jsonTimestamp struct {
+		Time      string `json:"time"`
+		TimeLocal string `json:"time_local"`
+		TimeISO   string `json:"time_iso8601"`
+		StartTime string `json:"start_time"`
+		Timestamp string `json:"timestamp"`
+	}
+)
+
+var (
+	timestampParsers = map[string]timestampParser{
+		"ltsv":      ltsvTimestamp,
+		"json":      jsonTimestampOf,
+		"combined":  combinedTimestamp,
+		"slowlog":   slowlogTimestamp,
+		"pgslowlog": pgslowlogTimestamp,
+	}
+
+	accessLogLayouts = []string{
+		time.RFC3339Nano,
+		"02/Jan/2006:15:04:05 -0700",
+	}
+)
+
+func ltsvTimestamp(line []byte) (time.Time, bool) {
+	for _, field := range strings.Split(strings.TrimSpace(string(line)), "\t") {
+		label, value, ok := strings.Cut(field, ":")
+		if ok && (label == "time" || label == "time_local" || label == "time_iso8601") {
+			return parseLayouts(value, accessLogLayouts)
+		}
+	}
+	return time.Time{}, false
+}
+
+func jsonTimestampOf(line []byte) (time.Time, bool) {
+	ts := &jsonTimestamp{}
+	if err := json.Unmarshal(line, ts); err != nil {
+		return time.Time{}, false
+	}
+	for _, value := range []string{ts.Time, ts.TimeLocal, ts.TimeISO, ts.StartTime, ts.Timestamp} {
+		if value != "" {
+			return parseLayouts(value, accessLogLayouts)
+		}
+	}
+	return time.Time{}, false
+}
+
+func combinedTimestamp(line []byte) (time.Time, bool) {
+	start := bytes.IndexByte(line, '[')
+	if start < 0 {
+		return time.Time{}, false
+	}
+	end := bytes.IndexByte(line[start:], ']')
+	if end < 0 {
+		return time.Time{}, false
+	}
+	return parseLayouts(string(line[start+1:start+end]), accessLogLayouts)
+}
+
+func slowlogTimestamp(line []byte) (time.Time, bool) {
+	value, ok := strings.CutPrefix(strings.TrimSpace(string(line)), "# Time: ")
+	if !ok {
+		return time.Time{}, false
+	}
+	if t, ok := parseLayouts(value, []string{time.RFC3339Nano}); ok {
+		return t, true
+	}
+
+	t, err := time.ParseInLocation("060102 15:04:05", strings.Join(strings.Fields(value), " "), time.Local)
+	return t, err == nil
+}
+
+func pgslowlogTimestamp(line []byte) (time.Time, bool) {
+	fields := strings.Fields(string(line))
+	if len(fields) < 3 {
+		return time.Time{}, false
+	}
+	value := strings.Join(fields[:3], " ")
+	return parseLayouts(value, []string{"2006-01-02 15:04:05.999999999 MST", "2006-01-02 15:04:05.999999999 -07"})
+}
+
+func parseLayouts(value string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s *fileSource) window(w io.Writer, format string, duration time.Duration) error {
+	parse, ok := timestampParsers[format]
+	if !ok {
+		return fmt.Errorf("unknown log format: %v", format)
+	}
+
+	file, err := os.Open(s.filename)
+	if err != nil {
+		return fmt.Errorf("failed to open: %w", err)
+	}
+	defer file.Close()
+
+	finfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat: %w", err)
+	}
+	size := finfo.Size()
+	since := time.Now().Add(-duration)
+
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		pos, ts, err := nextTimestamp(file, mid, size, parse)
+		if err != nil {
+			return err
+		}
+		if pos == size || !ts.Before(since) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	start, _, err := nextTimestamp(file, lo, size, parse)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, io.NewSectionReader(file, start, size-start)); err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+	return nil
+}
+
+func nextTimestamp(file *os.File, offset, size int64, parse timestampParser) (int64, time.Time, error) {
+	pos := offset
+	if offset > 0 {
+		pos = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(file, pos, size-pos))
+
+	if offset > 0 {
+		skipped, err := reader.ReadBytes('\n')
+		pos += int64(len(skipped))
+		if err == io.EOF {
+			return size, time.Time{}, nil
+		}
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("failed to read: %w", err)
+		}
+	}
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if ts, ok := parse(line); ok {
+				return pos, ts, nil
+			}
+			pos += int64(len(line))
+		}
+		if err == io.EOF {
+			return size, time.Time{}, nil
+		}
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("failed to read: %w", err)
+		}
+	}
+}
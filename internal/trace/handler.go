@@ -0,0 +1,83 @@
+package trace
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+type (
+	handler struct {
+		opts      *collect.Options
+		collector *collect.Collector
+		processor *processor
+	}
+)
+
+func NewHandler(opts *collect.Options) *handler {
+	return &handler{opts: opts}
+}
+
+func (h *handler) Register(g *echo.Group) error {
+	p := &processor{
+		mu:      &sync.RWMutex{},
+		viewers: map[string]*viewer{},
+	}
+	h.processor = p
+
+	var err error
+	h.collector, err = collect.New(p, h.opts)
+	if err != nil {
+		return fmt.Errorf("failed to initialize collector: %w", err)
+	}
+
+	g.GET("", h.getIndex)
+	g.POST("", h.postIndex)
+	g.Any("/:id/*", h.serveUI)
+
+	h.collector.RegisterHandlers(g)
+
+	return nil
+}
+
+func (h *handler) getIndex(c echo.Context) error {
+	return collect.ServeEntries(c, h.collector)
+}
+
+func (h *handler) postIndex(c echo.Context) error {
+	target := &collect.SnapshotTarget{}
+	if err := c.Bind(target); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to parse request body: %v", err))
+	}
+	if err := h.collector.EnforceQuota(); err != nil {
+		return echo.NewHTTPError(http.StatusInsufficientStorage, err.Error())
+	}
+
+	go func() {
+		if err := h.collector.Collect(target); err != nil {
+			log.Error("[!] collector aborted:", err)
+		}
+	}()
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) serveUI(c echo.Context) error {
+	v, ok := h.processor.viewer(c.Param("id"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("trace is not loaded: %v", c.Param("id")))
+	}
+
+	prefix := strings.TrimSuffix(c.Request().URL.Path, "/"+c.Param("*"))
+
+	req := c.Request().Clone(c.Request().Context())
+	req.URL.Path = "/" + c.Param("*")
+	req.URL.RawPath = ""
+	v.ServeHTTP(c.Response(), req, prefix)
+	return nil
+}
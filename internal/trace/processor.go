@@ -0,0 +1,178 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kaz/pprotein/internal/collect"
+)
+
+type (
+	processor struct {
+		mu      *sync.RWMutex
+		viewers map[string]*viewer
+	}
+
+	viewer struct {
+		cmd     *exec.Cmd
+		proxy   *httputil.ReverseProxy
+		stopped atomic.Bool
+	}
+
+	prefixKey struct{}
+)
+
+var (
+	listeningPattern = regexp.MustCompile(`Trace viewer is listening on (http://\S+)`)
+	absolutePattern  = regexp.MustCompile(`((?:href|src)="|url = ')/`)
+)
+
+func (p *processor) Cacheable() bool {
+	return false
+}
+
+func (p *processor) Process(ctx context.Context, snapshot *collect.Snapshot) (io.ReadCloser, error) {
+	bodyPath, err := snapshot.BodyPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find snapshot body: %w", err)
+	}
+
+	v, err := startViewer(ctx, bodyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if old, ok := p.viewers[snapshot.ID]; ok {
+		old.stop()
+	}
+	p.viewers[snapshot.ID] = v
+	return nil, nil
+}
+
+func (p *processor) Purge(snapshot *collect.Snapshot) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if v, ok := p.viewers[snapshot.ID]; ok {
+		v.stop()
+		delete(p.viewers, snapshot.ID)
+	}
+	return nil
+}
+
+func (p *processor) viewer(id string) (*viewer, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	v, ok := p.viewers[id]
+	return v, ok
+}
+
+func startViewer(ctx context.Context, bodyPath string) (*viewer, error) {
+	cmd := exec.Command("go", "tool", "trace", "-http=127.0.0.1:0", bodyPath)
+	cmd.Env = append(os.Environ(), "BROWSER=true")
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start go tool trace: %w", err)
+	}
+
+	found := make(chan string, 1)
+	output := &bytes.Buffer{}
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if m := listeningPattern.FindStringSubmatch(line); m != nil {
+				found <- m[1]
+				io.Copy(io.Discard, stderr)
+				return
+			}
+			output.WriteString(line + "\n")
+		}
+		close(found)
+	}()
+
+	select {
+	case addr, ok := <-found:
+		if !ok {
+			err := cmd.Wait()
+			return nil, fmt.Errorf("go tool trace aborted: %v: %s", err, strings.TrimSpace(output.String()))
+		}
+
+		target, err := url.Parse(addr)
+		if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, fmt.Errorf("unexpected listen address: %w", err)
+		}
+		v := &viewer{cmd: cmd, proxy: newProxy(target)}
+		go func() {
+			if err := cmd.Wait(); err != nil && !v.stopped.Load() {
+				log.Printf("[!] go tool trace exited: %v", err)
+			}
+		}()
+		return v, nil
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, ctx.Err()
+	}
+}
+
+func newProxy(target *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ModifyResponse = func(res *http.Response) error {
+		if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+			return nil
+		}
+		prefix, _ := res.Request.Context().Value(prefixKey{}).(string)
+		if prefix == "" {
+			return nil
+		}
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		res.Body.Close()
+
+		body = absolutePattern.ReplaceAll(body, []byte("${1}"+prefix+"/"))
+		res.Body = io.NopCloser(bytes.NewReader(body))
+		res.ContentLength = int64(len(body))
+		res.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return nil
+	}
+	return proxy
+}
+
+func (v *viewer) stop() {
+	v.stopped.Store(true)
+	if err := v.cmd.Process.Kill(); err != nil {
+		log.Printf("[!] failed to stop go tool trace: %v", err)
+	}
+}
+
+func (v *viewer) ServeHTTP(w http.ResponseWriter, r *http.Request, prefix string) {
+	v.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), prefixKey{}, prefix)))
+}
@@ -0,0 +1,241 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type (
+	Config struct {
+		Endpoint    string
+		Headers     map[string]string
+		ServiceName string
+	}
+
+	Exporter struct {
+		endpoint string
+		headers  map[string]string
+		service  string
+		client   *http.Client
+
+		queue chan *Span
+	}
+
+	otlpRequest struct {
+		ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   *otlpResource     `json:"resource"`
+		ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []*otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope *otlpScope  `json:"scope"`
+		Spans []*otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceId           string           `json:"traceId"`
+		SpanId            string           `json:"spanId"`
+		ParentSpanId      string           `json:"parentSpanId,omitempty"`
+		Name              string           `json:"name"`
+		Kind              int              `json:"kind"`
+		StartTimeUnixNano string           `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string           `json:"endTimeUnixNano"`
+		Attributes        []*otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus      `json:"status,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string     `json:"key"`
+		Value *otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+const (
+	DefaultServiceName = "pprotein"
+
+	scopeName = "github.com/kaz/pprotein"
+
+	spanKindInternal = 1
+	statusCodeError  = 2
+
+	exportQueue    = 2048
+	exportBatch    = 512
+	exportInterval = 5 * time.Second
+	exportTimeout  = 10 * time.Second
+)
+
+func NewExporter(cfg *Config) (*Exporter, error) {
+	if !strings.HasPrefix(cfg.Endpoint, "http://") && !strings.HasPrefix(cfg.Endpoint, "https://") {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %v", cfg.Endpoint)
+	}
+
+	ex := &Exporter{
+		endpoint: cfg.Endpoint,
+		headers:  cfg.Headers,
+		service:  cfg.ServiceName,
+		client:   &http.Client{Timeout: exportTimeout},
+		queue:    make(chan *Span, exportQueue),
+	}
+	if ex.service == "" {
+		ex.service = DefaultServiceName
+	}
+
+	go ex.run()
+	return ex, nil
+}
+
+func ParseHeaders(raw string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid header: %v", pair)
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers, nil
+}
+
+func (e *Exporter) enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		log.Printf("[!] trace queue is full, dropping span %v", span.Name)
+	}
+}
+
+func (e *Exporter) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatch)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			log.Printf("[!] failed to export %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= exportBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (e *Exporter) send(batch []*Span) error {
+	spans := make([]*otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		spans = append(spans, s.otlp())
+	}
+
+	body, err := json.Marshal(&otlpRequest{ResourceSpans: []*otlpResourceSpans{{
+		Resource: &otlpResource{Attributes: []*otlpAttribute{
+			otlpAttr(String("service.name", e.service)),
+		}},
+		ScopeSpans: []*otlpScopeSpans{{
+			Scope: &otlpScope{Name: scopeName},
+			Spans: spans,
+		}},
+	}}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (s *Span) otlp() *otlpSpan {
+	span := &otlpSpan{
+		TraceId:           hex.EncodeToString(s.TraceId[:]),
+		SpanId:            hex.EncodeToString(s.SpanId[:]),
+		Name:              s.Name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+	}
+	if s.ParentId != [8]byte{} {
+		span.ParentSpanId = hex.EncodeToString(s.ParentId[:])
+	}
+	for _, attr := range s.Attributes {
+		span.Attributes = append(span.Attributes, otlpAttr(attr))
+	}
+	if s.Error != "" {
+		span.Status = &otlpStatus{Code: statusCodeError, Message: s.Error}
+	}
+	return span
+}
+
+func otlpAttr(attr Attribute) *otlpAttribute {
+	value := &otlpValue{}
+	switch v := attr.Value.(type) {
+	case string:
+		value.StringValue = &v
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		value.IntValue = &s
+	case float64:
+		value.DoubleValue = &v
+	case bool:
+		value.BoolValue = &v
+	default:
+		s := fmt.Sprint(v)
+		value.StringValue = &s
+	}
+	return &otlpAttribute{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	Span struct {
+		exporter *Exporter
+
+		TraceId    [16]byte
+		SpanId     [8]byte
+		ParentId   [8]byte
+		Name       string
+		Start      time.Time
+		End        time.Time
+		Attributes []Attribute
+		Error      string
+		once       *sync.Once
+	}
+
+	Attribute struct {
+		Key   string
+		Value any
+	}
+
+	spanKey struct{}
+)
+
+var (
+	mu       = &sync.RWMutex{}
+	exporter *Exporter
+)
+
+func Configure(cfg *Config) error {
+	if cfg == nil || cfg.Endpoint == "" {
+		return nil
+	}
+
+	ex, err := NewExporter(cfg)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	exporter = ex
+	return nil
+}
+
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return exporter != nil
+}
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	mu.RLock()
+	ex := exporter
+	mu.RUnlock()
+
+	if ex == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		exporter:   ex,
+		Name:       name,
+		Start:      time.Now(),
+		Attributes: attrs,
+		once:       &sync.Once{},
+	}
+	if parent := FromContext(ctx); parent != nil {
+		span.TraceId = parent.TraceId
+		span.ParentId = parent.SpanId
+	} else {
+		rand.Read(span.TraceId[:])
+	}
+	rand.Read(span.SpanId[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func Inject(ctx context.Context, header http.Header) {
+	if span := FromContext(ctx); span != nil {
+		header.Set("traceparent", fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(span.TraceId[:]), hex.EncodeToString(span.SpanId[:])))
+	}
+}
+
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.Attributes = append(s.Attributes, attrs...)
+}
+
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		s.End = time.Now()
+		if err != nil {
+			s.Error = strings.TrimSpace(err.Error())
+		}
+		s.exporter.enqueue(s)
+	})
+}
@@ -0,0 +1,363 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/collect/run"
+	"github.com/kaz/pprotein/internal/persistent"
+	"github.com/kaz/pprotein/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	Dispatcher struct {
+		config    *persistent.Handler
+		validator *validator.Validate
+		client    *http.Client
+
+		mu    *sync.RWMutex
+		hooks []*Webhook
+
+		queue chan *delivery
+
+		runMu    *sync.Mutex
+		runs     *run.Handler
+		baseURL  string
+		settling map[string]*time.Timer
+		reported map[string]bool
+	}
+
+	Webhook struct {
+		Name     string `validate:"required"`
+		URL      string `validate:"required,url"`
+		Secret   string
+		Format   string `validate:"omitempty,oneof=json slack discord"`
+		Events   []string
+		Types    []string
+		Disabled bool
+	}
+
+	publicWebhook struct {
+		*Webhook
+
+		Secret collect.Secret
+	}
+
+	Payload struct {
+		Event     string
+		Timestamp time.Time
+		Webhook   string
+		Type      string
+		Status    collect.Status
+		Message   string
+		Snapshot  *collect.Snapshot
+		Alerts    []*collect.Alert `json:",omitempty"`
+
+		Text string `json:"text"`
+	}
+
+	delivery struct {
+		hook  *Webhook
+		event string
+		body  []byte
+	}
+)
+
+const (
+	EventHeader     = "X-Pprotein-Event"
+	SignatureHeader = "X-Pprotein-Signature"
+
+	EventTest = "webhook.test"
+
+	deliveryQueue    = 256
+	deliveryWorkers  = 2
+	deliveryAttempts = 3
+	deliveryBackoff  = 2 * time.Second
+	deliveryTimeout  = 10 * time.Second
+)
+
+func New(store storage.Storage) (*Dispatcher, error) {
+	d := &Dispatcher{
+		validator: validator.New(),
+		client:    &http.Client{Timeout: deliveryTimeout},
+		mu:        &sync.RWMutex{},
+		queue:     make(chan *delivery, deliveryQueue),
+		runMu:     &sync.Mutex{},
+		settling:  map[string]*time.Timer{},
+		reported:  map[string]bool{},
+	}
+
+	config, err := persistent.New(store, "webhooks.json", []byte("[]"), d.sanitize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhooks: %w", err)
+	}
+	d.config = config
+	d.config.OnUpdate(d.reload)
+	d.reload()
+
+	for i := 0; i < deliveryWorkers; i++ {
+		go d.work()
+	}
+	return d, nil
+}
+
+func (d *Dispatcher) RegisterHandlers(g *echo.Group) {
+	g.GET("", d.getWebhooks)
+	g.POST("", d.config.HandlePost)
+	g.POST("/:name/test", d.postTest)
+}
+
+func (d *Dispatcher) getWebhooks(c echo.Context) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	resp := make([]*publicWebhook, 0, len(d.hooks))
+	for _, hook := range d.hooks {
+		resp = append(resp, &publicWebhook{Webhook: hook, Secret: collect.Secret(hook.Secret)})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (d *Dispatcher) sanitize(raw []byte) ([]byte, error) {
+	hooks := []*Webhook{}
+	if err := json.Unmarshal(raw, &hooks); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	if err := d.validator.Var(hooks, "dive"); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	known := map[string]bool{EventRunCompleted: true}
+	for _, event := range collect.Events {
+		known[event] = true
+	}
+
+	stored := map[string]string{}
+	d.mu.RLock()
+	for _, hook := range d.hooks {
+		stored[hook.Name] = hook.Secret
+	}
+	d.mu.RUnlock()
+
+	seen := map[string]bool{}
+	for _, hook := range hooks {
+		if seen[hook.Name] {
+			return nil, fmt.Errorf("duplicated webhook name: %v", hook.Name)
+		}
+		seen[hook.Name] = true
+
+		if hook.Secret == collect.Redacted {
+			hook.Secret = stored[hook.Name]
+		}
+
+		for _, event := range hook.Events {
+			if !known[event] {
+				return nil, fmt.Errorf("unknown event for %v: %v", hook.Name, event)
+			}
+		}
+	}
+
+	res, err := json.MarshalIndent(hooks, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	return res, nil
+}
+
+func (d *Dispatcher) reload() {
+	raw, err := d.config.GetContent()
+	if err != nil {
+		log.Printf("[!] failed to load webhooks: %v", err)
+		return
+	}
+
+	hooks := []*Webhook{}
+	if err := json.Unmarshal(raw, &hooks); err != nil {
+		log.Printf("[!] failed to load webhooks: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hooks = hooks
+}
+
+func (d *Dispatcher) Notify(event string, entry *collect.Entry) {
+	d.observeRun(event, entry)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, hook := range d.hooks {
+		if hook.matches(event, entry.Snapshot.Type) {
+			d.enqueue(hook, event, entry)
+		}
+	}
+}
+
+func (d *Dispatcher) enqueue(hook *Webhook, event string, entry *collect.Entry) {
+	body, err := entryBody(hook, event, entry)
+	if err != nil {
+		log.Printf("[!] failed to marshal webhook payload: %v", err)
+		return
+	}
+	d.send(&delivery{hook: hook, event: event, body: body})
+}
+
+func (d *Dispatcher) send(dl *delivery) {
+	select {
+	case d.queue <- dl:
+	default:
+		log.Printf("[!] webhook queue is full, dropping %v for %v", dl.event, dl.hook.Name)
+	}
+}
+
+func entryBody(hook *Webhook, event string, entry *collect.Entry) ([]byte, error) {
+	switch hook.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": summary(event, entry)})
+	case FormatDiscord:
+		return json.Marshal(map[string]string{"content": summary(event, entry)})
+	}
+
+	return json.Marshal(&Payload{
+		Event:     event,
+		Timestamp: time.Now(),
+		Webhook:   hook.Name,
+		Type:      entry.Snapshot.Type,
+		Status:    entry.Status,
+		Message:   entry.Message,
+		Snapshot:  entry.Snapshot,
+		Alerts:    entry.Alerts,
+		Text:      summary(event, entry),
+	})
+}
+
+func (d *Dispatcher) work() {
+	for dl := range d.queue {
+		var err error
+		for attempt := 1; attempt <= deliveryAttempts; attempt++ {
+			if err = d.deliver(dl); err == nil {
+				break
+			}
+			if attempt < deliveryAttempts {
+				time.Sleep(deliveryBackoff * time.Duration(attempt))
+			}
+		}
+		if err != nil {
+			log.Printf("[!] failed to deliver %v to webhook %v: %v", dl.event, dl.hook.Name, err)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(dl *delivery) error {
+	req, err := http.NewRequest(http.MethodPost, dl.hook.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, dl.event)
+	if dl.hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(dl.hook.Secret, dl.body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) postTest(c echo.Context) error {
+	d.mu.RLock()
+	var hook *Webhook
+	for _, h := range d.hooks {
+		if h.Name == c.Param("name") {
+			hook = h
+		}
+	}
+	d.mu.RUnlock()
+
+	if hook == nil {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no such webhook: %v", c.Param("name")))
+	}
+
+	text := fmt.Sprintf("[pprotein] test notification for %v", hook.Name)
+	var body []byte
+	var err error
+	switch hook.Format {
+	case FormatSlack:
+		body, err = json.Marshal(map[string]string{"text": text})
+	case FormatDiscord:
+		body, err = json.Marshal(map[string]string{"content": text})
+	default:
+		body, err = json.Marshal(&Payload{Event: EventTest, Timestamp: time.Now(), Webhook: hook.Name, Text: text})
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to marshal payload: %v", err))
+	}
+
+	if err := d.deliver(&delivery{hook: hook, event: EventTest, body: body}); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("failed to deliver: %v", err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *Webhook) matches(event string, typ string) bool {
+	if h.Disabled {
+		return false
+	}
+	return (len(h.Events) == 0 || contains(h.Events, event)) && (typ == "" || len(h.Types) == 0 || contains(h.Types, typ))
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func summary(event string, entry *collect.Entry) string {
+	s := entry.Snapshot
+	label := ""
+	if s.SnapshotTarget != nil {
+		label = s.Label
+	}
+	text := fmt.Sprintf("[pprotein] %s: %s %s (%s)", event, s.Type, label, s.ID)
+	if event == collect.EventAlertTriggered {
+		for _, alert := range entry.Alerts {
+			text += "\n- " + alert.String()
+		}
+		return text
+	}
+	if entry.Message != "" {
+		text += ": " + entry.Message
+	}
+	return text
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/kaz/pprotein/internal/collect"
+	"github.com/kaz/pprotein/internal/collect/run"
+)
+
+type (
+	RunPayload struct {
+		Event     string
+		Timestamp time.Time
+		Webhook   string
+		RunId     string
+		Status    collect.Status
+		Entries   int
+		URL       string
+		Report    *run.Report
+
+		Text string `json:"text"`
+	}
+
+	linkFunc func(label string, url string) string
+)
+
+const (
+	EventRunCompleted = "run.completed"
+
+	FormatJSON    = "json"
+	FormatSlack   = "slack"
+	FormatDiscord = "discord"
+
+	runSettleDelay   = 5 * time.Second
+	runSummaryTop    = 5
+	runReportedLimit = 1024
+	discordLimit     = 2000
+	summaryQueryLen  = 80
+)
+
+func (d *Dispatcher) WatchRuns(runs *run.Handler, baseURL string) {
+	d.runMu.Lock()
+	defer d.runMu.Unlock()
+
+	d.runs = runs
+	d.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+func (d *Dispatcher) observeRun(event string, entry *collect.Entry) {
+	if !finishesEntry(event) || entry.Snapshot.SnapshotTarget == nil || entry.Snapshot.RunId == "" {
+		return
+	}
+	id := entry.Snapshot.RunId
+
+	d.runMu.Lock()
+	defer d.runMu.Unlock()
+
+	if d.runs == nil || d.reported[id] {
+		return
+	}
+	if timer, ok := d.settling[id]; ok {
+		timer.Reset(runSettleDelay)
+		return
+	}
+	d.settling[id] = time.AfterFunc(runSettleDelay, func() { d.completeRun(id) })
+}
+
+func finishesEntry(event string) bool {
+	switch event {
+	case collect.EventCollectionFailed, collect.EventProcessingCompleted, collect.EventProcessingFailed:
+		return true
+	}
+	return false
+}
+
+func (d *Dispatcher) completeRun(id string) {
+	d.runMu.Lock()
+	delete(d.settling, id)
+	runs := d.runs
+	d.runMu.Unlock()
+
+	r, ok := runs.Get(id)
+	if !ok || r.Status == collect.StatusPending {
+		return
+	}
+
+	d.runMu.Lock()
+	if d.reported[id] {
+		d.runMu.Unlock()
+		return
+	}
+	if len(d.reported) >= runReportedLimit {
+		d.reported = map[string]bool{}
+	}
+	d.reported[id] = true
+	d.runMu.Unlock()
+
+	report, err := runs.RunReport(r, runSummaryTop)
+	if err != nil {
+		log.Printf("[!] failed to build report of run %v: %v", id, err)
+		return
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, hook := range d.hooks {
+		if !hook.matches(EventRunCompleted, "") {
+			continue
+		}
+
+		body, err := d.runBody(hook, r, report)
+		if err != nil {
+			log.Printf("[!] failed to marshal webhook payload: %v", err)
+			continue
+		}
+		d.send(&delivery{hook: hook, event: EventRunCompleted, body: body})
+	}
+}
+
+func (d *Dispatcher) runBody(hook *Webhook, r *run.Run, report *run.Report) ([]byte, error) {
+	switch hook.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{
+			"text": d.runSummary(r, report, func(label, url string) string { return fmt.Sprintf("<%s|%s>", url, label) }),
+		})
+	case FormatDiscord:
+		content := d.runSummary(r, report, func(label, url string) string { return fmt.Sprintf("[%s](%s)", label, url) })
+		if len(content) > discordLimit {
+			content = content[:discordLimit-3] + "..."
+		}
+		return json.Marshal(map[string]string{"content": content})
+	}
+
+	return json.Marshal(&RunPayload{
+		Event:     EventRunCompleted,
+		Timestamp: time.Now(),
+		Webhook:   hook.Name,
+		RunId:     r.RunId,
+		Status:    r.Status,
+		Entries:   len(r.Entries),
+		URL:       d.groupURL(r),
+		Report:    report,
+		Text:      d.runSummary(r, report, func(label, url string) string { return fmt.Sprintf("%s (%s)", label, url) }),
+	})
+}
+
+func (d *Dispatcher) runSummary(r *run.Run, report *run.Report, link linkFunc) string {
+	types := map[string]string{}
+	for _, src := range report.Sources {
+		types[src.ID] = src.Type
+	}
+	source := func(id string) string {
+		return link("open", d.snapshotURL(r, types[id], id))
+	}
+
+	lines := []string{fmt.Sprintf("pprotein run %s finished: %s (%d entries) %s", r.RunId, r.Status, len(r.Entries), link("open", d.groupURL(r)))}
+
+	if hints := report.Hints; len(hints) > 0 {
+		if len(hints) > runSummaryTop {
+			hints = hints[:runSummaryTop]
+		}
+		lines = append(lines, "", "Hints:")
+		for i, hint := range hints {
+			lines = append(lines, fmt.Sprintf("%d. %s: %s %s", i+1, hint.Message, hint.Suggestion, source(hint.Source)))
+		}
+	}
+	if len(report.Endpoints) > 0 {
+		lines = append(lines, "", "Top endpoints:")
+		for i, ep := range report.Endpoints {
+			lines = append(lines, fmt.Sprintf("%d. `%s %s` sum=%.3f count=%.0f avg=%.3f %s", i+1, ep.Method, ep.Uri, ep.Sum, ep.Count, ep.Avg, source(ep.Source)))
+		}
+	}
+	if len(report.Functions) > 0 {
+		lines = append(lines, "", "Top functions:")
+		for i, fn := range report.Functions {
+			lines = append(lines, fmt.Sprintf("%d. `%s` flat=%s cum=%s %s", i+1, fn.Name, formatValue(fn.Flat, fn.Unit), formatValue(fn.Cum, fn.Unit), source(fn.Source)))
+		}
+	}
+	if len(report.Queries) > 0 {
+		lines = append(lines, "", "Top slow queries:")
+		for i, q := range report.Queries {
+			query := q.Query
+			if len(query) > summaryQueryLen {
+				query = query[:summaryQueryLen] + "..."
+			}
+			lines = append(lines, fmt.Sprintf("%d. `%s` sum=%.3f count=%.0f %s", i+1, query, q.Sum, q.Count, source(q.Source)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d *Dispatcher) groupURL(r *run.Run) string {
+	return fmt.Sprintf("%s/#/group/%s/", d.baseURL, runGroupId(r))
+}
+
+func (d *Dispatcher) snapshotURL(r *run.Run, typ string, id string) string {
+	return fmt.Sprintf("%s/#/group/%s/%s/%s/", d.baseURL, runGroupId(r), typ, id)
+}
+
+func runGroupId(r *run.Run) string {
+	for _, ent := range r.Entries {
+		if ent.Snapshot.SnapshotTarget != nil && ent.Snapshot.GroupId != "" {
+			return ent.Snapshot.GroupId
+		}
+	}
+	return r.RunId
+}
+
+func formatValue(v int64, unit string) string {
+	if unit == "nanoseconds" {
+		return time.Duration(v).String()
+	}
+	if unit == "" {
+		return fmt.Sprintf("%d", v)
+	}
+	return fmt.Sprintf("%d %s", v, unit)
+}
@@ -1,12 +1,19 @@
 import { createRouter, createWebHashHistory } from "vue-router";
 import EntryList from "./components/EntryList.vue";
+import GoroutineEntry from "./components/GoroutineEntry.vue";
 import GroupEntry from "./components/GroupEntry.vue";
 import GroupIndex from "./components/GroupIndex.vue";
 import GroupList from "./components/GroupList.vue";
+import HeapDeltaEntry from "./components/HeapDeltaEntry.vue";
 import HttpLogEntry from "./components/HttpLogEntry.vue";
 import PProfEntry from "./components/PProfEntry.vue";
+import RuntimeEntry from "./components/RuntimeEntry.vue";
+import ScoreList from "./components/ScoreList.vue";
 import SettingList from "./components/SettingList.vue";
 import SlowLogEntry from "./components/SlowLogEntry.vue";
+import SystemEntry from "./components/SystemEntry.vue";
+import TableDiffEntry from "./components/TableDiffEntry.vue";
+import TraceEntry from "./components/TraceEntry.vue";
 import MemoEntry from "./components/MemoEntry.vue";
 
 export default createRouter({
@@ -41,6 +48,63 @@ export default createRouter({
             title: "pprof:{{id}} | group:{{gid}}",
           },
         },
+        {
+          path: "fgprof/:id/",
+          component: PProfEntry,
+          meta: {
+            title: "fgprof:{{id}} | group:{{gid}}",
+          },
+          props: {
+            endpoint: "fgprof",
+          },
+        },
+        {
+          path: "block/:id/",
+          component: PProfEntry,
+          meta: {
+            title: "block:{{id}} | group:{{gid}}",
+          },
+          props: {
+            endpoint: "block",
+          },
+        },
+        {
+          path: "mutex/:id/",
+          component: PProfEntry,
+          meta: {
+            title: "mutex:{{id}} | group:{{gid}}",
+          },
+          props: {
+            endpoint: "mutex",
+          },
+        },
+        {
+          path: "java/:id/",
+          component: PProfEntry,
+          meta: {
+            title: "java:{{id}} | group:{{gid}}",
+          },
+          props: {
+            endpoint: "java",
+          },
+        },
+        {
+          path: "node/:id/",
+          component: PProfEntry,
+          meta: {
+            title: "node:{{id}} | group:{{gid}}",
+          },
+          props: {
+            endpoint: "node",
+          },
+        },
+        {
+          path: "trace/:id/",
+          component: TraceEntry,
+          meta: {
+            title: "trace:{{id}} | group:{{gid}}",
+          },
+        },
         {
           path: "httplog/:id/",
           component: HttpLogEntry,
@@ -55,6 +119,37 @@ export default createRouter({
             title: "slowlog:{{id}} | group:{{gid}}",
           },
         },
+        {
+          path: "pgslowlog/:id/",
+          component: SlowLogEntry,
+          meta: {
+            title: "pgslowlog:{{id}} | group:{{gid}}",
+          },
+          props: {
+            endpoint: "pgslowlog",
+          },
+        },
+        {
+          path: "goroutine/:id/",
+          component: GoroutineEntry,
+          meta: {
+            title: "goroutine:{{id}} | group:{{gid}}",
+          },
+        },
+        {
+          path: "runtime/:id/",
+          component: RuntimeEntry,
+          meta: {
+            title: "runtime:{{id}} | group:{{gid}}",
+          },
+        },
+        {
+          path: "system/:id/",
+          component: SystemEntry,
+          meta: {
+            title: "system:{{id}} | group:{{gid}}",
+          },
+        },
         {
           path: "memo/:id/",
           component: MemoEntry,
@@ -74,6 +169,13 @@ export default createRouter({
         endpoint: "pprof",
       },
     },
+    {
+      path: "/pprof/delta/:base/:target/",
+      component: HeapDeltaEntry,
+      meta: {
+        title: "pprof:{{base}}..{{target}}",
+      },
+    },
     {
       path: "/pprof/:id/",
       component: PProfEntry,
@@ -81,6 +183,123 @@ export default createRouter({
         title: "pprof:{{id}}",
       },
     },
+    {
+      path: "/fgprof/",
+      component: EntryList,
+      meta: {
+        title: "fgprof",
+      },
+      props: {
+        endpoint: "fgprof",
+      },
+    },
+    {
+      path: "/fgprof/:id/",
+      component: PProfEntry,
+      meta: {
+        title: "fgprof:{{id}}",
+      },
+      props: {
+        endpoint: "fgprof",
+      },
+    },
+    {
+      path: "/block/",
+      component: EntryList,
+      meta: {
+        title: "block",
+      },
+      props: {
+        endpoint: "block",
+      },
+    },
+    {
+      path: "/block/:id/",
+      component: PProfEntry,
+      meta: {
+        title: "block:{{id}}",
+      },
+      props: {
+        endpoint: "block",
+      },
+    },
+    {
+      path: "/mutex/",
+      component: EntryList,
+      meta: {
+        title: "mutex",
+      },
+      props: {
+        endpoint: "mutex",
+      },
+    },
+    {
+      path: "/mutex/:id/",
+      component: PProfEntry,
+      meta: {
+        title: "mutex:{{id}}",
+      },
+      props: {
+        endpoint: "mutex",
+      },
+    },
+    {
+      path: "/java/",
+      component: EntryList,
+      meta: {
+        title: "java",
+      },
+      props: {
+        endpoint: "java",
+      },
+    },
+    {
+      path: "/java/:id/",
+      component: PProfEntry,
+      meta: {
+        title: "java:{{id}}",
+      },
+      props: {
+        endpoint: "java",
+      },
+    },
+    {
+      path: "/node/",
+      component: EntryList,
+      meta: {
+        title: "node",
+      },
+      props: {
+        endpoint: "node",
+      },
+    },
+    {
+      path: "/node/:id/",
+      component: PProfEntry,
+      meta: {
+        title: "node:{{id}}",
+      },
+      props: {
+        endpoint: "node",
+      },
+    },
+    {
+      path: "/trace/",
+      component: EntryList,
+      meta: {
+        title: "trace",
+      },
+      props: {
+        endpoint: "trace",
+      },
+    },
+    {
+      path: "/trace/:id/",
+      component: TraceEntry,
+      meta: {
+        title: "trace:{{id}}",
+      },
+    },
     {
       path: "/httplog/",
       component: EntryList,
@@ -91,6 +310,16 @@ export default createRouter({
         endpoint: "httplog",
       },
     },
+    {
+      path: "/httplog/diff/:base/:target/",
+      component: TableDiffEntry,
+      meta: {
+        title: "httplog:{{base}}..{{target}}",
+      },
+      props: {
+        endpoint: "httplog",
+      },
+    },
     {
       path: "/httplog/:id/",
       component: HttpLogEntry,
@@ -108,6 +337,16 @@ export default createRouter({
         endpoint: "slowlog",
       },
     },
+    {
+      path: "/slowlog/diff/:base/:target/",
+      component: TableDiffEntry,
+      meta: {
+        title: "slowlog:{{base}}..{{target}}",
+      },
+      props: {
+        endpoint: "slowlog",
+      },
+    },
     {
       path: "/slowlog/:id/",
       component: SlowLogEntry,
@@ -115,6 +354,94 @@ export default createRouter({
         title: "slowlog:{{id}}",
       },
     },
+    {
+      path: "/pgslowlog/",
+      component: EntryList,
+      meta: {
+        title: "pgslowlog",
+      },
+      props: {
+        endpoint: "pgslowlog",
+      },
+    },
+    {
+      path: "/pgslowlog/diff/:base/:target/",
+      component: TableDiffEntry,
+      meta: {
+        title: "pgslowlog:{{base}}..{{target}}",
+      },
+      props: {
+        endpoint: "pgslowlog",
+      },
+    },
+    {
+      path: "/pgslowlog/:id/",
+      component: SlowLogEntry,
+      meta: {
+        title: "pgslowlog:{{id}}",
+      },
+      props: {
+        endpoint: "pgslowlog",
+      },
+    },
+    {
+      path: "/goroutine/",
+      component: EntryList,
+      meta: {
+        title: "goroutine",
+      },
+      props: {
+        endpoint: "goroutine",
+      },
+    },
+    {
+      path: "/goroutine/:id/",
+      component: GoroutineEntry,
+      meta: {
+        title: "goroutine:{{id}}",
+      },
+    },
+    {
+      path: "/runtime/",
+      component: EntryList,
+      meta: {
+        title: "runtime",
+      },
+      props: {
+        endpoint: "runtime",
+      },
+    },
+    {
+      path: "/runtime/:id/",
+      component: RuntimeEntry,
+      meta: {
+        title: "runtime:{{id}}",
+      },
+    },
+    {
+      path: "/system/",
+      component: EntryList,
+      meta: {
+        title: "system",
+      },
+      props: {
+        endpoint: "system",
+      },
+    },
+    {
+      path: "/system/:id/",
+      component: SystemEntry,
+      meta: {
+        title: "system:{{id}}",
+      },
+    },
+    {
+      path: "/score/",
+      component: ScoreList,
+      meta: {
+        title: "score",
+      },
+    },
     {
       path: "/setting/",
       component: SettingList,
@@ -1,24 +1,73 @@
 import { createStore, Store } from "vuex";
 
-export type StatusText = "ok" | "fail" | "pending";
+export type StatusText = "ok" | "fail" | "pending" | "deleted" | "corrupt";
 
 export interface Entry {
   Status: StatusText;
   Message: string;
+  Progress?: Progress;
+  Alerts?: Alert[];
   Snapshot: SnapshotMeta & SnapshotTarget;
 }
 
+export interface EntryDelta {
+  Action: "created" | "updated" | "deleted";
+  ID: string;
+  Type: string;
+  Snapshot?: SnapshotMeta & SnapshotTarget;
+  Status: StatusText;
+  Message: string;
+  Progress?: Progress;
+  Alerts?: Alert[];
+}
+
+export interface Alert {
+  Rule: string;
+  Subject: string;
+  Metric: string;
+  Value: number;
+  Threshold: number;
+}
+
+export interface Progress {
+  Bytes: number;
+  Elapsed: number;
+  Remaining: number;
+}
+
 interface SnapshotMeta {
   Type: string;
   ID: string;
   Datetime: Date;
   Repository?: RepositoryInfo;
+  Hash?: string;
+  DuplicateOf?: string;
+  Pinned?: boolean;
 }
 export interface SnapshotTarget {
   GroupId: string;
+  RunId?: string;
+  ScheduleId?: string;
   Label: string;
+  Tags?: { [key: string]: string };
   URL: string;
+  URLs?: string[];
   Duration: number;
+  Headers?: { [key: string]: string };
+  BasicAuth?: { Username: string; Password: string };
+  BearerToken?: string;
+  Client?: ClientOptions;
+  ProcessorOptions?: { [key: string]: unknown };
+}
+
+export interface ClientOptions {
+  Timeout?: number;
+  InsecureSkipVerify?: boolean;
+  CAFile?: string;
+  Proxy?: string;
+  DisableKeepAlives?: boolean;
+  MaxIdleConns?: number;
+  IdleConnTimeout?: number;
 }
 
 export interface RepositoryInfo {
@@ -29,6 +78,11 @@ export interface RepositoryInfo {
   Remote: string;
 }
 
+export interface Environments {
+  Active: string;
+  Names: string[];
+}
+
 interface SettingRecord {
   key: string;
   value: string;
@@ -39,19 +93,44 @@ export interface Config extends Omit<SnapshotTarget, "GroupId"> {
 }
 
 const state = {
-  endpoints: ["memo", "pprof", "httplog", "slowlog"],
+  endpoints: [
+    "memo",
+    "pprof",
+    "fgprof",
+    "block",
+    "mutex",
+    "java",
+    "node",
+    "trace",
+    "httplog",
+    "slowlog",
+    "pgslowlog",
+    "goroutine",
+    "runtime",
+    "system",
+  ],
   groups: [] as string[],
   entries: {} as { [key: string]: Entry },
 
-  settingKeys: ["group/targets", "httplog/config", "slowlog/config"],
+  settingKeys: [
+    "group/environments",
+    "group/targets",
+    "group/schedules",
+    "httplog/config",
+    "slowlog/config",
+    "webhooks",
+    "exporters",
+    "alerts",
+    "types",
+  ],
   settings: {} as { [key: string]: SettingRecord },
 };
 
 const syncEntriesPlugin = (store: Store<typeof state>) => {
-  const es = new EventSource("/api/event");
-  es.addEventListener("message", ({ data }) => {
-    const entry = JSON.parse(data) as Entry;
-    store.commit("saveEntry", entry);
+  const es = new EventSource("/api/event?format=delta");
+  es.addEventListener("entry", ({ data }) => {
+    const delta = JSON.parse(data) as EntryDelta;
+    store.commit("applyDelta", delta);
   });
 
   store.state.endpoints.forEach((endpoint) => {
@@ -60,18 +139,8 @@ const syncEntriesPlugin = (store: Store<typeof state>) => {
 };
 
 const syncSettingsPlugin = (store: Store<typeof state>) => {
-  store.state.settingKeys.forEach(async (key) => {
-    const resp = await fetch(`/api/${key}`);
-    if (!resp.ok) {
-      return alert(
-        `http error: status=${resp.status}, message=${await resp.text()}`
-      );
-    }
-
-    store.commit("saveSetting", {
-      key,
-      value: await resp.text(),
-    } as SettingRecord);
+  store.state.settingKeys.forEach((key) => {
+    store.dispatch("fetchSetting", { key });
   });
 };
 
@@ -80,6 +149,11 @@ export default createStore({
   plugins: [syncEntriesPlugin, syncSettingsPlugin],
   mutations: {
     saveEntry(state, entry: Entry) {
+      if (entry.Status == "deleted") {
+        delete state.entries[entry.Snapshot.ID];
+        return;
+      }
+
       entry.Snapshot.Datetime = new Date(entry.Snapshot.Datetime);
       state.entries[entry.Snapshot.ID] = entry;
 
@@ -91,6 +165,30 @@ export default createStore({
         state.groups.sort((a, b) => b.localeCompare(a));
       }
     },
+    applyDelta(state, delta: EntryDelta) {
+      if (delta.Action == "deleted") {
+        delete state.entries[delta.ID];
+        return;
+      }
+
+      const snapshot = delta.Snapshot || state.entries[delta.ID]?.Snapshot;
+      if (!snapshot) {
+        return;
+      }
+      snapshot.Datetime = new Date(snapshot.Datetime);
+      state.entries[delta.ID] = {
+        Snapshot: snapshot,
+        Status: delta.Status,
+        Message: delta.Message,
+        Progress: delta.Progress,
+        Alerts: delta.Alerts,
+      };
+
+      if (snapshot.GroupId && !state.groups.includes(snapshot.GroupId)) {
+        state.groups.push(snapshot.GroupId);
+        state.groups.sort((a, b) => b.localeCompare(a));
+      }
+    },
     saveSetting(state, record: SettingRecord) {
       state.settings[record.key] = record;
     },
@@ -113,6 +211,38 @@ export default createStore({
         return alert(e);
       }
     },
+    async fetchSetting(store, { key }: { key: string }) {
+      try {
+        const resp = await fetch(`/api/${key}`);
+        if (!resp.ok) {
+          return alert(
+            `http error: status=${resp.status}, message=${await resp.text()}`
+          );
+        }
+
+        store.commit("saveSetting", {
+          key,
+          value: await resp.text(),
+        } as SettingRecord);
+      } catch (e) {
+        return alert(e);
+      }
+    },
+    async switchEnvironment(store, { name }: { name: string }) {
+      const environments = store.getters.environments;
+      if (!environments) {
+        return;
+      }
+
+      await store.dispatch("updateSetting", {
+        key: "group/environments",
+        value: JSON.stringify({ ...environments, Active: name }, null, 2),
+      } as SettingRecord);
+
+      ["group/targets", "group/schedules"].forEach((key) => {
+        store.dispatch("fetchSetting", { key });
+      });
+    },
     async updateSetting(store, { key, value }: SettingRecord) {
       try {
         const resp = await fetch(`/api/${key}`, {
@@ -132,11 +262,19 @@ export default createStore({
     },
   },
   getters: {
+    environments: (state) => {
+      const record = state.settings["group/environments"];
+      if (!record) {
+        return null;
+      }
+      return JSON.parse(record.value) as Environments;
+    },
     entriesByType: (state) => (snapshotType: string) => {
       return Object.values(state.entries)
         .filter((e) => e.Snapshot.Type == snapshotType)
         .sort(
           (a, b) =>
+            Number(!!b.Snapshot.Pinned) - Number(!!a.Snapshot.Pinned) ||
             b.Snapshot.Datetime.getTime() - a.Snapshot.Datetime.getTime()
         );
     },